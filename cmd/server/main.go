@@ -2,15 +2,37 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
-
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anderson-ufrj/mcp-brasil/pkg/apierror"
 	"github.com/anderson-ufrj/mcp-brasil/pkg/bcb"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/cep"
 	"github.com/anderson-ufrj/mcp-brasil/pkg/cnpj"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/diskcache"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/dossier"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/httplog"
 	"github.com/anderson-ufrj/mcp-brasil/pkg/ibge"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/metrics"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/money"
 	"github.com/anderson-ufrj/mcp-brasil/pkg/pncp"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/timeout"
 	"github.com/anderson-ufrj/mcp-brasil/pkg/transparencia"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -22,28 +44,95 @@ var (
 	cnpjClient          *cnpj.Client
 	bcbClient           *bcb.Client
 	pncpClient          *pncp.Client
+	cepClient           *cep.Client
+)
+
+const (
+	serverName    = "MCP Brasil"
+	serverVersion = "2.0.0"
 )
 
+// buildDate is injected at build time with
+// -ldflags "-X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)".
+var buildDate string
+
+// exportEnabled reports whether an export directory is configured, which
+// export_contracts requires.
+var exportEnabled bool
+
 func main() {
+	transport := flag.String("transport", envOrDefault("MCP_TRANSPORT", "stdio"), "Transport: stdio, sse or http")
+	addr := flag.String("addr", envOrDefault("MCP_ADDR", ":8080"), "Listen address for the sse and http transports")
+	debug := flag.Bool("debug", os.Getenv("MCP_DEBUG") != "", "Log upstream requests to stderr")
+	cacheDir := flag.String("cache-dir", os.Getenv("MCP_CACHE_DIR"), "Directory caching static IBGE localities on disk (disabled if empty)")
+	cacheMaxAge := flag.Duration("cache-max-age", diskcache.DefaultMaxAge, "How long disk cache entries stay fresh")
+	dedupe := flag.Bool("dedupe", os.Getenv("MCP_DEDUPE") != "", "Share one upstream call among concurrent identical requests")
+	metricsAddr := flag.String("metrics-addr", os.Getenv("MCP_METRICS_ADDR"), "Listen address serving Prometheus metrics at /metrics (disabled if empty)")
+	exportDir := flag.String("export-dir", os.Getenv("MCP_EXPORT_DIR"), "Directory export_contracts writes into (the tool is disabled if empty)")
+	flag.Parse()
+
 	// Get API key from environment
 	apiKey := os.Getenv("TRANSPARENCY_API_KEY")
 	if apiKey == "" {
 		fmt.Fprintln(os.Stderr, "Warning: TRANSPARENCY_API_KEY not set, some features may not work")
 	}
 
+	pageSize, err := parseDefaultPageSize(os.Getenv("MCP_DEFAULT_PAGE_SIZE"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v, using per-tool defaults\n", err)
+	}
+	defaultPageSize = pageSize
+
+	logger := httplog.Discard
+	if *debug {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+
+	var collector metrics.Collector
+	if *metricsAddr != "" {
+		registry := metrics.NewRegistry()
+		collector = registry
+		go serveMetrics(registry, *metricsAddr)
+	}
+
 	// Initialize clients
-	transparenciaClient = transparencia.NewClient(apiKey)
-	ibgeClient = ibge.NewClient()
-	cnpjClient = cnpj.NewClient()
-	bcbClient = bcb.NewClient()
-	pncpClient = pncp.NewClient()
+	transparenciaOpts := []transparencia.Option{transparencia.WithLogger(logger), transparencia.WithMetrics(collector)}
+	ibgeOpts := []ibge.Option{ibge.WithLogger(logger), ibge.WithMetrics(collector)}
+	cnpjOpts := []cnpj.Option{cnpj.WithLogger(logger), cnpj.WithMetrics(collector)}
+	bcbOpts := []bcb.Option{bcb.WithLogger(logger), bcb.WithMetrics(collector)}
+	pncpOpts := []pncp.Option{pncp.WithLogger(logger), pncp.WithMetrics(collector)}
+	cepOpts := []cep.Option{cep.WithLogger(logger), cep.WithMetrics(collector)}
+	if *cacheDir != "" {
+		ibgeOpts = append(ibgeOpts, ibge.WithDiskCache(*cacheDir), ibge.WithCacheMaxAge(*cacheMaxAge))
+	}
+	if *exportDir != "" {
+		transparenciaOpts = append(transparenciaOpts, transparencia.WithExportDir(*exportDir))
+		exportEnabled = true
+	}
+	if *dedupe {
+		transparenciaOpts = append(transparenciaOpts, transparencia.WithDeduplication())
+		ibgeOpts = append(ibgeOpts, ibge.WithDeduplication())
+		cnpjOpts = append(cnpjOpts, cnpj.WithDeduplication())
+		bcbOpts = append(bcbOpts, bcb.WithDeduplication())
+		pncpOpts = append(pncpOpts, pncp.WithDeduplication())
+		cepOpts = append(cepOpts, cep.WithDeduplication())
+	}
+	transparenciaClient = transparencia.NewClient(apiKey, transparenciaOpts...)
+	ibgeClient = ibge.NewClient(ibgeOpts...)
+	cnpjClient = cnpj.NewClient(cnpjOpts...)
+	bcbClient = bcb.NewClient(bcbOpts...)
+	pncpClient = pncp.NewClient(pncpOpts...)
+	cepClient = cep.NewClient(cepOpts...)
 
 	// Create MCP server
 	s := server.NewMCPServer(
-		"MCP Brasil",
-		"2.0.0",
+		serverName,
+		serverVersion,
 		server.WithToolCapabilities(false),
 		server.WithResourceCapabilities(true, false),
+		server.WithPromptCapabilities(false),
+		server.WithToolHandlerMiddleware(resultMeta),
+		server.WithToolFilter(advertiseMeta),
 	)
 
 	// Register all tools
@@ -52,17 +141,57 @@ func main() {
 	registerCNPJTools(s)
 	registerBCBTools(s)
 	registerPNCPTools(s)
+	registerCEPTools(s)
+	registerServerTools(s)
 
 	// Register resources
 	registerResources(s)
 
-	// Run server over stdio
-	if err := server.ServeStdio(s); err != nil {
+	// Register prompts
+	registerPrompts(s)
+
+	if err := serve(s, *transport, *addr); err != nil {
 		fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
+// serve runs the server over the selected transport. stdio is the default;
+// sse and http (streamable HTTP) listen on addr for remote clients.
+func serve(s *server.MCPServer, transport, addr string) error {
+	switch transport {
+	case "", "stdio":
+		return server.ServeStdio(s)
+	case "sse":
+		fmt.Fprintf(os.Stderr, "Serving SSE on %s\n", addr)
+		return server.NewSSEServer(s).Start(addr)
+	case "http":
+		fmt.Fprintf(os.Stderr, "Serving streamable HTTP on %s\n", addr)
+		return server.NewStreamableHTTPServer(s).Start(addr)
+	default:
+		return fmt.Errorf("unknown transport %q: use stdio, sse or http", transport)
+	}
+}
+
+// serveMetrics serves the registry at /metrics on addr.
+func serveMetrics(registry *metrics.Registry, addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", registry.Handler())
+	fmt.Fprintf(os.Stderr, "Serving metrics on %s/metrics\n", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		fmt.Fprintf(os.Stderr, "Metrics server error: %v\n", err)
+	}
+}
+
+// envOrDefault returns the value of the environment variable key, or def if
+// it is unset.
+func envOrDefault(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
 // ==================== PORTAL DA TRANSPARENCIA ====================
 
 func registerTransparenciaTools(s *server.MCPServer) {
@@ -70,16 +199,42 @@ func registerTransparenciaTools(s *server.MCPServer) {
 	s.AddTool(mcp.NewTool("search_contracts",
 		mcp.WithDescription("Search government contracts from Portal da Transparencia"),
 		mcp.WithString("orgao_code", mcp.Description("Organization SIAPE code (e.g. 36000 for Ministry of Health)")),
+		mcp.WithString("start_date", mcp.Description("Start of vigencia window, YYYY-MM-DD (optional)")),
+		mcp.WithString("end_date", mcp.Description("End of vigencia window, YYYY-MM-DD (optional)")),
+		mcp.WithString("supplier_cnpj", mcp.Description("Supplier CNPJ, with or without formatting (optional)")),
 		mcp.WithNumber("page", mcp.Description("Page number (default 1)")),
 		mcp.WithNumber("page_size", mcp.Description("Results per page (max 500)")),
+		withFormat(),
+		withBRLFormat(),
+		withDebugURL(),
 	), handleSearchContracts)
 
+	// export_contracts, only when the server has an export directory
+	if exportEnabled {
+		s.AddTool(mcp.NewTool("export_contracts",
+			mcp.WithDescription("Export all contracts of an organization to a CSV or NDJSON file in the server export directory"),
+			mcp.WithString("orgao_code", mcp.Required(), mcp.Description("Organization SIAPE code (e.g. 36000 for Ministry of Health)")),
+			mcp.WithString("output_path", mcp.Required(), mcp.Description("File name relative to the export directory (must not exist)")),
+			mcp.WithString("format", mcp.Description("File format (default csv)"), mcp.Enum("csv", "ndjson")),
+		), handleExportContracts)
+	}
+
+	// contract_stats
+	s.AddTool(mcp.NewTool("contract_stats",
+		mcp.WithDescription("Get the count, total, average, minimum and maximum contract value of an organization"),
+		mcp.WithString("orgao_code", mcp.Required(), mcp.Description("Organization SIAPE code (e.g. 36000 for Ministry of Health)")),
+		withBRLFormat(),
+	), handleContractStats)
+
 	// search_servidores
 	s.AddTool(mcp.NewTool("search_servidores",
 		mcp.WithDescription("Search federal public servants by name"),
 		mcp.WithString("nome", mcp.Required(), mcp.Description("Name of the public servant")),
 		mcp.WithNumber("page", mcp.Description("Page number")),
 		mcp.WithNumber("page_size", mcp.Description("Results per page")),
+		withMaskCPF(),
+		withFormat(),
+		withDebugURL(),
 	), handleSearchServidores)
 
 	// get_remuneracao
@@ -87,6 +242,7 @@ func registerTransparenciaTools(s *server.MCPServer) {
 		mcp.WithDescription("Get salary data for a public servant by CPF"),
 		mcp.WithString("cpf", mcp.Required(), mcp.Description("CPF (11 digits)")),
 		mcp.WithString("mes_ano", mcp.Description("Month/Year MM/YYYY format")),
+		withMaskCPF(),
 	), handleGetRemuneracao)
 
 	// search_convenios
@@ -95,6 +251,9 @@ func registerTransparenciaTools(s *server.MCPServer) {
 		mcp.WithString("uf", mcp.Description("State code (e.g. MG, SP, RJ)")),
 		mcp.WithNumber("page", mcp.Description("Page number")),
 		mcp.WithNumber("page_size", mcp.Description("Results per page")),
+		withFormat(),
+		withBRLFormat(),
+		withDebugURL(),
 	), handleSearchConvenios)
 
 	// search_ceis
@@ -103,11 +262,134 @@ func registerTransparenciaTools(s *server.MCPServer) {
 		mcp.WithString("cnpj", mcp.Description("Company CNPJ (optional)")),
 		mcp.WithNumber("page", mcp.Description("Page number")),
 		mcp.WithNumber("page_size", mcp.Description("Results per page")),
+		withFormat(),
+		withDebugURL(),
 	), handleSearchCEIS)
 
+	// search_cnep
+	s.AddTool(mcp.NewTool("search_cnep",
+		mcp.WithDescription("Search companies punished under the anti-corruption law (CNEP)"),
+		mcp.WithString("cnpj", mcp.Description("Company CNPJ (optional)")),
+		mcp.WithNumber("page", mcp.Description("Page number")),
+		mcp.WithNumber("page_size", mcp.Description("Results per page")),
+		withFormat(),
+		withDebugURL(),
+	), handleSearchCNEP)
+
+	// search_cepim
+	s.AddTool(mcp.NewTool("search_cepim",
+		mcp.WithDescription("Search non-profit entities impeded from receiving federal transfers (CEPIM)"),
+		mcp.WithString("cnpj", mcp.Description("Entity CNPJ (optional)")),
+		mcp.WithNumber("page", mcp.Description("Page number")),
+		mcp.WithNumber("page_size", mcp.Description("Results per page")),
+		withFormat(),
+		withDebugURL(),
+	), handleSearchCEPIM)
+
+	// screen_company
+	s.AddTool(mcp.NewTool("screen_company",
+		mcp.WithDescription("Check a company against all federal sanction registries (CEIS, CNEP and CEPIM) at once"),
+		mcp.WithString("cnpj", mcp.Required(), mcp.Description("Company CNPJ (14 digits, with or without formatting)")),
+	), handleScreenCompany)
+
+	// transparencia_raw
+	s.AddTool(mcp.NewTool("transparencia_raw",
+		mcp.WithDescription("Call any Portal da Transparencia endpoint and get its raw JSON, for endpoints without a dedicated tool"),
+		mcp.WithString("endpoint", mcp.Required(), mcp.Description("API path starting with / (e.g. /ceis)")),
+		mcp.WithObject("params", mcp.Description("Query parameters as an object of strings (e.g. {\"pagina\": \"1\"})")),
+		withMaskCPF(),
+	), handleTransparenciaRaw)
+
+	// company_dossier
+	s.AddTool(mcp.NewTool("company_dossier",
+		mcp.WithDescription("Build a company dossier from its CNPJ: registration data, federal contracts and sanction status"),
+		mcp.WithString("cnpj", mcp.Required(), mcp.Description("Company CNPJ (14 digits, with or without formatting)")),
+	), handleCompanyDossier)
+
+	// search_despesas
+	s.AddTool(mcp.NewTool("search_despesas",
+		mcp.WithDescription("Search federal expense execution (empenhos, liquidacoes, pagamentos) by organization and year"),
+		mcp.WithString("orgao_code", mcp.Description("Organization SIAPE code (e.g. 36000 for Ministry of Health)")),
+		mcp.WithString("ano", mcp.Description("Year YYYY (default current year)")),
+		mcp.WithNumber("page", mcp.Description("Page number")),
+		mcp.WithNumber("page_size", mcp.Description("Results per page")),
+		withFormat(),
+		withDebugURL(),
+	), handleSearchDespesas)
+
+	// top_favorecidos
+	s.AddTool(mcp.NewTool("top_favorecidos",
+		mcp.WithDescription("Rank the recipients (favorecidos) of an organization's expenses in a year by amount paid"),
+		mcp.WithString("orgao_code", mcp.Description("Organization SIAPE code (default 36000)")),
+		mcp.WithString("ano", mcp.Description("Year YYYY (default current year)")),
+		mcp.WithNumber("limit", mcp.Description("Number of recipients to return (default 20)")),
+		withFormat(),
+		withBRLFormat(),
+	), handleTopFavorecidos)
+
+	// search_viagens
+	s.AddTool(mcp.NewTool("search_viagens",
+		mcp.WithDescription("Search official trips (viagens a servico) of federal public servants"),
+		mcp.WithString("orgao_code", mcp.Description("Organization SIAPE code (e.g. 36000 for Ministry of Health)")),
+		mcp.WithString("start_date", mcp.Description("Departure window start YYYY-MM-DD (default 30 days ago)")),
+		mcp.WithString("end_date", mcp.Description("Departure window end YYYY-MM-DD (default today)")),
+		mcp.WithNumber("page", mcp.Description("Page number")),
+		mcp.WithNumber("page_size", mcp.Description("Results per page")),
+		withFormat(),
+		withDebugURL(),
+	), handleSearchViagens)
+
+	// search_licitacoes
+	s.AddTool(mcp.NewTool("search_licitacoes",
+		mcp.WithDescription("Search bidding processes (licitacoes) of a federal organization"),
+		mcp.WithString("orgao_code", mcp.Description("Organization SIAPE code (e.g. 36000 for Ministry of Health)")),
+		mcp.WithString("start_date", mcp.Description("Opening window start YYYY-MM-DD (default 30 days ago)")),
+		mcp.WithString("end_date", mcp.Description("Opening window end YYYY-MM-DD (default today, window up to one month)")),
+		mcp.WithNumber("page", mcp.Description("Page number")),
+		mcp.WithNumber("page_size", mcp.Description("Results per page")),
+		withFormat(),
+		withDebugURL(),
+	), handleSearchLicitacoes)
+
+	// search_cartoes
+	s.AddTool(mcp.NewTool("search_cartoes",
+		mcp.WithDescription("Search government payment card (CPGF) spending by organization"),
+		mcp.WithString("orgao_code", mcp.Description("Organization SIAPE code (e.g. 36000 for Ministry of Health)")),
+		mcp.WithString("mes_ano_inicio", mcp.Description("First statement month MM/YYYY (default last month)")),
+		mcp.WithString("mes_ano_fim", mcp.Description("Last statement month MM/YYYY (default last month)")),
+		mcp.WithNumber("page", mcp.Description("Page number")),
+		mcp.WithNumber("page_size", mcp.Description("Results per page")),
+		withFormat(),
+		withDebugURL(),
+	), handleSearchCartoes)
+
+	// search_emendas
+	s.AddTool(mcp.NewTool("search_emendas",
+		mcp.WithDescription("Search parliamentary amendments (emendas parlamentares) to the federal budget"),
+		mcp.WithString("ano", mcp.Description("Year YYYY (default current year)")),
+		mcp.WithString("autor", mcp.Description("Author name (optional)")),
+		mcp.WithString("uf", mcp.Description("State code (e.g. MG, SP, RJ) (optional)")),
+		mcp.WithNumber("page", mcp.Description("Page number")),
+		mcp.WithNumber("page_size", mcp.Description("Results per page")),
+		withFormat(),
+		withDebugURL(),
+	), handleSearchEmendas)
+
+	// search_bolsa_familia
+	s.AddTool(mcp.NewTool("search_bolsa_familia",
+		mcp.WithDescription("Get Novo Bolsa Familia disbursement totals for a municipality"),
+		mcp.WithString("codigo_ibge", mcp.Required(), mcp.Description("Municipality IBGE code (7 digits)")),
+		mcp.WithString("mes_ano", mcp.Description("Month/Year MM/YYYY format (default last month)")),
+		mcp.WithNumber("page", mcp.Description("Page number")),
+		mcp.WithNumber("page_size", mcp.Description("Results per page")),
+		withFormat(),
+		withDebugURL(),
+	), handleSearchBolsaFamilia)
+
 	// list_orgaos
 	s.AddTool(mcp.NewTool("list_orgaos",
-		mcp.WithDescription("List known government organization codes (SIAPE)"),
+		mcp.WithDescription("List government organization codes (SIAFI), falling back to a built-in list when the API is unavailable"),
+		withFormat(),
 	), handleListOrgaos)
 }
 
@@ -117,19 +399,83 @@ func registerIBGETools(s *server.MCPServer) {
 	// ibge_states
 	s.AddTool(mcp.NewTool("ibge_states",
 		mcp.WithDescription("List all Brazilian states with their codes and regions"),
+		withFormat(),
 	), handleIBGEStates)
 
+	// ibge_regions
+	s.AddTool(mcp.NewTool("ibge_regions",
+		mcp.WithDescription("List the five Brazilian macro-regions"),
+	), handleIBGERegions)
+
+	// ibge_state
+	s.AddTool(mcp.NewTool("ibge_state",
+		mcp.WithDescription("Get a single Brazilian state by its IBGE code or sigla"),
+		mcp.WithString("state", mcp.Required(), mcp.Description("State code (e.g. 35) or sigla (e.g. SP)")),
+		mcp.WithBoolean("include_area", mcp.Description("Also fetch the territorial area in km² (one more request, default false)")),
+	), handleIBGEState)
+
 	// ibge_municipalities
 	s.AddTool(mcp.NewTool("ibge_municipalities",
 		mcp.WithDescription("List municipalities, optionally filtered by state"),
-		mcp.WithString("state_id", mcp.Description("State ID (e.g. 33 for RJ, 35 for SP). Leave empty for all.")),
+		mcp.WithString("state_id", mcp.Description("State code (e.g. 35), sigla (e.g. SP) or name (e.g. São Paulo). Leave empty for all.")),
+		mcp.WithString("name_contains", mcp.Description("Only municipalities whose name contains this text (case and accent insensitive)")),
+		mcp.WithBoolean("count_only", mcp.Description("Return only the number of municipalities")),
+		withFormat(),
+		withTimeout(),
 	), handleIBGEMunicipalities)
 
+	// ibge_mesoregions
+	s.AddTool(mcp.NewTool("ibge_mesoregions",
+		mcp.WithDescription("List mesoregions (mesorregioes), optionally filtered by state"),
+		mcp.WithString("state_id", mcp.Description("State ID (e.g. 33 for RJ, 35 for SP). Leave empty for all.")),
+		withFormat(),
+	), handleIBGEMesoregions)
+
+	// ibge_microregions
+	s.AddTool(mcp.NewTool("ibge_microregions",
+		mcp.WithDescription("List microregions (microrregioes), optionally filtered by state"),
+		mcp.WithString("state_id", mcp.Description("State ID (e.g. 33 for RJ, 35 for SP). Leave empty for all.")),
+		withFormat(),
+	), handleIBGEMicroregions)
+
+	// ibge_municipality
+	s.AddTool(mcp.NewTool("ibge_municipality",
+		mcp.WithDescription("Get a municipality by its IBGE code, with microregion, mesoregion and state"),
+		mcp.WithString("code", mcp.Required(), mcp.Description("Municipality IBGE code (7 digits, e.g. 3550308 for Sao Paulo)")),
+		mcp.WithBoolean("include_area", mcp.Description("Also fetch the territorial area in km² (one more request, default false)")),
+	), handleIBGEMunicipality)
+
+	// ibge_name_stats
+	s.AddTool(mcp.NewTool("ibge_name_stats",
+		mcp.WithDescription("Get how often a first name was registered per decade (IBGE API de Nomes, Censo 2010)"),
+		mcp.WithString("name", mcp.Required(), mcp.Description("First name (accents are ignored)")),
+		mcp.WithString("uf", mcp.Description("State sigla or code to restrict the count (optional)")),
+	), handleIBGENameStats)
+
+	// ibge_gdp
+	s.AddTool(mcp.NewTool("ibge_gdp",
+		mcp.WithDescription("Get the GDP (PIB) of a municipality for a year"),
+		mcp.WithString("municipality_id", mcp.Required(), mcp.Description("Municipality IBGE code (7 digits)")),
+		mcp.WithString("year", mcp.Description("Year YYYY (default latest available)")),
+	), handleIBGEGDP)
+
 	// ibge_population
 	s.AddTool(mcp.NewTool("ibge_population",
 		mcp.WithDescription("Get population data for Brazil or a specific location"),
-		mcp.WithString("location_id", mcp.Description("Municipality IBGE code (optional)")),
+		mcp.WithString("location_id", mcp.Description("State (2-digit) or municipality (7-digit) IBGE code (optional)")),
 	), handleIBGEPopulation)
+
+	// ibge_census_population
+	s.AddTool(mcp.NewTool("ibge_census_population",
+		mcp.WithDescription("Get the official resident population counted by the 2022 Census (ibge_population returns yearly estimates)"),
+		mcp.WithString("location_id", mcp.Description("IBGE code: 2 digits for a state, 7 for a municipality (empty for Brazil)")),
+	), handleIBGECensusPopulation)
+
+	// ibge_density
+	s.AddTool(mcp.NewTool("ibge_density",
+		mcp.WithDescription("Get the population density (inhabitants per km²) of a state or municipality"),
+		mcp.WithString("localidade_id", mcp.Required(), mcp.Description("State (2-digit) or municipality (7-digit) IBGE code")),
+	), handleIBGEDensity)
 }
 
 // ==================== CNPJ (Minha Receita) ====================
@@ -139,7 +485,19 @@ func registerCNPJTools(s *server.MCPServer) {
 	s.AddTool(mcp.NewTool("lookup_cnpj",
 		mcp.WithDescription("Look up company data by CNPJ. Returns registration info, address, partners (QSA), and economic activity."),
 		mcp.WithString("cnpj", mcp.Required(), mcp.Description("CNPJ (14 digits, with or without formatting)")),
+		mcp.WithBoolean("qsa_only", mcp.Description("Return only the partners (QSA) of the company")),
+		mcp.WithString("fields", mcp.Description("Comma-separated JSON keys to return, e.g. razao_social,situacao_cadastral,uf (default all)")),
+		withFormat(),
 	), handleLookupCNPJ)
+
+	// search_cnpj_by_name
+	s.AddTool(mcp.NewTool("search_cnpj_by_name",
+		mcp.WithDescription("Search companies by name to find their CNPJ (Casa dos Dados open data)"),
+		mcp.WithString("name", mcp.Required(), mcp.Description("Company name or part of it (razão social or nome fantasia)")),
+		mcp.WithString("uf", mcp.Description("State abbreviation to restrict the search (e.g. SP)")),
+		mcp.WithNumber("page", mcp.Description("Page number (default 1, 20 results per page)")),
+		withFormat(),
+	), handleSearchCNPJByName)
 }
 
 // ==================== BANCO CENTRAL ====================
@@ -161,15 +519,90 @@ func registerBCBTools(s *server.MCPServer) {
 	s.AddTool(mcp.NewTool("bcb_exchange_rate",
 		mcp.WithDescription("Get exchange rate for a currency (USD, EUR, etc.)"),
 		mcp.WithString("currency", mcp.Description("Currency code (default USD)")),
-		mcp.WithString("date", mcp.Description("Date in MM-DD-YYYY format (default today)")),
+		mcp.WithString("date", mcp.Description("Date as YYYY-MM-DD, DD/MM/YYYY or MM-DD-YYYY (default today)")),
 	), handleBCBExchangeRate)
 
+	// bcb_exchange_rate_period
+	s.AddTool(mcp.NewTool("bcb_exchange_rate_period",
+		mcp.WithDescription("Get the exchange rate history (PTAX bulletins) for a currency over a date window"),
+		mcp.WithString("currency", mcp.Description("Currency code (default USD)")),
+		mcp.WithString("start_date", mcp.Required(), mcp.Description("Start date as YYYY-MM-DD, DD/MM/YYYY or MM-DD-YYYY")),
+		mcp.WithString("end_date", mcp.Required(), mcp.Description("End date as YYYY-MM-DD, DD/MM/YYYY or MM-DD-YYYY")),
+	), handleBCBExchangeRatePeriod)
+
+	// bcb_exchange_rates
+	s.AddTool(mcp.NewTool("bcb_exchange_rates",
+		mcp.WithDescription("Get exchange rates for several currencies on the same date"),
+		mcp.WithString("currencies", mcp.Required(), mcp.Description("Comma-separated currency codes (e.g. USD,EUR,GBP)")),
+		mcp.WithString("date", mcp.Description("Date as YYYY-MM-DD, DD/MM/YYYY or MM-DD-YYYY (default today)")),
+	), handleBCBExchangeRates)
+
+	// bcb_convert
+	s.AddTool(mcp.NewTool("bcb_convert",
+		mcp.WithDescription("Convert an amount between currencies using PTAX rates (BRL as pivot)"),
+		mcp.WithNumber("amount", mcp.Required(), mcp.Description("Amount to convert")),
+		mcp.WithString("from", mcp.Required(), mcp.Description("Source currency code (e.g. USD, EUR, BRL)")),
+		mcp.WithString("to", mcp.Required(), mcp.Description("Target currency code (e.g. BRL, USD, EUR)")),
+		mcp.WithString("date", mcp.Description("Date as YYYY-MM-DD, DD/MM/YYYY or MM-DD-YYYY (default today)")),
+	), handleBCBConvert)
+
+	// bcb_pix_stats
+	s.AddTool(mcp.NewTool("bcb_pix_stats",
+		mcp.WithDescription("Get PIX transaction statistics from Banco Central"),
+		mcp.WithString("month", mcp.Description("Reference month YYYYMM (default last completed month)")),
+		mcp.WithBoolean("include_raw", mcp.Description("Also return the upstream records the statistics are computed from (default false)")),
+	), handleBCBPIXStats)
+
+	// bcb_focus
+	s.AddTool(mcp.NewTool("bcb_focus",
+		mcp.WithDescription("Get market expectations (Focus report) for an indicator: median, mean and reference year"),
+		mcp.WithString("indicator", mcp.Required(), mcp.Description("Indicator: ipca, selic, pib, cambio, igpm")),
+		mcp.WithNumber("last_n", mcp.Description("Number of survey results (default 10)")),
+	), handleBCBFocus)
+
 	// bcb_indicator
 	s.AddTool(mcp.NewTool("bcb_indicator",
-		mcp.WithDescription("Get any economic indicator: selic, selic_monthly, ipca, igpm, cdi"),
+		mcp.WithDescription("Get any economic indicator: selic (effective daily), selic_meta (COPOM target), selic_monthly, ipca, igpm, cdi"),
 		mcp.WithString("indicator", mcp.Required(), mcp.Description("Indicator name")),
-		mcp.WithNumber("last_n", mcp.Description("Number of data points")),
+		mcp.WithNumber("last_n", mcp.Description("Number of data points (max 3650)")),
+		mcp.WithString("start_date", mcp.Description("Start date DD/MM/YYYY (use with end_date instead of last_n)")),
+		mcp.WithString("end_date", mcp.Description("End date DD/MM/YYYY (default today when start_date is given)")),
 	), handleBCBIndicator)
+
+	// bcb_indicators
+	s.AddTool(mcp.NewTool("bcb_indicators",
+		mcp.WithDescription("Get several economic indicators in one call (e.g. selic,ipca,igpm,cdi)"),
+		mcp.WithString("indicators", mcp.Required(), mcp.Description("Comma-separated indicator names (see bcb_list_indicators)")),
+		mcp.WithNumber("last_n", mcp.Description("Number of data points per indicator (default 30)")),
+	), handleBCBIndicators)
+
+	// bcb_list_indicators
+	s.AddTool(mcp.NewTool("bcb_list_indicators",
+		mcp.WithDescription("List the indicator names accepted by bcb_indicator, with their SGS series codes and descriptions"),
+	), handleBCBListIndicators)
+
+	// bcb_series
+	s.AddTool(mcp.NewTool("bcb_series",
+		mcp.WithDescription("Get any BCB SGS time series by its numeric code (e.g. 24369 for unemployment)"),
+		mcp.WithNumber("series_code", mcp.Required(), mcp.Description("SGS series code")),
+		mcp.WithNumber("last_n", mcp.Description("Number of data points (default 30, max 3650)")),
+	), handleBCBSeries)
+
+	// inflation_adjust
+	s.AddTool(mcp.NewTool("inflation_adjust",
+		mcp.WithDescription("Adjust a monetary amount for inflation (IPCA) between two months"),
+		mcp.WithNumber("amount", mcp.Required(), mcp.Description("Amount in BRL at the 'from' month's prices")),
+		mcp.WithString("from", mcp.Required(), mcp.Description("Starting month MM/YYYY")),
+		mcp.WithString("to", mcp.Required(), mcp.Description("Target month MM/YYYY")),
+	), handleInflationAdjust)
+
+	// bcb_accumulate
+	s.AddTool(mcp.NewTool("bcb_accumulate",
+		mcp.WithDescription("Compute the accumulated SELIC or CDI rate over a period"),
+		mcp.WithString("indicator", mcp.Required(), mcp.Description("Daily indicator: selic or cdi")),
+		mcp.WithString("start_date", mcp.Required(), mcp.Description("Start date DD/MM/YYYY")),
+		mcp.WithString("end_date", mcp.Required(), mcp.Description("End date DD/MM/YYYY")),
+	), handleBCBAccumulate)
 }
 
 // ==================== PNCP ====================
@@ -182,15 +615,110 @@ func registerPNCPTools(s *server.MCPServer) {
 		mcp.WithString("end_date", mcp.Required(), mcp.Description("End date YYYYMMDD format")),
 		mcp.WithString("state", mcp.Description("State code (e.g. SP, RJ)")),
 		mcp.WithNumber("modality", mcp.Description("Procurement modality code (default 6 = pregao eletronico)")),
+		mcp.WithString("modality_name", mcp.Description("Procurement modality name (e.g. pregao_eletronico), alternative to modality")),
+		mcp.WithString("keyword", mcp.Description("Only contracts whose object contains this term (case and accent insensitive)")),
+		mcp.WithNumber("min_value", mcp.Description("Minimum estimated total value in BRL (inclusive)")),
+		mcp.WithNumber("max_value", mcp.Description("Maximum estimated total value in BRL (inclusive)")),
+		mcp.WithString("sort", mcp.Description("Sort order of the returned contracts"), mcp.Enum(pncp.SortValueDesc, pncp.SortValueAsc, pncp.SortDateDesc, pncp.SortDateAsc)),
 		mcp.WithNumber("page", mcp.Description("Page number")),
+		mcp.WithNumber("page_size", mcp.Description("Results per page (default 50, clamped to 10..500)")),
+		withFormat(),
+		withBRLFormat(),
+		withTimeout(),
+		withDebugURL(),
 	), handlePNCPContracts)
 
+	// pncp_contract_detail
+	s.AddTool(mcp.NewTool("pncp_contract_detail",
+		mcp.WithDescription("Get the full record of a PNCP procurement by its control number"),
+		mcp.WithString("numero_controle", mcp.Required(), mcp.Description("numeroControlePNCP (e.g. 00394452000103-1-000123/2024)")),
+	), handlePNCPContractDetail)
+
+	// pncp_contract_items
+	s.AddTool(mcp.NewTool("pncp_contract_items",
+		mcp.WithDescription("List the line items of a PNCP procurement"),
+		mcp.WithString("numero_controle", mcp.Required(), mcp.Description("numeroControlePNCP (e.g. 00394452000103-1-000123/2024)")),
+		withFormat(),
+		withBRLFormat(),
+	), handlePNCPContractItems)
+
+	// pncp_price_registrations
+	s.AddTool(mcp.NewTool("pncp_price_registrations",
+		mcp.WithDescription("Search price registration records (atas de registro de preco) from PNCP"),
+		mcp.WithString("state", mcp.Description("State code (e.g. SP, RJ)")),
+		mcp.WithNumber("page", mcp.Description("Page number")),
+		mcp.WithNumber("page_size", mcp.Description("Results per page (default 50, clamped to 10..500)")),
+		withFormat(),
+		withBRLFormat(),
+		withDebugURL(),
+	), handlePNCPPriceRegistrations)
+
+	// pncp_editais
+	s.AddTool(mcp.NewTool("pncp_editais",
+		mcp.WithDescription("Search open procurement opportunities (editais / avisos de contratacao) from PNCP, i.e. procurements still receiving proposals"),
+		mcp.WithString("start_date", mcp.Description("Earliest proposal closing date, YYYYMMDD format (optional; applied to each page after fetching, as PNCP has no such filter)")),
+		mcp.WithString("end_date", mcp.Required(), mcp.Description("Latest proposal closing date, YYYYMMDD format")),
+		mcp.WithString("state", mcp.Description("State code (e.g. SP, RJ)")),
+		mcp.WithNumber("modality", mcp.Description("Procurement modality code (default: all modalities)")),
+		mcp.WithString("modality_name", mcp.Description("Procurement modality name (e.g. pregao_eletronico), alternative to modality")),
+		mcp.WithNumber("page", mcp.Description("Page number")),
+		mcp.WithNumber("page_size", mcp.Description("Results per page (default 50, clamped to 10..500)")),
+		withFormat(),
+		withBRLFormat(),
+		withDebugURL(),
+	), handlePNCPEditais)
+
+	// pncp_modality_summary
+	s.AddTool(mcp.NewTool("pncp_modality_summary",
+		mcp.WithDescription("Break down procurement spend by modality in a date window: publication count and homologated total per modality"),
+		mcp.WithString("start_date", mcp.Required(), mcp.Description("Start date YYYYMMDD format")),
+		mcp.WithString("end_date", mcp.Required(), mcp.Description("End date YYYYMMDD format")),
+		mcp.WithString("state", mcp.Description("State code (e.g. SP, RJ)")),
+		withFormat(),
+		withBRLFormat(),
+	), handlePNCPModalitySummary)
+
 	// pncp_modalities
 	s.AddTool(mcp.NewTool("pncp_modalities",
 		mcp.WithDescription("List available procurement modality codes for PNCP queries"),
 	), handlePNCPModalities)
 }
 
+// ==================== CEP (ViaCEP) ====================
+
+func registerCEPTools(s *server.MCPServer) {
+	// lookup_cep
+	s.AddTool(mcp.NewTool("lookup_cep",
+		mcp.WithDescription("Look up an address by CEP, including its IBGE municipality code"),
+		mcp.WithString("cep", mcp.Required(), mcp.Description("CEP (8 digits, with or without formatting)")),
+	), handleLookupCEP)
+
+	// lookup_cep_batch
+	s.AddTool(mcp.NewTool("lookup_cep_batch",
+		mcp.WithDescription("Look up several CEPs at once; CEPs that are malformed or not found are reported per entry"),
+		mcp.WithString("ceps", mcp.Required(), mcp.Description("Comma-separated CEPs (up to 100, with or without formatting)")),
+	), handleLookupCEPBatch)
+}
+
+// ==================== SERVER ====================
+
+func registerServerTools(s *server.MCPServer) {
+	// healthcheck
+	s.AddTool(mcp.NewTool("healthcheck",
+		mcp.WithDescription("Check whether each upstream government API is reachable"),
+	), handleHealthcheck)
+
+	// server_info
+	s.AddTool(mcp.NewTool("server_info",
+		mcp.WithDescription("Get the server version and build information"),
+	), handleServerInfo)
+
+	// list_tools
+	s.AddTool(mcp.NewTool("list_tools",
+		mcp.WithDescription("List every tool this server offers with its description and parameter schema"),
+	), handleListTools)
+}
+
 // ==================== RESOURCES ====================
 
 func registerResources(s *server.MCPServer) {
@@ -201,32 +729,254 @@ func registerResources(s *server.MCPServer) {
 		mcp.WithMIMEType("text/markdown"),
 	)
 	s.AddResource(docResource, handleDocResource)
+
+	statesResource := mcp.NewResource(
+		"ibge://states",
+		"IBGE States",
+		mcp.WithResourceDescription("All Brazilian states with IBGE codes and regions"),
+		mcp.WithMIMEType("application/json"),
+	)
+	s.AddResource(statesResource, handleStatesResource)
+}
+
+// ==================== PROMPTS ====================
+
+func registerPrompts(s *server.MCPServer) {
+	s.AddPrompt(mcp.NewPrompt("due_diligence",
+		mcp.WithPromptDescription("Guide a due-diligence review of a company: registration, sanctions and federal contracts"),
+		mcp.WithArgument("cnpj",
+			mcp.ArgumentDescription("Company CNPJ (14 digits, with or without formatting)"),
+			mcp.RequiredArgument(),
+		),
+	), handleDueDiligencePrompt)
+
+	s.AddPrompt(mcp.NewPrompt("economic_snapshot",
+		mcp.WithPromptDescription("Guide an analysis of the Brazilian economy from SELIC, IPCA and the dollar exchange rate"),
+		mcp.WithArgument("period",
+			mcp.ArgumentDescription("Number of months to analyze (default 12, max 60)"),
+		),
+	), handleEconomicSnapshotPrompt)
+}
+
+// ==================== HANDLERS: Server ====================
+
+// healthTimeout bounds each upstream check made by the healthcheck tool.
+const healthTimeout = 5 * time.Second
+
+// SourceHealth is the reachability of one upstream API.
+type SourceHealth struct {
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// HealthReport aggregates the reachability of all upstream APIs. Status is
+// "ok" when every source is reachable and "degraded" otherwise.
+type HealthReport struct {
+	Status  string                  `json:"status"`
+	Sources map[string]SourceHealth `json:"sources"`
+}
+
+// checkHealth pings every source concurrently.
+func checkHealth(ctx context.Context, pings map[string]func(context.Context) error) *HealthReport {
+	report := &HealthReport{Status: "ok", Sources: make(map[string]SourceHealth, len(pings))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, ping := range pings {
+		wg.Add(1)
+		go func(name string, ping func(context.Context) error) {
+			defer wg.Done()
+			pingCtx, cancel := context.WithTimeout(ctx, healthTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := ping(pingCtx)
+			health := SourceHealth{Status: "ok", LatencyMS: time.Since(start).Milliseconds()}
+			if err != nil {
+				health.Status = "error"
+				health.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Sources[name] = health
+			if err != nil {
+				report.Status = "degraded"
+			}
+		}(name, ping)
+	}
+	wg.Wait()
+	return report
+}
+
+func handleHealthcheck(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return toJSONResult(checkHealth(ctx, map[string]func(context.Context) error{
+		"portal_transparencia": transparenciaClient.Ping,
+		"ibge":                 ibgeClient.Ping,
+		"minhareceita":         cnpjClient.Ping,
+		"bcb":                  bcbClient.Ping,
+		"pncp":                 pncpClient.Ping,
+		"viacep":               cepClient.Ping,
+	}))
+}
+
+// ServerInfo describes the running build.
+type ServerInfo struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	GoVersion   string `json:"go_version"`
+	VCSRevision string `json:"vcs_revision,omitempty"`
+	VCSTime     string `json:"vcs_time,omitempty"`
+	VCSModified bool   `json:"vcs_modified,omitempty"`
+	BuildDate   string `json:"build_date,omitempty"`
+}
+
+// serverInfo collects version and VCS metadata from the binary's build info.
+func serverInfo() ServerInfo {
+	info := ServerInfo{
+		Name:      serverName,
+		Version:   serverVersion,
+		GoVersion: runtime.Version(),
+		BuildDate: buildDate,
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.VCSRevision = setting.Value
+			case "vcs.time":
+				info.VCSTime = setting.Value
+			case "vcs.modified":
+				info.VCSModified = setting.Value == "true"
+			}
+		}
+	}
+	return info
+}
+
+func handleServerInfo(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return toJSONResult(serverInfo())
+}
+
+// catalogEntry describes a registered tool in the list_tools catalog.
+type catalogEntry struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	InputSchema mcp.ToolInputSchema `json:"inputSchema"`
+}
+
+// toolCatalog lists the tools registered on s, as a client sees them
+// through tools/list.
+func toolCatalog(ctx context.Context, s *server.MCPServer) ([]catalogEntry, error) {
+	msg := s.HandleMessage(ctx, json.RawMessage(`{"jsonrpc":"2.0","id":"list_tools","method":"tools/list"}`))
+	switch resp := msg.(type) {
+	case mcp.JSONRPCResponse:
+		raw, err := json.Marshal(resp.Result)
+		if err != nil {
+			return nil, err
+		}
+		var result mcp.ListToolsResult
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return nil, fmt.Errorf("parsing tools/list result: %w", err)
+		}
+		catalog := make([]catalogEntry, len(result.Tools))
+		for i, tool := range result.Tools {
+			catalog[i] = catalogEntry{Name: tool.Name, Description: tool.Description, InputSchema: tool.InputSchema}
+		}
+		return catalog, nil
+	case mcp.JSONRPCError:
+		return nil, fmt.Errorf("listing tools: %s", resp.Error.Message)
+	default:
+		return nil, fmt.Errorf("listing tools: unexpected response %T", msg)
+	}
+}
+
+func handleListTools(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s := server.ServerFromContext(ctx)
+	if s == nil {
+		return mcp.NewToolResultError("Error: the tool catalog is only available within a server session"), nil
+	}
+
+	catalog, err := toolCatalog(ctx, s)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toJSONResult(map[string]interface{}{
+		"tools": catalog,
+		"total": len(catalog),
+	})
 }
 
 // ==================== HANDLERS: Portal da Transparencia ====================
 
+func handleExportContracts(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	orgaoCode, err := request.RequireString("orgao_code")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'orgao_code' is required"), nil
+	}
+	outputPath, err := request.RequireString("output_path")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'output_path' is required"), nil
+	}
+	format, _ := request.GetArguments()["format"].(string)
+
+	result, err := transparenciaClient.ExportContracts(ctx, orgaoCode, outputPath, format)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toJSONResult(result)
+}
+
+func handleContractStats(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	orgaoCode, err := request.RequireString("orgao_code")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'orgao_code' is required"), nil
+	}
+
+	result, err := transparenciaClient.AggregateContracts(ctx, orgaoCode)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toFormattedResult(request, result)
+}
+
 func handleSearchContracts(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	orgaoCode, _ := request.GetArguments()["orgao_code"].(string)
+	startDate, _ := request.GetArguments()["start_date"].(string)
+	endDate, _ := request.GetArguments()["end_date"].(string)
+	supplierCNPJ, _ := request.GetArguments()["supplier_cnpj"].(string)
 	page := getIntArg(request, "page", 1)
-	pageSize := getIntArg(request, "page_size", 100)
+	pageSize := pageSizeArg(request, 100)
+
+	if debugURL, _ := request.GetArguments()["debug_url"].(bool); debugURL {
+		return transparenciaDebugURLResult(transparenciaClient.SearchContractsURL(orgaoCode, startDate, endDate, supplierCNPJ, page, pageSize))
+	}
 
-	result, err := transparenciaClient.SearchContracts(ctx, orgaoCode, page, pageSize)
+	result, err := transparenciaClient.SearchContracts(ctx, orgaoCode, startDate, endDate, supplierCNPJ, page, pageSize)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		return toolError(err), nil
 	}
-	return toJSONResult(result)
+	return toFormattedResult(request, result)
 }
 
 func handleSearchServidores(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	nome, _ := request.RequireString("nome")
 	page := getIntArg(request, "page", 1)
-	pageSize := getIntArg(request, "page_size", 100)
+	pageSize := pageSizeArg(request, 100)
+
+	if debugURL, _ := request.GetArguments()["debug_url"].(bool); debugURL {
+		return transparenciaDebugURLResult(transparenciaClient.SearchServidoresURL(nome, page, pageSize))
+	}
 
 	result, err := transparenciaClient.SearchServidores(ctx, nome, page, pageSize)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		return toolError(err), nil
 	}
-	return toJSONResult(result)
+	if maskCPF(request) {
+		result.MaskCPFs()
+	}
+	return toFormattedResult(request, result)
 }
 
 func handleGetRemuneracao(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -235,7 +985,10 @@ func handleGetRemuneracao(ctx context.Context, request mcp.CallToolRequest) (*mc
 
 	result, err := transparenciaClient.GetServidorRemuneracao(ctx, cpf, mesAno)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		return toolError(err), nil
+	}
+	if maskCPF(request) {
+		result.MaskCPF()
 	}
 	return toJSONResult(result)
 }
@@ -243,178 +996,1594 @@ func handleGetRemuneracao(ctx context.Context, request mcp.CallToolRequest) (*mc
 func handleSearchConvenios(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	uf, _ := request.GetArguments()["uf"].(string)
 	page := getIntArg(request, "page", 1)
-	pageSize := getIntArg(request, "page_size", 100)
+	pageSize := pageSizeArg(request, 100)
+
+	if debugURL, _ := request.GetArguments()["debug_url"].(bool); debugURL {
+		return transparenciaDebugURLResult(transparenciaClient.SearchConveniosURL(uf, page, pageSize))
+	}
 
 	result, err := transparenciaClient.SearchConvenios(ctx, uf, page, pageSize)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		return toolError(err), nil
 	}
-	return toJSONResult(result)
+	return toFormattedResult(request, result)
 }
 
 func handleSearchCEIS(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	cnpj, _ := request.GetArguments()["cnpj"].(string)
 	page := getIntArg(request, "page", 1)
-	pageSize := getIntArg(request, "page_size", 100)
+	pageSize := pageSizeArg(request, 100)
+
+	if debugURL, _ := request.GetArguments()["debug_url"].(bool); debugURL {
+		return transparenciaDebugURLResult(transparenciaClient.SearchCEISURL(cnpj, page, pageSize))
+	}
 
 	result, err := transparenciaClient.SearchCEIS(ctx, cnpj, page, pageSize)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		return toolError(err), nil
 	}
-	return toJSONResult(result)
+	return toFormattedResult(request, result)
 }
 
-func handleListOrgaos(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	return toJSONResult(transparenciaClient.ListOrgaos())
-}
+func handleSearchCNEP(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cnpj, _ := request.GetArguments()["cnpj"].(string)
+	page := getIntArg(request, "page", 1)
+	pageSize := pageSizeArg(request, 100)
 
-// ==================== HANDLERS: IBGE ====================
+	if debugURL, _ := request.GetArguments()["debug_url"].(bool); debugURL {
+		return transparenciaDebugURLResult(transparenciaClient.SearchCNEPURL(cnpj, page, pageSize))
+	}
 
-func handleIBGEStates(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	result, err := ibgeClient.GetStates(ctx)
+	result, err := transparenciaClient.SearchCNEP(ctx, cnpj, page, pageSize)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		return toolError(err), nil
 	}
-	return toJSONResult(result)
+	return toFormattedResult(request, result)
 }
 
-func handleIBGEMunicipalities(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	stateID, _ := request.GetArguments()["state_id"].(string)
+func handleSearchCEPIM(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cnpj, _ := request.GetArguments()["cnpj"].(string)
+	page := getIntArg(request, "page", 1)
+	pageSize := pageSizeArg(request, 100)
 
-	result, err := ibgeClient.GetMunicipalities(ctx, stateID)
+	if debugURL, _ := request.GetArguments()["debug_url"].(bool); debugURL {
+		return transparenciaDebugURLResult(transparenciaClient.SearchCEPIMURL(cnpj, page, pageSize))
+	}
+
+	result, err := transparenciaClient.SearchCEPIM(ctx, cnpj, page, pageSize)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		return toolError(err), nil
 	}
-	return toJSONResult(result)
+	return toFormattedResult(request, result)
 }
 
-func handleIBGEPopulation(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	locationID, _ := request.GetArguments()["location_id"].(string)
+func handleScreenCompany(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cnpjNum, err := request.RequireString("cnpj")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'cnpj' is required"), nil
+	}
 
-	result, err := ibgeClient.GetPopulation(ctx, locationID)
+	result, err := transparenciaClient.ScreenCompany(ctx, cnpjNum)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		return toolError(err), nil
 	}
 	return toJSONResult(result)
 }
 
-// ==================== HANDLERS: CNPJ ====================
-
-func handleLookupCNPJ(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func handleCompanyDossier(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	cnpjNum, err := request.RequireString("cnpj")
 	if err != nil {
 		return mcp.NewToolResultError("Parameter 'cnpj' is required"), nil
 	}
 
-	result, err := cnpjClient.GetCNPJ(ctx, cnpjNum)
+	result, err := dossier.NewBuilder(cnpjClient, transparenciaClient).BuildCompanyDossier(ctx, cnpjNum)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		return toolError(err), nil
 	}
 	return toJSONResult(result)
 }
 
-// ==================== HANDLERS: BCB ====================
+func handleTransparenciaRaw(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	endpoint, err := request.RequireString("endpoint")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'endpoint' is required"), nil
+	}
 
-func handleBCBSelic(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	lastN := getIntArg(request, "last_n", 30)
+	params := map[string]string{}
+	if raw, ok := request.GetArguments()["params"].(map[string]interface{}); ok {
+		for key, value := range raw {
+			params[key] = fmt.Sprint(value)
+		}
+	}
 
-	result, err := bcbClient.GetSELIC(ctx, lastN)
+	result, err := transparenciaClient.Query(ctx, endpoint, params)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		return toolError(err), nil
+	}
+	if maskCPF(request) {
+		if result, err = transparencia.MaskCPFsInJSON(result); err != nil {
+			return toolError(err), nil
+		}
 	}
 	return toJSONResult(result)
 }
 
-func handleBCBIPCA(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	lastN := getIntArg(request, "last_n", 12)
+func handleSearchDespesas(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	orgaoCode, _ := request.GetArguments()["orgao_code"].(string)
+	ano, _ := request.GetArguments()["ano"].(string)
+	page := getIntArg(request, "page", 1)
+	pageSize := pageSizeArg(request, 100)
 
-	result, err := bcbClient.GetIPCA(ctx, lastN)
+	if debugURL, _ := request.GetArguments()["debug_url"].(bool); debugURL {
+		return transparenciaDebugURLResult(transparenciaClient.SearchDespesasURL(orgaoCode, ano, page, pageSize))
+	}
+
+	result, err := transparenciaClient.SearchDespesas(ctx, orgaoCode, ano, page, pageSize)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		return toolError(err), nil
 	}
-	return toJSONResult(result)
+	return toFormattedResult(request, result)
 }
 
-func handleBCBExchangeRate(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	currency, _ := request.GetArguments()["currency"].(string)
-	date, _ := request.GetArguments()["date"].(string)
+func handleTopFavorecidos(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	orgaoCode, _ := request.GetArguments()["orgao_code"].(string)
+	ano, _ := request.GetArguments()["ano"].(string)
+	limit := getIntArg(request, "limit", transparencia.DefaultTopFavorecidos)
 
-	result, err := bcbClient.GetExchangeRate(ctx, currency, date)
+	result, err := transparenciaClient.AggregateDespesasByFavorecido(ctx, orgaoCode, ano, limit)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		return toolError(err), nil
 	}
-	return toJSONResult(result)
+	return toFormattedResult(request, result)
 }
 
-func handleBCBIndicator(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	indicator, err := request.RequireString("indicator")
-	if err != nil {
-		return mcp.NewToolResultError("Parameter 'indicator' is required"), nil
+func handleSearchViagens(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	orgaoCode, _ := request.GetArguments()["orgao_code"].(string)
+	startDate, _ := request.GetArguments()["start_date"].(string)
+	endDate, _ := request.GetArguments()["end_date"].(string)
+	page := getIntArg(request, "page", 1)
+	pageSize := pageSizeArg(request, 100)
+
+	if debugURL, _ := request.GetArguments()["debug_url"].(bool); debugURL {
+		return transparenciaDebugURLResult(transparenciaClient.SearchViagensURL(orgaoCode, startDate, endDate, page, pageSize))
 	}
-	lastN := getIntArg(request, "last_n", 30)
 
-	result, err := bcbClient.GetIndicator(ctx, indicator, lastN)
+	result, err := transparenciaClient.SearchViagens(ctx, orgaoCode, startDate, endDate, page, pageSize)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		return toolError(err), nil
 	}
-	return toJSONResult(result)
+	return toFormattedResult(request, result)
 }
 
-// ==================== HANDLERS: PNCP ====================
-
-func handlePNCPContracts(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	startDate, _ := request.RequireString("start_date")
-	endDate, _ := request.RequireString("end_date")
-	state, _ := request.GetArguments()["state"].(string)
-	modality := getIntArg(request, "modality", 6)
+func handleSearchLicitacoes(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	orgaoCode, _ := request.GetArguments()["orgao_code"].(string)
+	startDate, _ := request.GetArguments()["start_date"].(string)
+	endDate, _ := request.GetArguments()["end_date"].(string)
 	page := getIntArg(request, "page", 1)
+	pageSize := pageSizeArg(request, 100)
+
+	if debugURL, _ := request.GetArguments()["debug_url"].(bool); debugURL {
+		return transparenciaDebugURLResult(transparenciaClient.SearchLicitacoesURL(orgaoCode, startDate, endDate, page, pageSize))
+	}
 
-	result, err := pncpClient.SearchContracts(ctx, startDate, endDate, modality, state, page, 50)
+	result, err := transparenciaClient.SearchLicitacoes(ctx, orgaoCode, startDate, endDate, page, pageSize)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		return toolError(err), nil
 	}
-	return toJSONResult(result)
+	return toFormattedResult(request, result)
 }
 
-func handlePNCPModalities(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	return toJSONResult(pncpClient.ListModalities())
-}
+func handleSearchCartoes(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	orgaoCode, _ := request.GetArguments()["orgao_code"].(string)
+	mesAnoInicio, _ := request.GetArguments()["mes_ano_inicio"].(string)
+	mesAnoFim, _ := request.GetArguments()["mes_ano_fim"].(string)
+	page := getIntArg(request, "page", 1)
+	pageSize := pageSizeArg(request, 100)
 
-// ==================== HANDLERS: Resources ====================
+	if debugURL, _ := request.GetArguments()["debug_url"].(bool); debugURL {
+		return transparenciaDebugURLResult(transparenciaClient.SearchCartoesURL(orgaoCode, mesAnoInicio, mesAnoFim, page, pageSize))
+	}
 
-func handleDocResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
-	return []mcp.ResourceContents{
-		mcp.TextResourceContents{
-			URI:      "docs://api-reference",
-			MIMEType: "text/markdown",
-			Text:     getAPIDocumentation(),
-		},
-	}, nil
+	result, err := transparenciaClient.SearchCartoes(ctx, orgaoCode, mesAnoInicio, mesAnoFim, page, pageSize)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toFormattedResult(request, result)
 }
 
-// ==================== HELPERS ====================
+func handleSearchEmendas(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ano, _ := request.GetArguments()["ano"].(string)
+	autor, _ := request.GetArguments()["autor"].(string)
+	uf, _ := request.GetArguments()["uf"].(string)
+	page := getIntArg(request, "page", 1)
+	pageSize := pageSizeArg(request, 100)
 
-func getIntArg(request mcp.CallToolRequest, key string, defaultVal int) int {
-	args := request.GetArguments()
-	if val, ok := args[key].(float64); ok {
-		return int(val)
+	if debugURL, _ := request.GetArguments()["debug_url"].(bool); debugURL {
+		return transparenciaDebugURLResult(transparenciaClient.SearchEmendasURL(ano, autor, uf, page, pageSize))
 	}
-	return defaultVal
-}
 
-func toJSONResult(data interface{}) (*mcp.CallToolResult, error) {
-	jsonBytes, err := json.MarshalIndent(data, "", "  ")
+	result, err := transparenciaClient.SearchEmendas(ctx, ano, autor, uf, page, pageSize)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error encoding result: %v", err)), nil
+		return toolError(err), nil
 	}
-	return mcp.NewToolResultText(string(jsonBytes)), nil
+	return toFormattedResult(request, result)
 }
 
-func getAPIDocumentation() string {
-	return `# MCP Brasil - API Reference v2.0
+func handleSearchBolsaFamilia(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	codigoIbge, err := request.RequireString("codigo_ibge")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'codigo_ibge' is required"), nil
+	}
+	mesAno, _ := request.GetArguments()["mes_ano"].(string)
+	page := getIntArg(request, "page", 1)
+	pageSize := pageSizeArg(request, 100)
 
-## Overview
-This MCP server provides access to multiple Brazilian government data sources.
+	if debugURL, _ := request.GetArguments()["debug_url"].(bool); debugURL {
+		return transparenciaDebugURLResult(transparenciaClient.SearchBolsaFamiliaURL(codigoIbge, mesAno, page, pageSize))
+	}
+
+	result, err := transparenciaClient.SearchBolsaFamilia(ctx, codigoIbge, mesAno, page, pageSize)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toFormattedResult(request, result)
+}
+
+func handleListOrgaos(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return toFormattedResult(request, transparenciaClient.ListOrgaos(ctx))
+}
+
+// ==================== HANDLERS: IBGE ====================
+
+func handleIBGEStates(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	result, err := ibgeClient.GetStates(ctx)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toFormattedResult(request, result)
+}
+
+func handleIBGERegions(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	result, err := ibgeClient.GetRegions(ctx)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toJSONResult(result)
+}
+
+func handleIBGEState(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	state, err := request.RequireString("state")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'state' is required"), nil
+	}
+
+	result, err := ibgeClient.GetState(ctx, state)
+	if err != nil {
+		return toolError(err), nil
+	}
+	if includeArea, _ := request.GetArguments()["include_area"].(bool); includeArea {
+		if err := ibgeClient.FillStateArea(ctx, result); err != nil {
+			return toolError(err), nil
+		}
+	}
+	return toJSONResult(result)
+}
+
+func handleIBGEMunicipalities(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ctx, cancel := callContext(ctx, request)
+	defer cancel()
+
+	stateID, _ := request.GetArguments()["state_id"].(string)
+	nameContains, _ := request.GetArguments()["name_contains"].(string)
+	countOnly, _ := request.GetArguments()["count_only"].(bool)
+
+	if countOnly && nameContains == "" {
+		total, err := ibgeClient.CountMunicipalities(ctx, stateID)
+		if err != nil {
+			return toolError(err), nil
+		}
+		return toJSONResult(map[string]interface{}{
+			"state_id": stateID,
+			"total":    total,
+			"source":   "ibge_api",
+		})
+	}
+
+	result, err := ibgeClient.GetMunicipalities(ctx, stateID)
+	if err != nil {
+		return toolError(err), nil
+	}
+	if nameContains != "" {
+		result.Municipalities = ibge.FilterMunicipalities(result.Municipalities, nameContains)
+		result.Total = len(result.Municipalities)
+	}
+	if countOnly {
+		return toJSONResult(map[string]interface{}{
+			"state_id":      stateID,
+			"name_contains": nameContains,
+			"total":         result.Total,
+			"source":        "ibge_api",
+		})
+	}
+	return toFormattedResult(request, result)
+}
+
+func handleIBGEMesoregions(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	stateID, _ := request.GetArguments()["state_id"].(string)
+
+	result, err := ibgeClient.GetMesoregions(ctx, stateID)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toFormattedResult(request, result)
+}
+
+func handleIBGEMicroregions(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	stateID, _ := request.GetArguments()["state_id"].(string)
+
+	result, err := ibgeClient.GetMicroregions(ctx, stateID)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toFormattedResult(request, result)
+}
+
+func handleIBGEMunicipality(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	code, err := request.RequireString("code")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'code' is required"), nil
+	}
+
+	result, err := ibgeClient.GetMunicipality(ctx, code)
+	if err != nil {
+		return toolError(err), nil
+	}
+	if includeArea, _ := request.GetArguments()["include_area"].(bool); includeArea {
+		if err := ibgeClient.FillMunicipalityArea(ctx, result); err != nil {
+			return toolError(err), nil
+		}
+	}
+	return toJSONResult(result)
+}
+
+func handleIBGENameStats(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name, err := request.RequireString("name")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'name' is required"), nil
+	}
+	uf, _ := request.GetArguments()["uf"].(string)
+
+	result, err := ibgeClient.GetNameStats(ctx, name, uf)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toJSONResult(result)
+}
+
+func handleIBGEGDP(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	municipalityID, err := request.RequireString("municipality_id")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'municipality_id' is required"), nil
+	}
+	year, _ := request.GetArguments()["year"].(string)
+
+	result, err := ibgeClient.GetMunicipalGDP(ctx, municipalityID, year)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toJSONResult(result)
+}
+
+func handleIBGEPopulation(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	locationID, _ := request.GetArguments()["location_id"].(string)
+
+	result, err := ibgeClient.GetPopulation(ctx, locationID)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toJSONResult(result)
+}
+
+func handleIBGECensusPopulation(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	locationID, _ := request.GetArguments()["location_id"].(string)
+
+	result, err := ibgeClient.GetCensusPopulation(ctx, locationID)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toJSONResult(result)
+}
+
+func handleIBGEDensity(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	localidadeID, _ := request.GetArguments()["localidade_id"].(string)
+	if localidadeID == "" {
+		return mcp.NewToolResultError("Parameter 'localidade_id' is required"), nil
+	}
+
+	result, err := ibgeClient.GetDensity(ctx, localidadeID)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toJSONResult(result)
+}
+
+// ==================== HANDLERS: CNPJ ====================
+
+func handleLookupCNPJ(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cnpjNum, err := request.RequireString("cnpj")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'cnpj' is required"), nil
+	}
+
+	result, err := cnpjClient.GetCNPJ(ctx, cnpjNum)
+	if err != nil {
+		return toolError(err), nil
+	}
+
+	var data interface{} = result
+	if qsaOnly, _ := request.GetArguments()["qsa_only"].(bool); qsaOnly {
+		partners := result.Partners()
+		data = &cnpj.PartnersResponse{
+			Partners:    partners,
+			CNPJ:        result.CNPJ,
+			RazaoSocial: result.RazaoSocial,
+			Total:       len(partners),
+			Source:      result.Source,
+		}
+	}
+	if fields, _ := request.GetArguments()["fields"].(string); strings.TrimSpace(fields) != "" {
+		projected, err := projectFields(data, strings.Split(fields, ","))
+		if err != nil {
+			return toolError(err), nil
+		}
+		return toFormattedResult(request, projected)
+	}
+	return toFormattedResult(request, data)
+}
+
+func handleSearchCNPJByName(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name, err := request.RequireString("name")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'name' is required"), nil
+	}
+	uf, _ := request.GetArguments()["uf"].(string)
+	page := getIntArg(request, "page", 1)
+
+	result, err := cnpjClient.SearchCompaniesByName(ctx, name, uf, page)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toFormattedResult(request, result)
+}
+
+// ==================== HANDLERS: BCB ====================
+
+func handleBCBSelic(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	lastN := getIntArg(request, "last_n", 30)
+
+	result, err := bcbClient.GetSELIC(ctx, lastN)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toJSONResult(result)
+}
+
+func handleBCBIPCA(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	lastN := getIntArg(request, "last_n", 12)
+
+	result, err := bcbClient.GetIPCA(ctx, lastN)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toJSONResult(result)
+}
+
+func handleBCBExchangeRate(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	currency, _ := request.GetArguments()["currency"].(string)
+	date, _ := request.GetArguments()["date"].(string)
+
+	result, err := bcbClient.GetExchangeRate(ctx, currency, date)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toJSONResult(result)
+}
+
+func handleBCBExchangeRatePeriod(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	currency, _ := request.GetArguments()["currency"].(string)
+	startDate, err := request.RequireString("start_date")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'start_date' is required"), nil
+	}
+	endDate, err := request.RequireString("end_date")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'end_date' is required"), nil
+	}
+
+	result, err := bcbClient.GetExchangeRatePeriod(ctx, currency, startDate, endDate)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toJSONResult(result)
+}
+
+func handleBCBExchangeRates(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	currencies, err := request.RequireString("currencies")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'currencies' is required"), nil
+	}
+	date, _ := request.GetArguments()["date"].(string)
+
+	rates, err := bcbClient.GetExchangeRates(ctx, strings.Split(currencies, ","), date)
+	var currencyErrs bcb.CurrencyErrors
+	if err != nil && !errors.As(err, &currencyErrs) {
+		return toolError(err), nil
+	}
+
+	result := map[string]interface{}{
+		"rates":  rates,
+		"source": "bcb_api",
+	}
+	if len(currencyErrs) > 0 {
+		errMsgs := make(map[string]string, len(currencyErrs))
+		for currency, err := range currencyErrs {
+			errMsgs[currency] = err.Error()
+		}
+		result["errors"] = errMsgs
+	}
+	return toJSONResult(result)
+}
+
+func handleBCBConvert(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	amount, err := request.RequireFloat("amount")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'amount' is required"), nil
+	}
+	from, err := request.RequireString("from")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'from' is required"), nil
+	}
+	to, err := request.RequireString("to")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'to' is required"), nil
+	}
+	date, _ := request.GetArguments()["date"].(string)
+
+	result, err := bcbClient.ConvertCurrency(ctx, amount, from, to, date)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toJSONResult(result)
+}
+
+func handleBCBPIXStats(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	month, _ := request.GetArguments()["month"].(string)
+	includeRaw, _ := request.GetArguments()["include_raw"].(bool)
+
+	result, err := bcbClient.GetPIXStats(ctx, month, includeRaw)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toJSONResult(result)
+}
+
+func handleBCBIndicators(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	names, err := request.RequireString("indicators")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'indicators' is required"), nil
+	}
+	lastN := getIntArg(request, "last_n", 30)
+
+	indicators, err := bcbClient.GetIndicators(ctx, strings.Split(names, ","), lastN)
+	var indicatorErrs bcb.IndicatorErrors
+	if err != nil && !errors.As(err, &indicatorErrs) {
+		return toolError(err), nil
+	}
+
+	result := map[string]interface{}{
+		"indicators": indicators,
+		"source":     "bcb_api",
+	}
+	if len(indicatorErrs) > 0 {
+		errMsgs := make(map[string]string, len(indicatorErrs))
+		for name, err := range indicatorErrs {
+			errMsgs[name] = err.Error()
+		}
+		result["errors"] = errMsgs
+	}
+	return toJSONResult(result)
+}
+
+func handleBCBListIndicators(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return toJSONResult(bcbClient.ListIndicators())
+}
+
+func handleBCBFocus(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	indicator, err := request.RequireString("indicator")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'indicator' is required"), nil
+	}
+	lastN := getIntArg(request, "last_n", 10)
+
+	result, err := bcbClient.GetFocusExpectations(ctx, indicator, lastN)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toJSONResult(result)
+}
+
+func handleBCBIndicator(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	indicator, err := request.RequireString("indicator")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'indicator' is required"), nil
+	}
+	lastN := getIntArg(request, "last_n", 30)
+	startDate, _ := request.GetArguments()["start_date"].(string)
+	endDate, _ := request.GetArguments()["end_date"].(string)
+
+	var result *bcb.IndicatorResponse
+	if startDate != "" || endDate != "" {
+		result, err = bcbClient.GetIndicatorRange(ctx, indicator, startDate, endDate)
+	} else {
+		result, err = bcbClient.GetIndicator(ctx, indicator, lastN)
+	}
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toJSONResult(result)
+}
+
+func handleBCBSeries(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	code := getIntArg(request, "series_code", 0)
+	if code <= 0 {
+		return mcp.NewToolResultError("Parameter 'series_code' is required"), nil
+	}
+	lastN := getIntArg(request, "last_n", 30)
+
+	result, err := bcbClient.GetSeriesByCode(ctx, code, lastN)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toJSONResult(result)
+}
+
+func handleInflationAdjust(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	amount, err := request.RequireFloat("amount")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'amount' is required"), nil
+	}
+	from, err := request.RequireString("from")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'from' is required"), nil
+	}
+	to, err := request.RequireString("to")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'to' is required"), nil
+	}
+
+	adjusted, err := bcbClient.AdjustForInflation(ctx, amount, from, to)
+	if err != nil {
+		return toolError(err), nil
+	}
+
+	result := map[string]interface{}{
+		"amount":          amount,
+		"adjusted_amount": adjusted,
+		"from":            from,
+		"to":              to,
+		"index":           "ipca",
+		"source":          "bcb_api",
+	}
+	if amount != 0 {
+		result["factor"] = adjusted / amount
+	}
+	return toJSONResult(result)
+}
+
+func handleBCBAccumulate(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	indicator, err := request.RequireString("indicator")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'indicator' is required"), nil
+	}
+	startDate, err := request.RequireString("start_date")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'start_date' is required"), nil
+	}
+	endDate, err := request.RequireString("end_date")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'end_date' is required"), nil
+	}
+
+	accumulated, err := bcbClient.AccumulateDaily(ctx, indicator, startDate, endDate)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toJSONResult(map[string]interface{}{
+		"indicator":           indicator,
+		"start_date":          startDate,
+		"end_date":            endDate,
+		"accumulated_percent": accumulated,
+		"source":              "bcb_api",
+	})
+}
+
+// ==================== HANDLERS: PNCP ====================
+
+func handlePNCPContracts(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ctx, cancel := callContext(ctx, request)
+	defer cancel()
+
+	startDate, _ := request.RequireString("start_date")
+	endDate, _ := request.RequireString("end_date")
+	state, _ := request.GetArguments()["state"].(string)
+	keyword, _ := request.GetArguments()["keyword"].(string)
+	minValue, _ := request.GetArguments()["min_value"].(float64)
+	maxValue, _ := request.GetArguments()["max_value"].(float64)
+	sortMode, _ := request.GetArguments()["sort"].(string)
+	modality := getIntArg(request, "modality", 6)
+	if name, _ := request.GetArguments()["modality_name"].(string); name != "" {
+		code, ok := pncp.ModalityByName(name)
+		if !ok {
+			return mcp.NewToolResultError(fmt.Sprintf("Error: unknown modality %q, see pncp_modalities", name)), nil
+		}
+		modality = code
+	}
+	page := getIntArg(request, "page", 1)
+
+	filter := pncp.ContractFilter{Keyword: keyword, MinValue: minValue, MaxValue: maxValue}
+
+	if debugURL, _ := request.GetArguments()["debug_url"].(bool); debugURL {
+		return debugURLResult(pncpClient.SearchContractsURL(startDate, endDate, modality, state, page, pageSizeArg(request, 50)))
+	}
+
+	result, err := pncpClient.SearchContracts(ctx, startDate, endDate, modality, state, filter, page, pageSizeArg(request, 50))
+	if err != nil {
+		return toolError(err), nil
+	}
+	if err := pncp.SortContracts(result.Contracts, sortMode); err != nil {
+		return toolError(err), nil
+	}
+	return toFormattedResult(request, result)
+}
+
+func handlePNCPContractDetail(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	numeroControle, err := request.RequireString("numero_controle")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'numero_controle' is required"), nil
+	}
+
+	result, err := pncpClient.GetContractDetail(ctx, numeroControle)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toJSONResult(result)
+}
+
+func handlePNCPContractItems(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	numeroControle, err := request.RequireString("numero_controle")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'numero_controle' is required"), nil
+	}
+	control, err := pncp.ParseControlNumber(numeroControle)
+	if err != nil {
+		return toolError(err), nil
+	}
+
+	items, err := pncpClient.GetContractItems(ctx, control.OrgaoCNPJ, control.Ano, control.Sequencial)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toFormattedResult(request, items)
+}
+
+func handlePNCPPriceRegistrations(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	state, _ := request.GetArguments()["state"].(string)
+	page := getIntArg(request, "page", 1)
+	pageSize := pageSizeArg(request, 50)
+
+	if debugURL, _ := request.GetArguments()["debug_url"].(bool); debugURL {
+		return debugURLResult(pncpClient.SearchPriceRegistrationsURL(state, page, pageSize))
+	}
+
+	result, err := pncpClient.SearchPriceRegistrations(ctx, state, page, pageSize)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toFormattedResult(request, result)
+}
+
+func handlePNCPEditais(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	endDate, err := request.RequireString("end_date")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'end_date' is required"), nil
+	}
+	startDate, _ := request.GetArguments()["start_date"].(string)
+	state, _ := request.GetArguments()["state"].(string)
+	modality := getIntArg(request, "modality", 0)
+	if name, _ := request.GetArguments()["modality_name"].(string); name != "" {
+		code, ok := pncp.ModalityByName(name)
+		if !ok {
+			return mcp.NewToolResultError(fmt.Sprintf("Error: unknown modality %q, see pncp_modalities", name)), nil
+		}
+		modality = code
+	}
+	page := getIntArg(request, "page", 1)
+
+	if debugURL, _ := request.GetArguments()["debug_url"].(bool); debugURL {
+		return debugURLResult(pncpClient.SearchEditaisURL(startDate, endDate, modality, state, page, pageSizeArg(request, 50)))
+	}
+
+	result, err := pncpClient.SearchEditais(ctx, startDate, endDate, modality, state, page, pageSizeArg(request, 50))
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toFormattedResult(request, result)
+}
+
+func handlePNCPModalitySummary(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	startDate, err := request.RequireString("start_date")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'start_date' is required"), nil
+	}
+	endDate, err := request.RequireString("end_date")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'end_date' is required"), nil
+	}
+	state, _ := request.GetArguments()["state"].(string)
+
+	result, err := pncpClient.GroupContractsByModality(ctx, startDate, endDate, state)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toFormattedResult(request, result)
+}
+
+func handlePNCPModalities(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return toJSONResult(pncpClient.ListModalities())
+}
+
+// ==================== HANDLERS: CEP ====================
+
+func handleLookupCEP(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cepNum, err := request.RequireString("cep")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'cep' is required"), nil
+	}
+
+	result, err := cepClient.LookupCEP(ctx, cepNum)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toJSONResult(result)
+}
+
+func handleLookupCEPBatch(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ceps, err := request.RequireString("ceps")
+	if err != nil {
+		return mcp.NewToolResultError("Parameter 'ceps' is required"), nil
+	}
+
+	result, err := cepClient.BatchLookupCEP(ctx, strings.Split(ceps, ","))
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toJSONResult(result)
+}
+
+// ==================== HANDLERS: Resources ====================
+
+func handleDocResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      "docs://api-reference",
+			MIMEType: "text/markdown",
+			Text:     getAPIDocumentation(),
+		},
+	}, nil
+}
+
+func handleStatesResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	result, err := ibgeClient.CachedStates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching states: %w", err)
+	}
+	jsonBytes, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding states: %w", err)
+	}
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      "ibge://states",
+			MIMEType: "application/json",
+			Text:     string(jsonBytes),
+		},
+	}, nil
+}
+
+// ==================== HANDLERS: Prompts ====================
+
+func handleDueDiligencePrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	digits, err := cnpj.Validate(request.Params.Arguments["cnpj"])
+	if err != nil {
+		return nil, err
+	}
+
+	return mcp.NewGetPromptResult(
+		"Due diligence of CNPJ "+digits,
+		[]mcp.PromptMessage{
+			mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(dueDiligenceMessage(digits))),
+		},
+	), nil
+}
+
+// dueDiligenceMessage renders the due_diligence prompt for a CNPJ.
+func dueDiligenceMessage(cnpjNum string) string {
+	return fmt.Sprintf(`Perform a due-diligence review of the company with CNPJ %[1]s, calling the tools in this order:
+
+1. lookup_cnpj with cnpj=%[1]s: report the legal name, registration status (situação cadastral), opening date, main activity, share capital and partners (QSA).
+2. screen_company with cnpj=%[1]s: report whether the company appears in the CEIS, CNEP or CEPIM sanction lists, with the sanction details.
+3. company_dossier with cnpj=%[1]s: summarize the federal contracts signed with the company across all organizations (organizations, objects, values and dates). To dig into one organization, call search_contracts with supplier_cnpj=%[1]s and its orgao_code.
+
+Then write a short risk assessment. Flag an inactive registration, any sanction, or contracts signed while a sanction was in force. If a tool fails, say so instead of guessing.`, cnpjNum)
+}
+
+// defaultSnapshotMonths is the period of the economic_snapshot prompt when
+// none is given.
+const defaultSnapshotMonths = 12
+
+func handleEconomicSnapshotPrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	months := defaultSnapshotMonths
+	if period := strings.TrimSpace(request.Params.Arguments["period"]); period != "" {
+		n, err := strconv.Atoi(period)
+		if err != nil || n < 1 || n > 60 {
+			return nil, fmt.Errorf("invalid period %q: expected a number of months between 1 and 60", period)
+		}
+		months = n
+	}
+
+	return mcp.NewGetPromptResult(
+		fmt.Sprintf("Economic snapshot of the last %d months", months),
+		[]mcp.PromptMessage{
+			mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(economicSnapshotMessage(months, time.Now()))),
+		},
+	), nil
+}
+
+// economicSnapshotMessage renders the economic_snapshot prompt for the
+// months up to now. SELIC is daily, so about 21 business days are requested
+// per month.
+func economicSnapshotMessage(months int, now time.Time) string {
+	start := now.AddDate(0, -months, 0).Format("2006-01-02")
+	return fmt.Sprintf(`Write an economic snapshot of Brazil covering the last %[1]d months (since %[2]s), calling these tools:
+
+1. bcb_selic with last_n=%[3]d: the daily SELIC rate over the period.
+2. bcb_ipca with last_n=%[1]d: the monthly IPCA inflation over the period.
+3. bcb_exchange_rate with currency=USD, once without a date for the latest PTAX quote and once with date=%[2]s for the start of the period (use the closest business day if there is no quote).
+
+Then summarize the trends: how SELIC moved, the accumulated and latest monthly IPCA, the real interest rate (SELIC minus inflation) and how much the real gained or lost against the dollar. Quote the figures you use with their dates, and say so if a tool fails instead of guessing.`, months, start, months*21)
+}
+
+// ==================== HELPERS ====================
+
+// withMaskCPF adds the mask_cpf argument to tools returning CPFs.
+func withMaskCPF() mcp.ToolOption {
+	return mcp.WithBoolean("mask_cpf",
+		mcp.Description("Mask CPFs as ***.456.789-** (default true); set false only for authorized use"),
+	)
+}
+
+// maskCPF reports whether CPFs must be masked, which is the default.
+func maskCPF(request mcp.CallToolRequest) bool {
+	mask, ok := request.GetArguments()["mask_cpf"].(bool)
+	return !ok || mask
+}
+
+// toolError converts a client error into a tool error. Known failure kinds
+// are tagged with a category such as "[not_found]" or "[rate_limited]" so
+// the caller can react to them; the message keeps the error detail.
+func toolError(err error) *mcp.CallToolResult {
+	if category := errorCategory(err); category != "" {
+		return mcp.NewToolResultError(fmt.Sprintf("[%s] Error: %v", category, err))
+	}
+	return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err))
+}
+
+// errorCategory returns the category tag of err, or "" if it is not a known
+// kind.
+func errorCategory(err error) string {
+	switch {
+	case errors.Is(err, apierror.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, apierror.ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, apierror.ErrUnauthorized):
+		return "unauthorized"
+	case errors.Is(err, apierror.ErrUpstream):
+		return "upstream_error"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	default:
+		return ""
+	}
+}
+
+// maxPageSize is the largest page size accepted by the Portal da
+// Transparencia and PNCP APIs.
+const maxPageSize = 500
+
+// defaultPageSize, set from MCP_DEFAULT_PAGE_SIZE, overrides the default page
+// size of every paginated tool when positive.
+var defaultPageSize int
+
+// parseDefaultPageSize reads MCP_DEFAULT_PAGE_SIZE, clamped to maxPageSize.
+// Empty or invalid values leave the per-tool defaults in place.
+func parseDefaultPageSize(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid MCP_DEFAULT_PAGE_SIZE %q: must be a positive integer", value)
+	}
+	return min(n, maxPageSize), nil
+}
+
+// pageSizeArg returns the page_size argument or, when absent, the configured
+// default page size, falling back to the tool default def.
+func pageSizeArg(request mcp.CallToolRequest, def int) int {
+	if defaultPageSize > 0 {
+		def = defaultPageSize
+	}
+	return getIntArg(request, "page_size", def)
+}
+
+func getIntArg(request mcp.CallToolRequest, key string, defaultVal int) int {
+	args := request.GetArguments()
+	if val, ok := args[key].(float64); ok {
+		return int(val)
+	}
+	return defaultVal
+}
+
+// includeMetaArg is the argument, accepted by every tool, that wraps a JSON
+// result in an envelope with its fetch time and latency.
+const includeMetaArg = "include_meta"
+
+// advertiseMeta adds the include_meta argument to the schema of every listed
+// tool.
+func advertiseMeta(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+	for i, tool := range tools {
+		properties := make(map[string]interface{}, len(tool.InputSchema.Properties)+1)
+		for name, schema := range tool.InputSchema.Properties {
+			properties[name] = schema
+		}
+		properties[includeMetaArg] = map[string]interface{}{
+			"type":        "boolean",
+			"description": "Wrap a JSON result as {data, meta} with fetched_at (RFC3339) and latency_ms",
+		}
+		tools[i].InputSchema.Properties = properties
+	}
+	return tools
+}
+
+// resultMeta wraps tool results with their fetch time and latency when
+// include_meta is true. Without it, results are returned untouched.
+func resultMeta(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		result, err := next(ctx, request)
+		if include, _ := request.GetArguments()[includeMetaArg].(bool); !include || err != nil {
+			return result, err
+		}
+		if format, _ := request.GetArguments()["format"].(string); format != "" && format != "json" {
+			return result, nil
+		}
+		return withMeta(result, time.Now(), time.Since(start)), nil
+	}
+}
+
+// withMeta returns result as {"data": result, "meta": {...}}. Errors and
+// non-JSON results, such as CSV or Markdown output, are returned as is.
+func withMeta(result *mcp.CallToolResult, fetchedAt time.Time, latency time.Duration) *mcp.CallToolResult {
+	if result == nil || result.IsError || len(result.Content) != 1 {
+		return result
+	}
+	text, ok := result.Content[0].(mcp.TextContent)
+	if !ok || !json.Valid([]byte(text.Text)) {
+		return result
+	}
+
+	wrapped, _ := toJSONResult(map[string]interface{}{
+		"data": json.RawMessage(text.Text),
+		"meta": map[string]interface{}{
+			"fetched_at": fetchedAt.UTC().Format(time.RFC3339),
+			"latency_ms": latency.Milliseconds(),
+		},
+	})
+	return wrapped
+}
+
+func toJSONResult(data interface{}) (*mcp.CallToolResult, error) {
+	jsonBytes, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error encoding result: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(jsonBytes)), nil
+}
+
+// withFormat adds the optional output format argument to list/search tools.
+func withFormat() mcp.ToolOption {
+	return mcp.WithString("format",
+		mcp.Description("Output format: json (default), ndjson, csv or markdown"),
+		mcp.Enum("json", "ndjson", "csv", "markdown"),
+	)
+}
+
+// withDebugURL adds the optional debug_url argument to search tools.
+func withDebugURL() mcp.ToolOption {
+	return mcp.WithBoolean("debug_url",
+		mcp.Description("Return the upstream URL the search would request instead of calling the API"),
+	)
+}
+
+// debugURLResult answers a debug_url call with the upstream URL.
+func debugURLResult(reqURL string, err error) (*mcp.CallToolResult, error) {
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toJSONResult(map[string]interface{}{
+		"method": http.MethodGet,
+		"url":    reqURL,
+	})
+}
+
+// transparenciaDebugURLResult answers a debug_url call for the Portal da
+// Transparencia, whose API key travels in a header and is shown redacted.
+func transparenciaDebugURLResult(reqURL string, err error) (*mcp.CallToolResult, error) {
+	if err != nil {
+		return toolError(err), nil
+	}
+	return toJSONResult(map[string]interface{}{
+		"method":  http.MethodGet,
+		"url":     reqURL,
+		"headers": map[string]string{"chave-api-dados": "[REDACTED]"},
+	})
+}
+
+// maxCallTimeout caps the timeout_seconds argument of slow tools.
+const maxCallTimeout = 120 * time.Second
+
+// withTimeout adds the optional timeout_seconds argument to tools whose
+// upstream calls can outlast the default client timeout.
+func withTimeout() mcp.ToolOption {
+	return mcp.WithNumber("timeout_seconds",
+		mcp.Description("Per-call timeout in seconds, up to 120 (default 30)"),
+	)
+}
+
+// callContext applies the timeout_seconds argument to ctx, clamped to
+// maxCallTimeout. The deadline replaces the client timeout, so it can also
+// extend it. Without it, the client timeout applies.
+func callContext(ctx context.Context, request mcp.CallToolRequest) (context.Context, context.CancelFunc) {
+	seconds := getIntArg(request, "timeout_seconds", 0)
+	if seconds <= 0 {
+		return ctx, func() {}
+	}
+	d := time.Duration(seconds) * time.Second
+	if d > maxCallTimeout {
+		d = maxCallTimeout
+	}
+	return timeout.WithOverride(ctx, d)
+}
+
+// withBRLFormat adds the optional brl_format argument to tools returning
+// monetary fields.
+func withBRLFormat() mcp.ToolOption {
+	return mcp.WithBoolean("brl_format",
+		mcp.Description("Add R$-formatted companion fields (e.g. valorInicialFormatado) to JSON output"),
+	)
+}
+
+// withBRLFields returns a generic copy of data in which every numeric field
+// whose name starts with "valor" gets a "<name>Formatado" companion holding
+// the value formatted as BRL. The numeric fields are kept.
+func withBRLFields(data interface{}) interface{} {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return data
+	}
+	var generic interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return data
+	}
+	addBRLFields(generic)
+	return generic
+}
+
+func addBRLFields(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if n, ok := field.(float64); ok && strings.HasPrefix(strings.ToLower(key), "valor") && !strings.HasSuffix(key, "Formatado") {
+				v[key+"Formatado"] = money.FormatBRL(n)
+				continue
+			}
+			addBRLFields(field)
+		}
+	case []interface{}:
+		for _, item := range v {
+			addBRLFields(item)
+		}
+	}
+}
+
+// toFormattedResult encodes data in the format requested by the "format" argument.
+func toFormattedResult(request mcp.CallToolRequest, data interface{}) (*mcp.CallToolResult, error) {
+	format, _ := request.GetArguments()["format"].(string)
+	switch format {
+	case "", "json":
+		if brl, _ := request.GetArguments()["brl_format"].(bool); brl {
+			return toJSONResult(withBRLFields(data))
+		}
+		return toJSONResult(data)
+	case "ndjson":
+		return toNDJSONResult(data)
+	case "csv":
+		return toCSVResult(data)
+	case "markdown":
+		return toMarkdownResult(data)
+	default:
+		return mcp.NewToolResultError(fmt.Sprintf("Unknown format: %s", format)), nil
+	}
+}
+
+// toNDJSONResult encodes the records of a slice-based response as
+// newline-delimited JSON, one record per line. Other responses are encoded
+// as a single line.
+func toNDJSONResult(data interface{}) (*mcp.CallToolResult, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+
+	records, ok := findRecords(data)
+	if !ok {
+		if err := enc.Encode(data); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error encoding result: %v", err)), nil
+		}
+		return mcp.NewToolResultText(buf.String()), nil
+	}
+
+	for i := 0; i < records.Len(); i++ {
+		if err := enc.Encode(records.Index(i).Interface()); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error encoding result: %v", err)), nil
+		}
+	}
+	return mcp.NewToolResultText(buf.String()), nil
+}
+
+// toCSVResult encodes the records of a slice-based response as RFC 4180 CSV,
+// with a header row taken from the json tags. Responses without a slice of
+// records are returned as JSON.
+func toCSVResult(data interface{}) (*mcp.CallToolResult, error) {
+	records, ok := findRecords(data)
+	if !ok {
+		return toJSONResult(data)
+	}
+
+	text, err := encodeCSV(records)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error encoding result: %v", err)), nil
+	}
+	return mcp.NewToolResultText(text), nil
+}
+
+// toMarkdownResult renders slice-based responses as a GitHub-flavored
+// Markdown table, and any other result as a two-column key/value table.
+func toMarkdownResult(data interface{}) (*mcp.CallToolResult, error) {
+	var text string
+	var err error
+	if records, ok := findRecords(data); ok {
+		text, err = encodeMarkdownTable(records)
+	} else {
+		text, err = encodeMarkdownKeyValue(indirect(reflect.ValueOf(data)))
+	}
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error encoding result: %v", err)), nil
+	}
+	return mcp.NewToolResultText(text), nil
+}
+
+// encodeMarkdownTable renders a slice of records as a Markdown table, right
+// aligning numeric columns.
+func encodeMarkdownTable(records reflect.Value) (string, error) {
+	elemType := records.Type().Elem()
+	columns := recordColumns(elemType)
+
+	header := make([]string, len(columns))
+	align := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = escapeMarkdownCell(col.name)
+		align[i] = "---"
+		if isNumericColumn(elemType, col) {
+			align[i] = "---:"
+		}
+	}
+
+	var sb strings.Builder
+	writeMarkdownRow(&sb, header)
+	writeMarkdownRow(&sb, align)
+	for i := 0; i < records.Len(); i++ {
+		row, err := recordRow(records.Index(i), columns)
+		if err != nil {
+			return "", err
+		}
+		for j := range row {
+			row[j] = escapeMarkdownCell(row[j])
+		}
+		writeMarkdownRow(&sb, row)
+	}
+	return sb.String(), nil
+}
+
+// encodeMarkdownKeyValue renders a single struct or map as a two-column
+// Field/Value Markdown table.
+func encodeMarkdownKeyValue(v reflect.Value) (string, error) {
+	var sb strings.Builder
+	writeMarkdownRow(&sb, []string{"Field", "Value"})
+	writeMarkdownRow(&sb, []string{"---", "---"})
+
+	switch v.Kind() {
+	case reflect.Struct:
+		for _, col := range recordColumns(v.Type()) {
+			cell, err := formatCell(v.FieldByIndex(col.index))
+			if err != nil {
+				return "", err
+			}
+			writeMarkdownRow(&sb, []string{escapeMarkdownCell(col.name), escapeMarkdownCell(cell)})
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, key := range keys {
+			cell, err := formatCell(v.MapIndex(key))
+			if err != nil {
+				return "", err
+			}
+			writeMarkdownRow(&sb, []string{escapeMarkdownCell(fmt.Sprint(key)), escapeMarkdownCell(cell)})
+		}
+	default:
+		cell, err := formatCell(v)
+		if err != nil {
+			return "", err
+		}
+		writeMarkdownRow(&sb, []string{"value", escapeMarkdownCell(cell)})
+	}
+	return sb.String(), nil
+}
+
+// isNumericColumn reports whether a record column holds a numeric value.
+func isNumericColumn(t reflect.Type, col recordColumn) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct {
+		t = t.FieldByIndex(col.index).Type
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// escapeMarkdownCell escapes pipes and line breaks so a value stays in its cell.
+func escapeMarkdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	s = strings.ReplaceAll(s, "\r\n", "<br>")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+// writeMarkdownRow writes a single Markdown table row.
+func writeMarkdownRow(sb *strings.Builder, cells []string) {
+	sb.WriteString("| ")
+	sb.WriteString(strings.Join(cells, " | "))
+	sb.WriteString(" |\n")
+}
+
+// encodeCSV writes a slice of records as CSV with a header row.
+func encodeCSV(records reflect.Value) (string, error) {
+	columns := recordColumns(records.Type().Elem())
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.UseCRLF = true
+
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.name
+	}
+	if err := w.Write(header); err != nil {
+		return "", err
+	}
+
+	for i := 0; i < records.Len(); i++ {
+		row, err := recordRow(records.Index(i), columns)
+		if err != nil {
+			return "", err
+		}
+		if err := w.Write(row); err != nil {
+			return "", err
+		}
+	}
+
+	w.Flush()
+	return buf.String(), w.Error()
+}
+
+// recordColumn is a record field exported as a table column.
+type recordColumn struct {
+	name  string
+	index []int
+}
+
+// findRecords returns the slice of records carried by data: data itself when
+// it is a slice, or the leading slice field of a response struct.
+func findRecords(data interface{}) (reflect.Value, bool) {
+	v := indirect(reflect.ValueOf(data))
+
+	switch v.Kind() {
+	case reflect.Slice:
+		return v, true
+	case reflect.Struct:
+		if v.NumField() > 0 && v.Type().Field(0).IsExported() && v.Field(0).Kind() == reflect.Slice {
+			return v.Field(0), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// projectFields reduces a response struct to the given json keys. Unknown
+// keys are rejected with the list of available ones; known keys omitted
+// from the response because they are empty are left out.
+func projectFields(data interface{}, fields []string) (map[string]interface{}, error) {
+	known := make(map[string]bool)
+	var available []string
+	for _, col := range recordColumns(reflect.TypeOf(data)) {
+		known[col.name] = true
+		available = append(available, col.name)
+	}
+
+	var wanted, unknown []string
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		switch {
+		case field == "":
+		case known[field]:
+			wanted = append(wanted, field)
+		default:
+			unknown = append(unknown, field)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown fields: %s. Available: %s", strings.Join(unknown, ", "), strings.Join(available, ", "))
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]interface{}
+	if err := json.Unmarshal(raw, &all); err != nil {
+		return nil, err
+	}
+
+	projected := make(map[string]interface{}, len(wanted))
+	for _, field := range wanted {
+		if value, ok := all[field]; ok {
+			projected[field] = value
+		}
+	}
+	return projected, nil
+}
+
+// indirect dereferences pointers and interfaces, returning the zero Value for nil.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// recordColumns lists the columns of a record type from its json tags.
+// Non-struct records are exported as a single "value" column.
+func recordColumns(t reflect.Type) []recordColumn {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return []recordColumn{{name: "value"}}
+	}
+
+	var columns []recordColumn
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		columns = append(columns, recordColumn{name: name, index: field.Index})
+	}
+	return columns
+}
+
+// recordRow renders the columns of a single record as strings.
+func recordRow(record reflect.Value, columns []recordColumn) ([]string, error) {
+	record = indirect(record)
+	if !record.IsValid() {
+		return make([]string, len(columns)), nil
+	}
+
+	row := make([]string, len(columns))
+	for i, col := range columns {
+		field := record
+		if col.index != nil {
+			field = record.FieldByIndex(col.index)
+		}
+		cell, err := formatCell(field)
+		if err != nil {
+			return nil, err
+		}
+		row[i] = cell
+	}
+	return row, nil
+}
+
+// formatCell renders a single value; composite values are JSON-encoded.
+func formatCell(v reflect.Value) (string, error) {
+	v = indirect(v)
+
+	switch v.Kind() {
+	case reflect.Invalid:
+		return "", nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	}
+
+	if (v.Kind() == reflect.Map || v.Kind() == reflect.Slice) && v.IsNil() {
+		return "", nil
+	}
+	jsonBytes, err := json.Marshal(v.Interface())
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
+}
+
+func getAPIDocumentation() string {
+	return `# MCP Brasil - API Reference v2.0
+
+## Overview
+This MCP server provides access to multiple Brazilian government data sources.
 
 ## Available Tools
 
@@ -422,23 +2591,47 @@ This MCP server provides access to multiple Brazilian government data sources.
 | Tool | Description |
 |------|-------------|
 | search_contracts | Search federal government contracts |
+| export_contracts | Export all contracts of an organization to a file (needs MCP_EXPORT_DIR) |
+| contract_stats | Contract value totals and averages of an organization |
 | search_servidores | Search public servants by name |
 | get_remuneracao | Get salary by CPF |
 | search_convenios | Search agreements by state |
 | search_ceis | Search sanctioned companies |
+| search_cnep | Search companies punished under the anti-corruption law |
+| search_cepim | Search non-profit entities impeded from receiving transfers |
+| screen_company | Check a CNPJ against CEIS, CNEP and CEPIM |
+| company_dossier | Registration, contracts and sanctions for a CNPJ |
+| transparencia_raw | Raw JSON from any Portal endpoint |
+| search_despesas | Search expense execution by organization and year |
+| top_favorecidos | Top recipients of an organization's expenses |
+| search_viagens | Search official trips of public servants |
+| search_licitacoes | Search bidding processes of an organization |
+| search_cartoes | Search government payment card spending |
+| search_emendas | Search parliamentary amendments |
+| search_bolsa_familia | Get Bolsa Familia totals by municipality |
 | list_orgaos | List organization codes |
 
 ### IBGE (Statistics)
 | Tool | Description |
 |------|-------------|
 | ibge_states | List all Brazilian states |
+| ibge_state | Get a single state by code or sigla |
+| ibge_regions | List the five macro-regions |
 | ibge_municipalities | List municipalities (filter by state) |
+| ibge_mesoregions | List mesoregions (filter by state) |
+| ibge_microregions | List microregions (filter by state) |
+| ibge_municipality | Get a municipality by IBGE code |
 | ibge_population | Get population data |
+| ibge_census_population | Get the 2022 Census population |
+| ibge_density | Get population density (hab/km²) |
+| ibge_gdp | Get municipal GDP |
+| ibge_name_stats | Get first-name frequency by decade |
 
 ### CNPJ Lookup (Minha Receita)
 | Tool | Description |
 |------|-------------|
-| lookup_cnpj | Get company data by CNPJ |
+| lookup_cnpj | Get company data by CNPJ (qsa_only for partners, fields to project) |
+| search_cnpj_by_name | Find companies (and their CNPJ) by name |
 
 ### Banco Central (Economic Data)
 | Tool | Description |
@@ -446,19 +2639,72 @@ This MCP server provides access to multiple Brazilian government data sources.
 | bcb_selic | Get SELIC interest rate |
 | bcb_ipca | Get IPCA inflation index |
 | bcb_exchange_rate | Get exchange rates |
-| bcb_indicator | Get any indicator (selic, ipca, igpm, cdi) |
+| bcb_exchange_rate_period | Get exchange rate history over a date window |
+| bcb_exchange_rates | Get exchange rates for several currencies at once |
+| bcb_convert | Convert an amount between currencies (PTAX) |
+| bcb_pix_stats | Get PIX transaction statistics |
+| bcb_focus | Get Focus market expectations |
+| bcb_indicator | Get any indicator (selic, selic_meta, ipca, igpm, cdi) |
+| bcb_indicators | Get several indicators in one call |
+| bcb_list_indicators | List bcb_indicator names and codes |
+| bcb_series | Get any SGS series by numeric code |
+| inflation_adjust | Adjust an amount for IPCA inflation |
+| bcb_accumulate | Accumulated SELIC or CDI over a period |
 
 ### PNCP (Public Procurement)
 | Tool | Description |
 |------|-------------|
 | pncp_contracts | Search procurement contracts |
+| pncp_contract_detail | Get a procurement by control number |
+| pncp_contract_items | List the line items of a procurement |
+| pncp_price_registrations | Search price registration records |
+| pncp_editais | Search open procurement opportunities |
+| pncp_modality_summary | Spend breakdown by modality |
 | pncp_modalities | List procurement modalities |
 
+### CEP (ViaCEP)
+| Tool | Description |
+|------|-------------|
+| lookup_cep | Get address and IBGE code by CEP |
+| lookup_cep_batch | Look up several CEPs at once |
+
+### Server
+| Tool | Description |
+|------|-------------|
+| healthcheck | Check reachability of each upstream API |
+| server_info | Get server version and build information |
+| list_tools | List all tools with their parameter schemas |
+
+## Resources
+| URI | Description |
+|-----|-------------|
+| docs://api-reference | This document |
+| ibge://states | All states as JSON, cached after the first read |
+
+## Prompts
+| Prompt | Arguments | Description |
+|--------|-----------|-------------|
+| due_diligence | cnpj | Review a company's registration, sanctions and federal contracts |
+| economic_snapshot | period (months, optional) | Analyze SELIC, IPCA and the dollar over a period |
+
+## Output Formats
+List and search tools accept an optional ` + "`format`" + ` argument:
+- ` + "`json`" + ` (default): indented JSON
+- ` + "`ndjson`" + `: newline-delimited JSON, one result record per line
+- ` + "`csv`" + `: RFC 4180 CSV of the result records, with a header row
+- ` + "`markdown`" + `: GitHub-flavored Markdown table (key/value table for single records)
+
+Tools returning monetary fields (search_contracts, search_convenios and the PNCP searches) also accept ` + "`brl_format`" + `: when true, JSON output gets a formatted companion for each ` + "`valor*`" + ` field, e.g. ` + "`valorInicialFormatado: \"R$ 1.234.567,89\"`" + `.
+
+## Errors
+Tool errors caused by a known failure kind start with a category tag: ` + "`[not_found]`" + `, ` + "`[rate_limited]`" + `, ` + "`[unauthorized]`" + `, ` + "`[upstream_error]`" + `, ` + "`[timeout]`" + ` or ` + "`[canceled]`" + `.
+
 ## Data Sources
 - Portal da Transparencia: https://api.portaldatransparencia.gov.br
 - IBGE: https://servicodados.ibge.gov.br
 - Minha Receita: https://minhareceita.org
 - Banco Central: https://api.bcb.gov.br
 - PNCP: https://pncp.gov.br
+- ViaCEP: https://viacep.com.br
 `
 }
@@ -0,0 +1,1239 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anderson-ufrj/mcp-brasil/pkg/bcb"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/cep"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/cnpj"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/ibge"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/pncp"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/transparencia"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// newTestServer builds the MCP server the way main does, with every tool
+// registered.
+func newTestServer() *server.MCPServer {
+	s := server.NewMCPServer(serverName, serverVersion,
+		server.WithToolCapabilities(false),
+		server.WithResourceCapabilities(true, false),
+		server.WithPromptCapabilities(false),
+		server.WithToolHandlerMiddleware(resultMeta),
+		server.WithToolFilter(advertiseMeta),
+	)
+	registerTransparenciaTools(s)
+	registerIBGETools(s)
+	registerCNPJTools(s)
+	registerBCBTools(s)
+	registerPNCPTools(s)
+	registerCEPTools(s)
+	registerServerTools(s)
+	registerResources(s)
+	registerPrompts(s)
+	return s
+}
+
+// callTool calls a tool through tools/call, as a client would.
+func callTool(t *testing.T, s *server.MCPServer, name string, args map[string]interface{}) *mcp.CallToolResult {
+	t.Helper()
+	params, err := json.Marshal(map[string]interface{}{"name": name, "arguments": args})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := s.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":`+string(params)+`}`))
+	resp, ok := msg.(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatalf("tools/call %s: unexpected response %+v", name, msg)
+	}
+	raw, err := json.Marshal(resp.Result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := mcp.ParseCallToolResult((*json.RawMessage)(&raw))
+	if err != nil {
+		t.Fatalf("parsing tools/call result: %v", err)
+	}
+	return result
+}
+
+// stubServer serves h for the duration of the test and returns its URL.
+func stubServer(t *testing.T, h http.HandlerFunc) string {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// decodeResult unmarshals the JSON text of a successful tool result into v.
+func decodeResult(t *testing.T, result *mcp.CallToolResult, v interface{}) {
+	t.Helper()
+	text := resultText(t, result)
+	if result.IsError {
+		t.Fatalf("tool error: %s", text)
+	}
+	if err := json.Unmarshal([]byte(text), v); err != nil {
+		t.Fatalf("result is not JSON: %v\n%s", err, text)
+	}
+}
+
+// resultText returns the text of a single-content tool result.
+func resultText(t *testing.T, result *mcp.CallToolResult) string {
+	t.Helper()
+	if len(result.Content) != 1 {
+		t.Fatalf("result has %d contents, want 1", len(result.Content))
+	}
+	text, ok := result.Content[0].(mcp.TextContent)
+	if !ok {
+		t.Fatalf("result content is %T, want text", result.Content[0])
+	}
+	return text.Text
+}
+
+type testRecord struct {
+	Name  string  `json:"nome"`
+	Value float64 `json:"valor"`
+	Skip  string  `json:"-"`
+}
+
+func TestToCSVResult(t *testing.T) {
+	tests := []struct {
+		name string
+		data interface{}
+		want string
+	}{
+		{
+			name: "plain",
+			data: []testRecord{{Name: "ACME", Value: 10.5}},
+			want: "nome,valor\r\nACME,10.5\r\n",
+		},
+		{
+			name: "comma",
+			data: []testRecord{{Name: "ACME, LTDA", Value: 1}},
+			want: "nome,valor\r\n\"ACME, LTDA\",1\r\n",
+		},
+		{
+			name: "quote",
+			data: []testRecord{{Name: `Loja "Central"`, Value: 1}},
+			want: "nome,valor\r\n\"Loja \"\"Central\"\"\",1\r\n",
+		},
+		{
+			name: "newline",
+			data: []testRecord{{Name: "linha 1\nlinha 2", Value: 1}},
+			want: "nome,valor\r\n\"linha 1\r\nlinha 2\",1\r\n",
+		},
+		{
+			name: "records of a response struct",
+			data: struct {
+				Records []testRecord `json:"registros"`
+				Total   int          `json:"total"`
+			}{Records: []testRecord{{Name: "A"}, {Name: "B"}}, Total: 2},
+			want: "nome,valor\r\nA,0\r\nB,0\r\n",
+		},
+		{
+			name: "empty slice keeps the header",
+			data: []testRecord{},
+			want: "nome,valor\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := toCSVResult(tt.data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := resultText(t, result); got != tt.want {
+				t.Errorf("csv = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToCSVResultWithoutRecords(t *testing.T) {
+	result, err := toCSVResult(map[string]int{"total": 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := resultText(t, result); got != "{\n  \"total\": 1\n}" {
+		t.Errorf("non-slice result = %q, want JSON", got)
+	}
+}
+
+func TestToMarkdownResult(t *testing.T) {
+	tests := []struct {
+		name string
+		data interface{}
+		want string
+	}{
+		{
+			name: "table",
+			data: []testRecord{{Name: "ACME", Value: 10.5}},
+			want: "| nome | valor |\n| --- | ---: |\n| ACME | 10.5 |\n",
+		},
+		{
+			name: "pipe escaped",
+			data: []testRecord{{Name: "A | B", Value: 1}},
+			want: "| nome | valor |\n| --- | ---: |\n| A \\| B | 1 |\n",
+		},
+		{
+			name: "line breaks",
+			data: []testRecord{{Name: "a\nb\r\nc", Value: 1}},
+			want: "| nome | valor |\n| --- | ---: |\n| a<br>b<br>c | 1 |\n",
+		},
+		{
+			name: "key/value for a single struct",
+			data: testRecord{Name: "x|y", Value: 2},
+			want: "| Field | Value |\n| --- | --- |\n| nome | x\\|y |\n| valor | 2 |\n",
+		},
+		{
+			name: "key/value for a map",
+			data: map[string]interface{}{"b": 2, "a": "1"},
+			want: "| Field | Value |\n| --- | --- |\n| a | 1 |\n| b | 2 |\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := toMarkdownResult(tt.data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := resultText(t, result); got != tt.want {
+				t.Errorf("markdown =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBCBPIXStatsTool(t *testing.T) {
+	var uris []string
+	bcbClient = bcb.NewClient(bcb.WithRetry(1, 0), bcb.WithBaseURL(stubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		uris = append(uris, r.URL.RequestURI())
+		w.Write([]byte(`{"value":[{"AnoMes":202403,"NATUREZA":"P2P","FORMAINICIACAO":"CHAVE","VALOR":1500.5,"QUANTIDADE":10}]}`))
+	})))
+
+	var resp bcb.PIXResponse
+	decodeResult(t, callTool(t, newTestServer(), "bcb_pix_stats", map[string]interface{}{"month": "202403"}), &resp)
+	if len(uris) != 1 || !strings.Contains(uris[0], "@Database='202403'") {
+		t.Errorf("requests = %v", uris)
+	}
+	if resp.Stats.Database != "202403" || resp.Stats.TotalTransactions != 10 || resp.Stats.TotalValue != 1500.5 {
+		t.Errorf("stats = %+v", resp.Stats)
+	}
+}
+
+func TestPNCPPriceRegistrationsTool(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      map[string]interface{}
+		wantQuery []string
+	}{
+		{name: "defaults", args: map[string]interface{}{}, wantQuery: []string{"pagina=1", "tamanhoPagina=50"}},
+		{name: "state and page", args: map[string]interface{}{"state": "MG", "page": 2}, wantQuery: []string{"pagina=2", "uf=MG"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uris []string
+			pncpClient = pncp.NewClient(pncp.WithRetry(1, 0), pncp.WithBaseURL(stubServer(t, func(w http.ResponseWriter, r *http.Request) {
+				uris = append(uris, r.URL.RequestURI())
+				w.Write([]byte(`{"data":[{"numeroControlePNCP":"00394452000103-1-000001/2024","numeroAta":"1/2024","objetoAta":"Registro de preços de papel A4","valorTotalEstimado":12000}]}`))
+			})))
+
+			var resp pncp.PriceRegistrationsResponse
+			decodeResult(t, callTool(t, newTestServer(), "pncp_price_registrations", tt.args), &resp)
+			if len(uris) != 1 || !strings.HasPrefix(uris[0], "/consulta/v1/atas-registro-preco?") {
+				t.Fatalf("requests = %v", uris)
+			}
+			for _, q := range tt.wantQuery {
+				if !strings.Contains(uris[0], q) {
+					t.Errorf("request %s lacks %s", uris[0], q)
+				}
+			}
+			if resp.Total != 1 || resp.Registrations[0].NumeroAta != "1/2024" || resp.Source != "pncp_api" {
+				t.Errorf("response = %+v", resp)
+			}
+		})
+	}
+}
+
+func TestPNCPContractsModalityName(t *testing.T) {
+	pncpClient = pncp.NewClient()
+	tests := []struct {
+		name     string
+		args     map[string]interface{}
+		wantCode string
+		wantErr  bool
+	}{
+		{name: "default", args: map[string]interface{}{}, wantCode: "codigoModalidadeContratacao=6"},
+		{name: "numeric", args: map[string]interface{}{"modality": 2}, wantCode: "codigoModalidadeContratacao=2"},
+		{name: "name wins", args: map[string]interface{}{"modality": 2, "modality_name": "Leilão Eletrônico"}, wantCode: "codigoModalidadeContratacao=1"},
+		{name: "unknown name", args: map[string]interface{}{"modality_name": "pregao"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := map[string]interface{}{"start_date": "20240101", "end_date": "20240131", "debug_url": true}
+			for k, v := range tt.args {
+				args[k] = v
+			}
+			result := callTool(t, newTestServer(), "pncp_contracts", args)
+			if tt.wantErr {
+				if !result.IsError || !strings.Contains(resultText(t, result), "unknown modality") {
+					t.Errorf("result = %s, want an unknown modality error", resultText(t, result))
+				}
+				return
+			}
+			var got struct {
+				URL string `json:"url"`
+			}
+			decodeResult(t, result, &got)
+			if !strings.Contains(got.URL, tt.wantCode) {
+				t.Errorf("url = %s, want %s", got.URL, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestMissingAPIKeyToolError(t *testing.T) {
+	transparenciaClient = transparencia.NewClient("")
+
+	result := callTool(t, newTestServer(), "search_ceis", map[string]interface{}{})
+	text := resultText(t, result)
+	if !result.IsError || !strings.HasPrefix(text, "[unauthorized]") || !strings.Contains(text, "TRANSPARENCY_API_KEY is required") {
+		t.Errorf("result = %q, want an unauthorized error naming TRANSPARENCY_API_KEY", text)
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	go serve(newTestServer(), "http", addr)
+
+	post := func(sessionID, body string) (*http.Response, string) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, "http://"+addr+"/mcp", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if sessionID != "" {
+			req.Header.Set("Mcp-Session-Id", sessionID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, string(data)
+	}
+
+	// Wait for the listener.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not listen on %s: %v", addr, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	resp, body := post("", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, serverName) {
+		t.Fatalf("initialize: %s %s", resp.Status, body)
+	}
+	resp, body = post(resp.Header.Get("Mcp-Session-Id"), `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, `"name":"lookup_cep"`) {
+		t.Errorf("tools/list: %s %s", resp.Status, body)
+	}
+}
+
+func TestServeUnknownTransport(t *testing.T) {
+	if err := serve(newTestServer(), "websocket", ":0"); err == nil || !strings.Contains(err.Error(), "unknown transport") {
+		t.Errorf("error = %v, want unknown transport", err)
+	}
+}
+
+func TestHealthcheckTool(t *testing.T) {
+	var mu sync.Mutex
+	var methods []string
+	healthy := stubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		methods = append(methods, r.Method)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNotFound)
+	})
+	failing := stubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	transparenciaClient = transparencia.NewClient("test-key", transparencia.WithBaseURL(healthy))
+	ibgeClient = ibge.NewClient(ibge.WithBaseURL(healthy))
+	cnpjClient = cnpj.NewClient(cnpj.WithBaseURL(healthy))
+	bcbClient = bcb.NewClient(bcb.WithBaseURL(failing))
+	pncpClient = pncp.NewClient(pncp.WithBaseURL(healthy))
+	cepClient = cep.NewClient(cep.WithBaseURL(healthy))
+
+	var report HealthReport
+	decodeResult(t, callTool(t, newTestServer(), "healthcheck", map[string]interface{}{}), &report)
+	if report.Status != "degraded" || len(report.Sources) != 6 {
+		t.Fatalf("report = %+v, want degraded over 6 sources", report)
+	}
+	for name, source := range report.Sources {
+		if name == "bcb" {
+			if source.Status != "error" || !strings.Contains(source.Error, "503") {
+				t.Errorf("bcb = %+v, want an error with status 503", source)
+			}
+			continue
+		}
+		if source.Status != "ok" || source.Error != "" {
+			t.Errorf("%s = %+v, want ok", name, source)
+		}
+	}
+	for _, m := range methods {
+		if m != http.MethodHead {
+			t.Errorf("health probe used %s, want HEAD", m)
+		}
+	}
+
+	bcbClient = bcb.NewClient(bcb.WithBaseURL(healthy))
+	decodeResult(t, callTool(t, newTestServer(), "healthcheck", map[string]interface{}{}), &report)
+	if report.Status != "ok" {
+		t.Errorf("status = %s with every source reachable", report.Status)
+	}
+}
+
+func TestServerInfoTool(t *testing.T) {
+	defer func(old string) { buildDate = old }(buildDate)
+	buildDate = "2024-03-01T12:00:00Z"
+
+	var info ServerInfo
+	decodeResult(t, callTool(t, newTestServer(), "server_info", map[string]interface{}{}), &info)
+	if info.Name != serverName || info.Version == "" || info.Version != serverVersion {
+		t.Errorf("name, version = %q, %q", info.Name, info.Version)
+	}
+	if !strings.HasPrefix(info.GoVersion, "go") || info.BuildDate != buildDate {
+		t.Errorf("go version, build date = %q, %q", info.GoVersion, info.BuildDate)
+	}
+}
+
+func TestBRLFormat(t *testing.T) {
+	pncpClient = pncp.NewClient(pncp.WithRetry(1, 0), pncp.WithBaseURL(stubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":[{"numeroAta":"1/2024","valorTotalEstimado":1234567.891}]}`))
+	})))
+
+	tests := []struct {
+		name      string
+		brl       bool
+		wantField bool
+	}{
+		{name: "enabled", brl: true, wantField: true},
+		{name: "disabled", brl: false, wantField: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp struct {
+				Registrations []map[string]interface{} `json:"registrations"`
+			}
+			decodeResult(t, callTool(t, newTestServer(), "pncp_price_registrations", map[string]interface{}{"brl_format": tt.brl}), &resp)
+			reg := resp.Registrations[0]
+			if reg["valorTotalEstimado"] != 1234567.891 {
+				t.Errorf("valorTotalEstimado = %v, want the numeric value kept", reg["valorTotalEstimado"])
+			}
+			formatted, ok := reg["valorTotalEstimadoFormatado"]
+			if ok != tt.wantField || (ok && formatted != "R$ 1.234.567,89") {
+				t.Errorf("valorTotalEstimadoFormatado = %v, present %v", formatted, ok)
+			}
+		})
+	}
+}
+
+func TestStatesResource(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+	ibgeClient = ibge.NewClient(ibge.WithRetry(1, 0), ibge.WithBaseURL(stubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		w.Write([]byte(`[{"id":35,"sigla":"SP","nome":"São Paulo","regiao":{"id":3,"sigla":"SE","nome":"Sudeste"}}]`))
+	})))
+
+	s := newTestServer()
+	for i := 0; i < 2; i++ {
+		msg := s.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":"ibge://states"}}`))
+		resp, ok := msg.(mcp.JSONRPCResponse)
+		if !ok {
+			t.Fatalf("resources/read: unexpected response %+v", msg)
+		}
+		result, ok := resp.Result.(mcp.ReadResourceResult)
+		if !ok || len(result.Contents) != 1 {
+			t.Fatalf("resources/read result = %+v", resp.Result)
+		}
+		text, ok := result.Contents[0].(mcp.TextResourceContents)
+		if !ok || text.MIMEType != "application/json" {
+			t.Fatalf("contents = %+v, want JSON text", result.Contents[0])
+		}
+		var states ibge.StatesResponse
+		if err := json.Unmarshal([]byte(text.Text), &states); err != nil {
+			t.Fatalf("resource is not JSON: %v\n%s", err, text.Text)
+		}
+		if len(states.States) != 1 || states.States[0].Sigla != "SP" {
+			t.Errorf("states = %+v", states)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("IBGE requested %d times over two reads, want 1", requests)
+	}
+}
+
+func TestIBGEMunicipalitiesCountOnly(t *testing.T) {
+	ibgeClient = ibge.NewClient(ibge.WithRetry(1, 0), ibge.WithBaseURL(stubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/localidades/estados":
+			w.Write([]byte(`[{"id":33,"sigla":"RJ","nome":"Rio de Janeiro"}]`))
+		case "/v1/localidades/estados/33/municipios":
+			w.Write([]byte(`[{"id":3300100,"nome":"Angra dos Reis"},{"id":3304557,"nome":"Rio de Janeiro"},{"id":3304904,"nome":"São Gonçalo"}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	})))
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want float64
+	}{
+		{name: "code", args: map[string]interface{}{"state_id": "33", "count_only": true}, want: 3},
+		{name: "sigla", args: map[string]interface{}{"state_id": "RJ", "count_only": true}, want: 3},
+		{name: "filtered", args: map[string]interface{}{"state_id": "33", "name_contains": "sao", "count_only": true}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]interface{}
+			decodeResult(t, callTool(t, newTestServer(), "ibge_municipalities", tt.args), &got)
+			if got["total"] != tt.want {
+				t.Errorf("total = %v, want %v", got["total"], tt.want)
+			}
+			if _, ok := got["municipalities"]; ok {
+				t.Errorf("count_only result lists the municipalities: %v", got)
+			}
+		})
+	}
+
+	var full ibge.MunicipalitiesResponse
+	decodeResult(t, callTool(t, newTestServer(), "ibge_municipalities", map[string]interface{}{"state_id": "33"}), &full)
+	if full.Total != 3 || len(full.Municipalities) != 3 {
+		t.Errorf("without count_only: total = %d, %d municipalities", full.Total, len(full.Municipalities))
+	}
+}
+
+func TestToolErrorCategories(t *testing.T) {
+	tests := []struct {
+		status int
+		want   string
+	}{
+		{status: http.StatusTooManyRequests, want: "[rate_limited] Error: "},
+		{status: http.StatusNotFound, want: "[not_found] Error: "},
+		{status: http.StatusUnauthorized, want: "[unauthorized] Error: "},
+		{status: http.StatusBadGateway, want: "[upstream_error] Error: "},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			pncpClient = pncp.NewClient(pncp.WithRetry(1, 0), pncp.WithBaseURL(stubServer(t, func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "quota exceeded", tt.status)
+			})))
+
+			result := callTool(t, newTestServer(), "pncp_price_registrations", map[string]interface{}{})
+			text := resultText(t, result)
+			if !result.IsError || !strings.HasPrefix(text, tt.want) {
+				t.Errorf("result = %q, want it to start with %q", text, tt.want)
+			}
+			if !strings.Contains(text, "quota exceeded") {
+				t.Errorf("result = %q, want the upstream detail kept", text)
+			}
+		})
+	}
+
+	if got := errorCategory(context.DeadlineExceeded); got != "timeout" {
+		t.Errorf("errorCategory(DeadlineExceeded) = %q, want timeout", got)
+	}
+	if result := toolError(errors.New("plain")); resultText(t, result) != "Error: plain" {
+		t.Errorf("uncategorized error = %q", resultText(t, result))
+	}
+}
+
+func TestToNDJSONResult(t *testing.T) {
+	type response struct {
+		Records []testRecord `json:"registros"`
+		Total   int          `json:"total"`
+	}
+	tests := []struct {
+		name      string
+		data      interface{}
+		wantLines int
+	}{
+		{name: "slice", data: []testRecord{{Name: "A", Value: 1}, {Name: "B", Value: 2}}, wantLines: 2},
+		{name: "records of a response struct", data: response{Records: []testRecord{{Name: "A"}, {Name: "B"}, {Name: "C"}}, Total: 3}, wantLines: 3},
+		{name: "newline inside a field", data: []testRecord{{Name: "linha 1\nlinha 2"}}, wantLines: 1},
+		{name: "empty slice", data: []testRecord{}, wantLines: 0},
+		{name: "no records", data: map[string]int{"total": 1}, wantLines: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := toNDJSONResult(tt.data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			text := resultText(t, result)
+			if tt.wantLines > 0 && !strings.HasSuffix(text, "\n") {
+				t.Errorf("output %q does not end with a newline", text)
+			}
+			lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+			if text == "" {
+				lines = nil
+			}
+			if len(lines) != tt.wantLines {
+				t.Fatalf("%d lines, want %d:\n%s", len(lines), tt.wantLines, text)
+			}
+			for i, line := range lines {
+				if !json.Valid([]byte(line)) {
+					t.Errorf("line %d is not valid JSON: %s", i+1, line)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchServidoresMaskCPF(t *testing.T) {
+	transparenciaClient = transparencia.NewClient("test-key", transparencia.WithRetry(1, 0), transparencia.WithBaseURL(stubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id":1,"cpf":"123.456.789-09","nome":"MARIA DA SILVA"}]`))
+	})))
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want string
+	}{
+		{name: "default", args: map[string]interface{}{}, want: "***.456.789-**"},
+		{name: "mask", args: map[string]interface{}{"mask_cpf": true}, want: "***.456.789-**"},
+		{name: "authorized", args: map[string]interface{}{"mask_cpf": false}, want: "123.456.789-09"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.args["nome"] = "MARIA"
+			var resp transparencia.ServidoresResponse
+			decodeResult(t, callTool(t, newTestServer(), "search_servidores", tt.args), &resp)
+			if len(resp.Servidores) != 1 || resp.Servidores[0].CPF != tt.want {
+				t.Errorf("servidores = %+v, want CPF %s", resp.Servidores, tt.want)
+			}
+		})
+	}
+}
+
+func TestBCBIndicatorSelicAliases(t *testing.T) {
+	tests := []struct {
+		indicator string
+		wantURI   string
+		wantCode  int
+	}{
+		{indicator: "selic", wantURI: "/dados/serie/bcdata.sgs.11/dados/ultimos/3?formato=json", wantCode: 11},
+		{indicator: "selic_meta", wantURI: "/dados/serie/bcdata.sgs.432/dados/ultimos/3?formato=json", wantCode: 432},
+	}
+	for _, tt := range tests {
+		t.Run(tt.indicator, func(t *testing.T) {
+			var uris []string
+			bcbClient = bcb.NewClient(bcb.WithRetry(1, 0), bcb.WithBaseURL(stubServer(t, func(w http.ResponseWriter, r *http.Request) {
+				uris = append(uris, r.URL.RequestURI())
+				w.Write([]byte(`[{"data":"01/03/2024","valor":"10.75"}]`))
+			})))
+
+			var resp bcb.IndicatorResponse
+			decodeResult(t, callTool(t, newTestServer(), "bcb_indicator", map[string]interface{}{"indicator": tt.indicator, "last_n": 3}), &resp)
+			if len(uris) != 1 || uris[0] != tt.wantURI {
+				t.Errorf("requests = %v, want [%s]", uris, tt.wantURI)
+			}
+			if resp.Series == nil || resp.Series.Code != tt.wantCode {
+				t.Errorf("series = %+v, want code %d", resp.Series, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestLookupCNPJQSAOnly(t *testing.T) {
+	cnpjClient = cnpj.NewClient(cnpj.WithRetry(1, 0), cnpj.WithBaseURL(stubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"cnpj":"33000167000101","razao_social":"PETROBRAS","uf":"RJ",
+			"qsa":[{"nome_socio":"MARIA DA SILVA","qualificacao_socio":"Sócio-Administrador"}]}`))
+	})))
+
+	var resp cnpj.PartnersResponse
+	decodeResult(t, callTool(t, newTestServer(), "lookup_cnpj", map[string]interface{}{
+		"cnpj":     "33000167000101",
+		"qsa_only": true,
+	}), &resp)
+	if resp.Total != 1 || resp.Partners[0].Nome != "MARIA DA SILVA" || resp.RazaoSocial != "PETROBRAS" {
+		t.Errorf("qsa_only response = %+v", resp)
+	}
+
+	var full map[string]interface{}
+	decodeResult(t, callTool(t, newTestServer(), "lookup_cnpj", map[string]interface{}{"cnpj": "33000167000101"}), &full)
+	if full["uf"] != "RJ" {
+		t.Errorf("full response = %v, want company data", full)
+	}
+}
+
+// getPrompt renders a prompt through prompts/get, as a client would,
+// returning its only message text, or the JSON-RPC error message and false
+// when the request fails.
+func getPrompt(t *testing.T, s *server.MCPServer, name string, args map[string]string) (string, bool) {
+	t.Helper()
+	params, err := json.Marshal(map[string]interface{}{"name": name, "arguments": args})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := s.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"prompts/get","params":`+string(params)+`}`))
+	switch resp := msg.(type) {
+	case mcp.JSONRPCError:
+		return resp.Error.Message, false
+	case mcp.JSONRPCResponse:
+		result, ok := resp.Result.(mcp.GetPromptResult)
+		if !ok || len(result.Messages) != 1 {
+			t.Fatalf("prompts/get %s result = %+v", name, resp.Result)
+		}
+		text, ok := result.Messages[0].Content.(mcp.TextContent)
+		if !ok || result.Messages[0].Role != mcp.RoleUser {
+			t.Fatalf("prompt message = %+v, want user text", result.Messages[0])
+		}
+		return text.Text, true
+	}
+	t.Fatalf("prompts/get %s: unexpected response %+v", name, msg)
+	return "", false
+}
+
+func TestDueDiligencePrompt(t *testing.T) {
+	tests := []struct {
+		cnpj   string
+		wantOK bool
+	}{
+		{cnpj: "33000167000101", wantOK: true},
+		{cnpj: "33.000.167/0001-01", wantOK: true},
+		{cnpj: "33.000.167/0001-02"},
+		{cnpj: ""},
+	}
+	s := newTestServer()
+	for _, tt := range tests {
+		text, ok := getPrompt(t, s, "due_diligence", map[string]string{"cnpj": tt.cnpj})
+		if ok != tt.wantOK {
+			t.Errorf("cnpj %q: ok = %v, want %v (%s)", tt.cnpj, ok, tt.wantOK, text)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		for _, want := range []string{
+			"CNPJ 33000167000101",
+			"lookup_cnpj with cnpj=33000167000101",
+			"screen_company with cnpj=33000167000101",
+			"supplier_cnpj=33000167000101",
+		} {
+			if !strings.Contains(text, want) {
+				t.Errorf("cnpj %q: prompt lacks %q:\n%s", tt.cnpj, want, text)
+			}
+		}
+	}
+}
+
+func TestEconomicSnapshotPrompt(t *testing.T) {
+	tests := []struct {
+		args   map[string]string
+		wantOK bool
+		want   []string
+	}{
+		{args: map[string]string{}, wantOK: true, want: []string{"last 12 months", "bcb_selic with last_n=252", "bcb_ipca with last_n=12"}},
+		{args: map[string]string{"period": ""}, wantOK: true, want: []string{"last 12 months"}},
+		{args: map[string]string{"period": "6"}, wantOK: true, want: []string{"last 6 months", "bcb_selic with last_n=126", "bcb_ipca with last_n=6", "bcb_exchange_rate with currency=USD"}},
+		{args: map[string]string{"period": "0"}},
+		{args: map[string]string{"period": "61"}},
+		{args: map[string]string{"period": "um ano"}},
+	}
+	s := newTestServer()
+	for _, tt := range tests {
+		text, ok := getPrompt(t, s, "economic_snapshot", tt.args)
+		if ok != tt.wantOK {
+			t.Errorf("args %v: ok = %v, want %v (%s)", tt.args, ok, tt.wantOK, text)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(text, want) {
+				t.Errorf("args %v: prompt lacks %q:\n%s", tt.args, want, text)
+			}
+		}
+	}
+}
+
+func TestEconomicSnapshotMessageStart(t *testing.T) {
+	now := time.Date(2024, 3, 31, 10, 0, 0, 0, time.UTC)
+	text := economicSnapshotMessage(3, now)
+	if !strings.Contains(text, "since 2023-12-31") || !strings.Contains(text, "date=2023-12-31") {
+		t.Errorf("message does not start the period on 2023-12-31:\n%s", text)
+	}
+}
+
+func TestDefaultPageSize(t *testing.T) {
+	t.Cleanup(func() { defaultPageSize = 0 })
+	var sizes []string
+	transparenciaClient = transparencia.NewClient("test-key", transparencia.WithRetry(1, 0), transparencia.WithBaseURL(stubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/contratos" {
+			sizes = append(sizes, r.URL.Query().Get("tamanhoPagina"))
+		}
+		w.Write([]byte(`[]`))
+	})))
+
+	tests := []struct {
+		env      string
+		pageSize interface{}
+		wantErr  bool
+		want     string
+	}{
+		{env: "", want: "100"},
+		{env: "50", want: "50"},
+		{env: "1000", want: "500"},
+		{env: "0", wantErr: true, want: "100"},
+		{env: "-5", wantErr: true, want: "100"},
+		{env: "grande", wantErr: true, want: "100"},
+		{env: "200", pageSize: 20, want: "20"},
+	}
+	s := newTestServer()
+	for _, tt := range tests {
+		size, err := parseDefaultPageSize(tt.env)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseDefaultPageSize(%q) error = %v, want error %v", tt.env, err, tt.wantErr)
+		}
+		defaultPageSize = size
+
+		args := map[string]interface{}{"orgao_code": "36000"}
+		if tt.pageSize != nil {
+			args["page_size"] = tt.pageSize
+		}
+		sizes = nil
+		if result := callTool(t, s, "search_contracts", args); result.IsError {
+			t.Fatalf("env %q: %s", tt.env, resultText(t, result))
+		}
+		if len(sizes) != 1 || sizes[0] != tt.want {
+			t.Errorf("env %q: tamanhoPagina = %v, want %s", tt.env, sizes, tt.want)
+		}
+	}
+}
+
+func TestBCBListIndicatorsTool(t *testing.T) {
+	bcbClient = bcb.NewClient()
+
+	var resp bcb.IndicatorsResponse
+	decodeResult(t, callTool(t, newTestServer(), "bcb_list_indicators", map[string]interface{}{}), &resp)
+	if resp.Total != len(bcb.SeriesCodes) || len(resp.Indicators) != len(bcb.SeriesCodes) {
+		t.Fatalf("listed %d indicators (total %d), want %d", len(resp.Indicators), resp.Total, len(bcb.SeriesCodes))
+	}
+	for i, ind := range resp.Indicators {
+		if series, ok := bcb.SeriesCodes[ind.Name]; !ok || ind.Series != series {
+			t.Errorf("indicator %+v does not match SeriesCodes", ind)
+		}
+		if i > 0 && resp.Indicators[i-1].Name >= ind.Name {
+			t.Errorf("indicators not sorted: %q before %q", resp.Indicators[i-1].Name, ind.Name)
+		}
+	}
+}
+
+func TestLookupCEPBatchTool(t *testing.T) {
+	cepClient = cep.NewClient(cep.WithRetry(1, 0), cep.WithBaseURL(stubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/01001000/") {
+			w.Write([]byte(`{"cep":"01001-000","logradouro":"Praça da Sé","localidade":"São Paulo","uf":"SP"}`))
+			return
+		}
+		w.Write([]byte(`{"erro": true}`))
+	})))
+
+	tests := []struct {
+		ceps      string
+		wantError bool
+		want      []string // the CEP of each result, "!" marking failures
+	}{
+		{ceps: "01001-000, 99999-999,abc,01001000", want: []string{"01001-000", "!99999-999", "!abc"}},
+		{ceps: "01001000", want: []string{"01001000"}},
+		{ceps: " , ", wantError: true},
+	}
+	s := newTestServer()
+	for _, tt := range tests {
+		result := callTool(t, s, "lookup_cep_batch", map[string]interface{}{"ceps": tt.ceps})
+		if result.IsError != tt.wantError {
+			t.Errorf("ceps %q: IsError = %v (%s)", tt.ceps, result.IsError, resultText(t, result))
+			continue
+		}
+		if tt.wantError {
+			continue
+		}
+		var resp cep.BatchResponse
+		decodeResult(t, result, &resp)
+		var got []string
+		for _, r := range resp.Results {
+			if r.Error != "" {
+				got = append(got, "!"+r.CEP)
+			} else {
+				got = append(got, r.CEP)
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("ceps %q: results = %v, want %v", tt.ceps, got, tt.want)
+		}
+	}
+}
+
+func TestLookupCNPJFields(t *testing.T) {
+	cnpjClient = cnpj.NewClient(cnpj.WithRetry(1, 0), cnpj.WithBaseURL(stubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"cnpj":"33000167000101","razao_social":"PETROBRAS","situacao_cadastral":2,"uf":"RJ","municipio":"RIO DE JANEIRO",
+			"qsa":[{"nome_socio":"MARIA DA SILVA"}]}`))
+	})))
+
+	tests := []struct {
+		fields    string
+		qsaOnly   bool
+		want      map[string]interface{}
+		wantError string
+	}{
+		{
+			fields: "razao_social,situacao_cadastral,uf",
+			want:   map[string]interface{}{"razao_social": "PETROBRAS", "situacao_cadastral": float64(2), "uf": "RJ"},
+		},
+		{fields: " razao_social , ,uf ", want: map[string]interface{}{"razao_social": "PETROBRAS", "uf": "RJ"}},
+		{fields: "razao_social,nome_fantasia", want: map[string]interface{}{"razao_social": "PETROBRAS"}},
+		{fields: "razao_social,capital,faturamento", wantError: "unknown fields: capital, faturamento"},
+		{fields: "total,cnpj", qsaOnly: true, want: map[string]interface{}{"total": float64(1), "cnpj": "33000167000101"}},
+		{fields: "uf", qsaOnly: true, wantError: "unknown fields: uf"},
+	}
+	s := newTestServer()
+	for _, tt := range tests {
+		result := callTool(t, s, "lookup_cnpj", map[string]interface{}{
+			"cnpj":     "33000167000101",
+			"fields":   tt.fields,
+			"qsa_only": tt.qsaOnly,
+		})
+		if tt.wantError != "" {
+			if text := resultText(t, result); !result.IsError || !strings.Contains(text, tt.wantError) || !strings.Contains(text, "Available:") {
+				t.Errorf("fields %q: result = %s, want an error mentioning %q", tt.fields, text, tt.wantError)
+			}
+			continue
+		}
+		var got map[string]interface{}
+		decodeResult(t, result, &got)
+		if len(got) != len(tt.want) {
+			t.Errorf("fields %q: got %v, want %v", tt.fields, got, tt.want)
+			continue
+		}
+		for k, v := range tt.want {
+			if got[k] != v {
+				t.Errorf("fields %q: %s = %v, want %v", tt.fields, k, got[k], v)
+			}
+		}
+	}
+}
+
+func TestBCBIndicatorsTool(t *testing.T) {
+	bcbClient = bcb.NewClient(bcb.WithRetry(1, 0), bcb.WithBaseURL(stubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "bcdata.sgs.11/"):
+			w.Write([]byte(`[{"data":"02/01/2024","valor":"0.043739"}]`))
+		case strings.Contains(r.URL.Path, "bcdata.sgs.433/"):
+			w.Write([]byte(`[{"data":"01/12/2023","valor":"0.56"}]`))
+		default:
+			http.Error(w, "erro", http.StatusInternalServerError)
+		}
+	})))
+
+	var resp struct {
+		Indicators map[string]bcb.IndicatorResponse `json:"indicators"`
+		Errors     map[string]string                `json:"errors"`
+	}
+	decodeResult(t, callTool(t, newTestServer(), "bcb_indicators", map[string]interface{}{
+		"indicators": "selic, ipca,igpm,desemprego",
+		"last_n":     1,
+	}), &resp)
+	if len(resp.Indicators) != 2 || resp.Indicators["selic"].Data[0].Value != "0.043739" || resp.Indicators["ipca"].Data[0].Value != "0.56" {
+		t.Errorf("indicators = %+v", resp.Indicators)
+	}
+	if len(resp.Errors) != 2 || resp.Errors["igpm"] == "" || !strings.Contains(resp.Errors["desemprego"], "unknown indicator") {
+		t.Errorf("errors = %v, want igpm and desemprego", resp.Errors)
+	}
+
+	result := callTool(t, newTestServer(), "bcb_indicators", map[string]interface{}{"indicators": " , "})
+	if !result.IsError {
+		t.Errorf("empty list result = %s, want a tool error", resultText(t, result))
+	}
+}
+
+func TestCallContext(t *testing.T) {
+	tests := []struct {
+		seconds interface{}
+		want    time.Duration // zero for no deadline
+	}{
+		{seconds: nil},
+		{seconds: 0},
+		{seconds: -5},
+		{seconds: 45, want: 45 * time.Second},
+		{seconds: 600, want: maxCallTimeout},
+	}
+	for _, tt := range tests {
+		var request mcp.CallToolRequest
+		request.Params.Arguments = map[string]interface{}{}
+		if tt.seconds != nil {
+			request.Params.Arguments = map[string]interface{}{"timeout_seconds": float64(tt.seconds.(int))}
+		}
+		ctx, cancel := callContext(context.Background(), request)
+		deadline, ok := ctx.Deadline()
+		cancel()
+		if ok != (tt.want > 0) {
+			t.Errorf("timeout_seconds %v: has deadline = %v", tt.seconds, ok)
+			continue
+		}
+		if left := time.Until(deadline); ok && (left > tt.want || left < tt.want-time.Second) {
+			t.Errorf("timeout_seconds %v: deadline in %v, want %v", tt.seconds, left, tt.want)
+		}
+	}
+}
+
+func TestTimeoutOverrideCancelsSlowRequest(t *testing.T) {
+	slow := stubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(10 * time.Second):
+		case <-r.Context().Done():
+		}
+	})
+	pncpClient = pncp.NewClient(pncp.WithRetry(1, 0), pncp.WithBaseURL(slow))
+	ibgeClient = ibge.NewClient(ibge.WithRetry(1, 0), ibge.WithBaseURL(slow))
+
+	tests := []struct {
+		tool string
+		args map[string]interface{}
+	}{
+		{"pncp_contracts", map[string]interface{}{"start_date": "20240101", "end_date": "20240131", "timeout_seconds": 1}},
+		{"ibge_municipalities", map[string]interface{}{"timeout_seconds": 1}},
+	}
+	s := newTestServer()
+	for _, tt := range tests {
+		start := time.Now()
+		result := callTool(t, s, tt.tool, tt.args)
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("%s took %v with a 1s override", tt.tool, elapsed)
+		}
+		if text := resultText(t, result); !result.IsError || !strings.HasPrefix(text, "[timeout]") {
+			t.Errorf("%s result = %s, want a timeout error", tt.tool, text)
+		}
+	}
+}
+
+func TestDebugURL(t *testing.T) {
+	var uris []string
+	upstream := stubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		uris = append(uris, r.URL.RequestURI())
+		if strings.HasPrefix(r.URL.Path, "/consulta/") {
+			w.Write([]byte(`{"data":[],"totalRegistros":0}`))
+			return
+		}
+		w.Write([]byte(`[]`))
+	})
+	transparenciaClient = transparencia.NewClient("segredo", transparencia.WithRetry(1, 0), transparencia.WithBaseURL(upstream))
+	pncpClient = pncp.NewClient(pncp.WithRetry(1, 0), pncp.WithBaseURL(upstream))
+
+	tests := []struct {
+		tool     string
+		args     map[string]interface{}
+		redacted bool
+	}{
+		{"search_contracts", map[string]interface{}{"orgao_code": "36000", "supplier_cnpj": "33.000.167/0001-01"}, true},
+		{"search_ceis", map[string]interface{}{"cnpj": "33000167000101", "page": 2}, true},
+		{"pncp_contracts", map[string]interface{}{"start_date": "20240101", "end_date": "20240131", "state": "SP"}, false},
+	}
+	s := newTestServer()
+	for _, tt := range tests {
+		uris = nil
+		debugArgs := map[string]interface{}{"debug_url": true}
+		for k, v := range tt.args {
+			debugArgs[k] = v
+		}
+		var debug struct {
+			Method  string            `json:"method"`
+			URL     string            `json:"url"`
+			Headers map[string]string `json:"headers"`
+		}
+		decodeResult(t, callTool(t, s, tt.tool, debugArgs), &debug)
+		if len(uris) != 0 {
+			t.Errorf("%s: debug_url sent requests %v", tt.tool, uris)
+		}
+		if debug.Method != http.MethodGet || strings.Contains(debug.URL, "segredo") {
+			t.Errorf("%s: debug = %+v", tt.tool, debug)
+		}
+		if tt.redacted && debug.Headers["chave-api-dados"] != "[REDACTED]" {
+			t.Errorf("%s: headers = %v, want the API key redacted", tt.tool, debug.Headers)
+		}
+
+		if result := callTool(t, s, tt.tool, tt.args); result.IsError {
+			t.Fatalf("%s: %s", tt.tool, resultText(t, result))
+		}
+		if len(uris) == 0 || upstream+uris[0] != debug.URL {
+			t.Errorf("%s: debug_url = %s, the call requested %v", tt.tool, debug.URL, uris)
+		}
+	}
+}
+
+func TestListToolsCatalog(t *testing.T) {
+	// Every tool registered in main.go, read from its source so that a new
+	// registration cannot be missing from the catalog unnoticed.
+	src, err := os.ReadFile("main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var registered []string
+	for _, m := range regexp.MustCompile(`mcp\.NewTool\("([a-z0-9_]+)"`).FindAllStringSubmatch(string(src), -1) {
+		registered = append(registered, m[1])
+	}
+	if len(registered) < 50 {
+		t.Fatalf("found only %d registrations in main.go", len(registered))
+	}
+
+	exportEnabled = true
+	t.Cleanup(func() { exportEnabled = false })
+
+	var catalog struct {
+		Tools []catalogEntry `json:"tools"`
+		Total int            `json:"total"`
+	}
+	decodeResult(t, callTool(t, newTestServer(), "list_tools", map[string]interface{}{}), &catalog)
+	if catalog.Total != len(catalog.Tools) || catalog.Total != len(registered) {
+		t.Errorf("catalog lists %d tools (total %d), main.go registers %d", len(catalog.Tools), catalog.Total, len(registered))
+	}
+
+	entries := make(map[string]catalogEntry, len(catalog.Tools))
+	for _, entry := range catalog.Tools {
+		entries[entry.Name] = entry
+	}
+	for _, name := range registered {
+		entry, ok := entries[name]
+		if !ok {
+			t.Errorf("catalog lacks %s", name)
+			continue
+		}
+		if entry.Description == "" || entry.InputSchema.Type != "object" {
+			t.Errorf("%s: description = %q, schema type = %q", name, entry.Description, entry.InputSchema.Type)
+		}
+	}
+
+	cnpjEntry := entries["lookup_cnpj"]
+	if _, ok := cnpjEntry.InputSchema.Properties["qsa_only"]; !ok || len(cnpjEntry.InputSchema.Required) != 1 || cnpjEntry.InputSchema.Required[0] != "cnpj" {
+		t.Errorf("lookup_cnpj schema = %+v", cnpjEntry.InputSchema)
+	}
+}
+
+func TestIncludeMeta(t *testing.T) {
+	cepClient = cep.NewClient(cep.WithRetry(1, 0), cep.WithBaseURL(stubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/01001000/") {
+			w.Write([]byte(`{"cep":"01001-000","logradouro":"Praça da Sé","localidade":"São Paulo","uf":"SP"}`))
+			return
+		}
+		w.Write([]byte(`{"erro": true}`))
+	})))
+	transparenciaClient = transparencia.NewClient("test-key", transparencia.WithRetry(1, 0), transparencia.WithBaseURL(stubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id":1,"numero":"1/2024","valorInicial":10}]`))
+	})))
+	s := newTestServer()
+
+	// The handler output, without the middleware, is what clients got
+	// before include_meta existed.
+	var request mcp.CallToolRequest
+	request.Params.Arguments = map[string]interface{}{"cep": "01001-000"}
+	direct, err := handleLookupCEP(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := resultText(t, direct)
+
+	for _, args := range []map[string]interface{}{
+		{"cep": "01001-000"},
+		{"cep": "01001-000", "include_meta": false},
+	} {
+		if got := resultText(t, callTool(t, s, "lookup_cep", args)); got != want {
+			t.Errorf("args %v: output changed:\n%s\nwant:\n%s", args, got, want)
+		}
+	}
+
+	before := time.Now().UTC().Truncate(time.Second)
+	var wrapped struct {
+		Data json.RawMessage `json:"data"`
+		Meta struct {
+			FetchedAt string `json:"fetched_at"`
+			LatencyMS *int64 `json:"latency_ms"`
+		} `json:"meta"`
+	}
+	decodeResult(t, callTool(t, s, "lookup_cep", map[string]interface{}{"cep": "01001-000", "include_meta": true}), &wrapped)
+	var gotData, wantData interface{}
+	if err := json.Unmarshal([]byte(want), &wantData); err != nil {
+		t.Fatalf("default output is not JSON: %v", err)
+	}
+	json.Unmarshal(wrapped.Data, &gotData)
+	if !reflect.DeepEqual(gotData, wantData) {
+		t.Errorf("data = %s, want %s", wrapped.Data, want)
+	}
+	fetchedAt, err := time.Parse(time.RFC3339, wrapped.Meta.FetchedAt)
+	if err != nil || fetchedAt.Before(before) || fetchedAt.After(time.Now()) {
+		t.Errorf("fetched_at = %q (%v)", wrapped.Meta.FetchedAt, err)
+	}
+	if wrapped.Meta.LatencyMS == nil || *wrapped.Meta.LatencyMS < 0 {
+		t.Errorf("latency_ms = %v", wrapped.Meta.LatencyMS)
+	}
+
+	// Errors and non-JSON formats are left alone.
+	result := callTool(t, s, "lookup_cep", map[string]interface{}{"cep": "99999-999", "include_meta": true})
+	if text := resultText(t, result); !result.IsError || strings.Contains(text, "fetched_at") {
+		t.Errorf("error result = %s", text)
+	}
+	result = callTool(t, s, "search_contracts", map[string]interface{}{"orgao_code": "36000", "format": "csv", "include_meta": true})
+	if text := resultText(t, result); result.IsError || strings.Contains(text, "fetched_at") || !strings.HasPrefix(text, "id,") {
+		t.Errorf("CSV result = %s", text)
+	}
+}
+
+func TestIncludeMetaAdvertised(t *testing.T) {
+	msg := newTestServer().HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	resp, ok := msg.(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatalf("tools/list: unexpected response %+v", msg)
+	}
+	list, ok := resp.Result.(mcp.ListToolsResult)
+	if !ok || len(list.Tools) == 0 {
+		t.Fatalf("tools/list result = %+v", resp.Result)
+	}
+	for _, tool := range list.Tools {
+		if _, ok := tool.InputSchema.Properties[includeMetaArg]; !ok {
+			t.Errorf("%s does not advertise include_meta", tool.Name)
+		}
+	}
+}
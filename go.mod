@@ -4,7 +4,10 @@ go 1.23.0
 
 toolchain go1.24.4
 
-require github.com/mark3labs/mcp-go v0.32.0
+require (
+	github.com/mark3labs/mcp-go v0.32.0
+	golang.org/x/sync v0.10.0
+)
 
 require (
 	github.com/google/uuid v1.6.0 // indirect
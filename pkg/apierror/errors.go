@@ -0,0 +1,82 @@
+// Package apierror defines the errors returned by the API clients, so callers
+// can tell failure kinds apart with errors.Is and errors.As.
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrNotFound means the requested resource does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrRateLimited means the API rejected the request for exceeding its quota.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrUnauthorized means credentials are missing or were rejected.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrUpstream means the API failed for any other reason.
+	ErrUpstream = errors.New("upstream error")
+)
+
+// APIError is a non-200 response from an upstream API.
+type APIError struct {
+	StatusCode int
+	Body       string
+	// RetryAfter is the wait the response asked for in its Retry-After
+	// header, or zero when it had none.
+	RetryAfter time.Duration
+}
+
+// New returns an *APIError for a response with the given status and body.
+func New(statusCode int, body []byte) error {
+	return &APIError{StatusCode: statusCode, Body: string(body)}
+}
+
+// FromResponse returns an *APIError for resp and its already read body,
+// keeping the Retry-After header.
+func FromResponse(resp *http.Response, body []byte) error {
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// parseRetryAfter reads a Retry-After value, either delay seconds or an HTTP
+// date. Missing, invalid and past values give zero.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error (status %d): %s", e.StatusCode, e.Body)
+}
+
+// Unwrap maps the status code to its sentinel error.
+func (e *APIError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrUnauthorized
+	default:
+		return ErrUpstream
+	}
+}
@@ -5,36 +5,152 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/anderson-ufrj/mcp-brasil/pkg/apierror"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/dedup"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/health"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/httpbody"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/httplog"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/metrics"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/retry"
 )
 
 const (
 	SGSURL         = "https://api.bcb.gov.br/dados/serie/bcdata.sgs"
 	OlindaURL      = "https://olinda.bcb.gov.br/olinda/servico"
 	DefaultTimeout = 30 * time.Second
+
+	// SGSDateLayout is the DD/MM/YYYY date format used by the SGS API.
+	SGSDateLayout = "02/01/2006"
+	// MonthLayout is the MM/YYYY format used for monthly series.
+	MonthLayout = "01/2006"
+	// PTAXDateLayout is the MM-DD-YYYY date format used by the PTAX API.
+	PTAXDateLayout = "01-02-2006"
 )
 
-// Series codes for economic indicators.
-var SeriesCodes = map[string]int{
-	"selic":         11,   // SELIC daily
-	"selic_monthly": 4390, // SELIC accumulated monthly
-	"ipca":          433,  // IPCA monthly
-	"igpm":          189,  // IGP-M monthly
-	"cdi":           12,   // CDI daily
+// DefaultMaxLastN caps the lastN accepted by GetIndicator and
+// GetSeriesByCode, about ten years of a daily series.
+const DefaultMaxLastN = 3650
+
+// Series describes the SGS series behind an indicator alias.
+type Series struct {
+	Code        int    `json:"code"`
+	Description string `json:"description"`
+	Unit        string `json:"unit"`
+	Frequency   string `json:"frequency"`
+}
+
+// SeriesCodes maps indicator aliases to their SGS series.
+var SeriesCodes = map[string]Series{
+	"selic":         {Code: 11, Description: "SELIC effective rate", Unit: "% a.d.", Frequency: "daily"},
+	"selic_meta":    {Code: 432, Description: "SELIC target set by COPOM", Unit: "% a.a.", Frequency: "daily"},
+	"selic_monthly": {Code: 4390, Description: "SELIC accumulated in the month", Unit: "% a.m.", Frequency: "monthly"},
+	"ipca":          {Code: 433, Description: "IPCA consumer price inflation", Unit: "% monthly change", Frequency: "monthly"},
+	"igpm":          {Code: 189, Description: "IGP-M general market price index", Unit: "% monthly change", Frequency: "monthly"},
+	"cdi":           {Code: 12, Description: "CDI interbank deposit rate", Unit: "% a.d.", Frequency: "daily"},
+}
+
+// FocusIndicators maps aliases to the indicator names used by the Focus
+// market expectations report (Expectativas de Mercado).
+var FocusIndicators = map[string]string{
+	"ipca":   "IPCA",
+	"selic":  "Selic",
+	"pib":    "PIB Total",
+	"cambio": "Câmbio",
+	"igpm":   "IGP-M",
 }
 
 // Client represents the BCB API client.
 type Client struct {
 	httpClient *http.Client
+	logger     *slog.Logger
+	metrics    metrics.Collector
+	flight     *dedup.Group
+	retry      retry.Policy
+	maxLastN   int
+	sgsURL     string
+	olindaURL  string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithBaseURL points the client at another root serving both BCB APIs, such
+// as a mirror or a test server. "/dados/serie/bcdata.sgs" and
+// "/olinda/servico" are appended to it for SGS and Olinda respectively.
+func WithBaseURL(root string) Option {
+	return func(c *Client) {
+		root = strings.TrimRight(root, "/")
+		c.sgsURL = root + "/dados/serie/bcdata.sgs"
+		c.olindaURL = root + "/olinda/servico"
+	}
+}
+
+// WithLogger logs the Banco Central requests on l at debug level, as described
+// in httplog.Request. A nil l disables logging.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Client) {
+		c.logger = httplog.OrDiscard(l)
+	}
+}
+
+// WithMetrics records every request (status class and latency) on m.
+func WithMetrics(m metrics.Collector) Option {
+	return func(c *Client) {
+		c.metrics = m
+	}
+}
+
+// WithDeduplication makes concurrent identical requests share a single
+// upstream call.
+func WithDeduplication() Option {
+	return func(c *Client) {
+		c.flight = &dedup.Group{}
+	}
+}
+
+// WithRetry sets how many times a request failing with a 429, a 5xx or a
+// connection error is attempted in total, and the delay before the first
+// retry, which doubles on each further one. maxAttempts below 2 disables
+// retries.
+func WithRetry(maxAttempts int, baseDelay time.Duration) Option {
+	return func(c *Client) {
+		c.retry = retry.Policy{MaxAttempts: maxAttempts, BaseDelay: baseDelay}
+	}
+}
+
+// WithMaxLastN sets the largest lastN accepted by GetIndicator and
+// GetSeriesByCode (default DefaultMaxLastN). Values below 1 are ignored.
+func WithMaxLastN(n int) Option {
+	return func(c *Client) {
+		if n > 0 {
+			c.maxLastN = n
+		}
+	}
 }
 
 // NewClient creates a new BCB client.
-func NewClient() *Client {
-	return &Client{
+func NewClient(opts ...Option) *Client {
+	c := &Client{
 		httpClient: &http.Client{Timeout: DefaultTimeout},
+		logger:     httplog.Discard,
+		retry:      retry.Default,
+		maxLastN:   DefaultMaxLastN,
+		sgsURL:     SGSURL,
+		olindaURL:  OlindaURL,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 // DataPoint represents a single data point from BCB.
@@ -43,35 +159,190 @@ type DataPoint struct {
 	Value string `json:"valor"`
 }
 
+// Float parses the data point value. Both dot and comma decimal separators
+// are accepted.
+func (dp DataPoint) Float() (float64, error) {
+	value := strings.TrimSpace(dp.Value)
+	if strings.Contains(value, ",") {
+		value = strings.ReplaceAll(value, ".", "")
+		value = strings.Replace(value, ",", ".", 1)
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", dp.Value)
+	}
+	return f, nil
+}
+
+// ParsedDate parses the DD/MM/YYYY data point date.
+func (dp DataPoint) ParsedDate() (time.Time, error) {
+	t, err := time.Parse(SGSDateLayout, dp.Date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q: expected DD/MM/YYYY", dp.Date)
+	}
+	return t, nil
+}
+
+// NumericPoint is a data point with parsed date and value.
+type NumericPoint struct {
+	Date  time.Time `json:"data"`
+	Value float64   `json:"valor"`
+}
+
 // IndicatorResponse represents the response for indicator queries.
 type IndicatorResponse struct {
 	Indicator string      `json:"indicator"`
+	Series    *Series     `json:"series,omitempty"`
 	Data      []DataPoint `json:"data"`
 	Total     int         `json:"total"`
 	Source    string      `json:"source"`
 }
 
-// ExchangeRate represents an exchange rate data point.
+// NumericData returns the response data with parsed dates and values,
+// skipping points that cannot be parsed.
+func (r *IndicatorResponse) NumericData() []NumericPoint {
+	points := make([]NumericPoint, 0, len(r.Data))
+	for _, dp := range r.Data {
+		date, err := dp.ParsedDate()
+		if err != nil {
+			continue
+		}
+		value, err := dp.Float()
+		if err != nil {
+			continue
+		}
+		points = append(points, NumericPoint{Date: date, Value: value})
+	}
+	return points
+}
+
+// ExchangeRate represents an exchange rate data point. BuyRate and SellRate
+// are in BRL per unit of the foreign currency; InverseRate is the foreign
+// currency bought by one BRL (1/SellRate), or 0 when SellRate is 0.
 type ExchangeRate struct {
 	DateTime     string  `json:"dataHoraCotacao"`
 	BuyRate      float64 `json:"cotacaoCompra"`
 	SellRate     float64 `json:"cotacaoVenda"`
 	BulletinType string  `json:"tipoBoletim"`
+	InverseRate  float64 `json:"cotacaoVendaInversa"`
+}
+
+// inverseRate returns 1/rate, or 0 for a rate that cannot be inverted.
+func inverseRate(rate float64) float64 {
+	if rate <= 0 {
+		return 0
+	}
+	return 1 / rate
+}
+
+// fillInverseRates sets InverseRate on each rate from its SellRate.
+func fillInverseRates(rates []ExchangeRate) {
+	for i := range rates {
+		rates[i].InverseRate = inverseRate(rates[i].SellRate)
+	}
+}
+
+// Currency describes a currency quoted by PTAX.
+type Currency struct {
+	Name   string `json:"name"`
+	Symbol string `json:"symbol"`
+}
+
+// Currencies maps the PTAX currency codes to their name and symbol.
+var Currencies = map[string]Currency{
+	"AUD": {Name: "Dolar australiano", Symbol: "A$"},
+	"CAD": {Name: "Dolar canadense", Symbol: "C$"},
+	"CHF": {Name: "Franco suico", Symbol: "CHF"},
+	"DKK": {Name: "Coroa dinamarquesa", Symbol: "kr"},
+	"EUR": {Name: "Euro", Symbol: "€"},
+	"GBP": {Name: "Libra esterlina", Symbol: "£"},
+	"JPY": {Name: "Iene", Symbol: "¥"},
+	"NOK": {Name: "Coroa norueguesa", Symbol: "kr"},
+	"SEK": {Name: "Coroa sueca", Symbol: "kr"},
+	"USD": {Name: "Dolar dos Estados Unidos", Symbol: "US$"},
+}
+
+// currencyInfo returns the Currencies entry of code, or nil if unknown.
+func currencyInfo(code string) *Currency {
+	info, ok := Currencies[strings.ToUpper(code)]
+	if !ok {
+		return nil
+	}
+	return &info
 }
 
 // ExchangeRateResponse represents the response for exchange rate queries.
 type ExchangeRateResponse struct {
-	Currency string         `json:"currency"`
-	Date     string         `json:"date"`
-	Rates    []ExchangeRate `json:"rates"`
-	Source   string         `json:"source"`
+	Currency     string         `json:"currency"`
+	CurrencyInfo *Currency      `json:"currency_info,omitempty"`
+	Date         string         `json:"date"`
+	Rates        []ExchangeRate `json:"rates"`
+	Source       string         `json:"source"`
+}
+
+// PIXRecord represents one aggregate row of the PIX open data statistics.
+type PIXRecord struct {
+	AnoMes          int     `json:"AnoMes"`
+	PagadorTipo     string  `json:"PAG_PFPJ"`
+	RecebedorTipo   string  `json:"REC_PFPJ"`
+	PagadorRegiao   string  `json:"PAG_REGIAO"`
+	RecebedorRegiao string  `json:"REC_REGIAO"`
+	FormaIniciacao  string  `json:"FORMAINICIACAO"`
+	Natureza        string  `json:"NATUREZA"`
+	Finalidade      string  `json:"FINALIDADE"`
+	Valor           float64 `json:"VALOR"`
+	Quantidade      int64   `json:"QUANTIDADE"`
+}
+
+// PIXBreakdown aggregates PIX transactions for one category.
+type PIXBreakdown struct {
+	Transactions  int64   `json:"transactions"`
+	Value         float64 `json:"value"`
+	AverageTicket float64 `json:"average_ticket"`
 }
 
 // PIXStats represents PIX statistics.
 type PIXStats struct {
-	TotalTransactions int64   `json:"total_transactions,omitempty"`
-	TotalValue        float64 `json:"total_value,omitempty"`
-	Data              interface{} `json:"data,omitempty"`
+	Database          string                   `json:"database"`
+	TotalTransactions int64                    `json:"total_transactions"`
+	TotalValue        float64                  `json:"total_value"`
+	AverageTicket     float64                  `json:"average_ticket"`
+	ByNature          map[string]PIXBreakdown  `json:"by_nature,omitempty"`
+	ByInitiation      map[string]PIXBreakdown  `json:"by_initiation,omitempty"`
+	RawData           []map[string]interface{} `json:"raw_data,omitempty"`
+}
+
+// add accumulates a record into the breakdown.
+func (b PIXBreakdown) add(r PIXRecord) PIXBreakdown {
+	b.Transactions += r.Quantidade
+	b.Value += r.Valor
+	if b.Transactions > 0 {
+		b.AverageTicket = b.Value / float64(b.Transactions)
+	}
+	return b
+}
+
+// summarizePIX computes the totals and breakdowns of the PIX records.
+func summarizePIX(database string, records []PIXRecord) PIXStats {
+	stats := PIXStats{
+		Database:     database,
+		ByNature:     map[string]PIXBreakdown{},
+		ByInitiation: map[string]PIXBreakdown{},
+	}
+	for _, r := range records {
+		stats.TotalTransactions += r.Quantidade
+		stats.TotalValue += r.Valor
+		if r.Natureza != "" {
+			stats.ByNature[r.Natureza] = stats.ByNature[r.Natureza].add(r)
+		}
+		if r.FormaIniciacao != "" {
+			stats.ByInitiation[r.FormaIniciacao] = stats.ByInitiation[r.FormaIniciacao].add(r)
+		}
+	}
+	if stats.TotalTransactions > 0 {
+		stats.AverageTicket = stats.TotalValue / float64(stats.TotalTransactions)
+	}
+	return stats
 }
 
 // PIXResponse represents the response for PIX statistics.
@@ -80,45 +351,219 @@ type PIXResponse struct {
 	Source string   `json:"source"`
 }
 
+// Ping checks that the SGS API is reachable (see health.Ping).
+func (c *Client) Ping(ctx context.Context) error {
+	return health.Ping(ctx, c.httpClient, c.logger, c.sgsURL)
+}
+
+// doRequest performs a GET request, retrying transient failures and sharing
+// the result with identical concurrent requests when deduplication is enabled.
 func (c *Client) doRequest(ctx context.Context, url string) ([]byte, error) {
+	return c.flight.Do(url, func() ([]byte, error) {
+		return c.retry.Do(ctx, func() ([]byte, error) {
+			return c.fetch(ctx, url)
+		})
+	})
+}
+
+// fetch performs a GET request and returns the body of a 200 response.
+func (c *Client) fetch(ctx context.Context, url string) ([]byte, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 
 	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept-Encoding", httpbody.AcceptEncoding)
 
+	start := time.Now()
 	resp, err := c.httpClient.Do(req)
+	duration := time.Since(start)
+	httplog.Request(ctx, c.logger, req, resp, err, duration)
+	metrics.Observe(c.metrics, "bcb", resp, err, duration)
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
 		return nil, fmt.Errorf("executing request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := httpbody.Read(resp)
 	if err != nil {
 		return nil, fmt.Errorf("reading response: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, apierror.FromResponse(resp, body)
 	}
 
 	return body, nil
 }
 
+// IndicatorInfo describes an indicator alias accepted by GetIndicator.
+type IndicatorInfo struct {
+	Name string `json:"name"`
+	Series
+}
+
+// IndicatorsResponse lists the available indicator aliases.
+type IndicatorsResponse struct {
+	Indicators []IndicatorInfo `json:"indicators"`
+	Total      int             `json:"total"`
+	Source     string          `json:"source"`
+}
+
+// ListIndicators returns the aliases in SeriesCodes, sorted by name.
+func (c *Client) ListIndicators() *IndicatorsResponse {
+	indicators := make([]IndicatorInfo, 0, len(SeriesCodes))
+	for name, series := range SeriesCodes {
+		indicators = append(indicators, IndicatorInfo{Name: name, Series: series})
+	}
+	sort.Slice(indicators, func(i, j int) bool { return indicators[i].Name < indicators[j].Name })
+	return &IndicatorsResponse{
+		Indicators: indicators,
+		Total:      len(indicators),
+		Source:     "bcb_api",
+	}
+}
+
+// unknownIndicatorError reports an unknown alias along with the available ones.
+func unknownIndicatorError(indicator string) error {
+	names := make([]string, 0, len(SeriesCodes))
+	for name := range SeriesCodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("unknown indicator: %s. Available: %s (or query any SGS series by its numeric code). "+
+		"Note: selic is the effective daily rate, selic_meta is the COPOM target rate", indicator, strings.Join(names, ", "))
+}
+
 // GetIndicator retrieves economic indicator data.
 func (c *Client) GetIndicator(ctx context.Context, indicator string, lastN int) (*IndicatorResponse, error) {
-	seriesCode, ok := SeriesCodes[indicator]
+	series, ok := SeriesCodes[indicator]
 	if !ok {
-		return nil, fmt.Errorf("unknown indicator: %s. Available: selic, selic_monthly, ipca, igpm, cdi", indicator)
+		return nil, unknownIndicatorError(indicator)
+	}
+	resp, err := c.GetSeriesByCode(ctx, series.Code, lastN)
+	if err != nil {
+		return nil, err
+	}
+	resp.Indicator = indicator
+	resp.Series = &series
+	return resp, nil
+}
+
+// indicatorsConcurrency bounds the series GetIndicators fetches at once.
+const indicatorsConcurrency = 4
+
+// IndicatorErrors maps indicator names to the error returned while fetching
+// them.
+type IndicatorErrors map[string]error
+
+func (e IndicatorErrors) Error() string {
+	return joinErrors(e)
+}
+
+// GetIndicators retrieves the last N values of several indicators
+// concurrently. Indicators that fail, including unknown names, are reported
+// through an IndicatorErrors error while the successful ones are still
+// returned in the map.
+func (c *Client) GetIndicators(ctx context.Context, names []string, lastN int) (map[string]*IndicatorResponse, error) {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" && !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	if len(unique) == 0 {
+		return nil, fmt.Errorf("at least one indicator is required")
+	}
+
+	indicators := make(map[string]*IndicatorResponse)
+	errs := IndicatorErrors{}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, indicatorsConcurrency)
+	for _, name := range unique {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			resp, err := c.GetIndicator(ctx, name, lastN)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[name] = err
+				return
+			}
+			indicators[name] = resp
+		}(name)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return indicators, errs
 	}
+	return indicators, nil
+}
 
+// GetSeriesByCode retrieves the last N values of any SGS series by its numeric code.
+func (c *Client) GetSeriesByCode(ctx context.Context, code int, lastN int) (*IndicatorResponse, error) {
+	if code <= 0 {
+		return nil, fmt.Errorf("invalid series code: %d", code)
+	}
 	if lastN <= 0 {
 		lastN = 30 // Default to last 30 values
 	}
+	if lastN > c.maxLastN {
+		return nil, fmt.Errorf("last_n %d exceeds the maximum of %d data points: query a date range instead", lastN, c.maxLastN)
+	}
+
+	url := fmt.Sprintf("%s.%d/dados/ultimos/%d?formato=json", c.sgsURL, code, lastN)
+	return c.fetchSeries(ctx, url, fmt.Sprintf("%d", code))
+}
+
+// GetIndicatorRange retrieves economic indicator data between two dates
+// (DD/MM/YYYY, inclusive). An empty endDate defaults to today.
+func (c *Client) GetIndicatorRange(ctx context.Context, indicator, startDate, endDate string) (*IndicatorResponse, error) {
+	series, ok := SeriesCodes[indicator]
+	if !ok {
+		return nil, unknownIndicatorError(indicator)
+	}
+	if endDate == "" {
+		endDate = time.Now().Format(SGSDateLayout)
+	}
+
+	start, err := time.Parse(SGSDateLayout, startDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date %q: expected DD/MM/YYYY", startDate)
+	}
+	end, err := time.Parse(SGSDateLayout, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end date %q: expected DD/MM/YYYY", endDate)
+	}
+	if start.After(end) {
+		return nil, fmt.Errorf("start date %s is after end date %s", startDate, endDate)
+	}
 
-	url := fmt.Sprintf("%s.%d/dados/ultimos/%d?formato=json", SGSURL, seriesCode, lastN)
+	url := fmt.Sprintf("%s.%d/dados?formato=json&dataInicial=%s&dataFinal=%s", c.sgsURL, series.Code, startDate, endDate)
+	resp, err := c.fetchSeries(ctx, url, indicator)
+	if err != nil {
+		return nil, err
+	}
+	resp.Series = &series
+	return resp, nil
+}
 
+// fetchSeries downloads and parses an SGS series.
+func (c *Client) fetchSeries(ctx context.Context, url, indicator string) (*IndicatorResponse, error) {
 	body, err := c.doRequest(ctx, url)
 	if err != nil {
 		return nil, err
@@ -137,6 +582,92 @@ func (c *Client) GetIndicator(ctx context.Context, indicator string, lastN int)
 	}, nil
 }
 
+// AdjustForInflation corrects an amount by the IPCA between two months
+// (MM/YYYY). The amount is taken at fromDate's price level, so the monthly
+// variations after fromDate up to and including toDate are compounded.
+func (c *Client) AdjustForInflation(ctx context.Context, amount float64, fromDate, toDate string) (float64, error) {
+	from, err := time.Parse(MonthLayout, fromDate)
+	if err != nil {
+		return 0, fmt.Errorf("invalid from date %q: expected MM/YYYY", fromDate)
+	}
+	to, err := time.Parse(MonthLayout, toDate)
+	if err != nil {
+		return 0, fmt.Errorf("invalid to date %q: expected MM/YYYY", toDate)
+	}
+	if from.After(to) {
+		return 0, fmt.Errorf("from date %s is after to date %s", fromDate, toDate)
+	}
+	if to.After(time.Now()) {
+		return 0, fmt.Errorf("to date %s is in the future", toDate)
+	}
+	if from.Equal(to) {
+		return amount, nil
+	}
+
+	first := from.AddDate(0, 1, 0)
+	resp, err := c.GetIndicatorRange(ctx, "ipca", first.Format(SGSDateLayout), to.Format(SGSDateLayout))
+	if err != nil {
+		return 0, err
+	}
+
+	if len(resp.Data) == 0 {
+		return 0, fmt.Errorf("IPCA for %s has not been published yet", first.Format(MonthLayout))
+	}
+	factor, last, err := compoundSeries(resp.Data)
+	if err != nil {
+		return 0, err
+	}
+	if last.Before(to) {
+		return 0, fmt.Errorf("IPCA for %s has not been published yet (needed up to %s); latest available is %s",
+			last.AddDate(0, 1, 0).Format(MonthLayout), toDate, last.Format(MonthLayout))
+	}
+
+	return amount * factor, nil
+}
+
+// compoundSeries multiplies (1 + value/100) over the data points and
+// returns the resulting factor and the date of the last point.
+func compoundSeries(data []DataPoint) (float64, time.Time, error) {
+	factor := 1.0
+	var last time.Time
+	for _, dp := range data {
+		value, err := dp.Float()
+		if err != nil {
+			return 0, time.Time{}, fmt.Errorf("parsing %s: %w", dp.Date, err)
+		}
+		date, err := dp.ParsedDate()
+		if err != nil {
+			return 0, time.Time{}, err
+		}
+		factor *= 1 + value/100
+		last = date
+	}
+	return factor, last, nil
+}
+
+// AccumulateDaily compounds a daily rate series (selic or cdi) over every
+// business day between two dates (DD/MM/YYYY, inclusive) and returns the
+// accumulated rate in percent.
+func (c *Client) AccumulateDaily(ctx context.Context, indicator, startDate, endDate string) (float64, error) {
+	if indicator != "selic" && indicator != "cdi" {
+		return 0, fmt.Errorf("indicator %s is not a daily rate: only selic and cdi can be accumulated", indicator)
+	}
+
+	resp, err := c.GetIndicatorRange(ctx, indicator, startDate, endDate)
+	if err != nil {
+		return 0, err
+	}
+	if len(resp.Data) == 0 {
+		return 0, fmt.Errorf("no %s data between %s and %s", indicator, startDate, endDate)
+	}
+
+	factor, _, err := compoundSeries(resp.Data)
+	if err != nil {
+		return 0, err
+	}
+	return (factor - 1) * 100, nil
+}
+
 // GetSELIC retrieves SELIC rate data.
 func (c *Client) GetSELIC(ctx context.Context, lastN int) (*IndicatorResponse, error) {
 	return c.GetIndicator(ctx, "selic", lastN)
@@ -147,17 +678,46 @@ func (c *Client) GetIPCA(ctx context.Context, lastN int) (*IndicatorResponse, er
 	return c.GetIndicator(ctx, "ipca", lastN)
 }
 
-// GetExchangeRate retrieves exchange rate for a currency.
+// parsePTAXDate parses a date given as YYYY-MM-DD, DD/MM/YYYY or MM-DD-YYYY,
+// detecting the format from the input shape.
+func parsePTAXDate(date string) (time.Time, error) {
+	var layout string
+	switch {
+	case len(date) == 10 && date[4] == '-' && date[7] == '-':
+		layout = "2006-01-02"
+	case len(date) == 10 && date[2] == '/' && date[5] == '/':
+		layout = SGSDateLayout
+	case len(date) == 10 && date[2] == '-' && date[5] == '-':
+		layout = PTAXDateLayout
+	default:
+		return time.Time{}, fmt.Errorf("unrecognized date format %q: use YYYY-MM-DD, DD/MM/YYYY or MM-DD-YYYY", date)
+	}
+
+	t, err := time.Parse(layout, date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q: %w", date, err)
+	}
+	return t, nil
+}
+
+// GetExchangeRate retrieves exchange rate for a currency. The date may be
+// given as YYYY-MM-DD, DD/MM/YYYY or MM-DD-YYYY and defaults to today.
 func (c *Client) GetExchangeRate(ctx context.Context, currency, date string) (*ExchangeRateResponse, error) {
 	if currency == "" {
 		currency = "USD"
 	}
 	if date == "" {
-		date = time.Now().Format("01-02-2006")
+		date = time.Now().Format(PTAXDateLayout)
+	} else {
+		t, err := parsePTAXDate(date)
+		if err != nil {
+			return nil, err
+		}
+		date = t.Format(PTAXDateLayout)
 	}
 
 	url := fmt.Sprintf("%s/PTAX/versao/v1/odata/CotacaoMoedaDia(moeda=@moeda,dataCotacao=@dataCotacao)?@moeda='%s'&@dataCotacao='%s'&$format=json",
-		OlindaURL, currency, date)
+		c.olindaURL, currency, date)
 
 	body, err := c.doRequest(ctx, url)
 	if err != nil {
@@ -171,32 +731,329 @@ func (c *Client) GetExchangeRate(ctx context.Context, currency, date string) (*E
 		return nil, fmt.Errorf("parsing response: %w", err)
 	}
 
+	fillInverseRates(result.Value)
+
 	return &ExchangeRateResponse{
-		Currency: currency,
-		Date:     date,
-		Rates:    result.Value,
-		Source:   "bcb_api",
+		Currency:     currency,
+		CurrencyInfo: currencyInfo(currency),
+		Date:         date,
+		Rates:        result.Value,
+		Source:       "bcb_api",
+	}, nil
+}
+
+// ExchangeRatePeriodResponse represents the exchange rate bulletins in a date window.
+type ExchangeRatePeriodResponse struct {
+	Currency     string         `json:"currency"`
+	CurrencyInfo *Currency      `json:"currency_info,omitempty"`
+	StartDate    string         `json:"start_date"`
+	EndDate      string         `json:"end_date"`
+	Rates        []ExchangeRate `json:"rates"`
+	Total        int            `json:"total"`
+	Source       string         `json:"source"`
+}
+
+// GetExchangeRatePeriod retrieves every PTAX bulletin for a currency between
+// two dates (inclusive), in any format accepted by GetExchangeRate. Days without quotes, such as weekends
+// and holidays, are simply absent from the result.
+func (c *Client) GetExchangeRatePeriod(ctx context.Context, currency, startDate, endDate string) (*ExchangeRatePeriodResponse, error) {
+	if currency == "" {
+		currency = "USD"
+	}
+	start, err := parsePTAXDate(startDate)
+	if err != nil {
+		return nil, fmt.Errorf("start date: %w", err)
+	}
+	end, err := parsePTAXDate(endDate)
+	if err != nil {
+		return nil, fmt.Errorf("end date: %w", err)
+	}
+	if start.After(end) {
+		return nil, fmt.Errorf("start date %s is after end date %s", startDate, endDate)
+	}
+	startDate = start.Format(PTAXDateLayout)
+	endDate = end.Format(PTAXDateLayout)
+
+	url := fmt.Sprintf("%s/PTAX/versao/v1/odata/CotacaoMoedaPeriodo(moeda=@moeda,dataInicial=@dataInicial,dataFinalCotacao=@dataFinalCotacao)?@moeda='%s'&@dataInicial='%s'&@dataFinalCotacao='%s'&$format=json",
+		c.olindaURL, currency, startDate, endDate)
+
+	body, err := c.doRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Value []ExchangeRate `json:"value"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+	if result.Value == nil {
+		result.Value = []ExchangeRate{}
+	}
+	fillInverseRates(result.Value)
+
+	return &ExchangeRatePeriodResponse{
+		Currency:     currency,
+		CurrencyInfo: currencyInfo(currency),
+		StartDate:    startDate,
+		EndDate:      endDate,
+		Rates:        result.Value,
+		Total:        len(result.Value),
+		Source:       "bcb_api",
 	}, nil
 }
 
-// GetPIXStats retrieves PIX statistics.
-func (c *Client) GetPIXStats(ctx context.Context) (*PIXResponse, error) {
-	url := fmt.Sprintf("%s/Pix_DadosAbertos/versao/v1/odata/EstatisticasTransacoesPix(Database=@Database)?@Database='202401'&$format=json", OlindaURL)
+// CurrencyErrors maps currency codes to the error returned while fetching them.
+type CurrencyErrors map[string]error
+
+func (e CurrencyErrors) Error() string {
+	return joinErrors(e)
+}
+
+// joinErrors renders a map of errors as "key: error" pairs sorted by key.
+func joinErrors(errs map[string]error) string {
+	keys := make([]string, 0, len(errs))
+	for key := range errs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	msgs := make([]string, len(keys))
+	for i, key := range keys {
+		msgs[i] = fmt.Sprintf("%s: %v", key, errs[key])
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// GetExchangeRates retrieves exchange rates for several currencies on the same
+// date concurrently. Currencies that fail are reported through a CurrencyErrors
+// error while the successful ones are still returned in the map.
+func (c *Client) GetExchangeRates(ctx context.Context, currencies []string, date string) (map[string]*ExchangeRateResponse, error) {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, currency := range currencies {
+		currency = strings.ToUpper(strings.TrimSpace(currency))
+		if currency != "" && !seen[currency] {
+			seen[currency] = true
+			unique = append(unique, currency)
+		}
+	}
+	if len(unique) == 0 {
+		return nil, fmt.Errorf("at least one currency is required")
+	}
+
+	rates := make(map[string]*ExchangeRateResponse)
+	errs := CurrencyErrors{}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, currency := range unique {
+		wg.Add(1)
+		go func(currency string) {
+			defer wg.Done()
+			resp, err := c.GetExchangeRate(ctx, currency, date)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[currency] = err
+				return
+			}
+			rates[currency] = resp
+		}(currency)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return rates, errs
+	}
+	return rates, nil
+}
+
+// ptaxRate returns the BRL selling rate of a PTAX response, preferring the
+// closing bulletin ("Fechamento") over the intraday ones.
+func ptaxRate(resp *ExchangeRateResponse) (float64, error) {
+	if resp == nil {
+		return 0, fmt.Errorf("%w: no PTAX quote", apierror.ErrNotFound)
+	}
+	if len(resp.Rates) == 0 {
+		return 0, fmt.Errorf("%w: no PTAX quote for %s on %s (weekend or holiday?)", apierror.ErrNotFound, resp.Currency, resp.Date)
+	}
+	rate := resp.Rates[len(resp.Rates)-1]
+	for _, r := range resp.Rates {
+		if strings.EqualFold(r.BulletinType, "Fechamento") {
+			rate = r
+		}
+	}
+	if rate.SellRate <= 0 {
+		return 0, fmt.Errorf("invalid PTAX quote for %s on %s: %v", resp.Currency, resp.Date, rate.SellRate)
+	}
+	return rate.SellRate, nil
+}
+
+// Conversion is the result of ConvertCurrency. ExchangeRate is the amount of
+// To bought by one unit of From, and InverseRate the amount of From bought
+// by one unit of To.
+type Conversion struct {
+	Amount          float64 `json:"amount"`
+	ConvertedAmount float64 `json:"converted_amount"`
+	From            string  `json:"from"`
+	To              string  `json:"to"`
+	Date            string  `json:"date"`
+	ExchangeRate    float64 `json:"exchange_rate"`
+	InverseRate     float64 `json:"inverse_exchange_rate"`
+	Rate            string  `json:"rate"`
+	Source          string  `json:"source"`
+}
+
+// ConvertCurrency converts an amount between two currencies using the PTAX
+// selling rates of the date, with BRL as the pivot. The date accepts the
+// same formats as GetExchangeRate and defaults to today.
+func (c *Client) ConvertCurrency(ctx context.Context, amount float64, from, to, date string) (*Conversion, error) {
+	from = strings.ToUpper(strings.TrimSpace(from))
+	to = strings.ToUpper(strings.TrimSpace(to))
+	if from == "" || to == "" {
+		return nil, fmt.Errorf("both source and target currencies are required")
+	}
+	conversion := &Conversion{Amount: amount, From: from, To: to, Date: date, Rate: "ptax", Source: "bcb_api"}
+	if from == to {
+		conversion.ConvertedAmount, conversion.ExchangeRate, conversion.InverseRate = amount, 1, 1
+		return conversion, nil
+	}
+
+	var currencies []string
+	for _, currency := range []string{from, to} {
+		if currency != "BRL" {
+			currencies = append(currencies, currency)
+		}
+	}
+	rates, err := c.GetExchangeRates(ctx, currencies, date)
+	if err != nil {
+		return nil, err
+	}
+
+	brlPer := map[string]float64{"BRL": 1}
+	for _, currency := range currencies {
+		rate, err := ptaxRate(rates[currency])
+		if err != nil {
+			return nil, err
+		}
+		brlPer[currency] = rate
+	}
+
+	conversion.ExchangeRate = brlPer[from] / brlPer[to]
+	conversion.InverseRate = brlPer[to] / brlPer[from]
+	conversion.ConvertedAmount = amount * conversion.ExchangeRate
+	return conversion, nil
+}
+
+// GetPIXStats retrieves PIX statistics for a database month (YYYYMM),
+// defaulting to the most recent completed month. The upstream records are
+// included as RawData only when includeRaw is set.
+func (c *Client) GetPIXStats(ctx context.Context, database string, includeRaw bool) (*PIXResponse, error) {
+	if database == "" {
+		database = time.Now().AddDate(0, -1, 0).Format("200601")
+	} else if _, err := time.Parse("200601", database); err != nil || len(database) != 6 {
+		return nil, fmt.Errorf("invalid database month %q: expected YYYYMM", database)
+	}
+
+	url := fmt.Sprintf("%s/Pix_DadosAbertos/versao/v1/odata/EstatisticasTransacoesPix(Database=@Database)?@Database='%s'&$format=json", c.olindaURL, database)
 
 	body, err := c.doRequest(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 
-	var result map[string]interface{}
+	var result struct {
+		Value []PIXRecord `json:"value"`
+	}
 	if err := json.Unmarshal(body, &result); err != nil {
 		return nil, fmt.Errorf("parsing response: %w", err)
 	}
 
+	stats := summarizePIX(database, result.Value)
+	if includeRaw {
+		var raw struct {
+			Value []map[string]interface{} `json:"value"`
+		}
+		if err := json.Unmarshal(body, &raw); err != nil {
+			return nil, fmt.Errorf("parsing response: %w", err)
+		}
+		stats.RawData = raw.Value
+	}
+
 	return &PIXResponse{
-		Stats: PIXStats{
-			Data: result,
-		},
+		Stats:  stats,
 		Source: "bcb_api",
 	}, nil
 }
+
+// FocusExpectation represents one Focus survey result for a reference year.
+type FocusExpectation struct {
+	Indicator     string  `json:"Indicador"`
+	Date          string  `json:"Data"`
+	ReferenceDate string  `json:"DataReferencia"`
+	Mean          float64 `json:"Media"`
+	Median        float64 `json:"Mediana"`
+	StdDev        float64 `json:"DesvioPadrao"`
+	Min           float64 `json:"Minimo"`
+	Max           float64 `json:"Maximo"`
+	Respondents   int     `json:"numeroRespondentes"`
+}
+
+// FocusResponse represents the response for Focus expectations queries.
+type FocusResponse struct {
+	Indicator    string             `json:"indicator"`
+	Expectations []FocusExpectation `json:"expectations"`
+	Source       string             `json:"source"`
+}
+
+// parseFocus decodes an Expectativas de Mercado OData payload.
+func parseFocus(body []byte) ([]FocusExpectation, error) {
+	var result struct {
+		Value []FocusExpectation `json:"value"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+	return result.Value, nil
+}
+
+// GetFocusExpectations retrieves the last N annual market expectations from
+// the Focus report for an indicator (ipca, selic, pib, cambio or igpm). Only
+// the 30-day calculation base is returned, most recent survey first.
+func (c *Client) GetFocusExpectations(ctx context.Context, indicator string, lastN int) (*FocusResponse, error) {
+	name, ok := FocusIndicators[strings.ToLower(indicator)]
+	if !ok {
+		names := make([]string, 0, len(FocusIndicators))
+		for alias := range FocusIndicators {
+			names = append(names, alias)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown Focus indicator: %s. Available: %s", indicator, strings.Join(names, ", "))
+	}
+	if lastN <= 0 {
+		lastN = 10
+	}
+
+	filter := url.PathEscape(fmt.Sprintf("Indicador eq '%s' and baseCalculo eq 0", name))
+	u := fmt.Sprintf("%s/Expectativas/versao/v1/odata/ExpectativasMercadoAnuais?$top=%d&$filter=%s&$orderby=Data%%20desc&$format=json",
+		c.olindaURL, lastN, filter)
+
+	body, err := c.doRequest(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	expectations, err := parseFocus(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FocusResponse{
+		Indicator:    name,
+		Expectations: expectations,
+		Source:       "bcb_api",
+	}, nil
+}
@@ -0,0 +1,979 @@
+package bcb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anderson-ufrj/mcp-brasil/pkg/apierror"
+)
+
+// newTestClient returns a client whose requests are served by h, with
+// retries disabled.
+func newTestClient(t *testing.T, h http.HandlerFunc, opts ...Option) *Client {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return NewClient(append([]Option{WithBaseURL(srv.URL), WithRetry(1, 0)}, opts...)...)
+}
+
+// recordRequests answers every request with body, appending each request
+// URI to *uris.
+func recordRequests(body string, uris *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		*uris = append(*uris, r.URL.RequestURI())
+		w.Write([]byte(body))
+	}
+}
+
+func TestListIndicators(t *testing.T) {
+	listed := map[string]IndicatorInfo{}
+	resp := NewClient().ListIndicators()
+	for _, ind := range resp.Indicators {
+		listed[ind.Name] = ind
+	}
+	if resp.Total != len(SeriesCodes) || len(listed) != len(SeriesCodes) {
+		t.Fatalf("listed %d indicators (total %d), want %d", len(listed), resp.Total, len(SeriesCodes))
+	}
+
+	tests := []struct {
+		name string
+		code int
+	}{
+		{"selic", 11},
+		{"selic_meta", 432},
+		{"selic_monthly", 4390},
+		{"ipca", 433},
+		{"igpm", 189},
+		{"cdi", 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ind, ok := listed[tt.name]
+			if !ok {
+				t.Fatalf("alias %q not listed", tt.name)
+			}
+			if ind.Code != tt.code {
+				t.Errorf("code = %d, want %d", ind.Code, tt.code)
+			}
+		})
+	}
+
+	for name, series := range SeriesCodes {
+		if series.Description == "" {
+			t.Errorf("SeriesCodes[%q] has no description", name)
+		}
+	}
+}
+
+func TestGetSeriesByCodeMaxLastN(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"data":"02/01/2024","valor":"11.75"}]`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		lastN   int
+		wantErr bool
+	}{
+		{"at default cap", nil, DefaultMaxLastN, false},
+		{"above default cap", nil, DefaultMaxLastN + 1, true},
+		{"at custom cap", []Option{WithMaxLastN(10)}, 10, false},
+		{"above custom cap", []Option{WithMaxLastN(10)}, 11, true},
+		{"non-positive cap ignored", []Option{WithMaxLastN(0)}, DefaultMaxLastN, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient(append([]Option{WithBaseURL(srv.URL)}, tt.opts...)...)
+			_, err := c.GetSeriesByCode(context.Background(), 11, tt.lastN)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "exceeds the maximum") {
+					t.Fatalf("err = %v, want cap error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestGetIndicatorMaxLastN(t *testing.T) {
+	c := NewClient(WithBaseURL("http://127.0.0.1:1"), WithMaxLastN(5))
+	if _, err := c.GetIndicator(context.Background(), "selic", 6); err == nil || !strings.Contains(err.Error(), "exceeds the maximum") {
+		t.Fatalf("err = %v, want cap error", err)
+	}
+}
+
+func TestGetIndicatorRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		indicator string
+		start     string
+		end       string
+		wantURI   string
+		wantErr   string
+	}{
+		{
+			name: "ipca range", indicator: "ipca", start: "01/01/2020", end: "31/12/2023",
+			wantURI: "/dados/serie/bcdata.sgs.433/dados?formato=json&dataInicial=01/01/2020&dataFinal=31/12/2023",
+		},
+		{
+			name: "single day", indicator: "selic", start: "02/01/2024", end: "02/01/2024",
+			wantURI: "/dados/serie/bcdata.sgs.11/dados?formato=json&dataInicial=02/01/2024&dataFinal=02/01/2024",
+		},
+		{name: "unknown indicator", indicator: "pib", start: "01/01/2020", end: "31/12/2023", wantErr: "unknown indicator"},
+		{name: "ISO start", indicator: "ipca", start: "2020-01-01", end: "31/12/2023", wantErr: "invalid start date"},
+		{name: "bad end", indicator: "ipca", start: "01/01/2020", end: "31/13/2023", wantErr: "invalid end date"},
+		{name: "start after end", indicator: "ipca", start: "01/01/2024", end: "31/12/2023", wantErr: "is after end date"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uris []string
+			c := newTestClient(t, recordRequests(`[{"data":"01/01/2020","valor":"0.21"}]`, &uris))
+			resp, err := c.GetIndicatorRange(context.Background(), tt.indicator, tt.start, tt.end)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				if len(uris) != 0 {
+					t.Fatalf("requests sent despite invalid arguments: %v", uris)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(uris) != 1 || uris[0] != tt.wantURI {
+				t.Errorf("requests = %v, want [%s]", uris, tt.wantURI)
+			}
+			if resp.Series == nil || resp.Total != 1 {
+				t.Errorf("response = %+v", resp)
+			}
+		})
+	}
+}
+
+func TestGetSeriesByCodeURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    int
+		lastN   int
+		wantURI string
+	}{
+		{"unemployment", 24369, 12, "/dados/serie/bcdata.sgs.24369/dados/ultimos/12?formato=json"},
+		{"default last_n", 13621, 0, "/dados/serie/bcdata.sgs.13621/dados/ultimos/30?formato=json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uris []string
+			c := newTestClient(t, recordRequests(`[{"data":"01/01/2024","valor":"7.4"}]`, &uris))
+			resp, err := c.GetSeriesByCode(context.Background(), tt.code, tt.lastN)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(uris) != 1 || uris[0] != tt.wantURI {
+				t.Errorf("requests = %v, want [%s]", uris, tt.wantURI)
+			}
+			if resp.Total != 1 || resp.Data[0].Value != "7.4" {
+				t.Errorf("response = %+v", resp)
+			}
+		})
+	}
+}
+
+func TestGetSeriesByCodeInvalid(t *testing.T) {
+	c := NewClient(WithBaseURL("http://127.0.0.1:1"))
+	if _, err := c.GetSeriesByCode(context.Background(), 0, 10); err == nil {
+		t.Fatal("expected an error for series code 0")
+	}
+}
+
+func TestGetIndicatorUnknownAlias(t *testing.T) {
+	c := NewClient(WithBaseURL("http://127.0.0.1:1"))
+	_, err := c.GetIndicator(context.Background(), "unemployment", 10)
+	if err == nil {
+		t.Fatal("expected an error for an unknown alias")
+	}
+	for _, want := range []string{"unknown indicator: unemployment", "selic_meta", "numeric code"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestGetIndicatorDelegates(t *testing.T) {
+	var uris []string
+	c := newTestClient(t, recordRequests(`[]`, &uris))
+	resp, err := c.GetIndicator(context.Background(), "igpm", 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "/dados/serie/bcdata.sgs.189/dados/ultimos/5?formato=json"; len(uris) != 1 || uris[0] != want {
+		t.Errorf("requests = %v, want [%s]", uris, want)
+	}
+	if resp.Indicator != "igpm" || resp.Series == nil || resp.Series.Code != 189 {
+		t.Errorf("response = %+v", resp)
+	}
+}
+
+func TestAdjustForInflation(t *testing.T) {
+	const ipca = `[{"data":"01/02/2023","valor":"0.84"},{"data":"01/03/2023","valor":"0.71"}]`
+	nextMonth := time.Now().AddDate(0, 1, 0).Format(MonthLayout)
+
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		want     float64
+		wantURI  string
+		wantErr  string
+		requests int
+	}{
+		{
+			name: "two months", from: "01/2023", to: "03/2023", want: 1000 * 1.0084 * 1.0071, requests: 1,
+			wantURI: "/dados/serie/bcdata.sgs.433/dados?formato=json&dataInicial=01/02/2023&dataFinal=01/03/2023",
+		},
+		{name: "same month", from: "03/2023", to: "03/2023", want: 1000},
+		{name: "not yet published", from: "01/2023", to: "04/2023", wantErr: "has not been published yet", requests: 1},
+		{name: "future", from: "01/2023", to: nextMonth, wantErr: "is in the future"},
+		{name: "reversed", from: "03/2023", to: "01/2023", wantErr: "is after to date"},
+		{name: "bad format", from: "2023-01", to: "03/2023", wantErr: "invalid from date"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uris []string
+			c := newTestClient(t, recordRequests(ipca, &uris))
+			got, err := c.AdjustForInflation(context.Background(), 1000, tt.from, tt.to)
+			if len(uris) != tt.requests {
+				t.Fatalf("%d requests, want %d", len(uris), tt.requests)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("adjusted = %.10f, want %.10f", got, tt.want)
+			}
+			if tt.wantURI != "" && uris[0] != tt.wantURI {
+				t.Errorf("request = %s, want %s", uris[0], tt.wantURI)
+			}
+		})
+	}
+}
+
+func TestAccumulateDaily(t *testing.T) {
+	const daily = `[{"data":"02/01/2024","valor":"0.043739"},{"data":"03/01/2024","valor":"0.043739"},{"data":"04/01/2024","valor":"0.043739"}]`
+
+	tests := []struct {
+		name      string
+		indicator string
+		body      string
+		want      float64
+		wantErr   string
+	}{
+		{name: "selic", indicator: "selic", body: daily, want: 0.131274},
+		{name: "cdi", indicator: "cdi", body: daily, want: 0.131274},
+		{name: "monthly series rejected", indicator: "ipca", body: daily, wantErr: "not a daily rate"},
+		{name: "no data", indicator: "cdi", body: `[]`, wantErr: "no cdi data"},
+		{name: "unparsable value", indicator: "selic", body: `[{"data":"02/01/2024","valor":"n/a"}]`, wantErr: "parsing 02/01/2024"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uris []string
+			c := newTestClient(t, recordRequests(tt.body, &uris))
+			got, err := c.AccumulateDaily(context.Background(), tt.indicator, "02/01/2024", "04/01/2024")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if math.Round(got*1e6) != math.Round(tt.want*1e6) {
+				t.Errorf("accumulated = %.6f%%, want %.6f%%", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDataPointFloat(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{"13.75", 13.75, false},
+		{"13,75", 13.75, false},
+		{"1.234,56", 1234.56, false},
+		{" 0.04 ", 0.04, false},
+		{"-0,5", -0.5, false},
+		{"", 0, true},
+		{"abc", 0, true},
+		{"1,2,3", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := DataPoint{Value: tt.value}.Float()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Float() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Float() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDataPointParsedDate(t *testing.T) {
+	got, err := DataPoint{Date: "31/01/2024"}.ParsedDate()
+	if err != nil || !got.Equal(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("ParsedDate() = %v, %v", got, err)
+	}
+	if _, err := (DataPoint{Date: "2024-01-31"}).ParsedDate(); err == nil {
+		t.Error("expected an error for an ISO date")
+	}
+}
+
+func TestNumericDataSkipsMalformed(t *testing.T) {
+	resp := &IndicatorResponse{Data: []DataPoint{
+		{Date: "01/01/2024", Value: "0,42"},
+		{Date: "01/02/2024", Value: "-"},
+		{Date: "2024-03-01", Value: "0.16"},
+		{Date: "01/04/2024", Value: "0.38"},
+	}}
+	points := resp.NumericData()
+	if len(points) != 2 || points[0].Value != 0.42 || points[1].Value != 0.38 {
+		t.Errorf("NumericData() = %+v", points)
+	}
+	if resp.Data[0].Value != "0,42" {
+		t.Errorf("raw value changed to %q", resp.Data[0].Value)
+	}
+}
+
+// ptaxServer answers PTAX daily quotes with the selling rate of the
+// requested currency in rates, failing with a 500 for any other currency.
+func ptaxServer(rates map[string]float64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currency := strings.Trim(r.URL.Query().Get("@moeda"), "'")
+		rate, ok := rates[currency]
+		if !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintf(w, `{"value":[{"cotacaoCompra":%g,"cotacaoVenda":%g,"tipoBoletim":"Fechamento"}]}`, rate, rate)
+	}
+}
+
+func TestGetExchangeRates(t *testing.T) {
+	c := newTestClient(t, ptaxServer(map[string]float64{"USD": 4.95, "EUR": 5.40}))
+
+	rates, err := c.GetExchangeRates(context.Background(), []string{"usd", " EUR", "GBP", "USD", ""}, "2024-03-15")
+	var errs CurrencyErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("err = %v, want CurrencyErrors", err)
+	}
+	if len(errs) != 1 || errs["GBP"] == nil {
+		t.Errorf("errors = %v, want only GBP", errs)
+	}
+
+	tests := []struct {
+		currency string
+		want     float64
+	}{
+		{"USD", 4.95},
+		{"EUR", 5.40},
+	}
+	if len(rates) != len(tests) {
+		t.Fatalf("got %d currencies, want %d", len(rates), len(tests))
+	}
+	for _, tt := range tests {
+		resp := rates[tt.currency]
+		if resp == nil || len(resp.Rates) != 1 {
+			t.Fatalf("%s: response = %+v", tt.currency, resp)
+		}
+		if resp.Rates[0].SellRate != tt.want || resp.Date != "03-15-2024" {
+			t.Errorf("%s: rate %v on %s, want %v on 03-15-2024", tt.currency, resp.Rates[0].SellRate, resp.Date, tt.want)
+		}
+	}
+}
+
+func TestGetExchangeRatesNoCurrency(t *testing.T) {
+	c := NewClient(WithBaseURL("http://127.0.0.1:1"))
+	if _, err := c.GetExchangeRates(context.Background(), []string{" ", ""}, ""); err == nil {
+		t.Fatal("expected an error without currencies")
+	}
+}
+
+func TestGetExchangeRatePeriod(t *testing.T) {
+	const periodPath = "/olinda/servico/PTAX/versao/v1/odata/CotacaoMoedaPeriodo(moeda=@moeda,dataInicial=@dataInicial,dataFinalCotacao=@dataFinalCotacao)"
+
+	tests := []struct {
+		name      string
+		currency  string
+		start     string
+		end       string
+		body      string
+		wantQuery string
+		wantTotal int
+		wantErr   bool
+	}{
+		{
+			name: "week", currency: "EUR", start: "03-11-2024", end: "03-17-2024",
+			body:      `{"value":[{"cotacaoVenda":5.41},{"cotacaoVenda":5.43}]}`,
+			wantQuery: "@moeda='EUR'&@dataInicial='03-11-2024'&@dataFinalCotacao='03-17-2024'&$format=json",
+			wantTotal: 2,
+		},
+		{
+			name: "ISO dates and default currency", start: "2024-03-11", end: "2024-03-17",
+			body:      `{"value":[]}`,
+			wantQuery: "@moeda='USD'&@dataInicial='03-11-2024'&@dataFinalCotacao='03-17-2024'&$format=json",
+		},
+		{
+			name: "weekend without quotes", currency: "USD", start: "16/03/2024", end: "17/03/2024",
+			body:      `{}`,
+			wantQuery: "@moeda='USD'&@dataInicial='03-16-2024'&@dataFinalCotacao='03-17-2024'&$format=json",
+		},
+		{name: "reversed", currency: "USD", start: "03-17-2024", end: "03-11-2024", wantErr: true},
+		{name: "bad start", currency: "USD", start: "2024/03/11", end: "03-17-2024", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uris []string
+			c := newTestClient(t, recordRequests(tt.body, &uris))
+			resp, err := c.GetExchangeRatePeriod(context.Background(), tt.currency, tt.start, tt.end)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want := periodPath + "?" + tt.wantQuery; len(uris) != 1 || uris[0] != want {
+				t.Errorf("requests = %v, want [%s]", uris, want)
+			}
+			if resp.Rates == nil || resp.Total != tt.wantTotal {
+				t.Errorf("rates = %v (total %d), want %d", resp.Rates, resp.Total, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestGetExchangeRateDateFormats(t *testing.T) {
+	const want = "/olinda/servico/PTAX/versao/v1/odata/CotacaoMoedaDia(moeda=@moeda,dataCotacao=@dataCotacao)?@moeda='USD'&@dataCotacao='03-15-2024'&$format=json"
+
+	tests := []struct {
+		name    string
+		date    string
+		wantErr bool
+	}{
+		{name: "ISO", date: "2024-03-15"},
+		{name: "Brazilian", date: "15/03/2024"},
+		{name: "PTAX", date: "03-15-2024"},
+		{name: "compact", date: "20240315", wantErr: true},
+		{name: "day out of range", date: "32/03/2024", wantErr: true},
+		{name: "slashes in ISO order", date: "2024/03/15", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uris []string
+			c := newTestClient(t, recordRequests(`{"value":[]}`, &uris))
+			_, err := c.GetExchangeRate(context.Background(), "USD", tt.date)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				if len(uris) != 0 {
+					t.Fatalf("requests sent for an invalid date: %v", uris)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(uris) != 1 || uris[0] != want {
+				t.Errorf("requests = %v, want [%s]", uris, want)
+			}
+		})
+	}
+}
+
+func TestGetPIXStatsDatabase(t *testing.T) {
+	const pixPath = "/olinda/servico/Pix_DadosAbertos/versao/v1/odata/EstatisticasTransacoesPix(Database=@Database)"
+	lastMonth := time.Now().AddDate(0, -1, 0).Format("200601")
+
+	tests := []struct {
+		name     string
+		database string
+		want     string
+		wantErr  bool
+	}{
+		{name: "explicit month", database: "202403", want: "202403"},
+		{name: "defaults to last month", want: lastMonth},
+		{name: "month 13", database: "202413", wantErr: true},
+		{name: "with separator", database: "2024-03", wantErr: true},
+		{name: "too short", database: "20243", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uris []string
+			c := newTestClient(t, recordRequests(`{"value":[]}`, &uris))
+			resp, err := c.GetPIXStats(context.Background(), tt.database, false)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				if len(uris) != 0 {
+					t.Fatalf("requests sent for an invalid month: %v", uris)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want := pixPath + "?@Database='" + tt.want + "'&$format=json"; len(uris) != 1 || uris[0] != want {
+				t.Errorf("requests = %v, want [%s]", uris, want)
+			}
+			if resp.Stats.Database != tt.want {
+				t.Errorf("Database = %q, want %q", resp.Stats.Database, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetPIXStatsParsing(t *testing.T) {
+	const body = `{"value":[
+		{"AnoMes":202403,"PAG_PFPJ":"PF","REC_PFPJ":"PJ","NATUREZA":"P2B","FORMAINICIACAO":"QRDN","FINALIDADE":"Pix","VALOR":3000,"QUANTIDADE":20,"EXTRA":"x"},
+		{"AnoMes":202403,"PAG_PFPJ":"PF","REC_PFPJ":"PF","NATUREZA":"P2P","FORMAINICIACAO":"CHAVE","FINALIDADE":"Pix","VALOR":1000,"QUANTIDADE":30},
+		{"AnoMes":202403,"PAG_PFPJ":"PJ","REC_PFPJ":"PF","NATUREZA":"P2P","FORMAINICIACAO":"MANU","FINALIDADE":"Pix","VALOR":1000,"QUANTIDADE":50}
+	]}`
+
+	tests := []struct {
+		name       string
+		includeRaw bool
+	}{
+		{"typed fields only", false},
+		{"with raw data", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uris []string
+			c := newTestClient(t, recordRequests(body, &uris))
+			resp, err := c.GetPIXStats(context.Background(), "202403", tt.includeRaw)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			st := resp.Stats
+			if st.TotalTransactions != 100 || st.TotalValue != 5000 || st.AverageTicket != 50 {
+				t.Errorf("totals = %d transactions, %v value, %v ticket", st.TotalTransactions, st.TotalValue, st.AverageTicket)
+			}
+			if p2p := st.ByNature["P2P"]; p2p.Transactions != 80 || p2p.Value != 2000 || p2p.AverageTicket != 25 {
+				t.Errorf("ByNature[P2P] = %+v", p2p)
+			}
+			if p2b := st.ByNature["P2B"]; p2b.Transactions != 20 || p2b.AverageTicket != 150 {
+				t.Errorf("ByNature[P2B] = %+v", p2b)
+			}
+			if len(st.ByInitiation) != 3 || st.ByInitiation["CHAVE"].Transactions != 30 {
+				t.Errorf("ByInitiation = %+v", st.ByInitiation)
+			}
+			if tt.includeRaw {
+				if len(st.RawData) != 3 || st.RawData[0]["EXTRA"] != "x" {
+					t.Errorf("RawData = %v", st.RawData)
+				}
+			} else if st.RawData != nil {
+				t.Errorf("RawData = %v, want none", st.RawData)
+			}
+		})
+	}
+}
+
+func TestWithBaseURL(t *testing.T) {
+	if c := NewClient(); c.sgsURL != SGSURL || c.olindaURL != OlindaURL {
+		t.Errorf("default URLs = %s, %s", c.sgsURL, c.olindaURL)
+	}
+
+	var uris []string
+	srv := httptest.NewServer(recordRequests(`[]`, &uris))
+	defer srv.Close()
+	c := NewClient(WithRetry(1, 0), WithBaseURL(srv.URL+"/"))
+
+	c.GetSeriesByCode(context.Background(), 433, 1)
+	c.GetPIXStats(context.Background(), "202403", false)
+
+	want := []string{"/dados/serie/bcdata.sgs.433/", "/olinda/servico/"}
+	if len(uris) != len(want) {
+		t.Fatalf("requests = %v, want one per API", uris)
+	}
+	for i, prefix := range want {
+		if !strings.HasPrefix(uris[i], prefix) {
+			t.Errorf("request %s, want it under %s", uris[i], prefix)
+		}
+	}
+}
+
+func TestCancelMidFlight(t *testing.T) {
+	started := make(chan struct{})
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	}, WithRetry(3, time.Hour))
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.GetSeriesByCode(ctx, 433, 1)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("call did not return after cancellation")
+	}
+}
+
+func TestGetFocusExpectations(t *testing.T) {
+	const ipca = `{"@odata.context":"https://olinda.bcb.gov.br/olinda/servico/Expectativas/versao/v1/odata$metadata#ExpectativasMercadoAnuais","value":[
+		{"Indicador":"IPCA","IndicadorDetalhe":null,"Data":"2024-03-08","DataReferencia":"2024","Media":3.7612,"Mediana":3.76,"DesvioPadrao":0.1923,"Minimo":3.2,"Maximo":4.3,"numeroRespondentes":151,"baseCalculo":0},
+		{"Indicador":"IPCA","IndicadorDetalhe":null,"Data":"2024-03-08","DataReferencia":"2025","Media":3.5281,"Mediana":3.5,"DesvioPadrao":0.2144,"Minimo":3.0,"Maximo":4.5,"numeroRespondentes":146,"baseCalculo":0}
+	]}`
+	tests := []struct {
+		name      string
+		indicator string
+		lastN     int
+		body      string
+		wantQuery []string
+		wantLen   int
+		wantErr   string
+	}{
+		{name: "ipca", indicator: "IPCA", lastN: 2, body: ipca, wantQuery: []string{"$top=2", "Indicador%20eq%20%27IPCA%27%20and%20baseCalculo%20eq%200", "$orderby=Data%20desc"}, wantLen: 2},
+		{name: "default lastN", indicator: "ipca", body: ipca, wantQuery: []string{"$top=10"}, wantLen: 2},
+		{name: "no surveys", indicator: "selic", body: `{"value":[]}`, wantQuery: []string{"%27Selic%27"}},
+		{name: "unknown indicator", indicator: "unemployment", wantErr: "unknown Focus indicator: unemployment"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uris []string
+			c := newTestClient(t, recordRequests(tt.body, &uris))
+			resp, err := c.GetFocusExpectations(context.Background(), tt.indicator, tt.lastN)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				if len(uris) != 0 {
+					t.Errorf("requests sent for an unknown indicator: %v", uris)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(uris) != 1 || !strings.HasPrefix(uris[0], "/olinda/servico/Expectativas/versao/v1/odata/ExpectativasMercadoAnuais?") {
+				t.Fatalf("requests = %v", uris)
+			}
+			for _, q := range tt.wantQuery {
+				if !strings.Contains(uris[0], q) {
+					t.Errorf("request %s lacks %s", uris[0], q)
+				}
+			}
+			if resp.Expectations == nil || len(resp.Expectations) != tt.wantLen {
+				t.Fatalf("expectations = %v, want %d", resp.Expectations, tt.wantLen)
+			}
+			if tt.wantLen > 0 {
+				want := FocusExpectation{Indicator: "IPCA", Date: "2024-03-08", ReferenceDate: "2024", Mean: 3.7612, Median: 3.76, StdDev: 0.1923, Min: 3.2, Max: 4.3, Respondents: 151}
+				if resp.Expectations[0] != want || resp.Indicator != "IPCA" {
+					t.Errorf("first expectation = %+v, want %+v", resp.Expectations[0], want)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertCurrency(t *testing.T) {
+	tests := []struct {
+		name        string
+		amount      float64
+		from, to    string
+		want        float64
+		wantRate    float64
+		wantInverse float64
+		wantCalls   int
+		wantErr     string
+	}{
+		{name: "to BRL", amount: 100, from: "USD", to: "BRL", want: 500, wantRate: 5, wantInverse: 0.2, wantCalls: 1},
+		{name: "from BRL", amount: 500, from: "brl", to: " usd ", want: 100, wantRate: 0.2, wantInverse: 5, wantCalls: 1},
+		{name: "cross rate", amount: 100, from: "EUR", to: "USD", want: 110, wantRate: 1.1, wantInverse: 5.0 / 5.5, wantCalls: 2},
+		{name: "same currency", amount: 42, from: "USD", to: "usd", want: 42, wantRate: 1, wantInverse: 1},
+		{name: "zero rate", amount: 1, from: "JPY", to: "BRL", wantErr: "invalid PTAX quote", wantCalls: 1},
+		{name: "unavailable currency", amount: 1, from: "GBP", to: "BRL", wantErr: "GBP", wantCalls: 1},
+		{name: "missing currency", amount: 1, from: "", to: "BRL", wantErr: "both source and target currencies are required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			calls := 0
+			ptax := ptaxServer(map[string]float64{"USD": 5, "EUR": 5.5, "JPY": 0})
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				calls++
+				mu.Unlock()
+				ptax(w, r)
+			})
+			got, err := c.ConvertCurrency(context.Background(), tt.amount, tt.from, tt.to, "2024-03-15")
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if math.Abs(got.ConvertedAmount-tt.want) > 1e-9 || math.Abs(got.ExchangeRate-tt.wantRate) > 1e-9 || math.Abs(got.InverseRate-tt.wantInverse) > 1e-9 {
+				t.Errorf("conversion = %+v, want %v at %v (inverse %v)", got, tt.want, tt.wantRate, tt.wantInverse)
+			}
+		})
+	}
+
+	t.Run("weekend", func(t *testing.T) {
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"value":[]}`))
+		})
+		_, err := c.ConvertCurrency(context.Background(), 1, "USD", "BRL", "2024-03-16")
+		if !errors.Is(err, apierror.ErrNotFound) || !strings.Contains(err.Error(), "weekend or holiday") {
+			t.Errorf("error = %v, want a not-found error for a day without quotes", err)
+		}
+	})
+}
+
+func TestRetry(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			http.Error(w, "indisponível", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`[{"data":"02/01/2024","valor":"11.65"}]`))
+	}))
+	defer srv.Close()
+	c := NewClient(WithBaseURL(srv.URL), WithRetry(3, time.Millisecond))
+
+	if _, err := c.GetSeriesByCode(context.Background(), 432, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("%d requests, want 2", requests)
+	}
+}
+
+func TestIndicatorMetadata(t *testing.T) {
+	var uris []string
+	c := newTestClient(t, recordRequests(`[{"data":"02/01/2024","valor":"0.043739"}]`, &uris))
+	for name, series := range SeriesCodes {
+		t.Run(name, func(t *testing.T) {
+			resp, err := c.GetIndicator(context.Background(), name, 1)
+			if err != nil {
+				t.Fatalf("GetIndicator: %v", err)
+			}
+			if resp.Series == nil || *resp.Series != series {
+				t.Errorf("GetIndicator series = %+v, want %+v", resp.Series, series)
+			}
+
+			resp, err = c.GetIndicatorRange(context.Background(), name, "01/01/2024", "31/01/2024")
+			if err != nil {
+				t.Fatalf("GetIndicatorRange: %v", err)
+			}
+			if resp.Series == nil || *resp.Series != series {
+				t.Errorf("GetIndicatorRange series = %+v, want %+v", resp.Series, series)
+			}
+
+			data, err := json.Marshal(resp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := fmt.Sprintf(`"series":{"code":%d,"description":%q,"unit":%q,"frequency":%q}`, series.Code, series.Description, series.Unit, series.Frequency); !strings.Contains(string(data), want) {
+				t.Errorf("JSON %s lacks %s", data, want)
+			}
+		})
+	}
+
+	resp, err := c.GetSeriesByCode(context.Background(), 1, 1)
+	if err != nil {
+		t.Fatalf("GetSeriesByCode: %v", err)
+	}
+	if resp.Series != nil {
+		t.Errorf("raw series code got metadata %+v", resp.Series)
+	}
+}
+
+// seriesByCode answers each SGS series with a single point whose value is
+// the series code, failing the codes in fail with a 500, and records the
+// peak number of requests in flight.
+type seriesByCode struct {
+	fail     map[int]bool
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+}
+
+func (s *seriesByCode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.inFlight++
+	s.peak = max(s.peak, s.inFlight)
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.inFlight--
+		s.mu.Unlock()
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	var code int
+	fmt.Sscanf(strings.TrimPrefix(r.URL.Path, "/dados/serie/bcdata.sgs."), "%d", &code)
+	if s.fail[code] {
+		http.Error(w, "erro", http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintf(w, `[{"data":"02/01/2024","valor":"%d"}]`, code)
+}
+
+func TestGetIndicators(t *testing.T) {
+	stub := &seriesByCode{fail: map[int]bool{189: true}}
+	c := newTestClient(t, stub.ServeHTTP)
+
+	indicators, err := c.GetIndicators(context.Background(),
+		[]string{"selic", " IPCA ", "igpm", "cdi", "desemprego", "ipca", "selic_meta", "selic_monthly", ""}, 1)
+
+	var errs IndicatorErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("error = %v, want IndicatorErrors", err)
+	}
+	if len(errs) != 2 || !strings.Contains(errs["desemprego"].Error(), "unknown indicator") || !errors.Is(errs["igpm"], apierror.ErrUpstream) {
+		t.Errorf("errors = %v, want desemprego unknown and igpm upstream", errs)
+	}
+
+	want := map[string]string{"selic": "11", "ipca": "433", "cdi": "12", "selic_meta": "432", "selic_monthly": "4390"}
+	if len(indicators) != len(want) {
+		t.Errorf("indicators = %v, want %d", indicators, len(want))
+	}
+	for name, value := range want {
+		resp, ok := indicators[name]
+		if !ok {
+			t.Errorf("%s missing", name)
+			continue
+		}
+		if len(resp.Data) != 1 || resp.Data[0].Value != value || resp.Indicator != name {
+			t.Errorf("%s = %+v, want the value %s of its own series", name, resp, value)
+		}
+	}
+	if stub.peak > indicatorsConcurrency {
+		t.Errorf("%d series fetched at once, want at most %d", stub.peak, indicatorsConcurrency)
+	}
+
+	if _, err := c.GetIndicators(context.Background(), []string{" ", ""}, 1); err == nil || errors.As(err, &errs) {
+		t.Errorf("empty list error = %v, want a plain error", err)
+	}
+	indicators, err = c.GetIndicators(context.Background(), []string{"cdi"}, 1)
+	if err != nil || len(indicators) != 1 {
+		t.Errorf("single indicator = %v, %v", indicators, err)
+	}
+}
+
+func TestExchangeRateInverse(t *testing.T) {
+	tests := []struct {
+		name     string
+		currency string
+		body     string
+		want     []float64
+		wantInfo *Currency
+	}{
+		{
+			name: "dollar", currency: "USD",
+			body:     `{"value":[{"cotacaoCompra":4.9494,"cotacaoVenda":5.0,"tipoBoletim":"Fechamento"}]}`,
+			want:     []float64{0.2},
+			wantInfo: &Currency{Name: "Dolar dos Estados Unidos", Symbol: "US$"},
+		},
+		{
+			name: "zero and missing rates", currency: "EUR",
+			body:     `{"value":[{"cotacaoVenda":0},{"cotacaoCompra":5.4},{"cotacaoVenda":4}]}`,
+			want:     []float64{0, 0, 0.25},
+			wantInfo: &Currency{Name: "Euro", Symbol: "€"},
+		},
+		{
+			name: "unknown currency", currency: "XYZ",
+			body: `{"value":[{"cotacaoVenda":2}]}`,
+			want: []float64{0.5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uris []string
+			c := newTestClient(t, recordRequests(tt.body, &uris))
+			resp, err := c.GetExchangeRate(context.Background(), tt.currency, "2024-03-15")
+			if err != nil {
+				t.Fatalf("GetExchangeRate: %v", err)
+			}
+			if len(resp.Rates) != len(tt.want) {
+				t.Fatalf("got %d rates, want %d", len(resp.Rates), len(tt.want))
+			}
+			for i, r := range resp.Rates {
+				if math.IsInf(r.InverseRate, 0) || math.IsNaN(r.InverseRate) || math.Abs(r.InverseRate-tt.want[i]) > 1e-12 {
+					t.Errorf("rate %d: inverse of %v = %v, want %v", i, r.SellRate, r.InverseRate, tt.want[i])
+				}
+			}
+			if tt.name == "dollar" && (resp.Rates[0].BuyRate != 4.9494 || resp.Rates[0].SellRate != 5.0) {
+				t.Errorf("original rates changed: %+v", resp.Rates[0])
+			}
+			if (resp.CurrencyInfo == nil) != (tt.wantInfo == nil) || (tt.wantInfo != nil && *resp.CurrencyInfo != *tt.wantInfo) {
+				t.Errorf("currency info = %+v, want %+v", resp.CurrencyInfo, tt.wantInfo)
+			}
+
+			var raw struct {
+				Rates []map[string]any `json:"rates"`
+			}
+			b, _ := json.Marshal(resp)
+			if err := json.Unmarshal(b, &raw); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := raw.Rates[0]["cotacaoVendaInversa"]; !ok {
+				t.Errorf("marshalled rate lacks cotacaoVendaInversa: %s", b)
+			}
+		})
+	}
+}
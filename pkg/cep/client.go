@@ -0,0 +1,287 @@
+// Package cep provides a client for the ViaCEP API (postal code lookup).
+package cep
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anderson-ufrj/mcp-brasil/pkg/apierror"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/dedup"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/document"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/health"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/httpbody"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/httplog"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/metrics"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/retry"
+)
+
+const (
+	BaseURL        = "https://viacep.com.br/ws"
+	DefaultTimeout = 30 * time.Second
+
+	// MaxBatchSize is the largest number of distinct CEPs BatchLookupCEP
+	// accepts.
+	MaxBatchSize = 100
+	// batchConcurrency bounds the lookups BatchLookupCEP runs at once.
+	batchConcurrency = 5
+)
+
+// Client represents the ViaCEP API client.
+type Client struct {
+	httpClient *http.Client
+	logger     *slog.Logger
+	metrics    metrics.Collector
+	flight     *dedup.Group
+	retry      retry.Policy
+	baseURL    string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithBaseURL points the client at another API root, such as a mirror or a
+// test server, instead of BaseURL.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithLogger logs the ViaCEP requests on l at debug level, as described
+// in httplog.Request. A nil l disables logging.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Client) {
+		c.logger = httplog.OrDiscard(l)
+	}
+}
+
+// WithMetrics records every request (status class and latency) on m.
+func WithMetrics(m metrics.Collector) Option {
+	return func(c *Client) {
+		c.metrics = m
+	}
+}
+
+// WithDeduplication makes concurrent identical requests share a single
+// upstream call.
+func WithDeduplication() Option {
+	return func(c *Client) {
+		c.flight = &dedup.Group{}
+	}
+}
+
+// WithRetry sets how many times a request failing with a 429, a 5xx or a
+// connection error is attempted in total, and the delay before the first
+// retry, which doubles on each further one. maxAttempts below 2 disables
+// retries.
+func WithRetry(maxAttempts int, baseDelay time.Duration) Option {
+	return func(c *Client) {
+		c.retry = retry.Policy{MaxAttempts: maxAttempts, BaseDelay: baseDelay}
+	}
+}
+
+// NewClient creates a new ViaCEP client.
+func NewClient(opts ...Option) *Client {
+	c := &Client{
+		httpClient: &http.Client{Timeout: DefaultTimeout},
+		logger:     httplog.Discard,
+		retry:      retry.Default,
+		baseURL:    BaseURL,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// CEPInfo represents the address data for a CEP.
+type CEPInfo struct {
+	CEP         string `json:"cep"`
+	Logradouro  string `json:"logradouro"`
+	Complemento string `json:"complemento,omitempty"`
+	Bairro      string `json:"bairro"`
+	Municipio   string `json:"localidade"`
+	UF          string `json:"uf"`
+	CodigoIBGE  string `json:"ibge"`
+	DDD         string `json:"ddd,omitempty"`
+	Source      string `json:"source"`
+}
+
+// normalizeCEP strips formatting from a CEP and checks it has 8 digits.
+func normalizeCEP(cep string) (string, error) {
+	digits := document.NormalizeDigits(cep)
+
+	if len(digits) != 8 {
+		return "", fmt.Errorf("invalid CEP: must have 8 digits, got %d", len(digits))
+	}
+	return digits, nil
+}
+
+// Ping checks that ViaCEP is reachable (see health.Ping).
+func (c *Client) Ping(ctx context.Context) error {
+	return health.Ping(ctx, c.httpClient, c.logger, c.baseURL)
+}
+
+// doRequest performs a GET request, retrying transient failures and sharing
+// the result with identical concurrent requests when deduplication is enabled.
+func (c *Client) doRequest(ctx context.Context, url string) ([]byte, error) {
+	return c.flight.Do(url, func() ([]byte, error) {
+		return c.retry.Do(ctx, func() ([]byte, error) {
+			return c.fetch(ctx, url)
+		})
+	})
+}
+
+// fetch performs a GET request and returns the body of a 200 response.
+func (c *Client) fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept-Encoding", httpbody.AcceptEncoding)
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	duration := time.Since(start)
+	httplog.Request(ctx, c.logger, req, resp, err, duration)
+	metrics.Observe(c.metrics, "cep", resp, err, duration)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, fmt.Errorf("executing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := httpbody.Read(resp)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, apierror.FromResponse(resp, body)
+	}
+
+	return body, nil
+}
+
+// LookupCEP retrieves the address and IBGE municipality code for a CEP.
+func (c *Client) LookupCEP(ctx context.Context, cep string) (*CEPInfo, error) {
+	digits, err := normalizeCEP(cep)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/%s/json/", c.baseURL, digits)
+
+	body, err := c.doRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	// ViaCEP answers unknown CEPs with {"erro": true} (or "true") and status 200.
+	var notFound struct {
+		Erro interface{} `json:"erro"`
+	}
+	if err := json.Unmarshal(body, &notFound); err == nil && notFound.Erro != nil {
+		return nil, fmt.Errorf("%w: CEP %s", apierror.ErrNotFound, digits)
+	}
+
+	var info CEPInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	info.Source = "viacep_api"
+	return &info, nil
+}
+
+// BatchResult is the outcome of one CEP in a batch lookup: its address, or
+// the error that prevented finding it.
+type BatchResult struct {
+	CEP     string   `json:"cep"`
+	Address *CEPInfo `json:"address,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// BatchResponse holds the results of a batch lookup in input order.
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+	Total   int           `json:"total"`
+	Found   int           `json:"found"`
+	Failed  int           `json:"failed"`
+	Source  string        `json:"source"`
+}
+
+// BatchLookupCEP looks up several CEPs concurrently. Duplicates, formatted or
+// not, are looked up once, and the results keep the order of their first
+// occurrence. CEPs that are malformed or not found are reported in their
+// result's Error rather than failing the batch.
+func (c *Client) BatchLookupCEP(ctx context.Context, ceps []string) (*BatchResponse, error) {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, cep := range ceps {
+		cep = strings.TrimSpace(cep)
+		key := cep
+		if digits, err := normalizeCEP(cep); err == nil {
+			key = digits
+		}
+		if cep != "" && !seen[key] {
+			seen[key] = true
+			unique = append(unique, cep)
+		}
+	}
+	if len(unique) == 0 {
+		return nil, fmt.Errorf("at least one CEP is required")
+	}
+	if len(unique) > MaxBatchSize {
+		return nil, fmt.Errorf("too many CEPs: %d, the maximum is %d", len(unique), MaxBatchSize)
+	}
+
+	results := make([]BatchResult, len(unique))
+	sem := make(chan struct{}, batchConcurrency)
+	var wg sync.WaitGroup
+	for i, cep := range unique {
+		wg.Add(1)
+		go func(i int, cep string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i].CEP = cep
+			info, err := c.LookupCEP(ctx, cep)
+			if err != nil {
+				results[i].Error = err.Error()
+				return
+			}
+			results[i].Address = info
+		}(i, cep)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	response := &BatchResponse{
+		Results: results,
+		Total:   len(results),
+		Source:  "viacep_api",
+	}
+	for _, r := range results {
+		if r.Address != nil {
+			response.Found++
+		} else {
+			response.Failed++
+		}
+	}
+	return response, nil
+}
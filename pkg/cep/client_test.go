@@ -0,0 +1,233 @@
+package cep
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anderson-ufrj/mcp-brasil/pkg/apierror"
+)
+
+// viaCEP stubs ViaCEP with the addresses keyed by CEP digits, answering
+// other CEPs the way ViaCEP does, and counts the lookups per CEP.
+type viaCEP struct {
+	addresses map[string]string
+	mu        sync.Mutex
+	lookups   map[string]int
+}
+
+func (v *viaCEP) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	digits := strings.Split(strings.Trim(r.URL.Path, "/"), "/")[0]
+	v.mu.Lock()
+	v.lookups[digits]++
+	v.mu.Unlock()
+
+	body, ok := v.addresses[digits]
+	if !ok {
+		body = `{"erro": true}`
+	}
+	w.Write([]byte(body))
+}
+
+// newTestClient returns a client served by a ViaCEP stub holding addresses.
+func newTestClient(t *testing.T, addresses map[string]string) (*Client, *viaCEP) {
+	t.Helper()
+	stub := &viaCEP{addresses: addresses, lookups: map[string]int{}}
+	srv := httptest.NewServer(stub)
+	t.Cleanup(srv.Close)
+	return NewClient(WithBaseURL(srv.URL), WithRetry(1, 0)), stub
+}
+
+const pracaDaSe = `{"cep":"01001-000","logradouro":"Praça da Sé","complemento":"lado ímpar","bairro":"Sé","localidade":"São Paulo","uf":"SP","ibge":"3550308","ddd":"11"}`
+
+func TestLookupCEP(t *testing.T) {
+	tests := []struct {
+		name         string
+		cep          string
+		wantNotFound bool
+		wantErr      bool
+	}{
+		{name: "digits", cep: "01001000"},
+		{name: "formatted", cep: "01001-000"},
+		{name: "dotted", cep: "01.001-000"},
+		{name: "not found", cep: "99999999", wantNotFound: true},
+		{name: "seven digits", cep: "0100100", wantErr: true},
+		{name: "nine digits", cep: "010010000", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, stub := newTestClient(t, map[string]string{"01001000": pracaDaSe})
+			info, err := c.LookupCEP(context.Background(), tt.cep)
+			switch {
+			case tt.wantNotFound:
+				if !errors.Is(err, apierror.ErrNotFound) {
+					t.Fatalf("err = %v, want ErrNotFound", err)
+				}
+			case tt.wantErr:
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				if len(stub.lookups) != 0 {
+					t.Fatalf("lookups sent for an invalid CEP: %v", stub.lookups)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			default:
+				if stub.lookups["01001000"] != 1 {
+					t.Errorf("lookups = %v, want the normalized CEP", stub.lookups)
+				}
+				if info.Logradouro != "Praça da Sé" || info.Bairro != "Sé" || info.Municipio != "São Paulo" || info.UF != "SP" || info.CodigoIBGE != "3550308" || info.Source != "viacep_api" {
+					t.Errorf("info = %+v", info)
+				}
+			}
+		})
+	}
+}
+
+func TestLookupCEPStringErro(t *testing.T) {
+	c, _ := newTestClient(t, map[string]string{"99999999": `{"erro": "true"}`})
+	if _, err := c.LookupCEP(context.Background(), "99999-999"); !errors.Is(err, apierror.ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLookupCEPGzip(t *testing.T) {
+	var acceptEncoding string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		zw.Write([]byte(pracaDaSe))
+		zw.Close()
+	}))
+	defer srv.Close()
+	c := NewClient(WithBaseURL(srv.URL), WithRetry(1, 0))
+
+	info, err := c.LookupCEP(context.Background(), "01001000")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Logradouro != "Praça da Sé" || info.Municipio != "São Paulo" {
+		t.Errorf("address = %+v", info)
+	}
+	if !strings.Contains(acceptEncoding, "gzip") {
+		t.Errorf("Accept-Encoding = %q, want gzip", acceptEncoding)
+	}
+}
+
+const rioCentro = `{"cep":"20040-020","logradouro":"Avenida Rio Branco","bairro":"Centro","localidade":"Rio de Janeiro","uf":"RJ","ibge":"3304557","ddd":"21"}`
+
+func TestBatchLookupCEP(t *testing.T) {
+	c, stub := newTestClient(t, map[string]string{"01001000": pracaDaSe, "20040020": rioCentro})
+
+	resp, err := c.BatchLookupCEP(context.Background(), []string{
+		"01001-000", "99999999", "123", "01001000", " 20040-020 ", "", "99999-999", "abc",
+	})
+	if err != nil {
+		t.Fatalf("BatchLookupCEP: %v", err)
+	}
+
+	tests := []struct {
+		cep       string
+		municipio string // empty when the lookup fails
+		wantErr   string
+	}{
+		{cep: "01001-000", municipio: "São Paulo"},
+		{cep: "99999999", wantErr: "not found"},
+		{cep: "123", wantErr: "invalid cep"},
+		{cep: "20040-020", municipio: "Rio de Janeiro"},
+		{cep: "abc", wantErr: "invalid cep"},
+	}
+	if len(resp.Results) != len(tests) {
+		t.Fatalf("results = %+v, want %d entries", resp.Results, len(tests))
+	}
+	for i, tt := range tests {
+		r := resp.Results[i]
+		if r.CEP != tt.cep {
+			t.Errorf("result %d is for %q, want %q", i, r.CEP, tt.cep)
+		}
+		if tt.wantErr != "" {
+			if r.Address != nil || !strings.Contains(strings.ToLower(r.Error), tt.wantErr) {
+				t.Errorf("%s: address = %+v, error = %q, want an error mentioning %q", tt.cep, r.Address, r.Error, tt.wantErr)
+			}
+			continue
+		}
+		if r.Error != "" || r.Address == nil || r.Address.Municipio != tt.municipio {
+			t.Errorf("%s: address = %+v, error = %q, want %s", tt.cep, r.Address, r.Error, tt.municipio)
+		}
+	}
+	if resp.Total != 5 || resp.Found != 2 || resp.Failed != 3 {
+		t.Errorf("total = %d, found = %d, failed = %d, want 5, 2, 3", resp.Total, resp.Found, resp.Failed)
+	}
+
+	want := map[string]int{"01001000": 1, "99999999": 1, "20040020": 1}
+	if len(stub.lookups) != len(want) {
+		t.Errorf("lookups = %v, want %v", stub.lookups, want)
+	}
+	for cep, n := range want {
+		if stub.lookups[cep] != n {
+			t.Errorf("%s looked up %d times, want %d", cep, stub.lookups[cep], n)
+		}
+	}
+}
+
+func TestBatchLookupCEPLimits(t *testing.T) {
+	c, stub := newTestClient(t, nil)
+
+	for _, ceps := range [][]string{nil, {"", "  "}} {
+		if _, err := c.BatchLookupCEP(context.Background(), ceps); err == nil {
+			t.Errorf("BatchLookupCEP(%q) accepted an empty batch", ceps)
+		}
+	}
+
+	var tooMany []string
+	for i := 0; i <= MaxBatchSize; i++ {
+		tooMany = append(tooMany, fmt.Sprintf("%08d", i))
+	}
+	if _, err := c.BatchLookupCEP(context.Background(), tooMany); err == nil {
+		t.Errorf("BatchLookupCEP accepted %d CEPs", len(tooMany))
+	}
+	if len(stub.lookups) != 0 {
+		t.Errorf("rejected batches made lookups: %v", stub.lookups)
+	}
+}
+
+func TestBatchLookupCEPConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		peak = max(peak, inFlight)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		w.Write([]byte(pracaDaSe))
+	}))
+	defer srv.Close()
+	c := NewClient(WithBaseURL(srv.URL), WithRetry(1, 0))
+
+	var ceps []string
+	for i := 0; i < 4*batchConcurrency; i++ {
+		ceps = append(ceps, fmt.Sprintf("0100%04d", i))
+	}
+	resp, err := c.BatchLookupCEP(context.Background(), ceps)
+	if err != nil {
+		t.Fatalf("BatchLookupCEP: %v", err)
+	}
+	if resp.Found != len(ceps) {
+		t.Errorf("found = %d, want %d", resp.Found, len(ceps))
+	}
+	if peak > batchConcurrency {
+		t.Errorf("%d lookups in flight at once, want at most %d", peak, batchConcurrency)
+	}
+}
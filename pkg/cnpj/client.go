@@ -2,13 +2,26 @@
 package cnpj
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/anderson-ufrj/mcp-brasil/pkg/apierror"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/dedup"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/document"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/health"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/httpbody"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/httplog"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/metrics"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/paging"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/retry"
 )
 
 const (
@@ -16,16 +29,89 @@ const (
 	DefaultTimeout = 30 * time.Second
 )
 
+// SearchURL is the Casa dos Dados company search endpoint, used for name
+// searches as Minha Receita only supports lookups by CNPJ.
+const (
+	SearchURL      = "https://api.casadosdados.com.br/v2/public/cnpj/search"
+	SearchPageSize = 20
+)
+
 // Client represents the Minha Receita API client.
 type Client struct {
 	httpClient *http.Client
+	logger     *slog.Logger
+	metrics    metrics.Collector
+	flight     *dedup.Group
+	retry      retry.Policy
+	baseURL    string
+	searchURL  string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithBaseURL points the client at another API root, such as a mirror or a
+// test server, instead of BaseURL.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithSearchURL points name searches at another provider endpoint accepting
+// the Casa dos Dados search request, instead of SearchURL.
+func WithSearchURL(searchURL string) Option {
+	return func(c *Client) {
+		c.searchURL = searchURL
+	}
+}
+
+// WithLogger logs the CNPJ requests on l at debug level, as described
+// in httplog.Request. A nil l disables logging.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Client) {
+		c.logger = httplog.OrDiscard(l)
+	}
+}
+
+// WithMetrics records every request (status class and latency) on m.
+func WithMetrics(m metrics.Collector) Option {
+	return func(c *Client) {
+		c.metrics = m
+	}
+}
+
+// WithDeduplication makes concurrent identical requests share a single
+// upstream call.
+func WithDeduplication() Option {
+	return func(c *Client) {
+		c.flight = &dedup.Group{}
+	}
+}
+
+// WithRetry sets how many times a request failing with a 429, a 5xx or a
+// connection error is attempted in total, and the delay before the first
+// retry, which doubles on each further one. maxAttempts below 2 disables
+// retries.
+func WithRetry(maxAttempts int, baseDelay time.Duration) Option {
+	return func(c *Client) {
+		c.retry = retry.Policy{MaxAttempts: maxAttempts, BaseDelay: baseDelay}
+	}
 }
 
 // NewClient creates a new Minha Receita client.
-func NewClient() *Client {
-	return &Client{
+func NewClient(opts ...Option) *Client {
+	c := &Client{
 		httpClient: &http.Client{Timeout: DefaultTimeout},
+		logger:     httplog.Discard,
+		retry:      retry.Default,
+		baseURL:    BaseURL,
+		searchURL:  SearchURL,
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
 }
 
 // CNPJData represents company data from Minha Receita.
@@ -63,15 +149,77 @@ type Partner struct {
 	DataEntradaSociedade string `json:"data_entrada_sociedade,omitempty"`
 }
 
+// SituacoesCadastrais maps the Receita Federal registration status codes
+// (situação cadastral) to their descriptions.
+var SituacoesCadastrais = map[int]string{
+	1: "nula",
+	2: "ativa",
+	3: "suspensa",
+	4: "inapta",
+	8: "baixada",
+}
+
+// DescribeSituacaoCadastral returns the description of a registration status
+// code, or "desconhecida" for unknown codes.
+func DescribeSituacaoCadastral(code int) string {
+	if desc, ok := SituacoesCadastrais[code]; ok {
+		return desc
+	}
+	return "desconhecida"
+}
+
+// PartnersResponse represents the partners (QSA) of a company.
+type PartnersResponse struct {
+	Partners    []Partner `json:"qsa"`
+	CNPJ        string    `json:"cnpj"`
+	RazaoSocial string    `json:"razao_social"`
+	Total       int       `json:"total"`
+	Source      string    `json:"source"`
+}
+
+// Partners returns the company partners, or an empty list when the QSA is
+// empty (as for sole proprietorships).
+func (d *CNPJData) Partners() []Partner {
+	if d == nil || len(d.QSA) == 0 {
+		return []Partner{}
+	}
+	return d.QSA
+}
+
+// Validate checks a CNPJ (with or without formatting) against its check
+// digits and returns it as 14 unformatted digits.
+func Validate(cnpj string) (string, error) {
+	digits := document.NormalizeDigits(cnpj)
+	if len(digits) != 14 {
+		return "", fmt.Errorf("invalid CNPJ: must have 14 digits, got %d", len(digits))
+	}
+	if strings.Count(digits, digits[:1]) == 14 {
+		return "", fmt.Errorf("invalid CNPJ: %s", cnpj)
+	}
+
+	weights := []int{6, 5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}
+	for n := 12; n <= 13; n++ {
+		sum := 0
+		for i := 0; i < n; i++ {
+			sum += int(digits[i]-'0') * weights[len(weights)-n+i]
+		}
+		check := sum % 11
+		if check < 2 {
+			check = 0
+		} else {
+			check = 11 - check
+		}
+		if int(digits[n]-'0') != check {
+			return "", fmt.Errorf("invalid CNPJ: check digits do not match for %s", cnpj)
+		}
+	}
+
+	return digits, nil
+}
+
 // formatCNPJ formats a CNPJ string to the API format (XX.XXX.XXX/XXXX-XX).
 func formatCNPJ(cnpj string) (string, error) {
-	// Remove all non-digits
-	digits := strings.Map(func(r rune) rune {
-		if r >= '0' && r <= '9' {
-			return r
-		}
-		return -1
-	}, cnpj)
+	digits := document.NormalizeDigits(cnpj)
 
 	if len(digits) != 14 {
 		return "", fmt.Errorf("invalid CNPJ: must have 14 digits, got %d", len(digits))
@@ -82,39 +230,81 @@ func formatCNPJ(cnpj string) (string, error) {
 		digits[0:2], digits[2:5], digits[5:8], digits[8:12], digits[12:14]), nil
 }
 
-// GetCNPJ retrieves company data by CNPJ.
-func (c *Client) GetCNPJ(ctx context.Context, cnpj string) (*CNPJData, error) {
-	formattedCNPJ, err := formatCNPJ(cnpj)
-	if err != nil {
-		return nil, err
-	}
+// Ping checks that Minha Receita is reachable (see health.Ping).
+func (c *Client) Ping(ctx context.Context) error {
+	return health.Ping(ctx, c.httpClient, c.logger, c.baseURL)
+}
 
-	url := fmt.Sprintf("%s/%s", BaseURL, formattedCNPJ)
+// doRequest performs a request, retrying transient failures and sharing
+// identical concurrent ones. payload, when not nil, is sent as a JSON body,
+// and source labels the request in the metrics.
+func (c *Client) doRequest(ctx context.Context, method, url, source string, payload []byte) ([]byte, error) {
+	key := method + " " + url + " " + string(payload)
+	return c.flight.Do(key, func() ([]byte, error) {
+		return c.retry.Do(ctx, func() ([]byte, error) {
+			return c.fetch(ctx, method, url, source, payload)
+		})
+	})
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+// fetch performs a single request and returns the body of a 200 response.
+func (c *Client) fetch(ctx context.Context, method, url, source string, payload []byte) ([]byte, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 
 	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept-Encoding", httpbody.AcceptEncoding)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
+	start := time.Now()
 	resp, err := c.httpClient.Do(req)
+	duration := time.Since(start)
+	httplog.Request(ctx, c.logger, req, resp, err, duration)
+	metrics.Observe(c.metrics, source, resp, err, duration)
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
 		return nil, fmt.Errorf("executing request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	respBody, err := httpbody.Read(resp)
 	if err != nil {
 		return nil, fmt.Errorf("reading response: %w", err)
 	}
 
-	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("CNPJ not found: %s", formattedCNPJ)
+	if resp.StatusCode != http.StatusOK {
+		return nil, apierror.FromResponse(resp, respBody)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	return respBody, nil
+}
+
+// GetCNPJ retrieves company data by CNPJ.
+func (c *Client) GetCNPJ(ctx context.Context, cnpj string) (*CNPJData, error) {
+	formattedCNPJ, err := formatCNPJ(cnpj)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/%s", c.baseURL, formattedCNPJ)
+
+	body, err := c.doRequest(ctx, http.MethodGet, url, "cnpj", nil)
+	var apiErr *apierror.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: CNPJ %s", apierror.ErrNotFound, formattedCNPJ)
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	var data CNPJData
@@ -122,6 +312,116 @@ func (c *Client) GetCNPJ(ctx context.Context, cnpj string) (*CNPJData, error) {
 		return nil, fmt.Errorf("parsing response: %w", err)
 	}
 
+	if data.DescricaoSituacaoCadastral == "" {
+		data.DescricaoSituacaoCadastral = DescribeSituacaoCadastral(data.SituacaoCadastral)
+	}
 	data.Source = "minhareceita_api"
 	return &data, nil
 }
+
+// GetPartners retrieves the partners (QSA) of a company by CNPJ.
+func (c *Client) GetPartners(ctx context.Context, cnpj string) ([]Partner, error) {
+	data, err := c.GetCNPJ(ctx, cnpj)
+	if err != nil {
+		return nil, err
+	}
+	return data.Partners(), nil
+}
+
+// CompanySummary is the lightweight company record returned by name searches.
+type CompanySummary struct {
+	CNPJ              string `json:"cnpj"`
+	RazaoSocial       string `json:"razao_social"`
+	NomeFantasia      string `json:"nome_fantasia,omitempty"`
+	Municipio         string `json:"municipio,omitempty"`
+	UF                string `json:"uf,omitempty"`
+	SituacaoCadastral string `json:"situacao_cadastral,omitempty"`
+}
+
+// CompanySearchResponse represents a page of name search results.
+type CompanySearchResponse struct {
+	Companies []CompanySummary `json:"companies"`
+	Total     int              `json:"total"`
+	paging.Info
+	Source string `json:"source"`
+}
+
+// companySearchRequest is the search body expected by the provider.
+type companySearchRequest struct {
+	Query struct {
+		Termo []string `json:"termo"`
+		UF    []string `json:"uf,omitempty"`
+	} `json:"query"`
+	Extras struct {
+		SomenteMatriz bool `json:"somente_matriz"`
+	} `json:"extras"`
+	Page int `json:"page"`
+}
+
+// parseCompanySearch decodes a provider response page.
+func parseCompanySearch(body []byte, page int) (*CompanySearchResponse, error) {
+	var result struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+		Data    struct {
+			Count int              `json:"count"`
+			CNPJ  []CompanySummary `json:"cnpj"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+	if !result.Success {
+		return nil, fmt.Errorf("%w: search failed: %s", apierror.ErrUpstream, result.Message)
+	}
+
+	companies := result.Data.CNPJ
+	if companies == nil {
+		companies = []CompanySummary{}
+	}
+	info := paging.Info{Page: page, PageSize: SearchPageSize, HasMore: page*SearchPageSize < result.Data.Count}
+	if info.HasMore {
+		info.NextPage = page + 1
+	}
+	if len(companies) == 0 {
+		info.Message = paging.NoResults(page, false)
+	}
+	return &CompanySearchResponse{
+		Companies: companies,
+		Total:     result.Data.Count,
+		Info:      info,
+		Source:    "casadosdados_api",
+	}, nil
+}
+
+// SearchCompaniesByName searches companies whose name contains the given
+// terms, optionally restricted to a state (UF). Pages start at 1 and hold
+// up to SearchPageSize companies; no match yields an empty list and a
+// Message saying so.
+func (c *Client) SearchCompaniesByName(ctx context.Context, name, uf string, page int) (*CompanySearchResponse, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("company name is required")
+	}
+	if page <= 0 {
+		page = 1
+	}
+
+	var search companySearchRequest
+	search.Query.Termo = []string{name}
+	if uf = strings.ToUpper(strings.TrimSpace(uf)); uf != "" {
+		search.Query.UF = []string{uf}
+	}
+	search.Page = page
+
+	payload, err := json.Marshal(search)
+	if err != nil {
+		return nil, fmt.Errorf("encoding request: %w", err)
+	}
+
+	body, err := c.doRequest(ctx, http.MethodPost, c.searchURL, "casadosdados", payload)
+	if err != nil {
+		return nil, err
+	}
+	return parseCompanySearch(body, page)
+}
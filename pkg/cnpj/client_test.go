@@ -0,0 +1,313 @@
+package cnpj
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anderson-ufrj/mcp-brasil/pkg/apierror"
+)
+
+// newTestClient returns a client whose requests are served by h, with
+// retries disabled.
+func newTestClient(t *testing.T, h http.HandlerFunc, opts ...Option) *Client {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return NewClient(append([]Option{WithBaseURL(srv.URL), WithRetry(1, 0)}, opts...)...)
+}
+
+func TestWithBaseURL(t *testing.T) {
+	if c := NewClient(); c.baseURL != BaseURL || c.searchURL != SearchURL {
+		t.Errorf("default URLs = %s, %s", c.baseURL, c.searchURL)
+	}
+
+	for _, suffix := range []string{"", "/"} {
+		var paths []string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paths = append(paths, r.Method+" "+r.URL.Path)
+			switch r.Method {
+			case http.MethodGet:
+				w.Write([]byte(`{"cnpj":"33000167000101","razao_social":"PETROLEO BRASILEIRO S A PETROBRAS"}`))
+			default:
+				w.Write([]byte(`{"success":true,"data":{"count":0,"cnpj":[]}}`))
+			}
+		}))
+		c := NewClient(WithRetry(1, 0), WithBaseURL(srv.URL+suffix), WithSearchURL(srv.URL+"/search"))
+
+		data, err := c.GetCNPJ(context.Background(), "33000167000101")
+		if err != nil {
+			t.Fatalf("GetCNPJ: %v", err)
+		}
+		if data.RazaoSocial != "PETROLEO BRASILEIRO S A PETROBRAS" {
+			t.Errorf("razao social = %q", data.RazaoSocial)
+		}
+		if _, err := c.SearchCompaniesByName(context.Background(), "petrobras", "", 1); err != nil {
+			t.Fatalf("SearchCompaniesByName: %v", err)
+		}
+		srv.Close()
+
+		want := []string{"GET /33.000.167/0001-01", "POST /search"}
+		if len(paths) != len(want) || paths[0] != want[0] || paths[1] != want[1] {
+			t.Errorf("base URL %q: requests = %v, want %v", srv.URL+suffix, paths, want)
+		}
+	}
+}
+
+func TestGetCNPJErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{status: http.StatusNotFound, want: apierror.ErrNotFound},
+		{status: http.StatusTooManyRequests, want: apierror.ErrRateLimited},
+		{status: http.StatusForbidden, want: apierror.ErrUnauthorized},
+		{status: http.StatusBadGateway, want: apierror.ErrUpstream},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, `{"message":"erro"}`, tt.status)
+			})
+			_, err := c.GetCNPJ(context.Background(), "33.000.167/0001-01")
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("invalid CNPJ", func(t *testing.T) {
+		var calls int
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) { calls++ })
+		if _, err := c.GetCNPJ(context.Background(), "123"); err == nil {
+			t.Error("expected an error")
+		}
+		if calls != 0 {
+			t.Errorf("calls = %d for an invalid CNPJ", calls)
+		}
+	})
+}
+
+func TestSearchCompaniesByName(t *testing.T) {
+	tests := []struct {
+		name, uf     string
+		page         int
+		response     string
+		wantBody     string
+		wantCount    int
+		wantNext     int
+		wantMessage  bool
+		wantUpstream bool
+	}{
+		{
+			name:      "petrobras",
+			uf:        " rj ",
+			response:  `{"success":true,"data":{"count":45,"cnpj":[{"cnpj":"33000167000101","razao_social":"PETROLEO BRASILEIRO S A PETROBRAS","uf":"RJ"}]}}`,
+			wantBody:  `{"query":{"termo":["petrobras"],"uf":["RJ"]},"extras":{"somente_matriz":false},"page":1}`,
+			wantCount: 1,
+			wantNext:  2,
+		},
+		{
+			name:      "petrobras",
+			page:      3,
+			response:  `{"success":true,"data":{"count":45,"cnpj":[{"cnpj":"33000167000101"}]}}`,
+			wantBody:  `{"query":{"termo":["petrobras"]},"extras":{"somente_matriz":false},"page":3}`,
+			wantCount: 1,
+		},
+		{
+			name:        "inexistente",
+			response:    `{"success":true,"data":{"count":0,"cnpj":null}}`,
+			wantBody:    `{"query":{"termo":["inexistente"]},"extras":{"somente_matriz":false},"page":1}`,
+			wantMessage: true,
+		},
+		{
+			name:         "petrobras",
+			response:     `{"success":false,"message":"limite excedido"}`,
+			wantBody:     `{"query":{"termo":["petrobras"]},"extras":{"somente_matriz":false},"page":1}`,
+			wantUpstream: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/page%d", tt.name, tt.page), func(t *testing.T) {
+			var body string
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				body = string(b)
+				w.Write([]byte(tt.response))
+			})
+			c.searchURL = c.baseURL + "/search"
+
+			resp, err := c.SearchCompaniesByName(context.Background(), tt.name, tt.uf, tt.page)
+			if body != tt.wantBody {
+				t.Errorf("request body = %s, want %s", body, tt.wantBody)
+			}
+			if tt.wantUpstream {
+				if !errors.Is(err, apierror.ErrUpstream) {
+					t.Errorf("error = %v, want ErrUpstream", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SearchCompaniesByName: %v", err)
+			}
+			if len(resp.Companies) != tt.wantCount || resp.Companies == nil {
+				t.Errorf("companies = %v, want %d", resp.Companies, tt.wantCount)
+			}
+			if resp.NextPage != tt.wantNext || resp.HasMore != (tt.wantNext != 0) {
+				t.Errorf("paging = %+v, want next page %d", resp.Info, tt.wantNext)
+			}
+			if (resp.Message != "") != tt.wantMessage {
+				t.Errorf("message = %q", resp.Message)
+			}
+		})
+	}
+}
+
+func TestSearchCompaniesByNameRequiresName(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("unexpected request for an empty name")
+	})
+	if _, err := c.SearchCompaniesByName(context.Background(), "  ", "", 1); err == nil {
+		t.Error("empty name was accepted")
+	}
+}
+
+func TestGetPartners(t *testing.T) {
+	tests := []struct {
+		name, body string
+		want       []string
+	}{
+		{
+			name: "partners",
+			body: `{"cnpj":"33000167000101","qsa":[
+				{"nome_socio":"MARIA DA SILVA","qualificacao_socio":"Sócio-Administrador"},
+				{"nome_socio":"JOAO SOUZA","qualificacao_socio":"Sócio"}]}`,
+			want: []string{"MARIA DA SILVA", "JOAO SOUZA"},
+		},
+		{name: "empty qsa", body: `{"cnpj":"33000167000101","qsa":[]}`},
+		{name: "missing qsa", body: `{"cnpj":"33000167000101"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tt.body))
+			})
+			partners, err := c.GetPartners(context.Background(), "33000167000101")
+			if err != nil {
+				t.Fatalf("GetPartners: %v", err)
+			}
+			if partners == nil || len(partners) != len(tt.want) {
+				t.Fatalf("partners = %v, want %v", partners, tt.want)
+			}
+			for i, p := range partners {
+				if p.Nome != tt.want[i] {
+					t.Errorf("partner %d = %q, want %q", i, p.Nome, tt.want[i])
+				}
+			}
+		})
+	}
+
+	var nilData *CNPJData
+	if p := nilData.Partners(); p == nil || len(p) != 0 {
+		t.Errorf("nil data partners = %v, want an empty list", p)
+	}
+}
+
+func TestDescribeSituacaoCadastral(t *testing.T) {
+	tests := []struct {
+		code int
+		want string
+	}{
+		{1, "nula"},
+		{2, "ativa"},
+		{3, "suspensa"},
+		{4, "inapta"},
+		{8, "baixada"},
+		{0, "desconhecida"},
+		{5, "desconhecida"},
+		{-1, "desconhecida"},
+	}
+	for _, tt := range tests {
+		if got := DescribeSituacaoCadastral(tt.code); got != tt.want {
+			t.Errorf("DescribeSituacaoCadastral(%d) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestGetCNPJSituacaoDescription(t *testing.T) {
+	tests := []struct {
+		body, want string
+	}{
+		{`{"cnpj":"33000167000101","situacao_cadastral":8}`, "baixada"},
+		{`{"cnpj":"33000167000101","situacao_cadastral":2,"descricao_situacao_cadastral":"ATIVA"}`, "ATIVA"},
+		{`{"cnpj":"33000167000101","situacao_cadastral":99}`, "desconhecida"},
+	}
+	for _, tt := range tests {
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(tt.body))
+		})
+		data, err := c.GetCNPJ(context.Background(), "33000167000101")
+		if err != nil {
+			t.Fatalf("GetCNPJ: %v", err)
+		}
+		if data.DescricaoSituacaoCadastral != tt.want {
+			t.Errorf("%s: description = %q, want %q", tt.body, data.DescricaoSituacaoCadastral, tt.want)
+		}
+	}
+}
+
+func TestWithDeduplication(t *testing.T) {
+	for _, dedup := range []bool{true, false} {
+		var requests int32
+		release := make(chan struct{})
+		opts := []Option{}
+		if dedup {
+			opts = append(opts, WithDeduplication())
+		}
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			<-release
+			w.Write([]byte(`{"cnpj":"33000167000101","razao_social":"PETROBRAS"}`))
+		}, opts...)
+
+		const n = 8
+		var wg sync.WaitGroup
+		errs := make(chan error, n)
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				data, err := c.GetCNPJ(context.Background(), "33.000.167/0001-01")
+				if err == nil && data.RazaoSocial != "PETROBRAS" {
+					err = fmt.Errorf("razao social = %q", data.RazaoSocial)
+				}
+				errs <- err
+			}()
+		}
+		// Let every call reach the stub (or join the in-flight one).
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			if err != nil {
+				t.Errorf("GetCNPJ: %v", err)
+			}
+		}
+		want := int32(n)
+		if dedup {
+			want = 1
+		}
+		if requests != want {
+			t.Errorf("dedup %v: %d upstream requests, want %d", dedup, requests, want)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+// Package dedup shares one upstream call among concurrent identical requests.
+//
+// A nil *Group is valid and performs every call, so clients can use it
+// unconditionally and only allocate one when deduplication is enabled.
+package dedup
+
+import "golang.org/x/sync/singleflight"
+
+// Group deduplicates calls by key.
+type Group struct {
+	flight singleflight.Group
+}
+
+// Do runs fn, unless a call with the same key is already in flight, in which
+// case it waits for that call and returns its result. The returned body is
+// shared between callers and must not be modified.
+//
+// The shared call runs with the context of the caller that started it, so if
+// that caller is canceled the others receive the cancellation error too.
+func (g *Group) Do(key string, fn func() ([]byte, error)) ([]byte, error) {
+	if g == nil {
+		return fn()
+	}
+	v, err, _ := g.flight.Do(key, func() (interface{}, error) {
+		return fn()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]byte), nil
+}
@@ -0,0 +1,72 @@
+package dedup
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroupSharesInFlightCalls(t *testing.T) {
+	var g Group
+	var calls int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	fn := func() ([]byte, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+		}
+		<-release
+		return []byte("ok"), nil
+	}
+
+	const n = 10
+	results := make([]string, n)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		body, _ := g.Do("key", fn)
+		results[0] = string(body)
+	}()
+	<-started
+	for i := 1; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			body, _ := g.Do("key", fn)
+			results[i] = string(body)
+		}(i)
+	}
+	// Give the callers time to join the in-flight call.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("fn ran %d times, want 1", calls)
+	}
+	for i, r := range results {
+		if r != "ok" {
+			t.Errorf("caller %d got %q", i, r)
+		}
+	}
+}
+
+func TestNilGroup(t *testing.T) {
+	var g *Group
+	wantErr := errors.New("upstream down")
+	calls := 0
+	for i := 0; i < 2; i++ {
+		if _, err := g.Do("key", func() ([]byte, error) {
+			calls++
+			return nil, wantErr
+		}); err != wantErr {
+			t.Errorf("error = %v, want %v", err, wantErr)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("nil group ran fn %d times, want 2", calls)
+	}
+}
@@ -0,0 +1,94 @@
+// Package diskcache stores raw API responses as files on disk, for data that
+// rarely changes such as the IBGE localities lists.
+//
+// A nil *Cache is valid and caches nothing, so clients can call it
+// unconditionally.
+package diskcache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultMaxAge is how long cached entries stay fresh unless configured.
+const DefaultMaxAge = 30 * 24 * time.Hour
+
+// Cache reads and writes entries under a directory.
+type Cache struct {
+	dir    string
+	maxAge time.Duration
+}
+
+// New returns a cache storing entries in dir. Entries older than maxAge are
+// ignored; a maxAge of zero or less uses DefaultMaxAge.
+func New(dir string, maxAge time.Duration) *Cache {
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+	return &Cache{dir: dir, maxAge: maxAge}
+}
+
+// path maps a key to a file name, replacing anything but letters, digits,
+// dashes and underscores so keys cannot escape the directory.
+func (c *Cache) path(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, key)
+	return filepath.Join(c.dir, name+".json")
+}
+
+// Load returns the entry stored under key, if present and not expired.
+func (c *Cache) Load(key string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	path := c.path(key)
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > c.maxAge {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Store saves data under key. The file is written atomically so concurrent
+// readers never see a partial entry.
+func (c *Cache) Store(key string, data []byte) error {
+	if c == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return fmt.Errorf("creating cache dir: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating cache file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing cache file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		return fmt.Errorf("writing cache file: %w", err)
+	}
+	return nil
+}
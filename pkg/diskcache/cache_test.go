@@ -0,0 +1,65 @@
+package diskcache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	c := New(dir, time.Hour)
+
+	if _, ok := c.Load("ibge_states"); ok {
+		t.Fatal("Load found an entry in an empty cache")
+	}
+	if err := c.Store("ibge_states", []byte(`[{"id":35}]`)); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	data, ok := c.Load("ibge_states")
+	if !ok || string(data) != `[{"id":35}]` {
+		t.Errorf("Load = %s, %v", data, ok)
+	}
+
+	// Entries past maxAge are ignored.
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "ibge_states.json"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Load("ibge_states"); ok {
+		t.Error("Load returned an expired entry")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("cache dir holds %d files, want 1 (no leftover temp files)", len(entries))
+	}
+}
+
+func TestCacheKeysStayInDir(t *testing.T) {
+	dir := t.TempDir()
+	c := New(dir, 0)
+	if c.maxAge != DefaultMaxAge {
+		t.Errorf("maxAge = %v, want DefaultMaxAge", c.maxAge)
+	}
+	if err := c.Store("../../etc/passwd", []byte("x")); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "______etc_passwd.json")); err != nil {
+		t.Errorf("sanitized entry not found in the cache dir: %v", err)
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	var c *Cache
+	if err := c.Store("k", []byte("v")); err != nil {
+		t.Errorf("Store on a nil cache: %v", err)
+	}
+	if _, ok := c.Load("k"); ok {
+		t.Error("Load on a nil cache found an entry")
+	}
+}
@@ -0,0 +1,16 @@
+// Package document normalizes Brazilian document numbers such as CPF, CNPJ
+// and CEP, which users type with or without punctuation.
+package document
+
+import "strings"
+
+// NormalizeDigits removes everything but the ASCII digits from s, so that
+// "123.456.789-09", " 123 456 789 09 " and "12345678909" are all equal.
+func NormalizeDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
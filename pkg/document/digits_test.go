@@ -0,0 +1,25 @@
+package document
+
+import "testing"
+
+func TestNormalizeDigits(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"123.456.789-09", "12345678909"},
+		{"12345678909", "12345678909"},
+		{" 123 456 789 09 ", "12345678909"},
+		{"\t123.456.789/09\n", "12345678909"},
+		{"33.000.167/0001-01", "33000167000101"},
+		{"01310-100", "01310100"},
+		{"CPF: 123-456", "123456"},
+		{"١٢٣", ""}, // non-ASCII digits are dropped
+		{"", ""},
+		{"abc", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeDigits(tt.in); got != tt.want {
+			t.Errorf("NormalizeDigits(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
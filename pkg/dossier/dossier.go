@@ -0,0 +1,112 @@
+// Package dossier combines what the federal sources know about a company
+// into a single report.
+package dossier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/anderson-ufrj/mcp-brasil/pkg/cnpj"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/transparencia"
+)
+
+// ContractsLimit caps how many contracts a dossier lists.
+const ContractsLimit = 100
+
+// Registry looks up company registration data; *cnpj.Client implements it.
+type Registry interface {
+	GetCNPJ(ctx context.Context, cnpj string) (*cnpj.CNPJData, error)
+}
+
+// Portal lists the contracts and sanctions of a supplier;
+// *transparencia.Client implements it.
+type Portal interface {
+	SearchContractsBySupplier(ctx context.Context, cnpj string, page, pageSize int) (*transparencia.ContractsResponse, error)
+	ScreenCompany(ctx context.Context, cnpj string) (*transparencia.SanctionReport, error)
+}
+
+// CompanyDossier combines what the federal sources know about a company.
+// A failing source is reported in Errors and leaves its section empty.
+type CompanyDossier struct {
+	CNPJ      string                        `json:"cnpj"`
+	Cadastro  *cnpj.CNPJData                `json:"cadastro"`
+	Contratos []transparencia.Contract      `json:"contratos"`
+	Sancoes   *transparencia.SanctionReport `json:"sancoes"`
+	Errors    map[string]string             `json:"erros,omitempty"`
+}
+
+// Builder assembles company dossiers from a registry and the Portal da
+// Transparencia.
+type Builder struct {
+	registry Registry
+	portal   Portal
+}
+
+// NewBuilder creates a Builder querying registry and portal.
+func NewBuilder(registry Registry, portal Portal) *Builder {
+	return &Builder{registry: registry, portal: portal}
+}
+
+// BuildCompanyDossier fetches registration data, contracts awarded and
+// sanctions for a CNPJ concurrently. An error is only returned when the
+// CNPJ is invalid or every source fails.
+func (b *Builder) BuildCompanyDossier(ctx context.Context, cnpjNum string) (*CompanyDossier, error) {
+	digits, err := cnpj.Validate(cnpjNum)
+	if err != nil {
+		return nil, err
+	}
+
+	dossier := &CompanyDossier{
+		CNPJ:      digits,
+		Contratos: []transparencia.Contract{},
+		Errors:    map[string]string{},
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	setErr := func(source string, err error) {
+		mu.Lock()
+		dossier.Errors[source] = err.Error()
+		mu.Unlock()
+	}
+
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		data, err := b.registry.GetCNPJ(ctx, digits)
+		if err != nil {
+			setErr("cadastro", err)
+			return
+		}
+		dossier.Cadastro = data
+	}()
+	go func() {
+		defer wg.Done()
+		resp, err := b.portal.SearchContractsBySupplier(ctx, digits, 1, ContractsLimit)
+		if err != nil {
+			setErr("contratos", err)
+			return
+		}
+		dossier.Contratos = resp.Contracts
+	}()
+	go func() {
+		defer wg.Done()
+		report, err := b.portal.ScreenCompany(ctx, digits)
+		if err != nil {
+			setErr("sancoes", err)
+			return
+		}
+		for source, msg := range report.Errors {
+			setErr("sancoes_"+source, errors.New(msg))
+		}
+		dossier.Sancoes = report
+	}()
+	wg.Wait()
+
+	if dossier.Cadastro == nil && dossier.Sancoes == nil && dossier.Errors["contratos"] != "" {
+		return nil, fmt.Errorf("building dossier for %s: all sources failed: %v", digits, dossier.Errors)
+	}
+	return dossier, nil
+}
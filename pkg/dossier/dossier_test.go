@@ -0,0 +1,134 @@
+package dossier
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/anderson-ufrj/mcp-brasil/pkg/cnpj"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/transparencia"
+)
+
+type fakeRegistry struct {
+	err   error
+	calls int
+}
+
+func (f *fakeRegistry) GetCNPJ(ctx context.Context, cnpjNum string) (*cnpj.CNPJData, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &cnpj.CNPJData{CNPJ: cnpjNum, RazaoSocial: "ACME LTDA"}, nil
+}
+
+type fakePortal struct {
+	contractsErr  error
+	screenErr     error
+	screenPartial map[string]string
+}
+
+func (f *fakePortal) SearchContractsBySupplier(ctx context.Context, cnpjNum string, page, pageSize int) (*transparencia.ContractsResponse, error) {
+	if f.contractsErr != nil {
+		return nil, f.contractsErr
+	}
+	return &transparencia.ContractsResponse{Contracts: []transparencia.Contract{{Numero: "1/2024"}, {Numero: "2/2024"}}}, nil
+}
+
+func (f *fakePortal) ScreenCompany(ctx context.Context, cnpjNum string) (*transparencia.SanctionReport, error) {
+	if f.screenErr != nil {
+		return nil, f.screenErr
+	}
+	sanctioned := true
+	return &transparencia.SanctionReport{CNPJ: cnpjNum, Sanctioned: &sanctioned, Errors: f.screenPartial}, nil
+}
+
+func TestBuildCompanyDossier(t *testing.T) {
+	boom := errors.New("boom")
+	tests := []struct {
+		name          string
+		registryErr   error
+		portal        fakePortal
+		wantErrors    []string
+		wantCadastro  bool
+		wantContracts int
+		wantSancoes   bool
+	}{
+		{
+			name:          "all sources",
+			wantCadastro:  true,
+			wantContracts: 2,
+			wantSancoes:   true,
+		},
+		{
+			name:          "registry down",
+			registryErr:   boom,
+			wantErrors:    []string{"cadastro"},
+			wantContracts: 2,
+			wantSancoes:   true,
+		},
+		{
+			name:         "contracts down",
+			portal:       fakePortal{contractsErr: boom},
+			wantErrors:   []string{"contratos"},
+			wantCadastro: true,
+			wantSancoes:  true,
+		},
+		{
+			name:          "one sanction registry down",
+			portal:        fakePortal{screenPartial: map[string]string{"cnep": "timeout"}},
+			wantErrors:    []string{"sancoes_cnep"},
+			wantCadastro:  true,
+			wantContracts: 2,
+			wantSancoes:   true,
+		},
+		{
+			name:          "sanctions down",
+			portal:        fakePortal{screenErr: boom},
+			wantErrors:    []string{"sancoes"},
+			wantCadastro:  true,
+			wantContracts: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBuilder(&fakeRegistry{err: tt.registryErr}, &tt.portal)
+			d, err := b.BuildCompanyDossier(context.Background(), "11.222.333/0001-81")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if d.CNPJ != "11222333000181" {
+				t.Errorf("CNPJ = %q", d.CNPJ)
+			}
+			if (d.Cadastro != nil) != tt.wantCadastro || len(d.Contratos) != tt.wantContracts || (d.Sancoes != nil) != tt.wantSancoes {
+				t.Errorf("cadastro = %v, contratos = %d, sancoes = %v", d.Cadastro != nil, len(d.Contratos), d.Sancoes != nil)
+			}
+			if len(d.Errors) != len(tt.wantErrors) {
+				t.Fatalf("errors = %v, want %v", d.Errors, tt.wantErrors)
+			}
+			for _, source := range tt.wantErrors {
+				if d.Errors[source] == "" {
+					t.Errorf("errors = %v, want one for %s", d.Errors, source)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildCompanyDossierFailures(t *testing.T) {
+	boom := errors.New("boom")
+
+	b := NewBuilder(&fakeRegistry{err: boom}, &fakePortal{contractsErr: boom, screenErr: boom})
+	if _, err := b.BuildCompanyDossier(context.Background(), "11222333000181"); err == nil {
+		t.Error("expected an error when every source fails")
+	}
+
+	registry := &fakeRegistry{}
+	b = NewBuilder(registry, &fakePortal{})
+	if _, err := b.BuildCompanyDossier(context.Background(), "11222333000180"); err == nil {
+		t.Error("expected an error for an invalid CNPJ")
+	}
+	if registry.calls != 0 {
+		t.Errorf("registry called %d times for an invalid CNPJ", registry.calls)
+	}
+}
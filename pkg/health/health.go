@@ -0,0 +1,37 @@
+// Package health probes whether the upstream APIs are reachable.
+package health
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/anderson-ufrj/mcp-brasil/pkg/apierror"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/httplog"
+)
+
+// Ping sends a HEAD request to url. Any response below 500 counts as
+// reachable, as an API root may not serve content. The probe is logged on
+// logger like any request, but it is not recorded in the client metrics, so
+// health checks do not show up as API traffic.
+func Ping(ctx context.Context, client *http.Client, logger *slog.Logger, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	httplog.Request(ctx, logger, req, resp, err, time.Since(start))
+	if err != nil {
+		return fmt.Errorf("executing request: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return apierror.New(resp.StatusCode, nil)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package httpbody
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"testing"
+)
+
+const payload = `{"nome":"São Paulo","valor":1234.5}`
+
+func compress(t *testing.T, newWriter func(io.Writer) io.WriteCloser) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := newWriter(&buf)
+	if _, err := w.Write([]byte(payload)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRead(t *testing.T) {
+	gzipped := compress(t, func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) })
+	zlibbed := compress(t, func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) })
+	deflated := compress(t, func(w io.Writer) io.WriteCloser {
+		fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+		return fw
+	})
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		wantErr  bool
+	}{
+		{name: "plain", encoding: "", body: []byte(payload)},
+		{name: "identity", encoding: "identity", body: []byte(payload)},
+		{name: "gzip", encoding: "gzip", body: gzipped},
+		{name: "gzip uppercase", encoding: " GZIP ", body: gzipped},
+		{name: "x-gzip", encoding: "x-gzip", body: gzipped},
+		{name: "zlib deflate", encoding: "deflate", body: zlibbed},
+		{name: "raw deflate", encoding: "deflate", body: deflated},
+		{name: "corrupt gzip", encoding: "gzip", body: []byte(payload), wantErr: true},
+		{name: "unsupported", encoding: "br", body: []byte(payload), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				Header: http.Header{"Content-Encoding": {tt.encoding}},
+				Body:   io.NopCloser(bytes.NewReader(tt.body)),
+			}
+			got, err := Read(resp)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Read() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != payload {
+				t.Errorf("Read() = %q, want %q", got, payload)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+// Package httplog logs the upstream requests made by the API clients.
+package httplog
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Discard is the default client logger; it drops every record.
+var Discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// OrDiscard returns l, or Discard when l is nil, so that clients can take a
+// logger option without checking it on every request.
+func OrDiscard(l *slog.Logger) *slog.Logger {
+	if l == nil {
+		return Discard
+	}
+	return l
+}
+
+// sensitiveParams are query parameters whose values are never logged.
+var sensitiveParams = []string{"chave", "key", "token", "senha", "password", "secret"}
+
+// RedactURL returns u as a string with the values of credential-like query
+// parameters replaced by "REDACTED".
+func RedactURL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.String()
+	}
+	redacted := *u
+	query := redacted.Query()
+	for name := range query {
+		lower := strings.ToLower(name)
+		for _, s := range sensitiveParams {
+			if strings.Contains(lower, s) {
+				query.Set(name, "REDACTED")
+				break
+			}
+		}
+	}
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
+
+// Request logs a completed request at debug level. Only the method, the
+// redacted URL, the status code and the duration are logged, never headers.
+func Request(ctx context.Context, logger *slog.Logger, req *http.Request, resp *http.Response, err error, duration time.Duration) {
+	if logger == nil || !logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	attrs := []any{
+		"method", req.Method,
+		"url", RedactURL(req.URL),
+		"duration", duration,
+	}
+	if err != nil {
+		logger.DebugContext(ctx, "upstream request failed", append(attrs, "error", err)...)
+		return
+	}
+	logger.DebugContext(ctx, "upstream request", append(attrs, "status", resp.StatusCode)...)
+}
@@ -0,0 +1,31 @@
+package httplog
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestRedactURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "https://api.example/ceis", want: "https://api.example/ceis"},
+		{in: "https://api.example/ceis?pagina=1", want: "https://api.example/ceis?pagina=1"},
+		{in: "https://api.example/ceis?chave-api-dados=s3cr3t&pagina=1", want: "https://api.example/ceis?chave-api-dados=REDACTED&pagina=1"},
+		{in: "https://api.example/x?api_key=s3cr3t", want: "https://api.example/x?api_key=REDACTED"},
+		{in: "https://api.example/x?Token=a&token=b", want: "https://api.example/x?Token=REDACTED&token=REDACTED"},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := RedactURL(u); got != tt.want {
+			t.Errorf("RedactURL(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+		if u.String() != tt.in {
+			t.Errorf("RedactURL modified its argument: %s", u)
+		}
+	}
+}
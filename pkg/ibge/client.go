@@ -2,30 +2,144 @@
 package ibge
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
+	"log/slog"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/anderson-ufrj/mcp-brasil/pkg/apierror"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/dedup"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/diskcache"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/health"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/httpbody"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/httplog"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/metrics"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/retry"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/text"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/timeout"
+	"golang.org/x/sync/singleflight"
 )
 
 const (
 	LocalidadesURL = "https://servicodados.ibge.gov.br/api/v1/localidades"
 	AgregadosURL   = "https://servicodados.ibge.gov.br/api/v3/agregados"
+	NomesURL       = "https://servicodados.ibge.gov.br/api/v2/censos/nomes"
+	MalhasURL      = "https://servicodados.ibge.gov.br/api/v3/malhas"
 	DefaultTimeout = 30 * time.Second
 )
 
 // Client represents the IBGE API client.
 type Client struct {
-	httpClient *http.Client
+	httpClient     *http.Client
+	logger         *slog.Logger
+	metrics        metrics.Collector
+	flight         *dedup.Group
+	retry          retry.Policy
+	localidadesURL string
+	agregadosURL   string
+	nomesURL       string
+	malhasURL      string
+	cacheDir       string
+	cacheMaxAge    time.Duration
+	cache          *diskcache.Cache
+
+	statesFlight singleflight.Group
+	statesMu     sync.Mutex
+	states       []State
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithBaseURL points the client at another IBGE root, such as a mirror or a
+// test server. It takes the part shared by the service URLs
+// ("https://servicodados.ibge.gov.br/api" in production), to which
+// "/v1/localidades", "/v3/agregados", "/v2/censos/nomes" and "/v3/malhas"
+// are appended.
+func WithBaseURL(root string) Option {
+	return func(c *Client) {
+		root = strings.TrimRight(root, "/")
+		c.localidadesURL = root + "/v1/localidades"
+		c.agregadosURL = root + "/v3/agregados"
+		c.nomesURL = root + "/v2/censos/nomes"
+		c.malhasURL = root + "/v3/malhas"
+	}
+}
+
+// WithLogger logs the IBGE requests on l at debug level, as described
+// in httplog.Request. A nil l disables logging.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Client) {
+		c.logger = httplog.OrDiscard(l)
+	}
+}
+
+// WithMetrics records every request (status class and latency) on m.
+func WithMetrics(m metrics.Collector) Option {
+	return func(c *Client) {
+		c.metrics = m
+	}
+}
+
+// WithDeduplication makes concurrent identical requests share a single
+// upstream call.
+func WithDeduplication() Option {
+	return func(c *Client) {
+		c.flight = &dedup.Group{}
+	}
+}
+
+// WithRetry sets how many times a request failing with a 429, a 5xx or a
+// connection error is attempted in total, and the delay before the first
+// retry, which doubles on each further one. maxAttempts below 2 disables
+// retries.
+func WithRetry(maxAttempts int, baseDelay time.Duration) Option {
+	return func(c *Client) {
+		c.retry = retry.Policy{MaxAttempts: maxAttempts, BaseDelay: baseDelay}
+	}
+}
+
+// WithDiskCache keeps the states and municipalities lists as JSON files in
+// dir, reading them from disk before the network while they are fresh.
+func WithDiskCache(dir string) Option {
+	return func(c *Client) {
+		c.cacheDir = dir
+	}
+}
+
+// WithCacheMaxAge sets how long disk cache entries stay fresh
+// (diskcache.DefaultMaxAge otherwise).
+func WithCacheMaxAge(d time.Duration) Option {
+	return func(c *Client) {
+		c.cacheMaxAge = d
+	}
 }
 
 // NewClient creates a new IBGE client.
-func NewClient() *Client {
-	return &Client{
-		httpClient: &http.Client{Timeout: DefaultTimeout},
+func NewClient(opts ...Option) *Client {
+	c := &Client{
+		httpClient:     &http.Client{Timeout: DefaultTimeout},
+		logger:         httplog.Discard,
+		retry:          retry.Default,
+		localidadesURL: LocalidadesURL,
+		agregadosURL:   AgregadosURL,
+		nomesURL:       NomesURL,
+		malhasURL:      MalhasURL,
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	if c.cacheDir != "" {
+		c.cache = diskcache.New(c.cacheDir, c.cacheMaxAge)
+	}
+	return c
 }
 
 // State represents a Brazilian state.
@@ -34,6 +148,8 @@ type State struct {
 	Sigla  string `json:"sigla"`
 	Nome   string `json:"nome"`
 	Regiao Region `json:"regiao"`
+	// Area is the territorial area in km², set by FillStateArea.
+	Area float64 `json:"area_km2,omitempty"`
 }
 
 // Region represents a Brazilian region.
@@ -42,14 +158,27 @@ type Region struct {
 	Nome string `json:"nome"`
 }
 
+// Mesoregion represents an IBGE mesorregião.
+type Mesoregion struct {
+	ID   int    `json:"id"`
+	Nome string `json:"nome"`
+	UF   *State `json:"UF,omitempty"`
+}
+
+// Microregion represents an IBGE microrregião.
+type Microregion struct {
+	ID          int         `json:"id"`
+	Nome        string      `json:"nome"`
+	Mesorregiao *Mesoregion `json:"mesorregiao,omitempty"`
+}
+
 // Municipality represents a Brazilian municipality.
 type Municipality struct {
-	ID          int    `json:"id"`
-	Nome        string `json:"nome"`
-	Microrregiao struct {
-		ID   int    `json:"id"`
-		Nome string `json:"nome"`
-	} `json:"microrregiao"`
+	ID           int         `json:"id"`
+	Nome         string      `json:"nome"`
+	Microrregiao Microregion `json:"microrregiao"`
+	// Area is the territorial area in km², set by FillMunicipalityArea.
+	Area float64 `json:"area_km2,omitempty"`
 }
 
 // StatesResponse represents the response for states query.
@@ -59,6 +188,29 @@ type StatesResponse struct {
 	Source string  `json:"source"`
 }
 
+// RegionsResponse represents the response for regions query.
+type RegionsResponse struct {
+	Regions []Region `json:"regions"`
+	Total   int      `json:"total"`
+	Source  string   `json:"source"`
+}
+
+// MesoregionsResponse represents the response for mesoregions query.
+type MesoregionsResponse struct {
+	Mesoregions []Mesoregion `json:"mesoregions"`
+	Total       int          `json:"total"`
+	StateID     string       `json:"state_id,omitempty"`
+	Source      string       `json:"source"`
+}
+
+// MicroregionsResponse represents the response for microregions query.
+type MicroregionsResponse struct {
+	Microregions []Microregion `json:"microregions"`
+	Total        int           `json:"total"`
+	StateID      string        `json:"state_id,omitempty"`
+	Source       string        `json:"source"`
+}
+
 // MunicipalitiesResponse represents the response for municipalities query.
 type MunicipalitiesResponse struct {
 	Municipalities []Municipality `json:"municipalities"`
@@ -69,48 +221,135 @@ type MunicipalitiesResponse struct {
 
 // PopulationData represents population data.
 type PopulationData struct {
-	Location   string `json:"location"`
-	Year       string `json:"year"`
-	Population string `json:"population"`
+	Location        string `json:"location"`
+	Year            string `json:"year"`
+	Population      string `json:"population"`
+	PopulationValue int64  `json:"population_value"`
 }
 
 // PopulationResponse represents the response for population query.
 type PopulationResponse struct {
-	Data   []PopulationData `json:"data"`
-	Source string           `json:"source"`
+	Data          []PopulationData `json:"data"`
+	ParseWarnings int              `json:"parse_warnings,omitempty"`
+	Source        string           `json:"source"`
 }
 
+// GDPResponse represents the GDP of a municipality for one year.
+type GDPResponse struct {
+	MunicipalityID string  `json:"municipality_id"`
+	Municipality   string  `json:"municipality"`
+	Year           string  `json:"year"`
+	Value          float64 `json:"value"`
+	Unit           string  `json:"unit"`
+	Source         string  `json:"source"`
+}
+
+// NameFrequency represents how many people born in a decade received a name.
+type NameFrequency struct {
+	Period    string `json:"periodo"`
+	Frequency int64  `json:"frequencia"`
+}
+
+// NameStatsResponse represents the response for first-name frequency queries.
+type NameStatsResponse struct {
+	Name     string          `json:"name"`
+	Location string          `json:"location"`
+	Decades  []NameFrequency `json:"decades"`
+	Total    int64           `json:"total"`
+	Source   string          `json:"source"`
+}
+
+// agregadoVariable mirrors one variable in an IBGE agregados API response.
+type agregadoVariable struct {
+	ID         string `json:"id"`
+	Variavel   string `json:"variavel"`
+	Unidade    string `json:"unidade"`
+	Resultados []struct {
+		Series []agregadoSeries `json:"series"`
+	} `json:"resultados"`
+}
+
+// agregadoSeries holds the values of one locality, keyed by period.
+type agregadoSeries struct {
+	Localidade struct {
+		ID   string `json:"id"`
+		Nome string `json:"nome"`
+	} `json:"localidade"`
+	Serie map[string]string `json:"serie"`
+}
+
+// Ping checks that the IBGE localidades API is reachable (see health.Ping).
+func (c *Client) Ping(ctx context.Context) error {
+	return health.Ping(ctx, c.httpClient, c.logger, c.localidadesURL+"/regioes")
+}
+
+// doRequest performs a GET request, retrying transient failures and sharing
+// the result with identical concurrent requests when deduplication is enabled.
 func (c *Client) doRequest(ctx context.Context, url string) ([]byte, error) {
+	return c.flight.Do(url, func() ([]byte, error) {
+		return c.retry.Do(ctx, func() ([]byte, error) {
+			return c.fetch(ctx, url)
+		})
+	})
+}
+
+// fetch performs a GET request and returns the body of a 200 response.
+func (c *Client) fetch(ctx context.Context, url string) ([]byte, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 
 	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept-Encoding", httpbody.AcceptEncoding)
 
-	resp, err := c.httpClient.Do(req)
+	start := time.Now()
+	resp, err := timeout.Client(ctx, c.httpClient).Do(req)
+	duration := time.Since(start)
+	httplog.Request(ctx, c.logger, req, resp, err, duration)
+	metrics.Observe(c.metrics, "ibge", resp, err, duration)
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
 		return nil, fmt.Errorf("executing request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := httpbody.Read(resp)
 	if err != nil {
 		return nil, fmt.Errorf("reading response: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, apierror.FromResponse(resp, body)
 	}
 
 	return body, nil
 }
 
+// cachedRequest serves key from the disk cache, if any, falling back to a
+// request to url whose response is then cached.
+func (c *Client) cachedRequest(ctx context.Context, key, url string) ([]byte, error) {
+	if body, ok := c.cache.Load(key); ok {
+		return body, nil
+	}
+
+	body, err := c.doRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.cache.Store(key, body); err != nil {
+		c.logger.DebugContext(ctx, "disk cache write failed", "key", key, "error", err)
+	}
+	return body, nil
+}
+
 // GetStates returns all Brazilian states.
 func (c *Client) GetStates(ctx context.Context) (*StatesResponse, error) {
-	url := fmt.Sprintf("%s/estados?orderBy=nome", LocalidadesURL)
+	url := fmt.Sprintf("%s/estados?orderBy=nome", c.localidadesURL)
 
-	body, err := c.doRequest(ctx, url)
+	body, err := c.cachedRequest(ctx, "ibge_states", url)
 	if err != nil {
 		return nil, err
 	}
@@ -127,13 +366,157 @@ func (c *Client) GetStates(ctx context.Context) (*StatesResponse, error) {
 	}, nil
 }
 
-// GetMunicipalities returns municipalities, optionally filtered by state.
-func (c *Client) GetMunicipalities(ctx context.Context, stateID string) (*MunicipalitiesResponse, error) {
+// CachedStates returns the states like GetStates, but kept in memory after
+// the first successful request as the list practically never changes.
+// Concurrent first calls share a single request.
+func (c *Client) CachedStates(ctx context.Context) (*StatesResponse, error) {
+	c.statesMu.Lock()
+	states := c.states
+	c.statesMu.Unlock()
+
+	if states == nil {
+		v, err, _ := c.statesFlight.Do("states", func() (interface{}, error) {
+			resp, err := c.GetStates(ctx)
+			if err != nil {
+				return nil, err
+			}
+			c.statesMu.Lock()
+			defer c.statesMu.Unlock()
+			c.states = resp.States
+			return resp.States, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		states = v.([]State)
+	}
+	return &StatesResponse{States: states, Total: len(states), Source: "ibge_api"}, nil
+}
+
+// ResolveStateID returns the numeric code of a state given by its code, its
+// sigla ("SP") or its name ("São Paulo"), ignoring case and accents. An
+// empty state resolves to "".
+func (c *Client) ResolveStateID(ctx context.Context, state string) (string, error) {
+	state = strings.TrimSpace(state)
+	if strings.Trim(state, "0123456789") == "" {
+		return state, nil
+	}
+
+	states, err := c.CachedStates(ctx)
+	if err != nil {
+		return "", err
+	}
+	folded := text.Fold(state)
+	for _, st := range states.States {
+		if text.Fold(st.Sigla) == folded || text.Fold(st.Nome) == folded {
+			return strconv.Itoa(st.ID), nil
+		}
+	}
+	return "", fmt.Errorf("%w: state %s", apierror.ErrNotFound, state)
+}
+
+// GetRegions returns the five Brazilian macro-regions.
+func (c *Client) GetRegions(ctx context.Context) (*RegionsResponse, error) {
+	url := fmt.Sprintf("%s/regioes", c.localidadesURL)
+
+	body, err := c.doRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var regions []Region
+	if err := json.Unmarshal(body, &regions); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	return &RegionsResponse{
+		Regions: regions,
+		Total:   len(regions),
+		Source:  "ibge_api",
+	}, nil
+}
+
+// GetState returns a single state by its numeric IBGE code (e.g. "35") or
+// its two-letter sigla (e.g. "SP", case-insensitive).
+func (c *Client) GetState(ctx context.Context, idOrSigla string) (*State, error) {
+	idOrSigla = strings.ToUpper(strings.TrimSpace(idOrSigla))
+	if idOrSigla == "" {
+		return nil, fmt.Errorf("state id or sigla is required")
+	}
+
+	if _, err := strconv.Atoi(idOrSigla); err != nil {
+		if len(idOrSigla) != 2 {
+			return nil, fmt.Errorf("invalid state %q: expected a two-letter sigla or a numeric code", idOrSigla)
+		}
+		states, err := c.GetStates(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, state := range states.States {
+			if state.Sigla == idOrSigla {
+				return &state, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: state %s", apierror.ErrNotFound, idOrSigla)
+	}
+
+	url := fmt.Sprintf("%s/estados/%s", c.localidadesURL, idOrSigla)
+
+	body, err := c.doRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unknown codes are answered with an empty array instead of a 404.
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || trimmed[0] == '[' {
+		return nil, fmt.Errorf("%w: state %s", apierror.ErrNotFound, idOrSigla)
+	}
+
+	var state State
+	if err := json.Unmarshal(trimmed, &state); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+	if state.ID == 0 {
+		return nil, fmt.Errorf("%w: state %s", apierror.ErrNotFound, idOrSigla)
+	}
+	return &state, nil
+}
+
+// GetMesoregions returns mesoregions, optionally filtered by state.
+func (c *Client) GetMesoregions(ctx context.Context, stateID string) (*MesoregionsResponse, error) {
+	var url string
+	if stateID != "" {
+		url = fmt.Sprintf("%s/estados/%s/mesorregioes?orderBy=nome", c.localidadesURL, stateID)
+	} else {
+		url = fmt.Sprintf("%s/mesorregioes?orderBy=nome", c.localidadesURL)
+	}
+
+	body, err := c.doRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var mesoregions []Mesoregion
+	if err := json.Unmarshal(body, &mesoregions); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	return &MesoregionsResponse{
+		Mesoregions: mesoregions,
+		Total:       len(mesoregions),
+		StateID:     stateID,
+		Source:      "ibge_api",
+	}, nil
+}
+
+// GetMicroregions returns microregions, optionally filtered by state.
+func (c *Client) GetMicroregions(ctx context.Context, stateID string) (*MicroregionsResponse, error) {
 	var url string
 	if stateID != "" {
-		url = fmt.Sprintf("%s/estados/%s/municipios?orderBy=nome", LocalidadesURL, stateID)
+		url = fmt.Sprintf("%s/estados/%s/microrregioes?orderBy=nome", c.localidadesURL, stateID)
 	} else {
-		url = fmt.Sprintf("%s/municipios?orderBy=nome", LocalidadesURL)
+		url = fmt.Sprintf("%s/microrregioes?orderBy=nome", c.localidadesURL)
 	}
 
 	body, err := c.doRequest(ctx, url)
@@ -141,6 +524,39 @@ func (c *Client) GetMunicipalities(ctx context.Context, stateID string) (*Munici
 		return nil, err
 	}
 
+	var microregions []Microregion
+	if err := json.Unmarshal(body, &microregions); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	return &MicroregionsResponse{
+		Microregions: microregions,
+		Total:        len(microregions),
+		StateID:      stateID,
+		Source:       "ibge_api",
+	}, nil
+}
+
+// GetMunicipalities returns municipalities, optionally filtered by state,
+// given by its code, sigla or name.
+func (c *Client) GetMunicipalities(ctx context.Context, stateID string) (*MunicipalitiesResponse, error) {
+	stateID, err := c.ResolveStateID(ctx, stateID)
+	if err != nil {
+		return nil, err
+	}
+
+	var url string
+	if stateID != "" {
+		url = fmt.Sprintf("%s/estados/%s/municipios?orderBy=nome", c.localidadesURL, stateID)
+	} else {
+		url = fmt.Sprintf("%s/municipios?orderBy=nome", c.localidadesURL)
+	}
+
+	body, err := c.cachedRequest(ctx, "ibge_municipalities_"+stateID, url)
+	if err != nil {
+		return nil, err
+	}
+
 	var municipalities []Municipality
 	if err := json.Unmarshal(body, &municipalities); err != nil {
 		return nil, fmt.Errorf("parsing response: %w", err)
@@ -154,14 +570,259 @@ func (c *Client) GetMunicipalities(ctx context.Context, stateID string) (*Munici
 	}, nil
 }
 
-// GetPopulation returns population data for a location.
+// FilterMunicipalities returns the municipalities whose name contains term,
+// ignoring case and accents.
+func FilterMunicipalities(municipalities []Municipality, term string) []Municipality {
+	term = text.Fold(term)
+	filtered := []Municipality{}
+	for _, m := range municipalities {
+		if strings.Contains(text.Fold(m.Nome), term) {
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered
+}
+
+// CountMunicipalities returns the number of municipalities in a state, or in
+// the whole country when stateID is empty.
+func (c *Client) CountMunicipalities(ctx context.Context, stateID string) (int, error) {
+	result, err := c.GetMunicipalities(ctx, stateID)
+	if err != nil {
+		return 0, err
+	}
+	return result.Total, nil
+}
+
+// GetMunicipality returns a single municipality by its 7-digit IBGE code,
+// including its microrregião, mesorregião and UF.
+func (c *Client) GetMunicipality(ctx context.Context, code string) (*Municipality, error) {
+	code = strings.TrimSpace(code)
+	if len(code) != 7 || strings.Trim(code, "0123456789") != "" {
+		return nil, fmt.Errorf("invalid municipality code %q: must have 7 digits", code)
+	}
+
+	url := fmt.Sprintf("%s/municipios/%s", c.localidadesURL, code)
+
+	body, err := c.doRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unknown codes are answered with an empty array instead of a 404.
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || trimmed[0] == '[' {
+		return nil, fmt.Errorf("%w: municipality %s", apierror.ErrNotFound, code)
+	}
+
+	var municipality Municipality
+	if err := json.Unmarshal(trimmed, &municipality); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+	if municipality.ID == 0 {
+		return nil, fmt.Errorf("%w: municipality %s", apierror.ErrNotFound, code)
+	}
+	return &municipality, nil
+}
+
+// malhasLevels maps the length of an IBGE locality code to its level in the
+// malhas API.
+var malhasLevels = map[int]string{
+	1: "regioes",
+	2: "estados",
+	4: "mesorregioes",
+	5: "microrregioes",
+	7: "municipios",
+}
+
+// areaMetadata mirrors the area part of a malhas metadata record.
+type areaMetadata struct {
+	Area *struct {
+		Dimensao string `json:"dimensao"`
+		Unidade  struct {
+			ID string `json:"id"`
+		} `json:"unidade"`
+	} `json:"area"`
+}
+
+// parseArea extracts the area in km² from a malhas metadata payload.
+func parseArea(body []byte, localidadeID string) (float64, error) {
+	var records []areaMetadata
+	if err := json.Unmarshal(body, &records); err != nil {
+		return 0, fmt.Errorf("parsing response: %w", err)
+	}
+	if len(records) == 0 || records[0].Area == nil || records[0].Area.Dimensao == "" {
+		return 0, fmt.Errorf("%w: area of %s", apierror.ErrNotFound, localidadeID)
+	}
+
+	area := records[0].Area
+	value, err := strconv.ParseFloat(area.Dimensao, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing area %q: %w", area.Dimensao, err)
+	}
+	if unit := strings.ToLower(area.Unidade.ID); unit != "" && unit != "km2" {
+		return 0, fmt.Errorf("unexpected area unit %q for %s", area.Unidade.ID, localidadeID)
+	}
+	return value, nil
+}
+
+// GetArea returns the territorial area in km² of a region, state,
+// mesoregion, microregion or municipality, identified by its IBGE code.
+func (c *Client) GetArea(ctx context.Context, localidadeID string) (float64, error) {
+	localidadeID = strings.TrimSpace(localidadeID)
+	level, ok := malhasLevels[len(localidadeID)]
+	if !ok || strings.Trim(localidadeID, "0123456789") != "" {
+		return 0, fmt.Errorf("invalid locality code %q: expected an IBGE region, state, mesoregion, microregion or municipality code", localidadeID)
+	}
+
+	url := fmt.Sprintf("%s/%s/%s/metadados", c.malhasURL, level, localidadeID)
+	body, err := c.doRequest(ctx, url)
+	if err != nil {
+		return 0, err
+	}
+	return parseArea(body, localidadeID)
+}
+
+// FillStateArea sets the area of a state returned by GetState, which does
+// not fetch it.
+func (c *Client) FillStateArea(ctx context.Context, state *State) error {
+	area, err := c.GetArea(ctx, strconv.Itoa(state.ID))
+	if err != nil {
+		return fmt.Errorf("area of state %s: %w", state.Sigla, err)
+	}
+	state.Area = area
+	return nil
+}
+
+// FillMunicipalityArea sets the area of a municipality returned by
+// GetMunicipality, which does not fetch it.
+func (c *Client) FillMunicipalityArea(ctx context.Context, municipality *Municipality) error {
+	area, err := c.GetArea(ctx, strconv.Itoa(municipality.ID))
+	if err != nil {
+		return fmt.Errorf("area of municipality %d: %w", municipality.ID, err)
+	}
+	municipality.Area = area
+	return nil
+}
+
+// GetMunicipalGDP returns the GDP at current prices of a municipality
+// (PIB dos Municípios, agregado 5938) for a year, defaulting to the latest
+// available one.
+func (c *Client) GetMunicipalGDP(ctx context.Context, municipioCode, year string) (*GDPResponse, error) {
+	if len(municipioCode) != 7 || strings.Trim(municipioCode, "0123456789") != "" {
+		return nil, fmt.Errorf("invalid municipality code %q: must have 7 digits", municipioCode)
+	}
+	period := "-1"
+	if year != "" {
+		if _, err := strconv.Atoi(year); err != nil || len(year) != 4 {
+			return nil, fmt.Errorf("invalid year %q: expected YYYY", year)
+		}
+		period = year
+	}
+
+	// GDP at current prices (agregado 5938, variable 37)
+	url := fmt.Sprintf("%s/5938/periodos/%s/variaveis/37?localidades=N6[%s]", c.agregadosURL, period, municipioCode)
+
+	body, err := c.doRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var variables []agregadoVariable
+	if err := json.Unmarshal(body, &variables); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	for _, variable := range variables {
+		for _, resultado := range variable.Resultados {
+			for _, series := range resultado.Series {
+				for serieYear, raw := range series.Serie {
+					value, err := strconv.ParseFloat(raw, 64)
+					if err != nil {
+						// IBGE uses markers such as "-" or "..." for missing data.
+						continue
+					}
+					return &GDPResponse{
+						MunicipalityID: series.Localidade.ID,
+						Municipality:   series.Localidade.Nome,
+						Year:           serieYear,
+						Value:          value,
+						Unit:           variable.Unidade,
+						Source:         "ibge_api",
+					}, nil
+				}
+			}
+		}
+	}
+
+	if year == "" {
+		return nil, fmt.Errorf("no GDP data for municipality %s", municipioCode)
+	}
+	return nil, fmt.Errorf("no GDP data for municipality %s in %s", municipioCode, year)
+}
+
+// GetNameStats returns how often a first name was registered per decade
+// (Censo 2010), for Brazil or a single state given by sigla or code.
+func (c *Client) GetNameStats(ctx context.Context, name, uf string) (*NameStatsResponse, error) {
+	name = strings.ToUpper(text.StripAccents(strings.TrimSpace(name)))
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if strings.ContainsAny(name, "/|?&#") {
+		return nil, fmt.Errorf("invalid name %q", name)
+	}
+
+	url := fmt.Sprintf("%s/%s", c.nomesURL, name)
+	location := "BR"
+	if uf != "" {
+		state, err := c.GetState(ctx, uf)
+		if err != nil {
+			return nil, err
+		}
+		location = state.Sigla
+		url = fmt.Sprintf("%s?localidade=%d", url, state.ID)
+	}
+
+	body, err := c.doRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []struct {
+		Nome string          `json:"nome"`
+		Res  []NameFrequency `json:"res"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("%w: name %s", apierror.ErrNotFound, name)
+	}
+
+	response := &NameStatsResponse{
+		Name:     result[0].Nome,
+		Location: location,
+		Decades:  result[0].Res,
+		Source:   "ibge_api",
+	}
+	for _, decade := range response.Decades {
+		response.Total += decade.Frequency
+	}
+	return response, nil
+}
+
+// GetPopulation returns population data for a location: a state (2-digit
+// code), a municipality (7-digit code) or, when empty, the whole country.
 func (c *Client) GetPopulation(ctx context.Context, locationID string) (*PopulationResponse, error) {
 	// Population estimate (agregado 6579, variable 9324)
 	var url string
 	if locationID != "" {
-		url = fmt.Sprintf("%s/6579/periodos/-6/variaveis/9324?localidades=N6[%s]", AgregadosURL, locationID)
+		level := "N6"
+		if len(locationID) == 2 {
+			level = "N3"
+		}
+		url = fmt.Sprintf("%s/6579/periodos/-6/variaveis/9324?localidades=%s[%s]", c.agregadosURL, level, locationID)
 	} else {
-		url = fmt.Sprintf("%s/6579/periodos/-6/variaveis/9324?localidades=N1[all]", AgregadosURL)
+		url = fmt.Sprintf("%s/6579/periodos/-6/variaveis/9324?localidades=N1[all]", c.agregadosURL)
 	}
 
 	body, err := c.doRequest(ctx, url)
@@ -174,29 +835,232 @@ func (c *Client) GetPopulation(ctx context.Context, locationID string) (*Populat
 		return nil, fmt.Errorf("parsing response: %w", err)
 	}
 
+	data, warnings := parsePopulationSeries(result)
+
+	return &PopulationResponse{
+		Data:          data,
+		ParseWarnings: warnings,
+		Source:        "ibge_api",
+	}, nil
+}
+
+// CensusYear is the census GetCensusPopulation reports.
+const CensusYear = "2022"
+
+// CensusPopulationResponse is the official population counted by the census.
+type CensusPopulationResponse struct {
+	LocalidadeID  string `json:"localidade_id,omitempty"`
+	Location      string `json:"location"`
+	Year          string `json:"year"`
+	Population    int64  `json:"population"`
+	ParseWarnings int    `json:"parse_warnings,omitempty"`
+	Source        string `json:"source"`
+}
+
+// GetCensusPopulation returns the resident population counted by the 2022
+// Census for a state (2-digit code), a municipality (7-digit code) or, when
+// empty, the whole country. Unlike GetPopulation, which reports yearly
+// estimates, this is the official count.
+func (c *Client) GetCensusPopulation(ctx context.Context, localidadeID string) (*CensusPopulationResponse, error) {
+	localidadeID = strings.TrimSpace(localidadeID)
+	localidades := "N1[all]"
+	if localidadeID != "" {
+		if (len(localidadeID) != 2 && len(localidadeID) != 7) || strings.Trim(localidadeID, "0123456789") != "" {
+			return nil, fmt.Errorf("invalid locality code %q: expected a 2-digit state or 7-digit municipality code", localidadeID)
+		}
+		level := "N6"
+		if len(localidadeID) == 2 {
+			level = "N3"
+		}
+		localidades = fmt.Sprintf("%s[%s]", level, localidadeID)
+	}
+
+	// Resident population (agregado 4709, variable 93)
+	url := fmt.Sprintf("%s/4709/periodos/%s/variaveis/93?localidades=%s", c.agregadosURL, CensusYear, localidades)
+
+	body, err := c.doRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []map[string]interface{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+	return parseCensusPopulation(localidadeID, result)
+}
+
+// parseCensusPopulation picks the census year count out of an agregados
+// response.
+func parseCensusPopulation(localidadeID string, result []map[string]interface{}) (*CensusPopulationResponse, error) {
+	data, warnings := parsePopulationSeries(result)
+	for _, d := range data {
+		if d.Year == CensusYear && d.PopulationValue > 0 {
+			return &CensusPopulationResponse{
+				LocalidadeID:  localidadeID,
+				Location:      d.Location,
+				Year:          d.Year,
+				Population:    d.PopulationValue,
+				ParseWarnings: warnings,
+				Source:        "ibge_api",
+			}, nil
+		}
+	}
+	if localidadeID == "" {
+		localidadeID = "Brasil"
+	}
+	return nil, fmt.Errorf("%w: %s census population of %s", apierror.ErrNotFound, CensusYear, localidadeID)
+}
+
+// DensityResponse represents the population density of a locality.
+type DensityResponse struct {
+	LocalidadeID string  `json:"localidade_id"`
+	Location     string  `json:"location"`
+	Year         string  `json:"year"`
+	Population   int64   `json:"population"`
+	AreaKm2      float64 `json:"area_km2"`
+	Density      float64 `json:"density_per_km2"`
+	Source       string  `json:"source"`
+}
+
+// computeDensity divides the latest population estimate by the area.
+// Entries whose value did not parse, such as "..." for a year not yet
+// published, are skipped.
+func computeDensity(localidadeID string, population *PopulationResponse, area float64) (*DensityResponse, error) {
+	// Data is sorted by year, so the last valid entry is the latest estimate.
+	var latest *PopulationData
+	for i := len(population.Data) - 1; i >= 0; i-- {
+		if population.Data[i].PopulationValue > 0 {
+			latest = &population.Data[i]
+			break
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("%w: population of %s", apierror.ErrNotFound, localidadeID)
+	}
+	if area <= 0 {
+		return nil, fmt.Errorf("area of %s is unavailable, cannot compute density", localidadeID)
+	}
+
+	return &DensityResponse{
+		LocalidadeID: localidadeID,
+		Location:     latest.Location,
+		Year:         latest.Year,
+		Population:   latest.PopulationValue,
+		AreaKm2:      area,
+		Density:      float64(latest.PopulationValue) / area,
+		Source:       "ibge_api",
+	}, nil
+}
+
+// GetDensity returns the population density (inhabitants per km²) of a
+// state or municipality, from its latest population estimate and its area.
+func (c *Client) GetDensity(ctx context.Context, localidadeID string) (*DensityResponse, error) {
+	localidadeID = strings.TrimSpace(localidadeID)
+	if (len(localidadeID) != 2 && len(localidadeID) != 7) || strings.Trim(localidadeID, "0123456789") != "" {
+		return nil, fmt.Errorf("invalid locality code %q: expected a 2-digit state or 7-digit municipality code", localidadeID)
+	}
+
+	population, err := c.GetPopulation(ctx, localidadeID)
+	if err != nil {
+		return nil, err
+	}
+	area, err := c.GetArea(ctx, localidadeID)
+	if err != nil {
+		return nil, err
+	}
+	return computeDensity(localidadeID, population, area)
+}
+
+// parsePopulationSeries extracts population values from an agregados
+// response. Entries with an unexpected shape are skipped and counted as
+// warnings instead of aborting the whole parse.
+func parsePopulationSeries(result []map[string]interface{}) ([]PopulationData, int) {
 	var data []PopulationData
-	if len(result) > 0 {
-		if resultados, ok := result[0]["resultados"].([]interface{}); ok && len(resultados) > 0 {
-			if series, ok := resultados[0].(map[string]interface{})["series"].([]interface{}); ok {
-				for _, s := range series {
-					serie := s.(map[string]interface{})
-					localidade := serie["localidade"].(map[string]interface{})
-					if serieData, ok := serie["serie"].(map[string]interface{}); ok {
-						for year, pop := range serieData {
-							data = append(data, PopulationData{
-								Location:   localidade["nome"].(string),
-								Year:       year,
-								Population: fmt.Sprintf("%v", pop),
-							})
-						}
+	warnings := 0
+
+	for _, variable := range result {
+		resultados, ok := variable["resultados"].([]interface{})
+		if !ok {
+			warnings++
+			continue
+		}
+		for _, r := range resultados {
+			resultado, ok := r.(map[string]interface{})
+			if !ok {
+				warnings++
+				continue
+			}
+			series, ok := resultado["series"].([]interface{})
+			if !ok {
+				warnings++
+				continue
+			}
+			for _, s := range series {
+				serie, ok := s.(map[string]interface{})
+				if !ok {
+					warnings++
+					continue
+				}
+				localidade, ok := serie["localidade"].(map[string]interface{})
+				if !ok {
+					warnings++
+					continue
+				}
+				location, ok := localidade["nome"].(string)
+				if !ok {
+					warnings++
+					continue
+				}
+				serieData, ok := serie["serie"].(map[string]interface{})
+				if !ok {
+					warnings++
+					continue
+				}
+				for year, pop := range serieData {
+					if pop == nil {
+						warnings++
+						continue
+					}
+					population := fmt.Sprintf("%v", pop)
+					value, err := parsePopulationValue(population)
+					if err != nil {
+						warnings++
 					}
+					data = append(data, PopulationData{
+						Location:        location,
+						Year:            year,
+						Population:      population,
+						PopulationValue: value,
+					})
 				}
 			}
 		}
 	}
 
-	return &PopulationResponse{
-		Data:   data,
-		Source: "ibge_api",
-	}, nil
+	sort.Slice(data, func(i, j int) bool {
+		if data[i].Year != data[j].Year {
+			return data[i].Year < data[j].Year
+		}
+		return data[i].Location < data[j].Location
+	})
+
+	return data, warnings
+}
+
+// parsePopulationValue converts a population count to an integer, ignoring
+// thousands separators.
+func parsePopulationValue(population string) (int64, error) {
+	digits := strings.Map(func(r rune) rune {
+		if r == '.' || r == ',' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(population))
+
+	value, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid population %q", population)
+	}
+	return value, nil
 }
@@ -0,0 +1,802 @@
+package ibge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anderson-ufrj/mcp-brasil/pkg/apierror"
+)
+
+// newTestClient returns a client whose requests are served by h, with
+// retries disabled.
+func newTestClient(t *testing.T, h http.HandlerFunc, opts ...Option) *Client {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return NewClient(append([]Option{WithBaseURL(srv.URL), WithRetry(1, 0)}, opts...)...)
+}
+
+// routes answers each request with the body registered for its path, or a
+// 404, appending each request URI to *uris when uris is not nil.
+func routes(bodies map[string]string, uris *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if uris != nil {
+			*uris = append(*uris, r.URL.RequestURI())
+		}
+		body, ok := bodies[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}
+}
+
+const statesJSON = `[
+	{"id":31,"sigla":"MG","nome":"Minas Gerais","regiao":{"id":3,"sigla":"SE","nome":"Sudeste"}},
+	{"id":35,"sigla":"SP","nome":"São Paulo","regiao":{"id":3,"sigla":"SE","nome":"Sudeste"}}
+]`
+
+func TestGetState(t *testing.T) {
+	bodies := map[string]string{
+		"/v1/localidades/estados":    statesJSON,
+		"/v1/localidades/estados/35": `{"id":35,"sigla":"SP","nome":"São Paulo","regiao":{"id":3,"nome":"Sudeste"}}`,
+		"/v1/localidades/estados/99": `[]`,
+	}
+
+	tests := []struct {
+		name         string
+		input        string
+		wantID       int
+		wantNotFound bool
+		wantErr      bool
+	}{
+		{name: "sigla", input: "SP", wantID: 35},
+		{name: "lowercase sigla", input: " mg ", wantID: 31},
+		{name: "numeric code", input: "35", wantID: 35},
+		{name: "unknown sigla", input: "XX", wantNotFound: true},
+		{name: "unknown code", input: "99", wantNotFound: true},
+		{name: "not a sigla", input: "São", wantErr: true},
+		{name: "empty", input: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, routes(bodies, nil))
+			state, err := c.GetState(context.Background(), tt.input)
+			switch {
+			case tt.wantNotFound:
+				if !errors.Is(err, apierror.ErrNotFound) {
+					t.Fatalf("err = %v, want ErrNotFound", err)
+				}
+			case tt.wantErr:
+				if err == nil || errors.Is(err, apierror.ErrNotFound) {
+					t.Fatalf("err = %v, want a validation error", err)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case state.ID != tt.wantID || state.Regiao.Nome != "Sudeste":
+				t.Errorf("state = %+v, want id %d", state, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestGetRegions(t *testing.T) {
+	const regions = `[
+		{"id":1,"sigla":"N","nome":"Norte"},
+		{"id":2,"sigla":"NE","nome":"Nordeste"},
+		{"id":3,"sigla":"SE","nome":"Sudeste"},
+		{"id":4,"sigla":"S","nome":"Sul"},
+		{"id":5,"sigla":"CO","nome":"Centro-Oeste"}
+	]`
+	c := newTestClient(t, routes(map[string]string{"/v1/localidades/regioes": regions}, nil))
+
+	resp, err := c.GetRegions(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Total != 5 || len(resp.Regions) != 5 {
+		t.Fatalf("got %d regions (total %d), want 5", len(resp.Regions), resp.Total)
+	}
+	want := []string{"Norte", "Nordeste", "Sudeste", "Sul", "Centro-Oeste"}
+	for i, region := range resp.Regions {
+		if region.ID != i+1 || region.Nome != want[i] {
+			t.Errorf("region %d = %+v, want %d %s", i, region, i+1, want[i])
+		}
+	}
+}
+
+func TestGetMunicipality(t *testing.T) {
+	const bh = `{"id":3106200,"nome":"Belo Horizonte","microrregiao":{"id":31030,"nome":"Belo Horizonte","mesorregiao":{"id":3107,"nome":"Metropolitana de Belo Horizonte","UF":{"id":31,"sigla":"MG","nome":"Minas Gerais","regiao":{"id":3,"nome":"Sudeste"}}}}}`
+	bodies := map[string]string{
+		"/v1/localidades/municipios/3106200": bh,
+		"/v1/localidades/municipios/9999999": `[]`,
+	}
+
+	tests := []struct {
+		name         string
+		code         string
+		wantNotFound bool
+		wantErr      bool
+	}{
+		{name: "found", code: "3106200"},
+		{name: "unknown code", code: "9999999", wantNotFound: true},
+		{name: "six digits", code: "310620", wantErr: true},
+		{name: "letters", code: "31062OO", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uris []string
+			c := newTestClient(t, routes(bodies, &uris))
+			m, err := c.GetMunicipality(context.Background(), tt.code)
+			switch {
+			case tt.wantNotFound:
+				if !errors.Is(err, apierror.ErrNotFound) {
+					t.Fatalf("err = %v, want ErrNotFound", err)
+				}
+			case tt.wantErr:
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				if len(uris) != 0 {
+					t.Fatalf("requests sent for an invalid code: %v", uris)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			default:
+				meso := m.Microrregiao.Mesorregiao
+				if m.ID != 3106200 || m.Nome != "Belo Horizonte" || meso == nil || meso.Nome != "Metropolitana de Belo Horizonte" || meso.UF == nil || meso.UF.Sigla != "MG" {
+					t.Errorf("municipality = %+v", m)
+				}
+			}
+		})
+	}
+}
+
+func TestRegionHierarchy(t *testing.T) {
+	const mesos = `[{"id":3107,"nome":"Metropolitana de Belo Horizonte","UF":{"id":31,"sigla":"MG","nome":"Minas Gerais"}}]`
+	const micros = `[{"id":31030,"nome":"Belo Horizonte","mesorregiao":{"id":3107,"nome":"Metropolitana de Belo Horizonte"}}]`
+	bodies := map[string]string{
+		"/v1/localidades/mesorregioes":             mesos,
+		"/v1/localidades/estados/31/mesorregioes":  mesos,
+		"/v1/localidades/microrregioes":            micros,
+		"/v1/localidades/estados/31/microrregioes": micros,
+	}
+
+	tests := []struct {
+		name    string
+		stateID string
+		fetch   func(c *Client, stateID string) (int, string, error)
+		wantURI string
+	}{
+		{"mesoregions", "", mesoregionsOf, "/v1/localidades/mesorregioes?orderBy=nome"},
+		{"mesoregions of a state", "31", mesoregionsOf, "/v1/localidades/estados/31/mesorregioes?orderBy=nome"},
+		{"microregions", "", microregionsOf, "/v1/localidades/microrregioes?orderBy=nome"},
+		{"microregions of a state", "31", microregionsOf, "/v1/localidades/estados/31/microrregioes?orderBy=nome"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uris []string
+			c := newTestClient(t, routes(bodies, &uris))
+			total, parent, err := tt.fetch(c, tt.stateID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(uris) != 1 || uris[0] != tt.wantURI {
+				t.Errorf("requests = %v, want [%s]", uris, tt.wantURI)
+			}
+			if total != 1 || parent == "" {
+				t.Errorf("total = %d, parent = %q", total, parent)
+			}
+		})
+	}
+}
+
+// mesoregionsOf returns the count of GetMesoregions and the state of the
+// first mesoregion.
+func mesoregionsOf(c *Client, stateID string) (int, string, error) {
+	resp, err := c.GetMesoregions(context.Background(), stateID)
+	if err != nil || len(resp.Mesoregions) == 0 || resp.Mesoregions[0].UF == nil {
+		return 0, "", err
+	}
+	return resp.Total, resp.Mesoregions[0].UF.Sigla, nil
+}
+
+// microregionsOf returns the count of GetMicroregions and the mesoregion
+// of the first microregion.
+func microregionsOf(c *Client, stateID string) (int, string, error) {
+	resp, err := c.GetMicroregions(context.Background(), stateID)
+	if err != nil || len(resp.Microregions) == 0 || resp.Microregions[0].Mesorregiao == nil {
+		return 0, "", err
+	}
+	return resp.Total, resp.Microregions[0].Mesorregiao.Nome, nil
+}
+
+// agregadoJSON returns an agregados response with one series for BH.
+func agregadoJSON(unit, serie string) string {
+	return `[{"id":"37","variavel":"Produto Interno Bruto a preços correntes","unidade":"` + unit + `","resultados":[{"classificacoes":[],"series":[{"localidade":{"id":"3106200","nivel":{"id":"N6","nome":"Município"},"nome":"Belo Horizonte - MG"},"serie":` + serie + `}]}]}]`
+}
+
+func TestGetMunicipalGDP(t *testing.T) {
+	tests := []struct {
+		name      string
+		year      string
+		body      string
+		wantURI   string
+		wantYear  string
+		wantValue float64
+		wantErr   string
+	}{
+		{
+			name: "latest year", body: agregadoJSON("Mil Reais", `{"2021":"105829786"}`),
+			wantURI:  "/v3/agregados/5938/periodos/-1/variaveis/37?localidades=N6[3106200]",
+			wantYear: "2021", wantValue: 105829786,
+		},
+		{
+			name: "given year", year: "2019", body: agregadoJSON("Mil Reais", `{"2019":"97509884"}`),
+			wantURI:  "/v3/agregados/5938/periodos/2019/variaveis/37?localidades=N6[3106200]",
+			wantYear: "2019", wantValue: 97509884,
+		},
+		{name: "missing data marker", year: "2019", body: agregadoJSON("Mil Reais", `{"2019":"-"}`), wantErr: "no GDP data for municipality 3106200 in 2019"},
+		{name: "no series", body: `[]`, wantErr: "no GDP data for municipality 3106200"},
+		{name: "bad year", year: "19", wantErr: "invalid year"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uris []string
+			c := newTestClient(t, routes(map[string]string{
+				"/v3/agregados/5938/periodos/-1/variaveis/37":   tt.body,
+				"/v3/agregados/5938/periodos/2019/variaveis/37": tt.body,
+			}, &uris))
+			gdp, err := c.GetMunicipalGDP(context.Background(), "3106200", tt.year)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(uris) != 1 || uris[0] != tt.wantURI {
+				t.Errorf("requests = %v, want [%s]", uris, tt.wantURI)
+			}
+			if gdp.Year != tt.wantYear || gdp.Value != tt.wantValue || gdp.Unit != "Mil Reais" || gdp.Municipality != "Belo Horizonte - MG" {
+				t.Errorf("gdp = %+v", gdp)
+			}
+		})
+	}
+}
+
+func TestGetPopulationMalformed(t *testing.T) {
+	const valid = `{"localidade":{"id":"31","nome":"Minas Gerais"},"serie":{"2021":"21411923"}}`
+	tests := []struct {
+		name         string
+		body         string
+		wantCount    int
+		wantWarnings int
+	}{
+		{name: "valid", body: `[{"resultados":[{"series":[` + valid + `]}]}]`, wantCount: 1},
+		{name: "no resultados", body: `[{"id":"9324"}]`, wantWarnings: 1},
+		{name: "resultados not a list", body: `[{"resultados":"x"}]`, wantWarnings: 1},
+		{name: "resultado not an object", body: `[{"resultados":[42]}]`, wantWarnings: 1},
+		{name: "series missing", body: `[{"resultados":[{}]}]`, wantWarnings: 1},
+		{name: "localidade missing", body: `[{"resultados":[{"series":[{"serie":{"2021":"1"}}]}]}]`, wantWarnings: 1},
+		{name: "name not a string", body: `[{"resultados":[{"series":[{"localidade":{"nome":7},"serie":{"2021":"1"}}]}]}]`, wantWarnings: 1},
+		{name: "serie not an object", body: `[{"resultados":[{"series":[{"localidade":{"nome":"X"},"serie":[]}]}]}]`, wantWarnings: 1},
+		{name: "null value", body: `[{"resultados":[{"series":[{"localidade":{"nome":"X"},"serie":{"2021":null}}]}]}]`, wantWarnings: 1},
+		{
+			name:      "valid next to garbage",
+			body:      `[{"resultados":[{"series":[` + valid + `,"garbage",{"localidade":null}]}]},{"resultados":null}]`,
+			wantCount: 1, wantWarnings: 3,
+		},
+		{name: "empty", body: `[]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, routes(map[string]string{"/v3/agregados/6579/periodos/-6/variaveis/9324": tt.body}, nil))
+			resp, err := c.GetPopulation(context.Background(), "31")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(resp.Data) != tt.wantCount || resp.ParseWarnings != tt.wantWarnings {
+				t.Errorf("got %d entries and %d warnings, want %d and %d", len(resp.Data), resp.ParseWarnings, tt.wantCount, tt.wantWarnings)
+			}
+			if tt.wantCount > 0 && resp.Data[0].PopulationValue != 21411923 {
+				t.Errorf("data = %+v", resp.Data)
+			}
+		})
+	}
+}
+
+func TestGetPopulationTruncated(t *testing.T) {
+	c := newTestClient(t, routes(map[string]string{"/v3/agregados/6579/periodos/-6/variaveis/9324": `[{"resultados":[{"series":[`}, nil))
+	if _, err := c.GetPopulation(context.Background(), ""); err == nil || !strings.Contains(err.Error(), "parsing response") {
+		t.Fatalf("err = %v, want a parsing error", err)
+	}
+}
+
+func TestParsePopulationValue(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{"21411923", 21411923, false},
+		{"21.411.923", 21411923, false},
+		{"21,411,923", 21411923, false},
+		{" 203 080 756 ", 203080756, false},
+		{"-", 0, true},
+		{"...", 0, true},
+		{"12a", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parsePopulationValue(tt.input)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("parsePopulationValue(%q) = %d, %v, want %d (error %v)", tt.input, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetPopulationSorted(t *testing.T) {
+	const body = `[{"resultados":[{"series":[
+		{"localidade":{"nome":"São Paulo"},"serie":{"2024":"45973194","2021":"46649132","2022":"44411238"}},
+		{"localidade":{"nome":"Minas Gerais"},"serie":{"2022":"20539989","2024":"21322691","2021":"21411923"}}
+	]}]}]`
+	c := newTestClient(t, routes(map[string]string{"/v3/agregados/6579/periodos/-6/variaveis/9324": body}, nil))
+
+	for i := 0; i < 5; i++ {
+		resp, err := c.GetPopulation(context.Background(), "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var got []string
+		for _, d := range resp.Data {
+			got = append(got, d.Year+" "+d.Location)
+		}
+		want := "2021 Minas Gerais,2021 São Paulo,2022 Minas Gerais,2022 São Paulo,2024 Minas Gerais,2024 São Paulo"
+		if strings.Join(got, ",") != want {
+			t.Fatalf("order = %v, want %s", got, want)
+		}
+		if resp.Data[0].PopulationValue != 21411923 || resp.Data[0].Population != "21411923" {
+			t.Errorf("first entry = %+v", resp.Data[0])
+		}
+	}
+}
+
+func TestGetNameStats(t *testing.T) {
+	const joao = `[{"nome":"JOAO","sexo":null,"localidade":"BR","res":[{"periodo":"1930[","frequencia":60155},{"periodo":"[1930,1940[","frequencia":141772},{"periodo":"[2000,2010[","frequencia":794118}]}]`
+	bodies := map[string]string{
+		"/v2/censos/nomes/JOAO":   joao,
+		"/v2/censos/nomes/XPTO":   `[]`,
+		"/v1/localidades/estados": statesJSON,
+	}
+
+	tests := []struct {
+		name         string
+		input        string
+		uf           string
+		wantURI      string
+		wantLocation string
+		wantNotFound bool
+		wantErr      bool
+	}{
+		{name: "accented lowercase", input: " João ", wantURI: "/v2/censos/nomes/JOAO", wantLocation: "BR"},
+		{name: "by state", input: "joao", uf: "mg", wantURI: "/v2/censos/nomes/JOAO?localidade=31", wantLocation: "MG"},
+		{name: "unknown name", input: "xpto", wantNotFound: true},
+		{name: "empty", input: " ", wantErr: true},
+		{name: "path characters", input: "joao/../x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uris []string
+			c := newTestClient(t, routes(bodies, &uris))
+			resp, err := c.GetNameStats(context.Background(), tt.input, tt.uf)
+			switch {
+			case tt.wantNotFound:
+				if !errors.Is(err, apierror.ErrNotFound) {
+					t.Fatalf("err = %v, want ErrNotFound", err)
+				}
+				return
+			case tt.wantErr:
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if last := uris[len(uris)-1]; last != tt.wantURI {
+				t.Errorf("request = %s, want %s", last, tt.wantURI)
+			}
+			if resp.Location != tt.wantLocation || len(resp.Decades) != 3 || resp.Total != 60155+141772+794118 {
+				t.Errorf("response = %+v", resp)
+			}
+			if resp.Decades[2].Period != "[2000,2010[" || resp.Decades[2].Frequency != 794118 {
+				t.Errorf("last decade = %+v", resp.Decades[2])
+			}
+		})
+	}
+}
+
+func TestWithBaseURL(t *testing.T) {
+	c := NewClient()
+	if c.localidadesURL != LocalidadesURL || c.agregadosURL != AgregadosURL || c.nomesURL != NomesURL || c.malhasURL != MalhasURL {
+		t.Errorf("default URLs = %s, %s, %s, %s", c.localidadesURL, c.agregadosURL, c.nomesURL, c.malhasURL)
+	}
+
+	var uris []string
+	srv := httptest.NewServer(routes(nil, &uris))
+	defer srv.Close()
+	c = NewClient(WithRetry(1, 0), WithBaseURL(srv.URL+"/"))
+
+	ctx := context.Background()
+	c.GetStates(ctx)
+	c.GetMunicipalGDP(ctx, "3550308", "2021")
+	c.GetNameStats(ctx, "maria", "")
+	c.GetArea(ctx, "3550308")
+
+	want := []string{"/v1/localidades/", "/v3/agregados/", "/v2/censos/nomes/", "/v3/malhas/"}
+	if len(uris) != len(want) {
+		t.Fatalf("requests = %v, want one per service", uris)
+	}
+	for i, prefix := range want {
+		if !strings.HasPrefix(uris[i], prefix) {
+			t.Errorf("request %s, want it under %s", uris[i], prefix)
+		}
+	}
+}
+
+func TestFilterMunicipalities(t *testing.T) {
+	municipalities := []Municipality{
+		{ID: 3550308, Nome: "São Paulo"},
+		{ID: 3548708, Nome: "São Bernardo do Campo"},
+		{ID: 3509502, Nome: "Campinas"},
+	}
+	tests := []struct {
+		term string
+		want []int
+	}{
+		{term: "sao paulo", want: []int{3550308}},
+		{term: "São Paulo", want: []int{3550308}},
+		{term: "SÃO PAULO", want: []int{3550308}},
+		{term: "sÃo", want: []int{3550308, 3548708}},
+		{term: "campo", want: []int{3548708}},
+		{term: "campinas ", want: []int{3509502}},
+		{term: "recife", want: []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.term, func(t *testing.T) {
+			got := []int{}
+			for _, m := range FilterMunicipalities(municipalities, tt.term) {
+				got = append(got, m.ID)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("FilterMunicipalities(%q) = %v, want %v", tt.term, got, tt.want)
+			}
+		})
+	}
+}
+
+func areaJSON(dimensao, unit string) string {
+	return `[{"id":"3550308","area":{"dimensao":"` + dimensao + `","unidade":{"id":"` + unit + `","unidade":"quilômetro quadrado"}}}]`
+}
+
+func TestGetArea(t *testing.T) {
+	bodies := map[string]string{
+		"/v3/malhas/municipios/3550308/metadados": areaJSON("1521.202", "km2"),
+		"/v3/malhas/estados/35/metadados":         areaJSON("248219.485", "KM2"),
+		"/v3/malhas/municipios/1100015/metadados": `[{"id":"1100015"}]`,
+		"/v3/malhas/municipios/1100023/metadados": `[]`,
+		"/v3/malhas/municipios/1100031/metadados": areaJSON("muito", "km2"),
+		"/v3/malhas/municipios/1100049/metadados": areaJSON("10", "ha"),
+	}
+	tests := []struct {
+		name         string
+		id           string
+		want         float64
+		wantNotFound bool
+		wantErr      bool
+	}{
+		{name: "municipality", id: "3550308", want: 1521.202},
+		{name: "state", id: " 35 ", want: 248219.485},
+		{name: "no area", id: "1100015", wantNotFound: true},
+		{name: "no records", id: "1100023", wantNotFound: true},
+		{name: "malformed value", id: "1100031", wantErr: true},
+		{name: "other unit", id: "1100049", wantErr: true},
+		{name: "bad code length", id: "355030", wantErr: true},
+		{name: "non-numeric code", id: "SP", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, routes(bodies, nil))
+			got, err := c.GetArea(context.Background(), tt.id)
+			if tt.wantNotFound {
+				if !errors.Is(err, apierror.ErrNotFound) {
+					t.Errorf("error = %v, want ErrNotFound", err)
+				}
+				return
+			}
+			if tt.wantErr {
+				if err == nil || errors.Is(err, apierror.ErrNotFound) {
+					t.Errorf("error = %v, want a parse or validation error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("area = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFillArea(t *testing.T) {
+	c := newTestClient(t, routes(map[string]string{
+		"/v3/malhas/estados/35/metadados":         areaJSON("248219.485", "km2"),
+		"/v3/malhas/municipios/3550308/metadados": areaJSON("1521.202", "km2"),
+	}, nil))
+
+	state := &State{ID: 35, Sigla: "SP"}
+	if err := c.FillStateArea(context.Background(), state); err != nil || state.Area != 248219.485 {
+		t.Errorf("state area = %v, %v", state.Area, err)
+	}
+	municipality := &Municipality{ID: 3550308}
+	if err := c.FillMunicipalityArea(context.Background(), municipality); err != nil || municipality.Area != 1521.202 {
+		t.Errorf("municipality area = %v, %v", municipality.Area, err)
+	}
+	missing := &Municipality{ID: 1100015}
+	if err := c.FillMunicipalityArea(context.Background(), missing); !errors.Is(err, apierror.ErrNotFound) || missing.Area != 0 {
+		t.Errorf("missing area = %v, %v", missing.Area, err)
+	}
+}
+
+func TestGetDensity(t *testing.T) {
+	const population = `[{"resultados":[{"series":[{"localidade":{"nome":"São Paulo"},"serie":{"2022":"11451999","2024":"11895578"}}]}]}]`
+	tests := []struct {
+		name        string
+		id          string
+		population  string
+		area        string
+		wantDensity float64
+		wantErr     string
+		wantCalls   int
+	}{
+		{name: "municipality", id: "3550308", population: population, area: areaJSON("1521.202", "km2"), wantDensity: 11895578 / 1521.202, wantCalls: 2},
+		{name: "unpublished last year", id: "3550308", population: `[{"resultados":[{"series":[{"localidade":{"nome":"São Paulo"},"serie":{"2022":"11451999","2024":"11895578","2025":"..."}}]}]}]`, area: areaJSON("1521.202", "km2"), wantDensity: 11895578 / 1521.202, wantCalls: 2},
+		{name: "no published year", id: "3550308", population: `[{"resultados":[{"series":[{"localidade":{"nome":"São Paulo"},"serie":{"2024":"...","2025":"-"}}]}]}]`, area: areaJSON("1521.202", "km2"), wantErr: "not found", wantCalls: 2},
+		{name: "zero area", id: "3550308", population: population, area: areaJSON("0", "km2"), wantErr: "area of 3550308 is unavailable", wantCalls: 2},
+		{name: "no area", id: "3550308", population: population, area: `[]`, wantErr: "not found", wantCalls: 2},
+		{name: "no population", id: "3550308", population: `[]`, area: areaJSON("1521.202", "km2"), wantErr: "not found", wantCalls: 2},
+		{name: "invalid code", id: "355", wantErr: "invalid locality code"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uris []string
+			c := newTestClient(t, routes(map[string]string{
+				"/v3/agregados/6579/periodos/-6/variaveis/9324": tt.population,
+				"/v3/malhas/municipios/" + tt.id + "/metadados": tt.area,
+			}, &uris))
+			got, err := c.GetDensity(context.Background(), tt.id)
+			if len(uris) != tt.wantCalls {
+				t.Errorf("requests = %v, want %d", uris, tt.wantCalls)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Density != tt.wantDensity || got.Population != 11895578 || got.Year != "2024" || got.AreaKm2 != 1521.202 {
+				t.Errorf("density = %+v, want %v per km²", got, tt.wantDensity)
+			}
+		})
+	}
+}
+
+func TestDiskCache(t *testing.T) {
+	bodies := map[string]string{
+		"/v1/localidades/estados":               statesJSON,
+		"/v1/localidades/estados/35/municipios": `[{"id":3550308,"nome":"São Paulo"}]`,
+	}
+	var uris []string
+	srv := httptest.NewServer(routes(bodies, &uris))
+	t.Cleanup(srv.Close)
+	dir := t.TempDir()
+
+	fetch := func(opts ...Option) {
+		t.Helper()
+		c := NewClient(append([]Option{WithBaseURL(srv.URL), WithRetry(1, 0), WithDiskCache(dir)}, opts...)...)
+		states, err := c.GetStates(context.Background())
+		if err != nil || states.Total != 2 {
+			t.Fatalf("GetStates = %+v, %v", states, err)
+		}
+		munis, err := c.GetMunicipalities(context.Background(), "35")
+		if err != nil || munis.Total != 1 {
+			t.Fatalf("GetMunicipalities = %+v, %v", munis, err)
+		}
+	}
+
+	fetch()
+	if len(uris) != 2 {
+		t.Fatalf("first client made %d requests, want 2: %v", len(uris), uris)
+	}
+
+	// A fresh client has no in-memory state, so these come from disk.
+	fetch()
+	if len(uris) != 2 {
+		t.Errorf("second client hit the network: %v", uris[2:])
+	}
+
+	// Entries older than the max age are fetched again.
+	fetch(WithCacheMaxAge(time.Nanosecond))
+	if len(uris) != 4 {
+		t.Errorf("expired cache made %d requests in total, want 4", len(uris))
+	}
+}
+
+// fakeCollector records the status class of each observed request.
+type fakeCollector struct {
+	observed []string
+}
+
+func (f *fakeCollector) ObserveRequest(source, statusClass string, duration time.Duration) {
+	f.observed = append(f.observed, source+" "+statusClass)
+}
+
+func TestWithMetrics(t *testing.T) {
+	m := &fakeCollector{}
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/localidades/estados/35":
+			w.Write([]byte(`{"id":35,"sigla":"SP","nome":"São Paulo"}`))
+		case "/v1/localidades/estados/50":
+			http.Error(w, "indisponível", http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}, WithMetrics(m))
+
+	for _, id := range []string{"35", "50", "99"} {
+		c.GetState(context.Background(), id)
+	}
+	want := []string{"ibge 2xx", "ibge 5xx", "ibge 4xx"}
+	if strings.Join(m.observed, ",") != strings.Join(want, ",") {
+		t.Errorf("observed = %v, want %v", m.observed, want)
+	}
+
+	// Requests that get no response count as "error".
+	closed := NewClient(WithBaseURL("http://127.0.0.1:1"), WithRetry(1, 0), WithMetrics(m))
+	if _, err := closed.GetState(context.Background(), "35"); err == nil {
+		t.Fatal("request to a closed port succeeded")
+	}
+	if got := m.observed[len(m.observed)-1]; got != "ibge error" {
+		t.Errorf("failed request observed as %q, want \"ibge error\"", got)
+	}
+}
+
+func TestGetMunicipalitiesByStateName(t *testing.T) {
+	bodies := map[string]string{
+		"/v1/localidades/estados":               statesJSON,
+		"/v1/localidades/estados/35/municipios": `[{"id":3550308,"nome":"São Paulo"}]`,
+		"/v1/localidades/municipios":            `[{"id":3550308,"nome":"São Paulo"},{"id":3106200,"nome":"Belo Horizonte"}]`,
+	}
+	var uris []string
+	c := newTestClient(t, routes(bodies, &uris))
+
+	tests := []struct {
+		state, want string
+	}{
+		{"sp", "/v1/localidades/estados/35/municipios?orderBy=nome"},
+		{"SP", "/v1/localidades/estados/35/municipios?orderBy=nome"},
+		{"sao paulo", "/v1/localidades/estados/35/municipios?orderBy=nome"},
+		{" SÃO PAULO ", "/v1/localidades/estados/35/municipios?orderBy=nome"},
+		{"35", "/v1/localidades/estados/35/municipios?orderBy=nome"},
+		{"", "/v1/localidades/municipios?orderBy=nome"},
+	}
+	for _, tt := range tests {
+		uris = nil
+		if _, err := c.GetMunicipalities(context.Background(), tt.state); err != nil {
+			t.Fatalf("GetMunicipalities(%q): %v", tt.state, err)
+		}
+		// The states list is fetched once, for the first name, then cached.
+		want := []string{tt.want}
+		if tt.state == "sp" {
+			want = []string{"/v1/localidades/estados?orderBy=nome", tt.want}
+		}
+		if strings.Join(uris, " ") != strings.Join(want, " ") {
+			t.Errorf("GetMunicipalities(%q) requested %v, want %v", tt.state, uris, want)
+		}
+	}
+
+	uris = nil
+	if _, err := c.GetMunicipalities(context.Background(), "Atlântida"); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("unknown state error = %v, want ErrNotFound", err)
+	}
+	if len(uris) != 0 {
+		t.Errorf("unknown state requested %v", uris)
+	}
+}
+
+// censusJSON is an agregado 4709 response for one locality.
+func censusJSON(id, nivel, nome, value string) string {
+	return `[{"id":"93","variavel":"População residente","unidade":"Pessoas","resultados":[{"classificacoes":[],"series":[` +
+		`{"localidade":{"id":"` + id + `","nivel":{"id":"` + nivel + `"},"nome":"` + nome + `"},"serie":{"2022":"` + value + `"}}]}]}]`
+}
+
+func TestGetCensusPopulation(t *testing.T) {
+	tests := []struct {
+		name, localidade string
+		body             string
+		wantQuery        string // localidades parameter; empty when no request is expected
+		wantLocation     string
+		wantPopulation   int64
+		wantErr          error
+	}{
+		{
+			name: "municipality", localidade: "3106200",
+			body:      censusJSON("3106200", "N6", "Belo Horizonte - MG", "2315560"),
+			wantQuery: "N6[3106200]", wantLocation: "Belo Horizonte - MG", wantPopulation: 2315560,
+		},
+		{
+			name: "state", localidade: " 31 ",
+			body:      censusJSON("31", "N3", "Minas Gerais", "20538718"),
+			wantQuery: "N3[31]", wantLocation: "Minas Gerais", wantPopulation: 20538718,
+		},
+		{
+			name:      "country",
+			body:      censusJSON("1", "N1", "Brasil", "203080756"),
+			wantQuery: "N1[all]", wantLocation: "Brasil", wantPopulation: 203080756,
+		},
+		{
+			name: "value withheld", localidade: "3106200",
+			body:      censusJSON("3106200", "N6", "Belo Horizonte - MG", "..."),
+			wantQuery: "N6[3106200]", wantErr: apierror.ErrNotFound,
+		},
+		{name: "empty response", localidade: "31", body: `[]`, wantQuery: "N3[31]", wantErr: apierror.ErrNotFound},
+		{name: "three digits", localidade: "310"},
+		{name: "letters", localidade: "MG"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uris []string
+			c := newTestClient(t, routes(map[string]string{"/v3/agregados/4709/periodos/2022/variaveis/93": tt.body}, &uris))
+
+			resp, err := c.GetCensusPopulation(context.Background(), tt.localidade)
+			if tt.wantQuery == "" {
+				if err == nil || len(uris) != 0 {
+					t.Errorf("invalid code: error = %v, requests = %v", err, uris)
+				}
+				return
+			}
+			if want := "/v3/agregados/4709/periodos/2022/variaveis/93?localidades=" + tt.wantQuery; len(uris) != 1 || uris[0] != want {
+				t.Errorf("requests = %v, want [%s]", uris, want)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetCensusPopulation: %v", err)
+			}
+			if resp.Location != tt.wantLocation || resp.Population != tt.wantPopulation || resp.Year != CensusYear {
+				t.Errorf("response = %+v", resp)
+			}
+		})
+	}
+}
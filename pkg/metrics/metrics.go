@@ -0,0 +1,195 @@
+// Package metrics counts the upstream requests made by the API clients.
+//
+// Clients take an optional Collector; when none is configured nothing is
+// recorded. Registry is a dependency-free Collector that exposes its data in
+// the Prometheus text format.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Collector receives one observation per upstream request.
+type Collector interface {
+	// ObserveRequest records a request to source (e.g. "ibge") that ended
+	// with statusClass ("2xx", "4xx", "5xx", ... or "error" when no response
+	// was received) after duration.
+	ObserveRequest(source, statusClass string, duration time.Duration)
+}
+
+// StatusClass returns the status class label of a request outcome.
+func StatusClass(resp *http.Response, err error) string {
+	if err != nil || resp == nil {
+		return "error"
+	}
+	return fmt.Sprintf("%dxx", resp.StatusCode/100)
+}
+
+// Observe records a request on c, doing nothing when c is nil, including a
+// nil pointer stored in the interface such as a (*Registry)(nil).
+func Observe(c Collector, source string, resp *http.Response, err error, duration time.Duration) {
+	if isNil(c) {
+		return
+	}
+	c.ObserveRequest(source, StatusClass(resp, err), duration)
+}
+
+// isNil reports whether c is nil or holds a nil value.
+func isNil(c Collector) bool {
+	if c == nil {
+		return true
+	}
+	switch v := reflect.ValueOf(c); v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// Buckets are the latency histogram upper bounds, in seconds.
+var Buckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type requestKey struct {
+	source      string
+	statusClass string
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, non-cumulative
+	sum    float64
+	count  uint64
+}
+
+// Registry is an in-memory Collector.
+type Registry struct {
+	mu        sync.Mutex
+	requests  map[requestKey]uint64
+	errors    map[string]uint64
+	latencies map[string]*histogram
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		requests:  map[requestKey]uint64{},
+		errors:    map[string]uint64{},
+		latencies: map[string]*histogram{},
+	}
+}
+
+// ObserveRequest implements Collector. Requests without a response or with
+// a 4xx or 5xx status count as errors.
+func (r *Registry) ObserveRequest(source, statusClass string, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests[requestKey{source, statusClass}]++
+	if statusClass == "error" || statusClass == "4xx" || statusClass == "5xx" {
+		r.errors[source]++
+	}
+
+	h, ok := r.latencies[source]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(Buckets))}
+		r.latencies[source] = h
+	}
+	seconds := duration.Seconds()
+	for i, bound := range Buckets {
+		if seconds <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// Requests returns the number of requests observed for source and status class.
+func (r *Registry) Requests(source, statusClass string) uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.requests[requestKey{source, statusClass}]
+}
+
+// Errors returns the number of failed requests observed for source.
+func (r *Registry) Errors(source string) uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.errors[source]
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format.
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var err error
+	printf := func(format string, args ...interface{}) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+
+	keys := make([]requestKey, 0, len(r.requests))
+	for k := range r.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].source != keys[j].source {
+			return keys[i].source < keys[j].source
+		}
+		return keys[i].statusClass < keys[j].statusClass
+	})
+	printf("# HELP mcp_brasil_upstream_requests_total Upstream API requests.\n")
+	printf("# TYPE mcp_brasil_upstream_requests_total counter\n")
+	for _, k := range keys {
+		printf("mcp_brasil_upstream_requests_total{source=%q,status_class=%q} %d\n", k.source, k.statusClass, r.requests[k])
+	}
+
+	sources := make([]string, 0, len(r.latencies))
+	for source := range r.latencies {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	printf("# HELP mcp_brasil_upstream_errors_total Upstream API requests that failed or returned 4xx/5xx.\n")
+	printf("# TYPE mcp_brasil_upstream_errors_total counter\n")
+	for _, source := range sources {
+		printf("mcp_brasil_upstream_errors_total{source=%q} %d\n", source, r.errors[source])
+	}
+
+	printf("# HELP mcp_brasil_upstream_request_duration_seconds Upstream API request latency.\n")
+	printf("# TYPE mcp_brasil_upstream_request_duration_seconds histogram\n")
+	for _, source := range sources {
+		h := r.latencies[source]
+		var cumulative uint64
+		for i, bound := range Buckets {
+			cumulative += h.counts[i]
+			printf("mcp_brasil_upstream_request_duration_seconds_bucket{source=%q,le=\"%g\"} %d\n", source, bound, cumulative)
+		}
+		printf("mcp_brasil_upstream_request_duration_seconds_bucket{source=%q,le=\"+Inf\"} %d\n", source, h.count)
+		printf("mcp_brasil_upstream_request_duration_seconds_sum{source=%q} %g\n", source, h.sum)
+		printf("mcp_brasil_upstream_request_duration_seconds_count{source=%q} %d\n", source, h.count)
+	}
+	return err
+}
+
+// Handler serves the metrics in the Prometheus text exposition format.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		var buf bytes.Buffer
+		if err := r.WritePrometheus(&buf); err != nil {
+			http.Error(w, fmt.Sprintf("writing metrics: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write(buf.Bytes())
+	})
+}
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatusClass(t *testing.T) {
+	tests := []struct {
+		resp *http.Response
+		err  error
+		want string
+	}{
+		{&http.Response{StatusCode: 200}, nil, "2xx"},
+		{&http.Response{StatusCode: 304}, nil, "3xx"},
+		{&http.Response{StatusCode: 404}, nil, "4xx"},
+		{&http.Response{StatusCode: 503}, nil, "5xx"},
+		{nil, errors.New("connection refused"), "error"},
+		{nil, nil, "error"},
+	}
+	for _, tt := range tests {
+		if got := StatusClass(tt.resp, tt.err); got != tt.want {
+			t.Errorf("StatusClass(%v, %v) = %q, want %q", tt.resp, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestObserveNilCollector(t *testing.T) {
+	var r *Registry
+	// Neither call may panic.
+	Observe(nil, "ibge", &http.Response{StatusCode: 200}, nil, time.Millisecond)
+	Observe(r, "ibge", &http.Response{StatusCode: 200}, nil, time.Millisecond)
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	Observe(r, "ibge", &http.Response{StatusCode: 200}, nil, 3*time.Millisecond)
+	Observe(r, "ibge", &http.Response{StatusCode: 200}, nil, 200*time.Millisecond)
+	Observe(r, "ibge", &http.Response{StatusCode: 404}, nil, time.Millisecond)
+	Observe(r, "bcb", nil, errors.New("timeout"), 20*time.Second)
+
+	tests := []struct {
+		source, class string
+		want          uint64
+	}{
+		{"ibge", "2xx", 2},
+		{"ibge", "4xx", 1},
+		{"ibge", "5xx", 0},
+		{"bcb", "error", 1},
+	}
+	for _, tt := range tests {
+		if got := r.Requests(tt.source, tt.class); got != tt.want {
+			t.Errorf("Requests(%s, %s) = %d, want %d", tt.source, tt.class, got, tt.want)
+		}
+	}
+	if got := r.Errors("ibge"); got != 1 {
+		t.Errorf("Errors(ibge) = %d, want 1", got)
+	}
+	if got := r.Errors("bcb"); got != 1 {
+		t.Errorf("Errors(bcb) = %d, want 1", got)
+	}
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`mcp_brasil_upstream_requests_total{source="ibge",status_class="2xx"} 2`,
+		`mcp_brasil_upstream_errors_total{source="bcb"} 1`,
+		`mcp_brasil_upstream_request_duration_seconds_bucket{source="ibge",le="0.005"} 2`,
+		`mcp_brasil_upstream_request_duration_seconds_bucket{source="ibge",le="0.25"} 3`,
+		`mcp_brasil_upstream_request_duration_seconds_bucket{source="bcb",le="10"} 0`,
+		`mcp_brasil_upstream_request_duration_seconds_bucket{source="bcb",le="+Inf"} 1`,
+		`mcp_brasil_upstream_request_duration_seconds_count{source="ibge"} 3`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output lacks %q:\n%s", want, body)
+		}
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+}
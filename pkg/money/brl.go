@@ -0,0 +1,34 @@
+// Package money formats monetary values for Portuguese-language output.
+package money
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// FormatBRL formats v as Brazilian reais, e.g. 1234567.891 becomes
+// "R$ 1.234.567,89" and -5 becomes "-R$ 5,00".
+func FormatBRL(v float64) string {
+	cents := int64(math.Round(math.Abs(v) * 100))
+	whole := strconv.FormatInt(cents/100, 10)
+
+	var b strings.Builder
+	if v < 0 && cents > 0 {
+		b.WriteString("-")
+	}
+	b.WriteString("R$ ")
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(digit)
+	}
+	b.WriteString(",")
+	frac := cents % 100
+	if frac < 10 {
+		b.WriteByte('0')
+	}
+	b.WriteString(strconv.FormatInt(frac, 10))
+	return b.String()
+}
@@ -0,0 +1,28 @@
+package money
+
+import "testing"
+
+func TestFormatBRL(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want string
+	}{
+		{v: 0, want: "R$ 0,00"},
+		{v: 0.5, want: "R$ 0,50"},
+		{v: 0.05, want: "R$ 0,05"},
+		{v: 1, want: "R$ 1,00"},
+		{v: 999.99, want: "R$ 999,99"},
+		{v: 1000, want: "R$ 1.000,00"},
+		{v: 1234567.891, want: "R$ 1.234.567,89"},
+		{v: 1234567.895, want: "R$ 1.234.567,90"},
+		{v: 12345678901.2, want: "R$ 12.345.678.901,20"},
+		{v: -5, want: "-R$ 5,00"},
+		{v: -1234.5, want: "-R$ 1.234,50"},
+		{v: -0.001, want: "R$ 0,00"},
+	}
+	for _, tt := range tests {
+		if got := FormatBRL(tt.v); got != tt.want {
+			t.Errorf("FormatBRL(%v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+// Package paging describes where a page of search results sits, so every
+// paginated client reports it with the same fields.
+package paging
+
+import "fmt"
+
+// Info describes the position of a page of results and where to continue.
+// NextPage is 0 when there is nothing more to fetch.
+type Info struct {
+	Page     int  `json:"page"`
+	PageSize int  `json:"page_size"`
+	HasMore  bool `json:"has_more"`
+	NextPage int  `json:"next_page"`
+	// Message is set when the page holds no records, so that an empty result
+	// reads as "nothing found" rather than as a failure.
+	Message string `json:"message,omitempty"`
+}
+
+// New builds the Info of a page holding count records, inferring HasMore
+// from the page being full, for APIs that do not report totals. A page of
+// unknown size never has more.
+func New(page, pageSize, count int) Info {
+	info := Info{Page: page, PageSize: pageSize, HasMore: pageSize > 0 && count >= pageSize}
+	if info.HasMore {
+		info.NextPage = page + 1
+	}
+	if count == 0 {
+		info.Message = NoResults(page, false)
+	}
+	return info
+}
+
+// NoResults explains an empty page of results. hasMore reports that later
+// pages may still hold matches.
+func NoResults(page int, hasMore bool) string {
+	switch {
+	case hasMore:
+		return "No matching results in the pages scanned; more pages remain from next_page"
+	case page > 1:
+		return fmt.Sprintf("No results on page %d: the query has fewer pages", page)
+	default:
+		return "No results found for this query"
+	}
+}
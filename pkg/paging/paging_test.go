@@ -0,0 +1,50 @@
+package paging
+
+import "testing"
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		page        int
+		pageSize    int
+		count       int
+		want        Info
+		wantMessage bool
+	}{
+		{name: "full page", page: 1, pageSize: 15, count: 15, want: Info{Page: 1, PageSize: 15, HasMore: true, NextPage: 2}},
+		{name: "full later page", page: 4, pageSize: 15, count: 15, want: Info{Page: 4, PageSize: 15, HasMore: true, NextPage: 5}},
+		{name: "short page", page: 2, pageSize: 15, count: 7, want: Info{Page: 2, PageSize: 15}},
+		{name: "empty first page", page: 1, pageSize: 15, count: 0, want: Info{Page: 1, PageSize: 15}, wantMessage: true},
+		{name: "empty later page", page: 3, pageSize: 15, count: 0, want: Info{Page: 3, PageSize: 15}, wantMessage: true},
+		{name: "no page size", page: 1, pageSize: 0, count: 0, want: Info{Page: 1}, wantMessage: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := New(tt.page, tt.pageSize, tt.count)
+			if (got.Message != "") != tt.wantMessage {
+				t.Errorf("Message = %q", got.Message)
+			}
+			got.Message = ""
+			if got != tt.want {
+				t.Errorf("New(%d, %d, %d) = %+v, want %+v", tt.page, tt.pageSize, tt.count, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNoResults(t *testing.T) {
+	tests := []struct {
+		page    int
+		hasMore bool
+		want    string
+	}{
+		{page: 1, want: "No results found for this query"},
+		{page: 3, want: "No results on page 3: the query has fewer pages"},
+		{page: 1, hasMore: true, want: "No matching results in the pages scanned; more pages remain from next_page"},
+	}
+	for _, tt := range tests {
+		if got := NoResults(tt.page, tt.hasMore); got != tt.want {
+			t.Errorf("NoResults(%d, %v) = %q, want %q", tt.page, tt.hasMore, got, tt.want)
+		}
+	}
+}
@@ -5,131 +5,514 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/anderson-ufrj/mcp-brasil/pkg/apierror"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/dedup"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/health"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/httpbody"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/httplog"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/metrics"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/paging"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/retry"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/text"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/timeout"
 )
 
 const (
 	BaseURL        = "https://pncp.gov.br/api/consulta/v1"
+	PNCPURL        = "https://pncp.gov.br/api/pncp/v1"
 	DefaultTimeout = 30 * time.Second
+	DateLayout     = "20060102"
 )
 
-// Procurement modality codes.
-var Modalities = map[string]int{
-	"pregao_eletronico":       6,
-	"concorrencia_eletronica": 1,
-	"concorrencia":            2,
-	"concurso":                3,
-	"leilao_eletronico":       4,
-	"leilao":                  5,
-	"dialogo_competitivo":     7,
-	"credenciamento":          8,
+// MaxDateSpanDays is the widest dataInicial..dataFinal window accepted for
+// contract queries; PNCP rejects longer periods.
+var MaxDateSpanDays = 365
+
+var datePattern = regexp.MustCompile(`^\d{8}$`)
+
+// validateDateRange checks that startDate and endDate are real YYYYMMDD
+// dates, in order, and no more than MaxDateSpanDays apart.
+func validateDateRange(startDate, endDate string) error {
+	start, err := parseDate("start date", startDate)
+	if err != nil {
+		return err
+	}
+	end, err := parseDate("end date", endDate)
+	if err != nil {
+		return err
+	}
+	if end.Before(start) {
+		return fmt.Errorf("start date %s is after end date %s", startDate, endDate)
+	}
+	if span := int(end.Sub(start).Hours() / 24); MaxDateSpanDays > 0 && span > MaxDateSpanDays {
+		return fmt.Errorf("date range spans %d days, PNCP allows at most %d", span, MaxDateSpanDays)
+	}
+	return nil
+}
+
+func parseDate(name, value string) (time.Time, error) {
+	if !datePattern.MatchString(value) {
+		return time.Time{}, fmt.Errorf("invalid %s %q: expected YYYYMMDD", name, value)
+	}
+	t, err := time.Parse(DateLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q: not a valid date", name, value)
+	}
+	return t, nil
+}
+
+// modality is one entry of PNCP's modality table: the name accepted by
+// ModalityByName and the label PNCP reports as modalidadeNome.
+type modality struct {
+	name  string
+	label string
+}
+
+// modalityCodes is PNCP's table of procurement modality codes (Manual de
+// Integracao, "Modalidade de Contratacao"). Modalities, ModalityByName and
+// ModalityByCode are all derived from it.
+var modalityCodes = map[int]modality{
+	1:  {"leilao_eletronico", "Leilão - Eletrônico"},
+	2:  {"dialogo_competitivo", "Diálogo Competitivo"},
+	3:  {"concurso", "Concurso"},
+	4:  {"concorrencia_eletronica", "Concorrência - Eletrônica"},
+	5:  {"concorrencia_presencial", "Concorrência - Presencial"},
+	6:  {"pregao_eletronico", "Pregão - Eletrônico"},
+	7:  {"pregao_presencial", "Pregão - Presencial"},
+	8:  {"dispensa", "Dispensa de Licitação"},
+	9:  {"inexigibilidade", "Inexigibilidade"},
+	10: {"manifestacao_de_interesse", "Manifestação de Interesse"},
+	11: {"pre_qualificacao", "Pré-qualificação"},
+	12: {"credenciamento", "Credenciamento"},
+	13: {"leilao_presencial", "Leilão - Presencial"},
+}
+
+// Procurement modality codes, by name.
+var Modalities = func() map[string]int {
+	m := make(map[string]int, len(modalityCodes))
+	for code, mod := range modalityCodes {
+		m[mod.name] = code
+	}
+	return m
+}()
+
+// modalityKeys maps both the names and the folded labels of modalityCodes
+// to their codes, so that "dispensa" and "Dispensa de Licitação" resolve
+// alike.
+var modalityKeys = func() map[string]int {
+	m := make(map[string]int, 2*len(modalityCodes))
+	for code, mod := range modalityCodes {
+		m[mod.name] = code
+		m[modalityKey(mod.label)] = code
+	}
+	return m
+}()
+
+// modalityKey folds a modality name for lookup, ignoring case, accents,
+// spaces and hyphens.
+func modalityKey(name string) string {
+	return strings.Join(strings.FieldsFunc(text.Fold(name), func(r rune) bool {
+		return r == ' ' || r == '_' || r == '-'
+	}), "_")
 }
 
 // Client represents the PNCP API client.
 type Client struct {
 	httpClient *http.Client
+	logger     *slog.Logger
+	metrics    metrics.Collector
+	flight     *dedup.Group
+	retry      retry.Policy
+	baseURL    string
+	pncpURL    string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithBaseURL points the client at another PNCP root, such as a mirror or a
+// test server. It takes the part shared by BaseURL and PNCPURL
+// ("https://pncp.gov.br/api" in production), to which "/consulta/v1" and
+// "/pncp/v1" are appended.
+func WithBaseURL(root string) Option {
+	return func(c *Client) {
+		root = strings.TrimRight(root, "/")
+		c.baseURL = root + "/consulta/v1"
+		c.pncpURL = root + "/pncp/v1"
+	}
+}
+
+// WithLogger logs the PNCP requests on l at debug level, as described
+// in httplog.Request. A nil l disables logging.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Client) {
+		c.logger = httplog.OrDiscard(l)
+	}
+}
+
+// WithMetrics records every request (status class and latency) on m.
+func WithMetrics(m metrics.Collector) Option {
+	return func(c *Client) {
+		c.metrics = m
+	}
+}
+
+// WithDeduplication makes concurrent identical requests share a single
+// upstream call.
+func WithDeduplication() Option {
+	return func(c *Client) {
+		c.flight = &dedup.Group{}
+	}
+}
+
+// WithRetry sets how many times a request failing with a 429, a 5xx or a
+// connection error is attempted in total, and the delay before the first
+// retry, which doubles on each further one. maxAttempts below 2 disables
+// retries.
+func WithRetry(maxAttempts int, baseDelay time.Duration) Option {
+	return func(c *Client) {
+		c.retry = retry.Policy{MaxAttempts: maxAttempts, BaseDelay: baseDelay}
+	}
 }
 
 // NewClient creates a new PNCP client.
-func NewClient() *Client {
-	return &Client{
+func NewClient(opts ...Option) *Client {
+	c := &Client{
 		httpClient: &http.Client{Timeout: DefaultTimeout},
+		logger:     httplog.Discard,
+		retry:      retry.Default,
+		baseURL:    BaseURL,
+		pncpURL:    PNCPURL,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// OrgaoEntidade identifies the public body responsible for a publication.
+type OrgaoEntidade struct {
+	CNPJ        string `json:"cnpj"`
+	RazaoSocial string `json:"razaoSocial"`
+	PoderID     string `json:"poderId,omitempty"`
+	EsferaID    string `json:"esferaId,omitempty"`
+	// Extra keeps any fields PNCP returns beyond the ones above.
+	Extra map[string]interface{} `json:"extra,omitempty"`
+}
+
+// UnmarshalJSON decodes the known fields and collects the rest into Extra.
+func (o *OrgaoEntidade) UnmarshalJSON(data []byte) error {
+	type known OrgaoEntidade
+	var k known
+	if err := json.Unmarshal(data, &k); err != nil {
+		return err
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for _, key := range []string{"cnpj", "razaoSocial", "poderId", "esferaId", "extra"} {
+		delete(raw, key)
+	}
+
+	// Extra is kept when decoding an already-structured OrgaoEntidade.
+	*o = OrgaoEntidade(k)
+	for key, value := range raw {
+		if o.Extra == nil {
+			o.Extra = make(map[string]interface{}, len(raw))
+		}
+		o.Extra[key] = value
+	}
+	return nil
 }
 
 // ContractPublication represents a contract publication from PNCP.
 type ContractPublication struct {
-	SequencialCompra          int                    `json:"sequencialCompra,omitempty"`
-	NumeroCompra              string                 `json:"numeroCompra,omitempty"`
-	AnoCompra                 int                    `json:"anoCompra,omitempty"`
-	OrgaoEntidade             map[string]interface{} `json:"orgaoEntidade,omitempty"`
-	ModalidadeID              int                    `json:"modalidadeId,omitempty"`
-	ModalidadeNome            string                 `json:"modalidadeNome,omitempty"`
-	SituacaoCompraID          int                    `json:"situacaoCompraId,omitempty"`
-	SituacaoCompraNome        string                 `json:"situacaoCompraNome,omitempty"`
-	NumeroControlePNCP        string                 `json:"numeroControlePNCP,omitempty"`
-	DataPublicacaoPncp        string                 `json:"dataPublicacaoPncp,omitempty"`
-	DataAberturaProposta      string                 `json:"dataAberturaProposta,omitempty"`
-	DataEncerramentoProposta  string                 `json:"dataEncerramentoProposta,omitempty"`
-	ObjetoCompra              string                 `json:"objetoCompra,omitempty"`
-	ValorTotalEstimado        float64                `json:"valorTotalEstimado,omitempty"`
-	ValorTotalHomologado      float64                `json:"valorTotalHomologado,omitempty"`
+	SequencialCompra         int            `json:"sequencialCompra,omitempty"`
+	NumeroCompra             string         `json:"numeroCompra,omitempty"`
+	AnoCompra                int            `json:"anoCompra,omitempty"`
+	OrgaoEntidade            *OrgaoEntidade `json:"orgaoEntidade,omitempty"`
+	ModalidadeID             int            `json:"modalidadeId,omitempty"`
+	ModalidadeNome           string         `json:"modalidadeNome,omitempty"`
+	SituacaoCompraID         int            `json:"situacaoCompraId,omitempty"`
+	SituacaoCompraNome       string         `json:"situacaoCompraNome,omitempty"`
+	NumeroControlePNCP       string         `json:"numeroControlePNCP,omitempty"`
+	DataPublicacaoPncp       string         `json:"dataPublicacaoPncp,omitempty"`
+	DataAberturaProposta     string         `json:"dataAberturaProposta,omitempty"`
+	DataEncerramentoProposta string         `json:"dataEncerramentoProposta,omitempty"`
+	ObjetoCompra             string         `json:"objetoCompra,omitempty"`
+	ValorTotalEstimado       float64        `json:"valorTotalEstimado,omitempty"`
+	ValorTotalHomologado     float64        `json:"valorTotalHomologado,omitempty"`
 }
 
 // ContractsResponse represents the response for contracts query.
 type ContractsResponse struct {
 	Contracts []ContractPublication `json:"contracts"`
 	Total     int                   `json:"total"`
-	Page      int                   `json:"page"`
-	PageSize  int                   `json:"page_size"`
-	Source    string                `json:"source"`
+	paging.Info
+	Keyword      string `json:"keyword,omitempty"`
+	PagesScanned int    `json:"pages_scanned,omitempty"`
+	Source       string `json:"source"`
 }
 
 // PriceRegistration represents a price registration record.
 type PriceRegistration struct {
-	NumeroControlePNCP  string                 `json:"numeroControlePNCP,omitempty"`
-	OrgaoEntidade       map[string]interface{} `json:"orgaoEntidade,omitempty"`
-	NumeroAta           string                 `json:"numeroAta,omitempty"`
-	AnoAta              int                    `json:"anoAta,omitempty"`
-	DataPublicacaoPncp  string                 `json:"dataPublicacaoPncp,omitempty"`
-	DataVigenciaInicio  string                 `json:"dataVigenciaInicio,omitempty"`
-	DataVigenciaFim     string                 `json:"dataVigenciaFim,omitempty"`
-	ObjetoAta           string                 `json:"objetoAta,omitempty"`
-	ValorTotalEstimado  float64                `json:"valorTotalEstimado,omitempty"`
+	NumeroControlePNCP string         `json:"numeroControlePNCP,omitempty"`
+	OrgaoEntidade      *OrgaoEntidade `json:"orgaoEntidade,omitempty"`
+	NumeroAta          string         `json:"numeroAta,omitempty"`
+	AnoAta             int            `json:"anoAta,omitempty"`
+	DataPublicacaoPncp string         `json:"dataPublicacaoPncp,omitempty"`
+	DataVigenciaInicio string         `json:"dataVigenciaInicio,omitempty"`
+	DataVigenciaFim    string         `json:"dataVigenciaFim,omitempty"`
+	ObjetoAta          string         `json:"objetoAta,omitempty"`
+	ValorTotalEstimado float64        `json:"valorTotalEstimado,omitempty"`
 }
 
 // PriceRegistrationsResponse represents the response for price registrations query.
 type PriceRegistrationsResponse struct {
 	Registrations []PriceRegistration `json:"registrations"`
 	Total         int                 `json:"total"`
-	Page          int                 `json:"page"`
-	Source        string              `json:"source"`
+	paging.Info
+	Source string `json:"source"`
+}
+
+// Edital is a procurement notice (aviso de contratacao) open for proposals.
+type Edital struct {
+	NumeroControlePNCP       string         `json:"numeroControlePNCP,omitempty"`
+	OrgaoEntidade            *OrgaoEntidade `json:"orgaoEntidade,omitempty"`
+	ObjetoCompra             string         `json:"objetoCompra,omitempty"`
+	ModalidadeID             int            `json:"modalidadeId,omitempty"`
+	ModalidadeNome           string         `json:"modalidadeNome,omitempty"`
+	SituacaoCompraID         int            `json:"situacaoCompraId,omitempty"`
+	SituacaoCompraNome       string         `json:"situacaoCompraNome,omitempty"`
+	DataPublicacaoPncp       string         `json:"dataPublicacaoPncp,omitempty"`
+	DataAberturaProposta     string         `json:"dataAberturaProposta,omitempty"`
+	DataEncerramentoProposta string         `json:"dataEncerramentoProposta,omitempty"`
+	ValorTotalEstimado       float64        `json:"valorTotalEstimado,omitempty"`
+	LinkSistemaOrigem        string         `json:"linkSistemaOrigem,omitempty"`
+}
+
+// EditaisResponse represents the response for an editais query. Total is
+// PNCP's count, before the start date filter; FilteredOut counts the
+// editais of this page dropped by that filter.
+type EditaisResponse struct {
+	Editais     []Edital `json:"editais"`
+	Total       int      `json:"total"`
+	FilteredOut int      `json:"filtered_out,omitempty"`
+	paging.Info
+	Source string `json:"source"`
+}
+
+// pageBounds applies the paging defaults of the search methods: page 1,
+// and a page size clamped to the 10..500 PNCP accepts.
+func pageBounds(page, pageSize int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 10 {
+		pageSize = 10
+	} else if pageSize > 500 {
+		pageSize = 500
+	}
+	return page, pageSize
+}
+
+// queryPage returns the page and page size of a query built by one of the
+// *Params functions, after pageBounds.
+func queryPage(params url.Values) (int, int) {
+	page, _ := strconv.Atoi(params.Get("pagina"))
+	pageSize, _ := strconv.Atoi(params.Get("tamanhoPagina"))
+	return page, pageSize
 }
 
 func (c *Client) doRequest(ctx context.Context, endpoint string, params url.Values) ([]byte, error) {
-	reqURL := fmt.Sprintf("%s%s", BaseURL, endpoint)
+	return c.doRequestBase(ctx, c.baseURL, endpoint, params)
+}
+
+// Ping checks that the PNCP consulta API is reachable (see health.Ping).
+func (c *Client) Ping(ctx context.Context) error {
+	return health.Ping(ctx, c.httpClient, c.logger, c.baseURL)
+}
+
+// requestURL returns the URL of a request to endpoint of the given PNCP API
+// base with params.
+func requestURL(baseURL, endpoint string, params url.Values) string {
+	reqURL := fmt.Sprintf("%s%s", baseURL, endpoint)
 	if len(params) > 0 {
 		reqURL = fmt.Sprintf("%s?%s", reqURL, params.Encode())
 	}
+	return reqURL
+}
+
+// doRequestBase performs a request against an endpoint of the given PNCP API
+// base, as a few resources are only served by the main API and not by the
+// consultation one.
+func (c *Client) doRequestBase(ctx context.Context, baseURL, endpoint string, params url.Values) ([]byte, error) {
+	reqURL := requestURL(baseURL, endpoint, params)
+
+	return c.flight.Do(reqURL, func() ([]byte, error) {
+		return c.retry.Do(ctx, func() ([]byte, error) {
+			return c.fetch(ctx, reqURL)
+		})
+	})
+}
 
+// fetch performs a GET request and returns the body of a 200 response, or
+// an empty body for a 204.
+func (c *Client) fetch(ctx context.Context, reqURL string) ([]byte, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 
 	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept-Encoding", httpbody.AcceptEncoding)
 
-	resp, err := c.httpClient.Do(req)
+	start := time.Now()
+	resp, err := timeout.Client(ctx, c.httpClient).Do(req)
+	duration := time.Since(start)
+	httplog.Request(ctx, c.logger, req, resp, err, duration)
+	metrics.Observe(c.metrics, "pncp", resp, err, duration)
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
 		return nil, fmt.Errorf("executing request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := httpbody.Read(resp)
 	if err != nil {
 		return nil, fmt.Errorf("reading response: %w", err)
 	}
 
+	// PNCP answers 204 No Content when a query matches nothing.
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, apierror.FromResponse(resp, body)
 	}
 
 	return body, nil
 }
 
-// SearchContracts searches for contract publications.
-func (c *Client) SearchContracts(ctx context.Context, startDate, endDate string, modalityCode int, state string, page, pageSize int) (*ContractsResponse, error) {
-	if pageSize < 10 {
-		pageSize = 10
-	} else if pageSize > 500 {
-		pageSize = 500
+// maxFilterPages caps how many result pages a filtered search scans, since
+// the filters run client-side.
+const maxFilterPages = 10
+
+// ContractFilter holds client-side filters applied to PNCP contract
+// publications. Zero values disable the corresponding filter.
+type ContractFilter struct {
+	Keyword  string  // substring of ObjetoCompra, ignoring case and accents
+	MinValue float64 // inclusive lower bound on ValorTotalEstimado
+	MaxValue float64 // inclusive upper bound on ValorTotalEstimado
+}
+
+func (f ContractFilter) active() bool {
+	return strings.TrimSpace(f.Keyword) != "" || f.MinValue > 0 || f.MaxValue > 0
+}
+
+// matcher returns a predicate reporting whether a publication passes f.
+func (f ContractFilter) matcher() func(ContractPublication) bool {
+	term := text.Fold(f.Keyword)
+	return func(contract ContractPublication) bool {
+		if term != "" && !strings.Contains(text.Fold(contract.ObjetoCompra), term) {
+			return false
+		}
+		if f.MinValue > 0 && contract.ValorTotalEstimado < f.MinValue {
+			return false
+		}
+		if f.MaxValue > 0 && contract.ValorTotalEstimado > f.MaxValue {
+			return false
+		}
+		return true
 	}
-	if page < 1 {
-		page = 1
+}
+
+// SearchContracts searches for contract publications. When filter has any
+// criteria set, pages are scanned from page onwards (up to maxFilterPages)
+// and only matching publications are kept.
+func (c *Client) SearchContracts(ctx context.Context, startDate, endDate string, modalityCode int, state string, filter ContractFilter, page, pageSize int) (*ContractsResponse, error) {
+	params, err := contractsParams(startDate, endDate, modalityCode, state, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	page, pageSize = queryPage(params)
+
+	if !filter.active() {
+		result, err := c.fetchContractsPage(ctx, params, page)
+		if err != nil {
+			return nil, err
+		}
+		return &ContractsResponse{
+			Contracts: result.Data,
+			Total:     result.TotalRegistros,
+			Info:      paging.New(page, pageSize, len(result.Data)),
+			Source:    "pncp_api",
+		}, nil
 	}
+
+	match := filter.matcher()
+	matches := []ContractPublication{}
+	scanned := 0
+	exhausted := false
+	for p := page; scanned < maxFilterPages; p++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result, err := c.fetchContractsPage(ctx, params, p)
+		if err != nil {
+			return nil, err
+		}
+		scanned++
+		for _, contract := range result.Data {
+			if match(contract) {
+				matches = append(matches, contract)
+			}
+		}
+		if len(result.Data) < pageSize || (result.TotalPaginas > 0 && p >= result.TotalPaginas) {
+			exhausted = true
+			break
+		}
+	}
+
+	// Scanning stopped at the page cap: resume after the last scanned page.
+	pageInfo := paging.Info{Page: page, PageSize: pageSize, HasMore: !exhausted}
+	if pageInfo.HasMore {
+		pageInfo.NextPage = page + scanned
+	}
+	if len(matches) == 0 {
+		pageInfo.Message = paging.NoResults(page, pageInfo.HasMore)
+	}
+
+	return &ContractsResponse{
+		Contracts:    matches,
+		Total:        len(matches),
+		Info:         pageInfo,
+		Keyword:      strings.TrimSpace(filter.Keyword),
+		PagesScanned: scanned,
+		Source:       "pncp_api",
+	}, nil
+}
+
+// contractsParams builds the query of the first page SearchContracts
+// requests, validating its arguments.
+func contractsParams(startDate, endDate string, modalityCode int, state string, page, pageSize int) (url.Values, error) {
+	if err := validateDateRange(startDate, endDate); err != nil {
+		return nil, err
+	}
+	page, pageSize = pageBounds(page, pageSize)
 	if modalityCode == 0 {
 		modalityCode = 6 // Default: pregao eletronico
 	}
@@ -144,48 +527,184 @@ func (c *Client) SearchContracts(ctx context.Context, startDate, endDate string,
 	if state != "" {
 		params.Set("uf", state)
 	}
+	return params, nil
+}
 
-	body, err := c.doRequest(ctx, "/contratacoes/publicacao", params)
+// SearchContractsURL returns the URL SearchContracts would request first
+// with the same arguments, without calling the API. A ContractFilter does
+// not change it: filtering happens on the pages fetched from there.
+func (c *Client) SearchContractsURL(startDate, endDate string, modalityCode int, state string, page, pageSize int) (string, error) {
+	params, err := contractsParams(startDate, endDate, modalityCode, state, page, pageSize)
 	if err != nil {
+		return "", err
+	}
+	return requestURL(c.baseURL, "/contratacoes/publicacao", params), nil
+}
+
+// SearchAllContracts fetches consecutive pages of contract publications until
+// maxResults publications are collected or totalRegistros is exhausted. Total
+// reports the number of records PNCP holds for the query.
+func (c *Client) SearchAllContracts(ctx context.Context, startDate, endDate string, modality int, state string, maxResults int) (*ContractsResponse, error) {
+	const pageSize = 500
+	if err := validateDateRange(startDate, endDate); err != nil {
 		return nil, err
 	}
+	if maxResults <= 0 {
+		maxResults = 1000
+	}
+	if modality == 0 {
+		modality = 6 // Default: pregao eletronico
+	}
 
-	var result struct {
-		Data          []ContractPublication `json:"data"`
-		TotalRegistros int                  `json:"totalRegistros"`
+	params := url.Values{}
+	params.Set("dataInicial", startDate)
+	params.Set("dataFinal", endDate)
+	params.Set("codigoModalidadeContratacao", fmt.Sprintf("%d", modality))
+	params.Set("tamanhoPagina", fmt.Sprintf("%d", pageSize))
+
+	if state != "" {
+		params.Set("uf", state)
 	}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("parsing response: %w", err)
+
+	contracts := []ContractPublication{}
+	total := 0
+	for page := 1; len(contracts) < maxResults; page++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		result, err := c.fetchContractsPage(ctx, params, page)
+		if err != nil {
+			return nil, err
+		}
+		total = result.TotalRegistros
+		contracts = append(contracts, result.Data...)
+
+		if len(result.Data) == 0 || page*pageSize >= total {
+			break
+		}
+	}
+
+	if len(contracts) > maxResults {
+		contracts = contracts[:maxResults]
+	}
+
+	// Resume from the first page not entirely collected.
+	pageInfo := paging.Info{Page: 1, PageSize: pageSize, HasMore: len(contracts) < total}
+	if pageInfo.HasMore {
+		pageInfo.NextPage = len(contracts)/pageSize + 1
+	}
+	if len(contracts) == 0 {
+		pageInfo.Message = paging.NoResults(1, false)
 	}
 
 	return &ContractsResponse{
-		Contracts: result.Data,
-		Total:     result.TotalRegistros,
-		Page:      page,
-		PageSize:  pageSize,
+		Contracts: contracts,
+		Total:     total,
+		Info:      pageInfo,
 		Source:    "pncp_api",
 	}, nil
 }
 
-// SearchPriceRegistrations searches for price registration records.
-func (c *Client) SearchPriceRegistrations(ctx context.Context, state string, page, pageSize int) (*PriceRegistrationsResponse, error) {
-	if pageSize < 10 {
-		pageSize = 10
-	} else if pageSize > 500 {
-		pageSize = 500
+// Sort modes accepted by SortContracts.
+const (
+	SortValueDesc = "value_desc"
+	SortValueAsc  = "value_asc"
+	SortDateDesc  = "date_desc"
+	SortDateAsc   = "date_asc"
+)
+
+// publicationDateLayouts are the formats PNCP uses for dataPublicacaoPncp.
+var publicationDateLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04:05.999999999",
+	time.RFC3339,
+	"2006-01-02",
+}
+
+func parsePublicationDate(s string) (time.Time, bool) {
+	for _, layout := range publicationDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
 	}
-	if page < 1 {
-		page = 1
+	return time.Time{}, false
+}
+
+// SortContracts sorts contracts in place by estimated value or publication
+// date. Equal keys keep their API order, and publications with an
+// unparseable date are placed last. An empty mode leaves the order as is.
+func SortContracts(contracts []ContractPublication, mode string) error {
+	switch mode {
+	case "":
+		return nil
+	case SortValueDesc:
+		sort.SliceStable(contracts, func(i, j int) bool {
+			return contracts[i].ValorTotalEstimado > contracts[j].ValorTotalEstimado
+		})
+	case SortValueAsc:
+		sort.SliceStable(contracts, func(i, j int) bool {
+			return contracts[i].ValorTotalEstimado < contracts[j].ValorTotalEstimado
+		})
+	case SortDateDesc, SortDateAsc:
+		dates := make(map[string]time.Time, len(contracts))
+		for _, contract := range contracts {
+			if t, ok := parsePublicationDate(contract.DataPublicacaoPncp); ok {
+				dates[contract.DataPublicacaoPncp] = t
+			}
+		}
+		sort.SliceStable(contracts, func(i, j int) bool {
+			ti, okI := dates[contracts[i].DataPublicacaoPncp]
+			tj, okJ := dates[contracts[j].DataPublicacaoPncp]
+			if !okI || !okJ {
+				return okI && !okJ
+			}
+			if mode == SortDateDesc {
+				return ti.After(tj)
+			}
+			return ti.Before(tj)
+		})
+	default:
+		return fmt.Errorf("invalid sort %q: use %s, %s, %s or %s", mode, SortValueDesc, SortValueAsc, SortDateDesc, SortDateAsc)
 	}
+	return nil
+}
 
-	params := url.Values{}
-	params.Set("tamanhoPagina", fmt.Sprintf("%d", pageSize))
+// contractsPage is a raw page of /contratacoes/publicacao results.
+type contractsPage struct {
+	Data           []ContractPublication `json:"data"`
+	TotalRegistros int                   `json:"totalRegistros"`
+	TotalPaginas   int                   `json:"totalPaginas"`
+}
+
+func (c *Client) fetchContractsPage(ctx context.Context, params url.Values, page int) (*contractsPage, error) {
 	params.Set("pagina", fmt.Sprintf("%d", page))
 
-	if state != "" {
-		params.Set("uf", state)
+	body, err := c.doRequest(ctx, "/contratacoes/publicacao", params)
+	if err != nil {
+		return nil, err
 	}
 
+	var result contractsPage
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("parsing response: %w", err)
+		}
+	}
+	if result.Data == nil {
+		result.Data = []ContractPublication{}
+	}
+	return &result, nil
+}
+
+// SearchPriceRegistrations searches for price registration records.
+func (c *Client) SearchPriceRegistrations(ctx context.Context, state string, page, pageSize int) (*PriceRegistrationsResponse, error) {
+	params, err := priceRegistrationsParams(state, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	page, pageSize = queryPage(params)
+
 	body, err := c.doRequest(ctx, "/atas-registro-preco", params)
 	if err != nil {
 		return nil, err
@@ -197,15 +716,418 @@ func (c *Client) SearchPriceRegistrations(ctx context.Context, state string, pag
 	if err := json.Unmarshal(body, &result); err != nil {
 		return nil, fmt.Errorf("parsing response: %w", err)
 	}
+	if result.Data == nil {
+		result.Data = []PriceRegistration{}
+	}
 
 	return &PriceRegistrationsResponse{
 		Registrations: result.Data,
 		Total:         len(result.Data),
-		Page:          page,
+		Info:          paging.New(page, pageSize, len(result.Data)),
 		Source:        "pncp_api",
 	}, nil
 }
 
+// priceRegistrationsParams builds the query of SearchPriceRegistrations.
+func priceRegistrationsParams(state string, page, pageSize int) (url.Values, error) {
+	page, pageSize = pageBounds(page, pageSize)
+
+	params := url.Values{}
+	params.Set("tamanhoPagina", fmt.Sprintf("%d", pageSize))
+	params.Set("pagina", fmt.Sprintf("%d", page))
+
+	if state != "" {
+		params.Set("uf", state)
+	}
+	return params, nil
+}
+
+// SearchPriceRegistrationsURL returns the URL SearchPriceRegistrations
+// would request with the same arguments, without calling the API.
+func (c *Client) SearchPriceRegistrationsURL(state string, page, pageSize int) (string, error) {
+	params, err := priceRegistrationsParams(state, page, pageSize)
+	if err != nil {
+		return "", err
+	}
+	return requestURL(c.baseURL, "/atas-registro-preco", params), nil
+}
+
+// SearchEditais searches for procurement notices whose proposal period is
+// still open and closes between startDate and endDate (YYYYMMDD). PNCP only
+// filters on the closing date up to endDate, so startDate, when set, is
+// applied to each page after it is fetched: pages may come back short, and
+// paging follows the upstream pages.
+func (c *Client) SearchEditais(ctx context.Context, startDate, endDate string, modality int, state string, page, pageSize int) (*EditaisResponse, error) {
+	params, err := editaisParams(startDate, endDate, modality, state, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	page, pageSize = queryPage(params)
+
+	body, err := c.doRequest(ctx, "/contratacoes/proposta", params)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := parseEditaisPage(body)
+	if err != nil {
+		return nil, err
+	}
+
+	editais := editaisClosingFrom(result.Data, startDate)
+	info := paging.New(page, pageSize, len(result.Data))
+	if len(editais) == 0 {
+		info.Message = paging.NoResults(page, info.HasMore)
+	}
+
+	return &EditaisResponse{
+		Editais:     editais,
+		Total:       result.TotalRegistros,
+		FilteredOut: len(result.Data) - len(editais),
+		Info:        info,
+		Source:      "pncp_api",
+	}, nil
+}
+
+// editaisParams builds the query of SearchEditais, validating its
+// arguments. startDate is optional and not sent upstream.
+func editaisParams(startDate, endDate string, modality int, state string, page, pageSize int) (url.Values, error) {
+	if startDate != "" {
+		if err := validateDateRange(startDate, endDate); err != nil {
+			return nil, err
+		}
+	} else if _, err := parseDate("end date", endDate); err != nil {
+		return nil, err
+	}
+	page, pageSize = pageBounds(page, pageSize)
+
+	params := url.Values{}
+	params.Set("dataFinal", endDate)
+	params.Set("tamanhoPagina", fmt.Sprintf("%d", pageSize))
+	params.Set("pagina", fmt.Sprintf("%d", page))
+	if modality > 0 {
+		params.Set("codigoModalidadeContratacao", fmt.Sprintf("%d", modality))
+	}
+	if state != "" {
+		params.Set("uf", state)
+	}
+	return params, nil
+}
+
+// SearchEditaisURL returns the URL SearchEditais would request with the
+// same arguments, without calling the API.
+func (c *Client) SearchEditaisURL(startDate, endDate string, modality int, state string, page, pageSize int) (string, error) {
+	params, err := editaisParams(startDate, endDate, modality, state, page, pageSize)
+	if err != nil {
+		return "", err
+	}
+	return requestURL(c.baseURL, "/contratacoes/proposta", params), nil
+}
+
+// editaisClosingFrom keeps the editais whose proposal period closes on or
+// after startDate (YYYYMMDD). All editais are kept when startDate is
+// empty, and so are those without a closing date.
+func editaisClosingFrom(editais []Edital, startDate string) []Edital {
+	if startDate == "" {
+		return editais
+	}
+	start, err := time.Parse(DateLayout, startDate)
+	if err != nil {
+		return editais
+	}
+	kept := make([]Edital, 0, len(editais))
+	for _, edital := range editais {
+		if len(edital.DataEncerramentoProposta) >= 10 {
+			closing, err := time.Parse("2006-01-02", edital.DataEncerramentoProposta[:10])
+			if err == nil && closing.Before(start) {
+				continue
+			}
+		}
+		kept = append(kept, edital)
+	}
+	return kept
+}
+
+// editaisPage is a raw page of /contratacoes/proposta results.
+type editaisPage struct {
+	Data           []Edital `json:"data"`
+	TotalRegistros int      `json:"totalRegistros"`
+	TotalPaginas   int      `json:"totalPaginas"`
+}
+
+// parseEditaisPage decodes a /contratacoes/proposta page. PNCP answers an
+// empty 204 when nothing matches.
+func parseEditaisPage(body []byte) (*editaisPage, error) {
+	var result editaisPage
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("parsing response: %w", err)
+		}
+	}
+	if result.Data == nil {
+		result.Data = []Edital{}
+	}
+	return &result, nil
+}
+
+// controlNumberPattern matches a numeroControlePNCP such as
+// "00394452000103-1-000123/2024" (CNPJ-tipo-sequencial/ano).
+var controlNumberPattern = regexp.MustCompile(`^(\d{14})-(\d)-(\d{1,6})/(\d{4})$`)
+
+// ControlNumber holds the components of a numeroControlePNCP.
+type ControlNumber struct {
+	OrgaoCNPJ  string `json:"orgao_cnpj"`
+	Tipo       int    `json:"tipo"`
+	Sequencial int    `json:"sequencial"`
+	Ano        string `json:"ano"`
+}
+
+// ParseControlNumber splits a numeroControlePNCP into its órgão CNPJ, type,
+// sequential number and year.
+func ParseControlNumber(numeroControlePNCP string) (*ControlNumber, error) {
+	m := controlNumberPattern.FindStringSubmatch(strings.TrimSpace(numeroControlePNCP))
+	if m == nil {
+		return nil, fmt.Errorf("invalid numeroControlePNCP %q: expected CNPJ-T-SEQUENCIAL/ANO (e.g. 00394452000103-1-000123/2024)", numeroControlePNCP)
+	}
+
+	tipo, _ := strconv.Atoi(m[2])
+	sequencial, _ := strconv.Atoi(m[3])
+	return &ControlNumber{
+		OrgaoCNPJ:  m[1],
+		Tipo:       tipo,
+		Sequencial: sequencial,
+		Ano:        m[4],
+	}, nil
+}
+
+// ContractDetail represents the full record of a procurement.
+type ContractDetail struct {
+	ContractPublication
+	InformacaoComplementar string                 `json:"informacaoComplementar,omitempty"`
+	ProcessoNumero         string                 `json:"processo,omitempty"`
+	SRP                    bool                   `json:"srp,omitempty"`
+	AmparoLegal            map[string]interface{} `json:"amparoLegal,omitempty"`
+	UnidadeOrgao           map[string]interface{} `json:"unidadeOrgao,omitempty"`
+	LinkSistemaOrigem      string                 `json:"linkSistemaOrigem,omitempty"`
+	DataInclusao           string                 `json:"dataInclusao,omitempty"`
+	DataAtualizacao        string                 `json:"dataAtualizacao,omitempty"`
+	Source                 string                 `json:"source"`
+}
+
+// GetContractDetail retrieves the full record of a procurement by its
+// numeroControlePNCP.
+func (c *Client) GetContractDetail(ctx context.Context, numeroControlePNCP string) (*ContractDetail, error) {
+	control, err := ParseControlNumber(numeroControlePNCP)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("/orgaos/%s/compras/%s/%d", control.OrgaoCNPJ, control.Ano, control.Sequencial)
+	body, err := c.doRequest(ctx, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) == 0 {
+		return nil, fmt.Errorf("%w: procurement %s", apierror.ErrNotFound, numeroControlePNCP)
+	}
+
+	var detail ContractDetail
+	if err := json.Unmarshal(body, &detail); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	detail.Source = "pncp_api"
+	return &detail, nil
+}
+
+// ContractItem represents a line item of a procurement.
+type ContractItem struct {
+	NumeroItem            int     `json:"numeroItem"`
+	Descricao             string  `json:"descricao"`
+	MaterialOuServico     string  `json:"materialOuServicoNome,omitempty"`
+	Quantidade            float64 `json:"quantidade"`
+	UnidadeMedida         string  `json:"unidadeMedida"`
+	ValorUnitarioEstimado float64 `json:"valorUnitarioEstimado"`
+	ValorTotal            float64 `json:"valorTotal"`
+	SituacaoItem          string  `json:"situacaoCompraItemNome,omitempty"`
+}
+
+// GetContractItems retrieves the line items of a procurement. A procurement
+// without items yields an empty slice.
+func (c *Client) GetContractItems(ctx context.Context, orgaoCnpj, ano string, sequencial int) ([]ContractItem, error) {
+	if orgaoCnpj == "" || ano == "" || sequencial <= 0 {
+		return nil, fmt.Errorf("orgaoCnpj, ano and sequencial are required")
+	}
+
+	endpoint := fmt.Sprintf("/orgaos/%s/compras/%s/%d/itens", orgaoCnpj, ano, sequencial)
+	body, err := c.doRequestBase(ctx, c.pncpURL, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []ContractItem
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &items); err != nil {
+			return nil, fmt.Errorf("parsing response: %w", err)
+		}
+	}
+	if items == nil {
+		items = []ContractItem{}
+	}
+	return items, nil
+}
+
+// ModalityByName resolves a modality name such as "pregao_eletronico" or
+// "Pregão Eletrônico" to its code. Case, accents, spaces and hyphens are
+// ignored.
+func ModalityByName(name string) (int, bool) {
+	code, ok := modalityKeys[modalityKey(name)]
+	return code, ok
+}
+
+// ModalityByCode returns the name of a modality code.
+func ModalityByCode(code int) (string, bool) {
+	mod, ok := modalityCodes[code]
+	return mod.name, ok
+}
+
+// maxSummaryPages caps the pages scanned per modality by
+// GroupContractsByModality.
+const maxSummaryPages = 10
+
+// ModalitySummary aggregates the publications of one modality.
+type ModalitySummary struct {
+	ModalidadeID         int     `json:"modalidade_id"`
+	ModalidadeNome       string  `json:"modalidade_nome"`
+	Count                int     `json:"count"`
+	ValorTotalHomologado float64 `json:"valor_total_homologado"`
+}
+
+// ModalitySummaryResponse represents the spend breakdown by modality.
+type ModalitySummaryResponse struct {
+	Modalities           []ModalitySummary `json:"modalities"`
+	Total                int               `json:"total"`
+	ValorTotalHomologado float64           `json:"valor_total_homologado"`
+	StartDate            string            `json:"start_date"`
+	EndDate              string            `json:"end_date"`
+	State                string            `json:"state,omitempty"`
+	// Truncated is set when a modality had more than maxSummaryPages pages,
+	// so its totals only cover the first ones.
+	Truncated bool   `json:"truncated"`
+	Source    string `json:"source"`
+}
+
+// modalityGroups sums publications per modality as pages arrive, so no
+// page has to be kept once it is counted.
+type modalityGroups struct {
+	index  map[string]int
+	groups []ModalitySummary
+}
+
+// add counts the publications of one page. Publications are grouped by
+// modality code, named after the modalidadeNome reported by PNCP.
+func (g *modalityGroups) add(contracts []ContractPublication) {
+	if g.index == nil {
+		g.index = map[string]int{}
+	}
+	for _, ct := range contracts {
+		key := ct.ModalidadeNome
+		if ct.ModalidadeID != 0 {
+			key = fmt.Sprintf("%d", ct.ModalidadeID)
+		}
+		i, ok := g.index[key]
+		if !ok {
+			i = len(g.groups)
+			g.index[key] = i
+			g.groups = append(g.groups, ModalitySummary{ModalidadeID: ct.ModalidadeID})
+		}
+		if g.groups[i].ModalidadeNome == "" {
+			g.groups[i].ModalidadeNome = ct.ModalidadeNome
+		}
+		g.groups[i].Count++
+		g.groups[i].ValorTotalHomologado += ct.ValorTotalHomologado
+	}
+}
+
+// sorted returns the groups, largest homologated total first.
+func (g *modalityGroups) sorted() []ModalitySummary {
+	groups := g.groups
+	if groups == nil {
+		groups = []ModalitySummary{}
+	}
+	for i := range groups {
+		if groups[i].ModalidadeNome == "" {
+			groups[i].ModalidadeNome = modalityCodes[groups[i].ModalidadeID].label
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].ValorTotalHomologado > groups[j].ValorTotalHomologado
+	})
+	return groups
+}
+
+// GroupContractsByModality returns, for each modality with publications in
+// the date window (YYYYMMDD), the number of publications and the sum of their
+// homologated values. As PNCP requires a modality per query, every code of
+// its modality table is queried in turn, up to maxSummaryPages pages each.
+func (c *Client) GroupContractsByModality(ctx context.Context, startDate, endDate, state string) (*ModalitySummaryResponse, error) {
+	const pageSize = 500
+	if err := validateDateRange(startDate, endDate); err != nil {
+		return nil, err
+	}
+
+	codes := make([]int, 0, len(modalityCodes))
+	for code := range modalityCodes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	response := &ModalitySummaryResponse{
+		StartDate: startDate,
+		EndDate:   endDate,
+		State:     state,
+		Source:    "pncp_api",
+	}
+	var groups modalityGroups
+	for _, code := range codes {
+		params := url.Values{}
+		params.Set("dataInicial", startDate)
+		params.Set("dataFinal", endDate)
+		params.Set("codigoModalidadeContratacao", fmt.Sprintf("%d", code))
+		params.Set("tamanhoPagina", fmt.Sprintf("%d", pageSize))
+		if state != "" {
+			params.Set("uf", state)
+		}
+
+		for page := 1; ; page++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			result, err := c.fetchContractsPage(ctx, params, page)
+			if err != nil {
+				return nil, err
+			}
+			groups.add(result.Data)
+
+			if len(result.Data) == 0 || page*pageSize >= result.TotalRegistros {
+				break
+			}
+			if page == maxSummaryPages {
+				response.Truncated = true
+				break
+			}
+		}
+	}
+
+	response.Modalities = groups.sorted()
+	for _, m := range response.Modalities {
+		response.Total += m.Count
+		response.ValorTotalHomologado += m.ValorTotalHomologado
+	}
+	return response, nil
+}
+
 // ListModalities returns available procurement modalities.
 func (c *Client) ListModalities() map[string]int {
 	return Modalities
@@ -0,0 +1,945 @@
+package pncp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/anderson-ufrj/mcp-brasil/pkg/apierror"
+)
+
+// newTestClient returns a client whose requests are served by h, with
+// retries disabled.
+func newTestClient(t *testing.T, h http.HandlerFunc, opts ...Option) *Client {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return NewClient(append([]Option{WithBaseURL(srv.URL), WithRetry(1, 0)}, opts...)...)
+}
+
+// routes answers each request with the body registered for its path, a 204
+// for an empty body, or a 404, appending each request URI to *uris when
+// uris is not nil.
+func routes(bodies map[string]string, uris *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if uris != nil {
+			*uris = append(*uris, r.URL.RequestURI())
+		}
+		body, ok := bodies[r.URL.Path]
+		switch {
+		case !ok:
+			http.NotFound(w, r)
+		case body == "":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Write([]byte(body))
+		}
+	}
+}
+
+func TestParseControlNumber(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    ControlNumber
+		wantErr bool
+	}{
+		{input: "00394452000103-1-000123/2024", want: ControlNumber{OrgaoCNPJ: "00394452000103", Tipo: 1, Sequencial: 123, Ano: "2024"}},
+		{input: " 00394452000103-2-7/2023 ", want: ControlNumber{OrgaoCNPJ: "00394452000103", Tipo: 2, Sequencial: 7, Ano: "2023"}},
+		{input: "00.394.452/0001-03-1-000123/2024", wantErr: true},
+		{input: "00394452000103-1-000123", wantErr: true},
+		{input: "00394452000103-1-0001234567/2024", wantErr: true},
+		{input: "00394452000103-1-000123/24", wantErr: true},
+		{input: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseControlNumber(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseControlNumber() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("ParseControlNumber() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetContractDetail(t *testing.T) {
+	bodies := map[string]string{
+		"/consulta/v1/orgaos/00394452000103/compras/2024/123": `{"numeroControlePNCP":"00394452000103-1-000123/2024","objetoCompra":"Aquisição de insumos","valorTotalEstimado":150000.5,"processo":"23000.000123/2024-11","srp":true}`,
+		"/consulta/v1/orgaos/00394452000103/compras/2024/124": "",
+	}
+
+	tests := []struct {
+		name         string
+		control      string
+		wantNotFound bool
+		wantErr      bool
+	}{
+		{name: "found", control: "00394452000103-1-000123/2024"},
+		{name: "no content", control: "00394452000103-1-000124/2024", wantNotFound: true},
+		{name: "unknown", control: "00394452000103-1-000125/2024", wantNotFound: true},
+		{name: "malformed", control: "123/2024", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uris []string
+			c := newTestClient(t, routes(bodies, &uris))
+			detail, err := c.GetContractDetail(context.Background(), tt.control)
+			switch {
+			case tt.wantNotFound:
+				if !errors.Is(err, apierror.ErrNotFound) {
+					t.Fatalf("err = %v, want ErrNotFound", err)
+				}
+			case tt.wantErr:
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				if len(uris) != 0 {
+					t.Fatalf("requests sent for a malformed control number: %v", uris)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			default:
+				if detail.ObjetoCompra != "Aquisição de insumos" || detail.ValorTotalEstimado != 150000.5 || !detail.SRP || detail.ProcessoNumero == "" || detail.Source != "pncp_api" {
+					t.Errorf("detail = %+v", detail)
+				}
+			}
+		})
+	}
+}
+
+func TestGetContractItems(t *testing.T) {
+	const items = `[
+		{"numeroItem":1,"descricao":"Luva de procedimento","quantidade":1000,"unidadeMedida":"Caixa","valorUnitarioEstimado":25.9,"valorTotal":25900},
+		{"numeroItem":2,"descricao":"Máscara N95","quantidade":500,"unidadeMedida":"Unidade","valorUnitarioEstimado":4.5,"valorTotal":2250}
+	]`
+	tests := []struct {
+		name       string
+		sequencial int
+		body       string
+		wantCount  int
+		wantErr    bool
+	}{
+		{name: "items", sequencial: 123, body: items, wantCount: 2},
+		{name: "empty list", sequencial: 124, body: `[]`},
+		{name: "null", sequencial: 125, body: `null`},
+		{name: "no content", sequencial: 126, body: ""},
+		{name: "missing sequencial", sequencial: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uris []string
+			path := fmt.Sprintf("/pncp/v1/orgaos/00394452000103/compras/2024/%d/itens", tt.sequencial)
+			c := newTestClient(t, routes(map[string]string{path: tt.body}, &uris))
+			got, err := c.GetContractItems(context.Background(), "00394452000103", "2024", tt.sequencial)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got == nil || len(got) != tt.wantCount {
+				t.Fatalf("got %v, want %d items", got, tt.wantCount)
+			}
+			if len(uris) != 1 || uris[0] != path {
+				t.Errorf("requests = %v, want [%s]", uris, path)
+			}
+			if tt.wantCount > 0 {
+				it := got[0]
+				if it.Descricao != "Luva de procedimento" || it.Quantidade != 1000 || it.UnidadeMedida != "Caixa" || it.ValorUnitarioEstimado != 25.9 {
+					t.Errorf("first item = %+v", it)
+				}
+			}
+		})
+	}
+}
+
+// publicationPages serves /contratacoes/publicacao from pages, indexed by
+// the pagina parameter, counting requests in *calls. Pages past the end
+// are empty.
+func publicationPages(pages [][]ContractPublication, calls *int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/consulta/v1/contratacoes/publicacao" {
+			http.NotFound(w, r)
+			return
+		}
+		*calls++
+		result := contractsPage{Data: []ContractPublication{}, TotalPaginas: len(pages)}
+		if p, err := strconv.Atoi(r.URL.Query().Get("pagina")); err == nil && p >= 1 && p <= len(pages) {
+			result.Data = pages[p-1]
+		}
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+func TestSearchContractsKeyword(t *testing.T) {
+	objects := []ContractPublication{
+		{NumeroControlePNCP: "1", ObjetoCompra: "Aquisição de gêneros alimentícios para MERENDA ESCOLAR"},
+		{NumeroControlePNCP: "2", ObjetoCompra: "Contratação de serviços de limpeza"},
+		{NumeroControlePNCP: "3", ObjetoCompra: "Fornecimento de merenda escolar – ensino fundamental"},
+		{NumeroControlePNCP: "4", ObjetoCompra: "Aquisicao de material de construcao"},
+	}
+	tests := []struct {
+		keyword string
+		want    []string
+	}{
+		{keyword: "merenda escolar", want: []string{"1", "3"}},
+		{keyword: "Merenda Escolar", want: []string{"1", "3"}},
+		{keyword: "aquisicao", want: []string{"1", "4"}},
+		{keyword: "AQUISIÇÃO", want: []string{"1", "4"}},
+		{keyword: "construção", want: []string{"4"}},
+		{keyword: "  limpeza ", want: []string{"2"}},
+		{keyword: "ambulância", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.keyword, func(t *testing.T) {
+			var calls int
+			c := newTestClient(t, publicationPages([][]ContractPublication{objects}, &calls))
+			resp, err := c.SearchContracts(context.Background(), "20240101", "20240131", 0, "", ContractFilter{Keyword: tt.keyword}, 1, 10)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := []string{}
+			for _, contract := range resp.Contracts {
+				got = append(got, contract.NumeroControlePNCP)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("matches = %v, want %v", got, tt.want)
+			}
+			if resp.Total != len(tt.want) || resp.Keyword != strings.TrimSpace(tt.keyword) || resp.PagesScanned != 1 || calls != 1 {
+				t.Errorf("total = %d, keyword = %q, scanned = %d, calls = %d", resp.Total, resp.Keyword, resp.PagesScanned, calls)
+			}
+			if resp.HasMore {
+				t.Error("HasMore = true after the last page")
+			}
+			if (len(tt.want) == 0) != (resp.Message != "") {
+				t.Errorf("Message = %q with %d matches", resp.Message, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestSearchContractsKeywordPageCap(t *testing.T) {
+	full := make([]ContractPublication, 10)
+	for i := range full {
+		full[i] = ContractPublication{ObjetoCompra: "Serviços de vigilância"}
+	}
+	pages := make([][]ContractPublication, maxFilterPages+5)
+	for i := range pages {
+		pages[i] = full
+	}
+
+	var calls int
+	c := newTestClient(t, publicationPages(pages, &calls))
+	resp, err := c.SearchContracts(context.Background(), "20240101", "20240131", 0, "", ContractFilter{Keyword: "merenda"}, 3, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != maxFilterPages || resp.PagesScanned != maxFilterPages {
+		t.Errorf("calls = %d, scanned = %d, want %d", calls, resp.PagesScanned, maxFilterPages)
+	}
+	if len(resp.Contracts) != 0 || !resp.HasMore || resp.NextPage != 3+maxFilterPages {
+		t.Errorf("contracts = %d, HasMore = %v, NextPage = %d", len(resp.Contracts), resp.HasMore, resp.NextPage)
+	}
+}
+
+func TestSearchNextPage(t *testing.T) {
+	full := make([]ContractPublication, 10)
+	for i := range full {
+		full[i] = ContractPublication{ObjetoCompra: "Serviços de vigilância"}
+	}
+	pages := [][]ContractPublication{full, full, full[:4]}
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		filter       ContractFilter
+		page         int
+		wantScanned  int
+		wantNextPage int
+	}{
+		{name: "full page", page: 2, wantNextPage: 3},
+		{name: "last page", page: 3},
+		{name: "keyword scan to the last page", filter: ContractFilter{Keyword: "merenda"}, page: 1, wantScanned: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			c := newTestClient(t, publicationPages(pages, &calls))
+			resp, err := c.SearchContracts(ctx, "20240101", "20240131", 0, "", tt.filter, tt.page, 10)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.PagesScanned != tt.wantScanned {
+				t.Errorf("scanned = %d, want %d", resp.PagesScanned, tt.wantScanned)
+			}
+			if resp.Page != tt.page || resp.HasMore != (tt.wantNextPage > 0) || resp.NextPage != tt.wantNextPage {
+				t.Errorf("Page = %d, HasMore = %v, NextPage = %d, want next page %d", resp.Page, resp.HasMore, resp.NextPage, tt.wantNextPage)
+			}
+		})
+	}
+
+	for _, tt := range []struct {
+		count        int
+		wantNextPage int
+	}{{count: 10, wantNextPage: 3}, {count: 4}} {
+		t.Run(fmt.Sprintf("price registrations %d", tt.count), func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(map[string]any{"data": make([]PriceRegistration, tt.count)})
+			})
+			resp, err := c.SearchPriceRegistrations(ctx, "MG", 2, 10)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.HasMore != (tt.wantNextPage > 0) || resp.NextPage != tt.wantNextPage {
+				t.Errorf("HasMore = %v, NextPage = %d, want next page %d", resp.HasMore, resp.NextPage, tt.wantNextPage)
+			}
+		})
+	}
+}
+
+func TestSearchContractsValueRange(t *testing.T) {
+	values := []float64{0, 999.99, 1000, 5000, 10000, 10000.01}
+	contracts := make([]ContractPublication, len(values))
+	for i, v := range values {
+		contracts[i] = ContractPublication{ObjetoCompra: "Aquisição", ValorTotalEstimado: v}
+	}
+	tests := []struct {
+		name   string
+		filter ContractFilter
+		want   []float64
+	}{
+		{name: "no bounds", filter: ContractFilter{}, want: values},
+		{name: "inclusive range", filter: ContractFilter{MinValue: 1000, MaxValue: 10000}, want: []float64{1000, 5000, 10000}},
+		{name: "min only", filter: ContractFilter{MinValue: 10000}, want: []float64{10000, 10000.01}},
+		{name: "max only", filter: ContractFilter{MaxValue: 1000}, want: []float64{0, 999.99, 1000}},
+		{name: "negative bounds disabled", filter: ContractFilter{MinValue: -1, MaxValue: -1}, want: values},
+		{name: "with keyword", filter: ContractFilter{Keyword: "aquisicao", MaxValue: 999.99}, want: []float64{0, 999.99}},
+		{name: "empty range", filter: ContractFilter{MinValue: 6000, MaxValue: 7000}, want: []float64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			c := newTestClient(t, publicationPages([][]ContractPublication{contracts}, &calls))
+			resp, err := c.SearchContracts(context.Background(), "20240101", "20240131", 0, "", tt.filter, 1, 10)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := []float64{}
+			for _, contract := range resp.Contracts {
+				got = append(got, contract.ValorTotalEstimado)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("values = %v, want %v", got, tt.want)
+			}
+			if calls != 1 {
+				t.Errorf("calls = %d, want 1", calls)
+			}
+		})
+	}
+}
+
+func TestSortContracts(t *testing.T) {
+	contracts := []ContractPublication{
+		{NumeroControlePNCP: "a", ValorTotalEstimado: 500, DataPublicacaoPncp: "2024-03-10T09:00:00"},
+		{NumeroControlePNCP: "b", ValorTotalEstimado: 100, DataPublicacaoPncp: "2024-01-05"},
+		{NumeroControlePNCP: "c", ValorTotalEstimado: 500, DataPublicacaoPncp: ""},
+		{NumeroControlePNCP: "d", ValorTotalEstimado: 900, DataPublicacaoPncp: "2024-03-10T09:00:00"},
+		{NumeroControlePNCP: "e", ValorTotalEstimado: 100, DataPublicacaoPncp: "2024-02-01T12:30:00.123"},
+	}
+	tests := []struct {
+		mode    string
+		want    string
+		wantErr bool
+	}{
+		{mode: "", want: "abcde"},
+		{mode: SortValueDesc, want: "dacbe"},
+		{mode: SortValueAsc, want: "beacd"},
+		{mode: SortDateDesc, want: "adebc"},
+		{mode: SortDateAsc, want: "beadc"},
+		{mode: "value", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			got := append([]ContractPublication(nil), contracts...)
+			err := SortContracts(got, tt.mode)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var order strings.Builder
+			for _, contract := range got {
+				order.WriteString(contract.NumeroControlePNCP)
+			}
+			if order.String() != tt.want {
+				t.Errorf("order = %s, want %s", order.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchAllContracts(t *testing.T) {
+	const total = 700
+	handler := func(calls *int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			*calls++
+			page, _ := strconv.Atoi(r.URL.Query().Get("pagina"))
+			size, _ := strconv.Atoi(r.URL.Query().Get("tamanhoPagina"))
+			result := contractsPage{Data: []ContractPublication{}, TotalRegistros: total}
+			for i := (page - 1) * size; i < page*size && i < total; i++ {
+				result.Data = append(result.Data, ContractPublication{NumeroControlePNCP: strconv.Itoa(i)})
+			}
+			json.NewEncoder(w).Encode(result)
+		}
+	}
+	tests := []struct {
+		name         string
+		maxResults   int
+		wantLen      int
+		wantCalls    int
+		wantNextPage int
+	}{
+		{name: "all pages", maxResults: 0, wantLen: total, wantCalls: 2},
+		{name: "capped on the second page", maxResults: 600, wantLen: 600, wantCalls: 2, wantNextPage: 2},
+		{name: "capped on the first page", maxResults: 300, wantLen: 300, wantCalls: 1, wantNextPage: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			c := newTestClient(t, handler(&calls))
+			resp, err := c.SearchAllContracts(context.Background(), "20240101", "20240131", 0, "SP", tt.maxResults)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(resp.Contracts) != tt.wantLen || resp.Total != total || calls != tt.wantCalls {
+				t.Errorf("len = %d, total = %d, calls = %d", len(resp.Contracts), resp.Total, calls)
+			}
+			if resp.Contracts[len(resp.Contracts)-1].NumeroControlePNCP != strconv.Itoa(tt.wantLen-1) {
+				t.Errorf("last contract = %s, pages not concatenated in order", resp.Contracts[len(resp.Contracts)-1].NumeroControlePNCP)
+			}
+			if resp.HasMore != (tt.wantNextPage > 0) || resp.NextPage != tt.wantNextPage {
+				t.Errorf("HasMore = %v, NextPage = %d", resp.HasMore, resp.NextPage)
+			}
+		})
+	}
+
+	t.Run("cancelled", func(t *testing.T) {
+		var calls int
+		c := newTestClient(t, handler(&calls))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := c.SearchAllContracts(ctx, "20240101", "20240131", 0, "", 0); !errors.Is(err, context.Canceled) {
+			t.Errorf("error = %v, want context.Canceled", err)
+		}
+		if calls != 0 {
+			t.Errorf("calls = %d after cancellation", calls)
+		}
+	})
+}
+
+func TestSearchContractsDateValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end string
+		wantErr    string
+	}{
+		{name: "valid", start: "20240101", end: "20240131"},
+		{name: "same day", start: "20240229", end: "20240229"},
+		{name: "full span", start: "20240101", end: "20241231"},
+		{name: "dashes", start: "2024-01-01", end: "20240131", wantErr: "expected YYYYMMDD"},
+		{name: "short", start: "20240101", end: "2024013", wantErr: "expected YYYYMMDD"},
+		{name: "empty", start: "", end: "20240131", wantErr: "expected YYYYMMDD"},
+		{name: "not a date", start: "20230229", end: "20230301", wantErr: "not a valid date"},
+		{name: "month 13", start: "20240101", end: "20241301", wantErr: "not a valid date"},
+		{name: "reversed", start: "20240201", end: "20240101", wantErr: "is after end date"},
+		{name: "too long", start: "20230101", end: "20240102", wantErr: "PNCP allows at most 365"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			c := newTestClient(t, publicationPages(nil, &calls))
+			_, err := c.SearchContracts(context.Background(), tt.start, tt.end, 0, "", ContractFilter{}, 1, 10)
+			if tt.wantErr == "" {
+				if err != nil || calls != 1 {
+					t.Errorf("error = %v, calls = %d", err, calls)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to mention %q", err, tt.wantErr)
+			}
+			if calls != 0 {
+				t.Errorf("calls = %d for an invalid range", calls)
+			}
+		})
+	}
+}
+
+func TestMaxDateSpanDays(t *testing.T) {
+	defer func(old int) { MaxDateSpanDays = old }(MaxDateSpanDays)
+
+	MaxDateSpanDays = 30
+	if err := validateDateRange("20240101", "20240201"); err == nil {
+		t.Error("a 31-day range passed a 30-day limit")
+	}
+	MaxDateSpanDays = 0
+	if err := validateDateRange("20200101", "20240101"); err != nil {
+		t.Errorf("a zero limit should disable the span check: %v", err)
+	}
+}
+
+func TestModalityByName(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   int
+		wantOK bool
+	}{
+		{name: "pregao_eletronico", want: 6, wantOK: true},
+		{name: "Pregão Eletrônico", want: 6, wantOK: true},
+		{name: "  PREGAO-ELETRONICO ", want: 6, wantOK: true},
+		{name: "Concorrência Eletrônica", want: 4, wantOK: true},
+		{name: "Diálogo  Competitivo", want: 2, wantOK: true},
+		{name: "leilao_eletronico", want: 1, wantOK: true},
+		{name: "credenciamento", want: 12, wantOK: true},
+		{name: "dispensa", want: 8, wantOK: true},
+		{name: "Dispensa de Licitação", want: 8, wantOK: true},
+		{name: "Pré-qualificação", want: 11, wantOK: true},
+		{name: "pregao", wantOK: false},
+		{name: "concorrencia", wantOK: false},
+		{name: "", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ModalityByName(tt.name)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("ModalityByName(%q) = %d, %v, want %d, %v", tt.name, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestModalityByCode(t *testing.T) {
+	for name, code := range Modalities {
+		if got, ok := ModalityByCode(code); !ok || got != name {
+			t.Errorf("ModalityByCode(%d) = %q, %v, want %q", code, got, ok, name)
+		}
+	}
+	if len(Modalities) != len(modalityCodes) {
+		t.Errorf("Modalities has %d names, want one per code (%d)", len(Modalities), len(modalityCodes))
+	}
+	for _, code := range []int{0, -1, 14, 99} {
+		if got, ok := ModalityByCode(code); ok {
+			t.Errorf("ModalityByCode(%d) = %q, want no match", code, got)
+		}
+	}
+}
+
+func TestOrgaoEntidadeParsing(t *testing.T) {
+	const orgao = `{"cnpj":"00394452000103","razaoSocial":"MINISTÉRIO DA SAÚDE","poderId":"E","esferaId":"F","codigoUnidade":"250005"}`
+	bodies := map[string]string{
+		"/consulta/v1/contratacoes/publicacao": `{"data":[{"numeroControlePNCP":"1","orgaoEntidade":` + orgao + `},{"numeroControlePNCP":"2","orgaoEntidade":null}],"totalRegistros":2}`,
+		"/consulta/v1/atas-registro-preco":     `{"data":[{"numeroAta":"1/2024","orgaoEntidade":` + orgao + `}]}`,
+	}
+	want := OrgaoEntidade{
+		CNPJ:        "00394452000103",
+		RazaoSocial: "MINISTÉRIO DA SAÚDE",
+		PoderID:     "E",
+		EsferaID:    "F",
+		Extra:       map[string]interface{}{"codigoUnidade": "250005"},
+	}
+	check := func(t *testing.T, got *OrgaoEntidade) {
+		t.Helper()
+		if got == nil {
+			t.Fatal("orgaoEntidade not parsed")
+		}
+		if got.CNPJ != want.CNPJ || got.RazaoSocial != want.RazaoSocial || got.PoderID != want.PoderID || got.EsferaID != want.EsferaID {
+			t.Errorf("orgaoEntidade = %+v, want %+v", *got, want)
+		}
+		if len(got.Extra) != 1 || got.Extra["codigoUnidade"] != "250005" {
+			t.Errorf("Extra = %v, want %v", got.Extra, want.Extra)
+		}
+	}
+	c := newTestClient(t, routes(bodies, nil))
+
+	contracts, err := c.SearchContracts(context.Background(), "20240101", "20240131", 0, "", ContractFilter{}, 1, 10)
+	if err != nil {
+		t.Fatalf("SearchContracts: %v", err)
+	}
+	check(t, contracts.Contracts[0].OrgaoEntidade)
+	if contracts.Contracts[1].OrgaoEntidade != nil {
+		t.Errorf("null orgaoEntidade = %+v, want nil", contracts.Contracts[1].OrgaoEntidade)
+	}
+
+	registrations, err := c.SearchPriceRegistrations(context.Background(), "", 1, 10)
+	if err != nil {
+		t.Fatalf("SearchPriceRegistrations: %v", err)
+	}
+	check(t, registrations.Registrations[0].OrgaoEntidade)
+
+	t.Run("round trip", func(t *testing.T) {
+		data, err := json.Marshal(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got OrgaoEntidade
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		check(t, &got)
+	})
+}
+
+func TestWithBaseURL(t *testing.T) {
+	if c := NewClient(); c.baseURL != BaseURL || c.pncpURL != PNCPURL {
+		t.Errorf("default URLs = %s, %s", c.baseURL, c.pncpURL)
+	}
+
+	var uris []string
+	srv := httptest.NewServer(routes(nil, &uris))
+	defer srv.Close()
+	c := NewClient(WithRetry(1, 0), WithBaseURL(srv.URL+"/"))
+
+	c.SearchPriceRegistrations(context.Background(), "", 1, 10)
+	c.GetContractItems(context.Background(), "00394452000103", "2024", 1)
+
+	want := []string{"/consulta/v1/atas-registro-preco?", "/pncp/v1/orgaos/00394452000103/compras/2024/1/itens"}
+	if len(uris) != len(want) {
+		t.Fatalf("requests = %v, want one per API", uris)
+	}
+	for i, prefix := range want {
+		if !strings.HasPrefix(uris[i], prefix) {
+			t.Errorf("request %s, want it under %s", uris[i], prefix)
+		}
+	}
+}
+
+func TestGroupContractsByModality(t *testing.T) {
+	pregao := func(v float64) ContractPublication {
+		return ContractPublication{ModalidadeID: 6, ModalidadeNome: "Pregão - Eletrônico", ValorTotalHomologado: v}
+	}
+	// pages holds, per modality code, its total record count and pages.
+	pages := map[int]struct {
+		total int
+		pages [][]ContractPublication
+	}{
+		6: {total: 600, pages: [][]ContractPublication{{pregao(100), pregao(250.5)}, {pregao(49.5)}}},
+		8: {total: 2, pages: [][]ContractPublication{{
+			{ModalidadeID: 8, ValorTotalHomologado: 30},
+			{ModalidadeID: 8, ValorTotalHomologado: 70},
+		}}},
+	}
+
+	tests := []struct {
+		name      string
+		truncate  bool
+		want      []ModalitySummary
+		wantCalls int
+	}{
+		{
+			name: "grouped",
+			want: []ModalitySummary{
+				{ModalidadeID: 6, ModalidadeNome: "Pregão - Eletrônico", Count: 3, ValorTotalHomologado: 400},
+				{ModalidadeID: 8, ModalidadeNome: "Dispensa de Licitação", Count: 2, ValorTotalHomologado: 100},
+			},
+			wantCalls: len(modalityCodes) + 1,
+		},
+		{
+			name:     "page cap",
+			truncate: true,
+			want: []ModalitySummary{
+				{ModalidadeID: 12, ModalidadeNome: "Credenciamento", Count: maxSummaryPages, ValorTotalHomologado: 10 * maxSummaryPages},
+			},
+			wantCalls: len(modalityCodes) - 1 + maxSummaryPages,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			var ufs []string
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				q := r.URL.Query()
+				ufs = append(ufs, q.Get("uf"))
+				code, _ := strconv.Atoi(q.Get("codigoModalidadeContratacao"))
+				page, _ := strconv.Atoi(q.Get("pagina"))
+				result := contractsPage{Data: []ContractPublication{}}
+				if tt.truncate {
+					if code == 12 {
+						result.TotalRegistros = 1 << 20
+						result.Data = []ContractPublication{{ModalidadeID: 12, ValorTotalHomologado: 10}}
+					}
+				} else if m, ok := pages[code]; ok && page <= len(m.pages) {
+					result.TotalRegistros = m.total
+					result.Data = m.pages[page-1]
+				}
+				json.NewEncoder(w).Encode(result)
+			})
+
+			resp, err := c.GroupContractsByModality(context.Background(), "20240101", "20240131", "MG")
+			if err != nil {
+				t.Fatalf("GroupContractsByModality: %v", err)
+			}
+			if len(resp.Modalities) != len(tt.want) {
+				t.Fatalf("modalities = %+v, want %+v", resp.Modalities, tt.want)
+			}
+			var count int
+			var sum float64
+			for i, m := range resp.Modalities {
+				if m != tt.want[i] {
+					t.Errorf("modality %d = %+v, want %+v", i, m, tt.want[i])
+				}
+				count += m.Count
+				sum += m.ValorTotalHomologado
+			}
+			if resp.Total != count || resp.ValorTotalHomologado != sum || resp.Truncated != tt.truncate {
+				t.Errorf("total = %d, sum = %v, truncated = %v", resp.Total, resp.ValorTotalHomologado, resp.Truncated)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("%d requests, want %d", calls, tt.wantCalls)
+			}
+			for _, uf := range ufs {
+				if uf != "MG" {
+					t.Fatalf("request with uf = %q, want MG", uf)
+				}
+			}
+		})
+	}
+}
+
+func TestGroupContractsByModalityDates(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("unexpected request for an invalid date range")
+	})
+	for _, dates := range [][2]string{{"2024-01-01", "20240131"}, {"20240131", "20240101"}} {
+		if _, err := c.GroupContractsByModality(context.Background(), dates[0], dates[1], ""); err == nil {
+			t.Errorf("dates %v accepted", dates)
+		}
+	}
+}
+
+func TestSearchURLs(t *testing.T) {
+	var uris []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uris = append(uris, r.URL.RequestURI())
+		w.Write([]byte(`{"data":[],"totalRegistros":0}`))
+	}))
+	defer srv.Close()
+	c := NewClient(WithBaseURL(srv.URL), WithRetry(1, 0))
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		url    func() (string, error)
+		search func() error
+	}{
+		{
+			"SearchContracts",
+			func() (string, error) { return c.SearchContractsURL("20240101", "20240131", 6, "sp", 2, 50) },
+			func() error {
+				_, err := c.SearchContracts(ctx, "20240101", "20240131", 6, "sp", ContractFilter{}, 2, 50)
+				return err
+			},
+		},
+		{
+			"SearchPriceRegistrations",
+			func() (string, error) { return c.SearchPriceRegistrationsURL("MG", 1, 20) },
+			func() error { _, err := c.SearchPriceRegistrations(ctx, "MG", 1, 20); return err },
+		},
+		{
+			"SearchEditais",
+			func() (string, error) { return c.SearchEditaisURL("", "20240131", 8, "", 1, 10) },
+			func() error { _, err := c.SearchEditais(ctx, "", "20240131", 8, "", 1, 10); return err },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uris = nil
+			got, err := tt.url()
+			if err != nil {
+				t.Fatalf("URL: %v", err)
+			}
+			if len(uris) != 0 {
+				t.Fatalf("building the URL sent requests: %v", uris)
+			}
+			if err := tt.search(); err != nil {
+				t.Fatalf("search: %v", err)
+			}
+			if len(uris) != 1 || srv.URL+uris[0] != got {
+				t.Errorf("URL = %s, search requested %v", got, uris)
+			}
+		})
+	}
+}
+
+const editaisJSON = `{"data":[{
+	"numeroControlePNCP":"18715383000140-1-000123/2024",
+	"orgaoEntidade":{"cnpj":"18715383000140","razaoSocial":"MUNICIPIO DE BELO HORIZONTE","poderId":"E","esferaId":"M"},
+	"objetoCompra":"Aquisição de material escolar",
+	"modalidadeId":6,"modalidadeNome":"Pregão - Eletrônico",
+	"situacaoCompraId":1,"situacaoCompraNome":"Divulgada no PNCP",
+	"dataPublicacaoPncp":"2024-01-10T09:00:00",
+	"dataAberturaProposta":"2024-01-11T08:00:00","dataEncerramentoProposta":"2024-01-31T10:00:00",
+	"valorTotalEstimado":154320.5,"linkSistemaOrigem":"https://compras.example/123"
+}],"totalRegistros":31,"totalPaginas":4}`
+
+func TestSearchEditais(t *testing.T) {
+	tests := []struct {
+		name            string
+		body            string
+		startDate       string
+		modality        int
+		state           string
+		wantQuery       string
+		wantLen         int
+		wantTotal       int
+		wantFilteredOut int
+	}{
+		{
+			name: "parsed", body: editaisJSON, modality: 6, state: "MG",
+			wantQuery: "codigoModalidadeContratacao=6&dataFinal=20240131&pagina=1&tamanhoPagina=10&uf=MG",
+			wantLen:   1, wantTotal: 31,
+		},
+		{
+			name: "closing on the start date", body: editaisJSON, startDate: "20240131",
+			wantQuery: "dataFinal=20240131&pagina=1&tamanhoPagina=10",
+			wantLen:   1, wantTotal: 31,
+		},
+		{
+			name: "closing before the start date", body: editaisJSON, startDate: "20240201",
+			wantQuery: "dataFinal=20240215&pagina=1&tamanhoPagina=10",
+			wantTotal: 31, wantFilteredOut: 1,
+		},
+		{
+			name:      "no match",
+			wantQuery: "dataFinal=20240131&pagina=1&tamanhoPagina=10",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uris []string
+			c := newTestClient(t, routes(map[string]string{"/consulta/v1/contratacoes/proposta": tt.body}, &uris))
+
+			endDate := "20240131"
+			if tt.startDate > endDate {
+				endDate = "20240215"
+			}
+			resp, err := c.SearchEditais(context.Background(), tt.startDate, endDate, tt.modality, tt.state, 0, 0)
+			if err != nil {
+				t.Fatalf("SearchEditais: %v", err)
+			}
+			if want := "/consulta/v1/contratacoes/proposta?" + tt.wantQuery; len(uris) != 1 || uris[0] != want {
+				t.Errorf("requests = %v, want [%s]", uris, want)
+			}
+			if resp.Editais == nil || len(resp.Editais) != tt.wantLen || resp.Total != tt.wantTotal || resp.FilteredOut != tt.wantFilteredOut {
+				t.Fatalf("editais = %+v, total = %d, filtered out = %d", resp.Editais, resp.Total, resp.FilteredOut)
+			}
+			if tt.wantLen == 0 {
+				if resp.Message == "" {
+					t.Error("empty page has no message")
+				}
+				return
+			}
+			e := resp.Editais[0]
+			if e.ObjetoCompra != "Aquisição de material escolar" || e.ModalidadeNome != "Pregão - Eletrônico" ||
+				e.SituacaoCompraNome != "Divulgada no PNCP" || e.DataAberturaProposta != "2024-01-11T08:00:00" ||
+				e.DataEncerramentoProposta != "2024-01-31T10:00:00" || e.ValorTotalEstimado != 154320.5 {
+				t.Errorf("edital = %+v", e)
+			}
+			if e.OrgaoEntidade == nil || e.OrgaoEntidade.RazaoSocial != "MUNICIPIO DE BELO HORIZONTE" {
+				t.Errorf("orgao = %+v", e.OrgaoEntidade)
+			}
+		})
+	}
+}
+
+func TestSearchEditaisDates(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL)
+	})
+	for _, date := range []string{"", "2024-01-31", "31012024", "20240132", "2024131"} {
+		if _, err := c.SearchEditais(context.Background(), "", date, 0, "", 1, 10); err == nil {
+			t.Errorf("end date %q accepted", date)
+		}
+	}
+	for _, start := range []string{"2024-01-01", "20240132", "20240201"} {
+		if _, err := c.SearchEditais(context.Background(), start, "20240131", 0, "", 1, 10); err == nil {
+			t.Errorf("start date %q accepted with end date 20240131", start)
+		}
+	}
+}
+
+func TestSearchEmptyPage(t *testing.T) {
+	ctx := context.Background()
+	searches := []struct {
+		name   string
+		search func(c *Client) (any, error)
+	}{
+		{"SearchContracts", func(c *Client) (any, error) {
+			return c.SearchContracts(ctx, "20240101", "20240131", 6, "MG", ContractFilter{}, 1, 10)
+		}},
+		{"SearchContracts keyword", func(c *Client) (any, error) {
+			return c.SearchContracts(ctx, "20240101", "20240131", 0, "", ContractFilter{Keyword: "merenda"}, 1, 10)
+		}},
+		{"SearchAllContracts", func(c *Client) (any, error) { return c.SearchAllContracts(ctx, "20240101", "20240131", 0, "", 100) }},
+		{"SearchPriceRegistrations", func(c *Client) (any, error) { return c.SearchPriceRegistrations(ctx, "MG", 1, 10) }},
+		{"SearchEditais", func(c *Client) (any, error) { return c.SearchEditais(ctx, "", "20240131", 0, "", 1, 10) }},
+	}
+	for _, s := range searches {
+		t.Run(s.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"data":[],"totalRegistros":0,"totalPaginas":0}`))
+			})
+			resp, err := s.search(c)
+			if err != nil {
+				t.Fatalf("empty page returned error: %v", err)
+			}
+			b, err := json.Marshal(resp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got map[string]any
+			if err := json.Unmarshal(b, &got); err != nil {
+				t.Fatal(err)
+			}
+			if msg, _ := got["message"].(string); msg == "" {
+				t.Errorf("no message in %s", b)
+			}
+			if got["total"] != 0.0 || got["has_more"] != false {
+				t.Errorf("empty page reported as %s", b)
+			}
+			for k, v := range got {
+				if v == nil {
+					t.Errorf("%s is null in %s", k, b)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchPriceRegistrationsBlankBody(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {})
+	if resp, err := c.SearchPriceRegistrations(context.Background(), "MG", 1, 10); err == nil {
+		t.Errorf("blank body decoded as %+v, want an error", resp)
+	}
+}
@@ -0,0 +1,97 @@
+// Package retry repeats failed upstream requests with exponential backoff.
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/anderson-ufrj/mcp-brasil/pkg/apierror"
+)
+
+// Policy configures how a request is retried.
+type Policy struct {
+	// MaxAttempts is the total number of attempts, the first one included.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// BaseDelay is the wait before the second attempt; it doubles after
+	// each further failure.
+	BaseDelay time.Duration
+}
+
+// Default is the policy used by the clients unless configured otherwise.
+var Default = Policy{MaxAttempts: 3, BaseDelay: 500 * time.Millisecond}
+
+// MaxRetryAfter is the longest Retry-After wait honored; a 429 asking for
+// more is returned to the caller instead of retried.
+const MaxRetryAfter = 30 * time.Second
+
+// Retryable reports whether err is transient: a 429 or 5xx response, a
+// refused or reset connection, or a connection closed mid-response. Errors
+// that would fail the same way again, such as an unknown host or an
+// unsupported URL scheme, are not retried. Neither are timeouts, as the
+// request may already have used most of the caller's patience.
+func Retryable(err error) bool {
+	var apiErr *apierror.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= http.StatusInternalServerError
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return false
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary && !dnsErr.IsNotFound
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// retryAfter returns the wait a 429 response asked for, or zero.
+func retryAfter(err error) time.Duration {
+	var apiErr *apierror.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests {
+		return apiErr.RetryAfter
+	}
+	return 0
+}
+
+// Do calls fn until it succeeds, fails with an error that is not Retryable,
+// or MaxAttempts is reached, and returns the last result. After a 429 it
+// waits as long as the Retry-After header asked, when that is longer than
+// the backoff and at most MaxRetryAfter. Waits between attempts end early,
+// with ctx.Err(), when ctx is done.
+func (p Policy) Do(ctx context.Context, fn func() ([]byte, error)) ([]byte, error) {
+	delay := p.BaseDelay
+	for attempt := 1; ; attempt++ {
+		body, err := fn()
+		if err == nil || attempt >= p.MaxAttempts || !Retryable(err) {
+			return body, err
+		}
+
+		wait := delay
+		if after := retryAfter(err); after > MaxRetryAfter {
+			return body, err
+		} else if after > wait {
+			wait = after
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
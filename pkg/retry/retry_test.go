@@ -0,0 +1,145 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/anderson-ufrj/mcp-brasil/pkg/apierror"
+)
+
+func TestDoCancelDuringBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	start := time.Now()
+	_, err := Policy{MaxAttempts: 5, BaseDelay: time.Hour}.Do(ctx, func() ([]byte, error) {
+		attempts++
+		cancel()
+		return nil, apierror.New(http.StatusServiceUnavailable, nil)
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Do returned after %v, want it to stop waiting on cancellation", elapsed)
+	}
+}
+
+func TestDo(t *testing.T) {
+	unavailable := apierror.New(http.StatusServiceUnavailable, nil)
+	tests := []struct {
+		name         string
+		errs         []error // the first results, then success
+		wantErr      error
+		wantAttempts int
+		minElapsed   time.Duration
+	}{
+		{name: "503 then 200", errs: []error{unavailable}, wantAttempts: 2},
+		{name: "502 and 503 then 200", errs: []error{apierror.New(http.StatusBadGateway, nil), unavailable}, wantAttempts: 3},
+		{name: "always 503", errs: []error{unavailable, unavailable, unavailable}, wantErr: apierror.ErrUpstream, wantAttempts: 3},
+		{name: "404 is not retried", errs: []error{apierror.New(http.StatusNotFound, nil)}, wantErr: apierror.ErrNotFound, wantAttempts: 1},
+		{
+			name:         "429 waits for Retry-After",
+			errs:         []error{&apierror.APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 50 * time.Millisecond}},
+			wantAttempts: 2,
+			minElapsed:   50 * time.Millisecond,
+		},
+		{
+			name:         "429 asking too long a wait is not retried",
+			errs:         []error{&apierror.APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: MaxRetryAfter + time.Second}},
+			wantErr:      apierror.ErrRateLimited,
+			wantAttempts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			start := time.Now()
+			body, err := Policy{MaxAttempts: 3, BaseDelay: time.Millisecond}.Do(context.Background(), func() ([]byte, error) {
+				attempts++
+				if attempts <= len(tt.errs) {
+					return nil, tt.errs[attempts-1]
+				}
+				return []byte("ok"), nil
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && string(body) != "ok" {
+				t.Errorf("body = %q", body)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("%d attempts, want %d", attempts, tt.wantAttempts)
+			}
+			if elapsed := time.Since(start); elapsed < tt.minElapsed {
+				t.Errorf("retried after %v, want at least %v", elapsed, tt.minElapsed)
+			}
+		})
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "429", err: apierror.New(http.StatusTooManyRequests, nil), want: true},
+		{name: "500", err: apierror.New(http.StatusInternalServerError, nil), want: true},
+		{name: "404", err: apierror.New(http.StatusNotFound, nil)},
+		{name: "401", err: apierror.New(http.StatusUnauthorized, nil)},
+		{name: "connection refused", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, want: true},
+		{name: "unexpected EOF", err: fmt.Errorf("reading body: %w", io.ErrUnexpectedEOF), want: true},
+		{name: "unknown host", err: &net.DNSError{Err: "no such host", IsNotFound: true}},
+		{name: "canceled", err: fmt.Errorf("executing request: %w", context.Canceled)},
+		{name: "deadline", err: context.DeadlineExceeded},
+		{name: "other", err: errors.New("unsupported protocol scheme")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Retryable(tt.err); got != tt.want {
+				t.Errorf("Retryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDoCancelMidFlight(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	attempts := 0
+	done := make(chan error, 1)
+	go func() {
+		_, err := Policy{MaxAttempts: 3, BaseDelay: time.Hour}.Do(ctx, func() ([]byte, error) {
+			attempts++
+			close(started)
+			<-ctx.Done()
+			return nil, fmt.Errorf("executing request: %w", ctx.Err())
+		})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("error = %v, want context.Canceled", err)
+		}
+		if attempts != 1 {
+			t.Errorf("attempts = %d, want 1", attempts)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Do did not return after cancellation")
+	}
+}
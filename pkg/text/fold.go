@@ -0,0 +1,32 @@
+// Package text normalizes Portuguese text so that names and keywords can be
+// compared regardless of case and accents.
+package text
+
+import "strings"
+
+// accentReplacer maps accented Portuguese letters to their unaccented form.
+var accentReplacer = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "õ", "o", "ö", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "ñ", "n",
+	"Á", "A", "À", "A", "Â", "A", "Ã", "A", "Ä", "A",
+	"É", "E", "È", "E", "Ê", "E", "Ë", "E",
+	"Í", "I", "Ì", "I", "Î", "I", "Ï", "I",
+	"Ó", "O", "Ò", "O", "Ô", "O", "Õ", "O", "Ö", "O",
+	"Ú", "U", "Ù", "U", "Û", "U", "Ü", "U",
+	"Ç", "C", "Ñ", "N",
+)
+
+// StripAccents removes diacritics from Portuguese text (é→e, ã→a, ç→c).
+func StripAccents(s string) string {
+	return accentReplacer.Replace(s)
+}
+
+// Fold trims and lowercases s and strips its accents, so that "São Paulo",
+// " sao paulo" and "SÃO PAULO" fold to the same string.
+func Fold(s string) string {
+	return strings.ToLower(StripAccents(strings.TrimSpace(s)))
+}
@@ -0,0 +1,30 @@
+package text
+
+import "testing"
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "São Paulo", want: "sao paulo"},
+		{in: " sao paulo ", want: "sao paulo"},
+		{in: "SÃO PAULO", want: "sao paulo"},
+		{in: "sÃo", want: "sao"},
+		{in: "Florianópolis", want: "florianopolis"},
+		{in: "Ceará-Mirim", want: "ceara-mirim"},
+		{in: "Açaílândia", want: "acailandia"},
+		{in: "", want: ""},
+	}
+	for _, tt := range tests {
+		if got := Fold(tt.in); got != tt.want {
+			t.Errorf("Fold(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestStripAccents(t *testing.T) {
+	if got := StripAccents("Ação Pública Ñandú Über"); got != "Acao Publica Nandu Uber" {
+		t.Errorf("StripAccents() = %q", got)
+	}
+}
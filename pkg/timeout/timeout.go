@@ -0,0 +1,32 @@
+// Package timeout lets a single call replace the default HTTP timeout of a
+// client, to shorten or extend it.
+package timeout
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// overrideKey marks a context whose deadline replaces the client timeout.
+type overrideKey struct{}
+
+// WithOverride returns a copy of parent that times out after d, marked so
+// that Client lets this deadline replace the client timeout instead of
+// only shortening it.
+func WithOverride(parent context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(parent, d)
+	return context.WithValue(ctx, overrideKey{}, true), cancel
+}
+
+// Client returns the HTTP client to use for a request made with ctx: client
+// itself, or a copy without its Timeout when ctx comes from WithOverride.
+// Any other deadline on ctx still applies on top of the client timeout.
+func Client(ctx context.Context, client *http.Client) *http.Client {
+	if ctx.Value(overrideKey{}) == nil {
+		return client
+	}
+	c := *client
+	c.Timeout = 0
+	return &c
+}
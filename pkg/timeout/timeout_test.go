@@ -0,0 +1,75 @@
+package timeout
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClient(t *testing.T) {
+	base := &http.Client{Timeout: 30 * time.Second}
+
+	if got := Client(context.Background(), base); got != base {
+		t.Error("Client replaced the client for a context without override")
+	}
+	plain, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if got := Client(plain, base); got != base {
+		t.Error("Client replaced the client for a plain deadline")
+	}
+
+	ctx, cancel := WithOverride(context.Background(), time.Second)
+	defer cancel()
+	got := Client(ctx, base)
+	if got == base || got.Timeout != 0 {
+		t.Errorf("override client = %+v, want a copy without timeout", got)
+	}
+	if base.Timeout != 30*time.Second {
+		t.Errorf("base client timeout changed to %v", base.Timeout)
+	}
+	if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > time.Second {
+		t.Errorf("deadline = %v, %v", deadline, ok)
+	}
+}
+
+func TestOverrideShortensAndExtends(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	get := func(ctx context.Context, client *http.Client) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := Client(ctx, client).Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	short := &http.Client{Timeout: 50 * time.Millisecond}
+	if err := get(context.Background(), short); err == nil {
+		t.Error("slow request succeeded within the client timeout")
+	}
+	ctx, cancel := WithOverride(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := get(ctx, short); err != nil {
+		t.Errorf("override did not extend the client timeout: %v", err)
+	}
+
+	long := &http.Client{Timeout: time.Minute}
+	ctx, cancel = WithOverride(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := get(ctx, long); err == nil || time.Since(start) > 150*time.Millisecond {
+		t.Errorf("override did not shorten the client timeout: %v after %v", err, time.Since(start))
+	}
+}
@@ -2,18 +2,55 @@
 package transparencia
 
 import (
+	"bytes"
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/anderson-ufrj/mcp-brasil/pkg/apierror"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/cnpj"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/dedup"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/document"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/health"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/httpbody"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/httplog"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/metrics"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/paging"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/retry"
+	"golang.org/x/sync/singleflight"
 )
 
 const (
 	BaseURL        = "https://api.portaldatransparencia.gov.br/api-de-dados"
 	DefaultTimeout = 30 * time.Second
+
+	// maxOrgaoPages caps how many pages FetchOrgaos reads from /orgaos-siafi.
+	maxOrgaoPages = 50
+	// orgaosRetryDelay is how long a failed organization fetch is
+	// remembered before the next lookup tries again.
+	orgaosRetryDelay = time.Minute
+	// orgaosFetchTimeout bounds a shared organization fetch, which runs
+	// detached from the context of the caller that started it.
+	orgaosFetchTimeout = 2 * DefaultTimeout
+
+	// DateLayout is the date format accepted by the client methods.
+	DateLayout = "2006-01-02"
+	// apiDateLayout is the date format expected by the Portal API.
+	apiDateLayout = "02/01/2006"
+	// MesAnoLayout is the MM/YYYY period format used by monthly endpoints.
+	MesAnoLayout = "01/2006"
 )
 
 // Known organization codes (SIAPE)
@@ -30,50 +67,159 @@ var KnownOrgaos = map[string]string{
 // Client represents the Portal da Transparencia API client.
 type Client struct {
 	httpClient *http.Client
+	logger     *slog.Logger
+	metrics    metrics.Collector
+	flight     *dedup.Group
+	retry      retry.Policy
 	apiKey     string
 	baseURL    string
+	exportDir  string
+
+	orgaosFlight singleflight.Group
+	orgaosMu     sync.Mutex
+	orgaos       []Orgao
+	orgaosErr    error
+	orgaosErrAt  time.Time
+	orgaoNames   map[string]orgaoLookup
+}
+
+// orgaoLookup is the cached result of looking up a single organization
+// code: its name, empty when unknown, or the error of the last attempt.
+type orgaoLookup struct {
+	name string
+	err  error
+	at   time.Time
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithBaseURL points the client at another API root, such as a mirror or a
+// test server, instead of BaseURL.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithLogger logs the Portal da Transparencia requests on l at debug level, as described
+// in httplog.Request. A nil l disables logging.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Client) {
+		c.logger = httplog.OrDiscard(l)
+	}
+}
+
+// WithMetrics records every request (status class and latency) on m.
+func WithMetrics(m metrics.Collector) Option {
+	return func(c *Client) {
+		c.metrics = m
+	}
+}
+
+// WithDeduplication makes concurrent identical requests share a single
+// upstream call.
+func WithDeduplication() Option {
+	return func(c *Client) {
+		c.flight = &dedup.Group{}
+	}
+}
+
+// WithExportDir allows ExportContracts to write files, only inside dir.
+// Without it exports are refused.
+func WithExportDir(dir string) Option {
+	return func(c *Client) {
+		c.exportDir = dir
+	}
+}
+
+// WithRetry sets how many times a request failing with a 429, a 5xx or a
+// connection error is attempted in total, and the delay before the first
+// retry, which doubles on each further one. maxAttempts below 2 disables
+// retries.
+func WithRetry(maxAttempts int, baseDelay time.Duration) Option {
+	return func(c *Client) {
+		c.retry = retry.Policy{MaxAttempts: maxAttempts, BaseDelay: baseDelay}
+	}
 }
 
 // NewClient creates a new Portal da Transparencia client.
-func NewClient(apiKey string) *Client {
-	return &Client{
+func NewClient(apiKey string, opts ...Option) *Client {
+	c := &Client{
 		httpClient: &http.Client{Timeout: DefaultTimeout},
+		logger:     httplog.Discard,
+		retry:      retry.Default,
 		apiKey:     apiKey,
 		baseURL:    BaseURL,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
-// doRequest performs an HTTP request to the API.
-func (c *Client) doRequest(ctx context.Context, endpoint string, params url.Values) ([]byte, error) {
+// Ping checks that the Portal da Transparencia API is reachable (see health.Ping).
+func (c *Client) Ping(ctx context.Context) error {
+	return health.Ping(ctx, c.httpClient, c.logger, c.baseURL)
+}
+
+// requestURL returns the URL of a request to endpoint with params.
+func (c *Client) requestURL(endpoint string, params url.Values) string {
 	reqURL := fmt.Sprintf("%s%s", c.baseURL, endpoint)
 	if len(params) > 0 {
 		reqURL = fmt.Sprintf("%s?%s", reqURL, params.Encode())
 	}
+	return reqURL
+}
+
+// doRequest performs an HTTP request to the API. Every endpoint requires an
+// API key, so requests fail fast with ErrUnauthorized when none is set.
+func (c *Client) doRequest(ctx context.Context, endpoint string, params url.Values) ([]byte, error) {
+	reqURL := c.requestURL(endpoint, params)
+
+	if c.apiKey == "" {
+		return nil, fmt.Errorf("%w: TRANSPARENCY_API_KEY is required for this endpoint", apierror.ErrUnauthorized)
+	}
+
+	return c.flight.Do(reqURL, func() ([]byte, error) {
+		return c.retry.Do(ctx, func() ([]byte, error) {
+			return c.fetch(ctx, reqURL)
+		})
+	})
+}
 
+// fetch performs a GET request and returns the body of a 200 response.
+func (c *Client) fetch(ctx context.Context, reqURL string) ([]byte, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 
 	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept-Encoding", httpbody.AcceptEncoding)
 	req.Header.Set("User-Agent", "MCP-Brasil/1.0 (Go)")
-	if c.apiKey != "" {
-		req.Header.Set("chave-api-dados", c.apiKey)
-	}
+	req.Header.Set("chave-api-dados", c.apiKey)
 
+	start := time.Now()
 	resp, err := c.httpClient.Do(req)
+	duration := time.Since(start)
+	httplog.Request(ctx, c.logger, req, resp, err, duration)
+	metrics.Observe(c.metrics, "transparencia", resp, err, duration)
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
 		return nil, fmt.Errorf("executing request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := httpbody.Read(resp)
 	if err != nil {
 		return nil, fmt.Errorf("reading response: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, apierror.FromResponse(resp, body)
 	}
 
 	return body, nil
@@ -101,292 +247,2029 @@ type Contract struct {
 // ContractsResponse represents the API response for contracts.
 type ContractsResponse struct {
 	Contracts []Contract `json:"contratos"`
-	Total     int        `json:"total"`
-	Page      int        `json:"pagina"`
-	PageSize  int        `json:"tamanhoPagina"`
-	OrgaoCode string     `json:"orgaoConsultado"`
-	OrgaoName string     `json:"orgaoNome"`
-	Source    string     `json:"source"`
+	PageCount int        `json:"registrosNaPagina"`
+	PageInfo
+	OrgaoCode string `json:"orgaoConsultado"`
+	OrgaoName string `json:"orgaoNome"`
+	Source    string `json:"source"`
 }
 
-// SearchContracts searches for government contracts.
-func (c *Client) SearchContracts(ctx context.Context, orgaoCode string, page, pageSize int) (*ContractsResponse, error) {
-	if orgaoCode == "" {
-		orgaoCode = "36000" // Default: Ministerio da Saude
+// validateEndpoint checks that endpoint is an API path such as "/ceis" that
+// cannot escape the API root.
+func validateEndpoint(endpoint string) error {
+	// Check the path the server will see, so that %2e%2e/ cannot hide a "..".
+	decoded, err := url.PathUnescape(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint %q: %w", endpoint, err)
 	}
-	if page < 1 {
-		page = 1
+	if strings.Contains(decoded, "%") {
+		return fmt.Errorf("invalid endpoint %q: must not be percent-encoded twice", endpoint)
 	}
-	if pageSize < 1 || pageSize > 500 {
-		pageSize = 100
+	if !strings.HasPrefix(decoded, "/") || strings.HasPrefix(decoded, "//") {
+		return fmt.Errorf("invalid endpoint %q: must start with a single /", endpoint)
 	}
+	if strings.Contains(decoded, "..") {
+		return fmt.Errorf("invalid endpoint %q: must not contain ..", endpoint)
+	}
+	if strings.ContainsAny(decoded, "?#\\") {
+		return fmt.Errorf("invalid endpoint %q: must not contain ?, # or \\ (pass query parameters separately)", endpoint)
+	}
+	return nil
+}
 
-	params := url.Values{}
-	params.Set("codigoOrgao", orgaoCode)
-	params.Set("pagina", fmt.Sprintf("%d", page))
-	params.Set("tamanhoPagina", fmt.Sprintf("%d", pageSize))
-
-	body, err := c.doRequest(ctx, "/contratos", params)
-	if err != nil {
+// Query calls any API endpoint with the given query parameters and returns
+// the raw JSON, for endpoints the client does not model yet.
+func (c *Client) Query(ctx context.Context, endpoint string, params map[string]string) (json.RawMessage, error) {
+	if err := validateEndpoint(endpoint); err != nil {
 		return nil, err
 	}
 
-	var contracts []Contract
-	if err := json.Unmarshal(body, &contracts); err != nil {
-		return nil, fmt.Errorf("parsing response: %w", err)
+	values := url.Values{}
+	for key, value := range params {
+		values.Set(key, value)
 	}
 
-	orgaoName := KnownOrgaos[orgaoCode]
-	if orgaoName == "" {
-		orgaoName = "Orgao Desconhecido"
+	body, err := c.doRequest(ctx, endpoint, values)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("parsing response: endpoint %s did not return JSON", endpoint)
 	}
+	return json.RawMessage(body), nil
+}
 
-	return &ContractsResponse{
-		Contracts: contracts,
-		Total:     len(contracts),
-		Page:      page,
-		PageSize:  pageSize,
-		OrgaoCode: orgaoCode,
-		OrgaoName: orgaoName,
-		Source:    "portal_transparencia_api",
-	}, nil
+// errorObject is the body some endpoints return instead of a list when a
+// parameter is rejected.
+type errorObject struct {
+	Message string `json:"message"`
+	Error   string `json:"error"`
 }
 
-// Servidor represents a public servant.
-type Servidor struct {
-	ID               int64   `json:"id"`
-	CPF              string  `json:"cpf"`
-	Nome             string  `json:"nome"`
-	Matricula        string  `json:"matricula"`
-	CodigoOrgao      string  `json:"codigoOrgaoLotacao"`
-	NomeOrgao        string  `json:"nomeOrgaoLotacao"`
-	CodigoUorg       string  `json:"codigoUorgLotacao"`
-	NomeUorg         string  `json:"nomeUorgLotacao"`
-	TipoVinculo      string  `json:"tipoVinculo"`
-	SituacaoVinculo  string  `json:"situacaoVinculo"`
-	DataIngressoCarg string  `json:"dataIngressoCargo"`
+// decodeList unmarshals a list response into v. When the API answered with
+// an error object instead, its message is returned as the error. A null body
+// decodes as an empty list, but a blank one is an error: it is a truncated
+// reply, not the absence of results.
+func decodeList(body []byte, v any) error {
+	if string(bytes.TrimSpace(body)) == "null" {
+		body = []byte("[]")
+	}
+	err := json.Unmarshal(body, v)
+	if err == nil {
+		return nil
+	}
+	var obj errorObject
+	if json.Unmarshal(body, &obj) == nil {
+		if obj.Message != "" {
+			return fmt.Errorf("%w: %s", apierror.ErrUpstream, obj.Message)
+		}
+		if obj.Error != "" {
+			return fmt.Errorf("%w: %s", apierror.ErrUpstream, obj.Error)
+		}
+	}
+	return fmt.Errorf("parsing response: %w", err)
 }
 
-// ServidoresResponse represents the API response for public servants.
-type ServidoresResponse struct {
-	Servidores []Servidor `json:"servidores"`
-	Total      int        `json:"total"`
-	Page       int        `json:"pagina"`
-	PageSize   int        `json:"tamanhoPagina"`
-	Source     string     `json:"source"`
+// PageInfo describes the position of a page of results. HasMore is inferred
+// from the page being full, as the API does not report totals.
+type PageInfo struct {
+	Page     int  `json:"pagina"`
+	PageSize int  `json:"tamanhoPagina"`
+	HasMore  bool `json:"temMaisPaginas"`
+	NextPage int  `json:"proximaPagina"`
+	// Message says that nothing was found when the page is empty.
+	Message string `json:"message,omitempty"`
 }
 
-// SearchServidores searches for public servants by name.
-func (c *Client) SearchServidores(ctx context.Context, nome string, page, pageSize int) (*ServidoresResponse, error) {
-	if nome == "" {
-		return nil, fmt.Errorf("nome is required")
-	}
+// newPageInfo builds the PageInfo of a page holding count records, as
+// paging.New does, keeping the keys of the other transparência fields.
+func newPageInfo(page, pageSize, count int) PageInfo {
+	return PageInfo(paging.New(page, pageSize, count))
+}
+
+// pageBounds applies the defaults of the search methods: page 1 and 100
+// records per page, the API accepting at most 500.
+func pageBounds(page, pageSize int) (int, int) {
 	if page < 1 {
 		page = 1
 	}
 	if pageSize < 1 || pageSize > 500 {
 		pageSize = 100
 	}
+	return page, pageSize
+}
 
-	params := url.Values{}
-	params.Set("nome", nome)
-	params.Set("pagina", fmt.Sprintf("%d", page))
-	params.Set("tamanhoPagina", fmt.Sprintf("%d", pageSize))
+// queryPage returns the page and page size of a query built by one of the
+// *Params functions, after pageBounds.
+func queryPage(params url.Values) (int, int) {
+	page, _ := strconv.Atoi(params.Get("pagina"))
+	pageSize, _ := strconv.Atoi(params.Get("tamanhoPagina"))
+	return page, pageSize
+}
 
-	body, err := c.doRequest(ctx, "/servidores", params)
+// queryDate returns the date held under key by a query built by one of the
+// *Params functions, as YYYY-MM-DD, or "" when it has none.
+func queryDate(params url.Values, key string) string {
+	t, err := time.Parse(apiDateLayout, params.Get(key))
 	if err != nil {
-		return nil, err
+		return ""
 	}
+	return t.Format(DateLayout)
+}
 
-	var servidores []Servidor
-	if err := json.Unmarshal(body, &servidores); err != nil {
-		return nil, fmt.Errorf("parsing response: %w", err)
+// parseDateRange validates an optional YYYY-MM-DD date window and converts
+// the provided bounds to the DD/MM/YYYY format used by the API. Empty bounds
+// are returned empty.
+func parseDateRange(startDate, endDate string) (string, string, error) {
+	var start, end time.Time
+	var err error
+
+	if startDate != "" {
+		start, err = time.Parse(DateLayout, startDate)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid start date %q: expected YYYY-MM-DD", startDate)
+		}
+	}
+	if endDate != "" {
+		end, err = time.Parse(DateLayout, endDate)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid end date %q: expected YYYY-MM-DD", endDate)
+		}
+	}
+	if !start.IsZero() && !end.IsZero() && start.After(end) {
+		return "", "", fmt.Errorf("start date %s is after end date %s", startDate, endDate)
 	}
 
-	return &ServidoresResponse{
-		Servidores: servidores,
-		Total:      len(servidores),
-		Page:       page,
-		PageSize:   pageSize,
-		Source:     "portal_transparencia_api",
-	}, nil
+	var apiStart, apiEnd string
+	if !start.IsZero() {
+		apiStart = start.Format(apiDateLayout)
+	}
+	if !end.IsZero() {
+		apiEnd = end.Format(apiDateLayout)
+	}
+	return apiStart, apiEnd, nil
 }
 
-// Remuneracao represents a public servant's salary.
-type Remuneracao struct {
-	MesAno                 string  `json:"mesAno"`
-	RemuneracaoBasicaBruta float64 `json:"remuneracaoBasicaBruta"`
-	AbateGratificacao      float64 `json:"abateGratificacao"`
-	GratificacaoNatalina   float64 `json:"gratificacaoNatalina"`
-	AbateTeto              float64 `json:"abateTeto"`
-	RendimentoLiquido      float64 `json:"rendimentoLiquido"`
+// defaultWindow fills in a missing date window (YYYY-MM-DD): the end
+// defaults to today and the start to 30 days before the end.
+func defaultWindow(dataInicio, dataFim string) (string, string, error) {
+	if dataFim == "" {
+		dataFim = time.Now().Format(DateLayout)
+	}
+	if dataInicio == "" {
+		end, err := time.Parse(DateLayout, dataFim)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid end date %q: expected YYYY-MM-DD", dataFim)
+		}
+		dataInicio = end.AddDate(0, 0, -30).Format(DateLayout)
+	}
+	return dataInicio, dataFim, nil
 }
 
-// RemuneracaoResponse represents the API response for salary data.
-type RemuneracaoResponse struct {
-	CPF         string        `json:"cpf"`
-	Remuneracao []Remuneracao `json:"remuneracao"`
-	MesAno      string        `json:"mesAno"`
-	Source      string        `json:"source"`
+// previousMonth returns last month as MM/YYYY, the default period of the
+// monthly endpoints.
+func previousMonth() string {
+	return time.Now().AddDate(0, -1, 0).Format(MesAnoLayout)
 }
 
-// GetServidorRemuneracao gets salary data for a public servant by CPF.
-func (c *Client) GetServidorRemuneracao(ctx context.Context, cpf, mesAno string) (*RemuneracaoResponse, error) {
-	if cpf == "" {
-		return nil, fmt.Errorf("cpf is required")
-	}
-	if mesAno == "" {
-		// Default to last month
-		lastMonth := time.Now().AddDate(0, -1, 0)
-		mesAno = lastMonth.Format("01/2006")
+// parseMesAno validates a MM/YYYY period.
+func parseMesAno(mesAno string) (time.Time, error) {
+	t, err := time.Parse(MesAnoLayout, mesAno)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid period %q: expected MM/YYYY", mesAno)
 	}
+	return t, nil
+}
 
-	params := url.Values{}
-	params.Set("mesAno", mesAno)
-
-	endpoint := fmt.Sprintf("/servidores/%s/remuneracao", cpf)
-	body, err := c.doRequest(ctx, endpoint, params)
+// SearchContracts searches for government contracts. The optional
+// dataInicial and dataFinal (YYYY-MM-DD) restrict results by the start of
+// the contract's vigência, and cnpjContratado restricts them to a supplier.
+func (c *Client) SearchContracts(ctx context.Context, orgaoCode, dataInicial, dataFinal, cnpjContratado string, page, pageSize int) (*ContractsResponse, error) {
+	params, err := contractsParams(orgaoCode, dataInicial, dataFinal, cnpjContratado, page, pageSize)
 	if err != nil {
 		return nil, err
 	}
+	page, pageSize = queryPage(params)
 
-	var remuneracoes []Remuneracao
-	if err := json.Unmarshal(body, &remuneracoes); err != nil {
-		return nil, fmt.Errorf("parsing response: %w", err)
+	body, err := c.doRequest(ctx, "/contratos", params)
+	if err != nil {
+		return nil, err
 	}
 
-	return &RemuneracaoResponse{
-		CPF:         cpf,
-		Remuneracao: remuneracoes,
-		MesAno:      mesAno,
-		Source:      "portal_transparencia_api",
-	}, nil
-}
+	var contracts []Contract
+	if err := decodeList(body, &contracts); err != nil {
+		return nil, err
+	}
 
-// Convenio represents a government agreement/covenant.
-type Convenio struct {
-	Numero          string  `json:"numero"`
-	Objeto          string  `json:"objeto"`
-	SituacaoConveni string  `json:"situacaoConvenio"`
-	ValorLiberado   float64 `json:"valorLiberado"`
-	ValorConvenio   float64 `json:"valorConvenio"`
-	UF              string  `json:"uf"`
-	Municipio       string  `json:"municipio"`
-	OrgaoSuperior   string  `json:"orgaoSuperior"`
-	DataInicio      string  `json:"dataInicioVigencia"`
-	DataFim         string  `json:"dataFimVigencia"`
-}
+	orgaoCode = params.Get("codigoOrgao")
+	orgaoName := c.orgaoName(ctx, orgaoCode)
 
-// ConveniosResponse represents the API response for agreements.
-type ConveniosResponse struct {
-	Convenios []Convenio `json:"convenios"`
-	Total     int        `json:"total"`
-	Page      int        `json:"pagina"`
-	PageSize  int        `json:"tamanhoPagina"`
-	UF        string     `json:"uf"`
-	Source    string     `json:"source"`
+	return &ContractsResponse{
+		Contracts: contracts,
+		PageCount: len(contracts),
+		PageInfo:  newPageInfo(page, pageSize, len(contracts)),
+		OrgaoCode: orgaoCode,
+		OrgaoName: orgaoName,
+		Source:    "portal_transparencia_api",
+	}, nil
 }
 
-// SearchConvenios searches for government agreements by state.
-func (c *Client) SearchConvenios(ctx context.Context, uf string, page, pageSize int) (*ConveniosResponse, error) {
-	if uf == "" {
-		uf = "MG" // Default: Minas Gerais
+// contractsParams builds the query of SearchContracts, validating its
+// arguments.
+func contractsParams(orgaoCode, dataInicial, dataFinal, cnpjContratado string, page, pageSize int) (url.Values, error) {
+	if orgaoCode == "" {
+		orgaoCode = "36000" // Default: Ministerio da Saude
 	}
-	if page < 1 {
-		page = 1
+	apiStart, apiEnd, err := parseDateRange(dataInicial, dataFinal)
+	if err != nil {
+		return nil, err
 	}
-	if pageSize < 1 || pageSize > 500 {
-		pageSize = 100
+	if cnpjContratado != "" {
+		cnpjContratado, err = cnpj.Validate(cnpjContratado)
+		if err != nil {
+			return nil, err
+		}
 	}
+	page, pageSize = pageBounds(page, pageSize)
 
 	params := url.Values{}
-	params.Set("uf", uf)
+	params.Set("codigoOrgao", orgaoCode)
+	if apiStart != "" {
+		params.Set("dataInicial", apiStart)
+	}
+	if apiEnd != "" {
+		params.Set("dataFinal", apiEnd)
+	}
+	if cnpjContratado != "" {
+		params.Set("cnpjContratado", cnpjContratado)
+	}
 	params.Set("pagina", fmt.Sprintf("%d", page))
 	params.Set("tamanhoPagina", fmt.Sprintf("%d", pageSize))
+	return params, nil
+}
 
-	body, err := c.doRequest(ctx, "/convenios", params)
+// SearchContractsURL returns the URL SearchContracts would request with the
+// same arguments, without calling the API.
+func (c *Client) SearchContractsURL(orgaoCode, dataInicial, dataFinal, cnpjContratado string, page, pageSize int) (string, error) {
+	params, err := contractsParams(orgaoCode, dataInicial, dataFinal, cnpjContratado, page, pageSize)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
+	return c.requestURL("/contratos", params), nil
+}
 
-	var convenios []Convenio
-	if err := json.Unmarshal(body, &convenios); err != nil {
-		return nil, fmt.Errorf("parsing response: %w", err)
+// exportPageSize is the page size used when exporting contracts, the
+// largest the API accepts.
+const exportPageSize = 500
+
+// maxContractPages caps the pages fetched by ExportContracts and
+// AggregateContracts, i.e. at most 50000 contracts.
+const maxContractPages = 100
+
+// contractCSVHeader lists the columns of a contracts CSV export.
+var contractCSVHeader = []string{
+	"id", "numero", "objeto", "numeroProcesso", "fundamentoLegal", "dataAssinatura",
+	"dataVigenciaInicio", "dataVigenciaFim", "valorInicial", "situacao", "modalidadeCompra",
+	"codigoOrgao", "nomeOrgao", "cnpjFornecedor", "nomeFornecedor",
+}
+
+// csvRecord returns the contract as a row matching contractCSVHeader.
+func (ct Contract) csvRecord() []string {
+	return []string{
+		strconv.FormatInt(ct.ID, 10), ct.Numero, ct.Objeto, ct.NumeroProcesso, ct.FundamentoLegal, ct.DataAssinatura,
+		ct.DataVigenciaInicio, ct.DataVigenciaFim, strconv.FormatFloat(ct.ValorInicial, 'f', 2, 64), ct.Situacao, ct.ModalidadeCompra,
+		ct.CodigoOrgao, ct.NomeOrgao, ct.CNPJFornecedor, ct.NomeFornecedor,
 	}
+}
 
-	return &ConveniosResponse{
-		Convenios: convenios,
-		Total:     len(convenios),
-		Page:      page,
-		PageSize:  pageSize,
-		UF:        uf,
-		Source:    "portal_transparencia_api",
-	}, nil
+// ExportResult describes a completed contracts export. Truncated is set when
+// the page cap was reached, so the file only holds the first contracts.
+type ExportResult struct {
+	Path      string `json:"arquivo"`
+	Format    string `json:"formato"`
+	Records   int    `json:"registros"`
+	Pages     int    `json:"paginasConsultadas"`
+	Truncated bool   `json:"truncado"`
+	OrgaoCode string `json:"orgaoConsultado"`
+	Source    string `json:"source"`
 }
 
-// CEIS represents a company in the sanctions list.
-type CEIS struct {
-	CNPJ            string `json:"cnpjSancionado"`
-	RazaoSocial     string `json:"razaoSocialSancionado"`
-	NomeFantasia    string `json:"nomeFantasia"`
-	TipoSancao      string `json:"tipoSancao"`
-	DataInicioSanca string `json:"dataInicioSancao"`
-	DataFimSancao   string `json:"dataFimSancao"`
-	OrgaoSancionado string `json:"orgaoSancionador"`
+// contractWriter writes contracts to an export file as CSV or NDJSON.
+type contractWriter struct {
+	csv  *csv.Writer
+	json *json.Encoder
 }
 
-// CEISResponse represents the API response for sanctions.
-type CEISResponse struct {
-	Empresas []CEIS `json:"empresas"`
-	Total    int    `json:"total"`
-	Page     int    `json:"pagina"`
-	PageSize int    `json:"tamanhoPagina"`
-	Source   string `json:"source"`
+// newContractWriter returns a writer for format ("csv" or "ndjson"). CSV
+// exports start with a header row.
+func newContractWriter(w io.Writer, format string) (*contractWriter, error) {
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(contractCSVHeader); err != nil {
+			return nil, err
+		}
+		return &contractWriter{csv: cw}, nil
+	case "ndjson":
+		return &contractWriter{json: json.NewEncoder(w)}, nil
+	default:
+		return nil, fmt.Errorf("unknown export format %q: use csv or ndjson", format)
+	}
 }
 
-// SearchCEIS searches for sanctioned companies.
-func (c *Client) SearchCEIS(ctx context.Context, cnpj string, page, pageSize int) (*CEISResponse, error) {
-	if page < 1 {
-		page = 1
+func (cw *contractWriter) write(ct Contract) error {
+	if cw.csv != nil {
+		return cw.csv.Write(ct.csvRecord())
 	}
-	if pageSize < 1 || pageSize > 500 {
-		pageSize = 100
+	return cw.json.Encode(ct)
+}
+
+// flush writes buffered CSV rows to the file.
+func (cw *contractWriter) flush() error {
+	if cw.csv == nil {
+		return nil
 	}
+	cw.csv.Flush()
+	return cw.csv.Error()
+}
 
-	params := url.Values{}
-	if cnpj != "" {
-		params.Set("cnpj", cnpj)
+// exportPath resolves name, a path relative to dir, and checks that it
+// stays inside dir once symlinks in its directories are followed.
+func exportPath(dir, name string) (string, error) {
+	if dir == "" {
+		return "", fmt.Errorf("exports are disabled: no export directory is configured")
+	}
+	if name == "" {
+		return "", fmt.Errorf("output path is required")
+	}
+	if filepath.IsAbs(name) {
+		return "", fmt.Errorf("invalid output path %q: must be relative to the export directory", name)
 	}
-	params.Set("pagina", fmt.Sprintf("%d", page))
-	params.Set("tamanhoPagina", fmt.Sprintf("%d", pageSize))
 
-	body, err := c.doRequest(ctx, "/ceis", params)
+	root, err := filepath.Abs(dir)
+	if err == nil {
+		root, err = filepath.EvalSymlinks(root)
+	}
 	if err != nil {
-		return nil, err
+		return "", fmt.Errorf("export directory: %w", err)
 	}
-
-	var empresas []CEIS
-	if err := json.Unmarshal(body, &empresas); err != nil {
-		return nil, fmt.Errorf("parsing response: %w", err)
+	path := filepath.Join(root, name)
+	if !within(root, path) {
+		return "", fmt.Errorf("invalid output path %q: escapes the export directory", name)
+	}
+	parent, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return "", fmt.Errorf("invalid output path %q: %w", name, err)
 	}
+	if !within(root, parent) {
+		return "", fmt.Errorf("invalid output path %q: escapes the export directory", name)
+	}
+	return filepath.Join(parent, filepath.Base(path)), nil
+}
 
-	return &CEISResponse{
-		Empresas: empresas,
-		Total:    len(empresas),
-		Page:     page,
-		PageSize: pageSize,
-		Source:   "portal_transparencia_api",
-	}, nil
+// within reports whether the clean absolute path is root or inside it.
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
 }
 
-// ListOrgaos returns the list of known organization codes.
-func (c *Client) ListOrgaos() []map[string]string {
-	result := make([]map[string]string, 0, len(KnownOrgaos))
-	for code, name := range KnownOrgaos {
-		result = append(result, map[string]string{
-			"codigo": code,
-			"nome":   name,
-		})
+// ExportContracts writes the contracts of an organization to outputPath,
+// relative to the directory set with WithExportDir, as CSV or NDJSON. Pages
+// are fetched and written one at a time, up to maxContractPages, so memory
+// use does not grow with the number of contracts. The file must not exist
+// yet; it is removed if the export fails midway.
+func (c *Client) ExportContracts(ctx context.Context, orgaoCode, outputPath, format string) (*ExportResult, error) {
+	if orgaoCode == "" {
+		return nil, fmt.Errorf("organization code is required")
+	}
+	if format == "" {
+		format = "csv"
+	}
+	path, err := exportPath(c.exportDir, outputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("creating export file: %w", err)
+	}
+
+	result, err := c.exportContracts(ctx, f, orgaoCode, format)
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("writing export file: %w", closeErr)
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	result.Path = path
+	return result, nil
+}
+
+// eachContract pages through the contracts of an organization, calling fn
+// for each one, and returns the number of pages fetched. It stops after
+// maxContractPages pages, reporting truncated when more were left.
+func (c *Client) eachContract(ctx context.Context, orgaoCode string, fn func(Contract) error) (pages int, truncated bool, err error) {
+	for page := 1; ; page++ {
+		resp, err := c.SearchContracts(ctx, orgaoCode, "", "", "", page, exportPageSize)
+		if err != nil {
+			return page - 1, false, fmt.Errorf("fetching page %d: %w", page, err)
+		}
+		for _, ct := range resp.Contracts {
+			if err := fn(ct); err != nil {
+				return page, false, err
+			}
+		}
+		if !resp.HasMore {
+			return page, false, nil
+		}
+		if page == maxContractPages {
+			return page, true, nil
+		}
+	}
+}
+
+// exportContracts writes the contracts of the organization to w.
+func (c *Client) exportContracts(ctx context.Context, w io.Writer, orgaoCode, format string) (*ExportResult, error) {
+	cw, err := newContractWriter(w, format)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ExportResult{Format: format, OrgaoCode: orgaoCode, Source: "portal_transparencia_api"}
+	result.Pages, result.Truncated, err = c.eachContract(ctx, orgaoCode, func(ct Contract) error {
+		if err := cw.write(ct); err != nil {
+			return fmt.Errorf("writing export file: %w", err)
+		}
+		result.Records++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := cw.flush(); err != nil {
+		return nil, fmt.Errorf("writing export file: %w", err)
+	}
+	return result, nil
+}
+
+// ContractStats summarizes the initial values of an organization contracts.
+// All values are zero when the organization has no contracts. Truncated is
+// set when the page cap was reached, so the stats only cover the first
+// contracts.
+type ContractStats struct {
+	OrgaoCode string  `json:"orgaoConsultado"`
+	OrgaoName string  `json:"orgaoNome"`
+	Count     int     `json:"quantidade"`
+	Total     float64 `json:"valorTotal"`
+	Average   float64 `json:"valorMedio"`
+	Min       float64 `json:"valorMinimo"`
+	Max       float64 `json:"valorMaximo"`
+	Pages     int     `json:"paginasConsultadas"`
+	Truncated bool    `json:"truncado"`
+	Source    string  `json:"source"`
+}
+
+// add accumulates a contract value into the stats.
+func (st *ContractStats) add(value float64) {
+	if st.Count == 0 || value < st.Min {
+		st.Min = value
+	}
+	if st.Count == 0 || value > st.Max {
+		st.Max = value
+	}
+	st.Count++
+	st.Total += value
+	st.Average = st.Total / float64(st.Count)
+}
+
+// AggregateContracts computes the count, sum, average, minimum and maximum
+// of the initial value (valorInicial) of the contracts of an organization,
+// reading at most maxContractPages pages.
+func (c *Client) AggregateContracts(ctx context.Context, orgaoCode string) (*ContractStats, error) {
+	if orgaoCode == "" {
+		return nil, fmt.Errorf("organization code is required")
+	}
+
+	stats := &ContractStats{OrgaoCode: orgaoCode, Source: "portal_transparencia_api"}
+	pages, truncated, err := c.eachContract(ctx, orgaoCode, func(ct Contract) error {
+		stats.add(ct.ValorInicial)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	stats.Pages, stats.Truncated = pages, truncated
+	stats.OrgaoName = c.orgaoName(ctx, orgaoCode)
+	return stats, nil
+}
+
+// SearchContractsBySupplier searches government contracts signed with a
+// supplier across all organizations.
+func (c *Client) SearchContractsBySupplier(ctx context.Context, cnpjNum string, page, pageSize int) (*ContractsResponse, error) {
+	params, err := contractsBySupplierParams(cnpjNum, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	page, pageSize = queryPage(params)
+
+	body, err := c.doRequest(ctx, "/contratos/cpf-cnpj", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var contracts []Contract
+	if err := decodeList(body, &contracts); err != nil {
+		return nil, err
+	}
+	if contracts == nil {
+		contracts = []Contract{}
+	}
+
+	return &ContractsResponse{
+		Contracts: contracts,
+		PageCount: len(contracts),
+		PageInfo:  newPageInfo(page, pageSize, len(contracts)),
+		Source:    "portal_transparencia_api",
+	}, nil
+}
+
+// contractsBySupplierParams builds the query of SearchContractsBySupplier,
+// validating its arguments.
+func contractsBySupplierParams(cnpjNum string, page, pageSize int) (url.Values, error) {
+	digits, err := cnpj.Validate(cnpjNum)
+	if err != nil {
+		return nil, err
+	}
+	page, pageSize = pageBounds(page, pageSize)
+
+	params := url.Values{}
+	params.Set("cpfCnpj", digits)
+	params.Set("pagina", fmt.Sprintf("%d", page))
+	params.Set("tamanhoPagina", fmt.Sprintf("%d", pageSize))
+	return params, nil
+}
+
+// SearchContractsBySupplierURL returns the URL SearchContractsBySupplier would
+// request with the same arguments, without calling the API.
+func (c *Client) SearchContractsBySupplierURL(cnpjNum string, page, pageSize int) (string, error) {
+	params, err := contractsBySupplierParams(cnpjNum, page, pageSize)
+	if err != nil {
+		return "", err
+	}
+	return c.requestURL("/contratos/cpf-cnpj", params), nil
+}
+
+// MaskCPF hides the first three and last two digits of a CPF, as in
+// "***.456.789-**", the format the Portal uses for public disclosure. Values
+// that are empty or already masked are returned as is, and anything that is
+// not an 11-digit CPF is fully masked.
+func MaskCPF(cpf string) string {
+	if cpf == "" || strings.Contains(cpf, "*") {
+		return cpf
+	}
+	digits := document.NormalizeDigits(cpf)
+	if len(digits) != 11 {
+		return "***.***.***-**"
+	}
+	return fmt.Sprintf("***.%s.%s-**", digits[3:6], digits[6:9])
+}
+
+// MaskCPFsInJSON masks the CPFs of a raw API response: every string value
+// holding 11 digits under a key containing "cpf" (such as "cpf" or
+// "cpfFormatado") goes through MaskCPF. CNPJs under keys such as "cpfCnpj"
+// are left as is.
+func MaskCPFsInJSON(raw json.RawMessage) (json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+	masked, err := json.Marshal(maskCPFValues(v, false))
+	if err != nil {
+		return nil, err
+	}
+	return masked, nil
+}
+
+// maskCPFValues walks a decoded JSON value, masking the strings found under
+// CPF keys. inCPF reports whether v is the value of such a key.
+func maskCPFValues(v interface{}, inCPF bool) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for key, child := range val {
+			val[key] = maskCPFValues(child, strings.Contains(strings.ToLower(key), "cpf"))
+		}
+	case []interface{}:
+		for i, child := range val {
+			val[i] = maskCPFValues(child, inCPF)
+		}
+	case string:
+		if inCPF && len(document.NormalizeDigits(val)) == 11 {
+			return MaskCPF(val)
+		}
+	}
+	return v
+}
+
+// MaskCPFs masks the CPF of every servant in the response.
+func (r *ServidoresResponse) MaskCPFs() {
+	for i := range r.Servidores {
+		r.Servidores[i].CPF = MaskCPF(r.Servidores[i].CPF)
+	}
+}
+
+// MaskCPF masks the servant's CPF in the response.
+func (r *RemuneracaoResponse) MaskCPF() {
+	r.CPF = MaskCPF(r.CPF)
+}
+
+// Servidor represents a public servant.
+type Servidor struct {
+	ID               int64   `json:"id"`
+	CPF              string  `json:"cpf"`
+	Nome             string  `json:"nome"`
+	Matricula        string  `json:"matricula"`
+	CodigoOrgao      string  `json:"codigoOrgaoLotacao"`
+	NomeOrgao        string  `json:"nomeOrgaoLotacao"`
+	CodigoUorg       string  `json:"codigoUorgLotacao"`
+	NomeUorg         string  `json:"nomeUorgLotacao"`
+	TipoVinculo      string  `json:"tipoVinculo"`
+	SituacaoVinculo  string  `json:"situacaoVinculo"`
+	DataIngressoCarg string  `json:"dataIngressoCargo"`
+}
+
+// ServidoresResponse represents the API response for public servants.
+type ServidoresResponse struct {
+	Servidores []Servidor `json:"servidores"`
+	PageCount  int        `json:"registrosNaPagina"`
+	PageInfo
+	Source string `json:"source"`
+}
+
+// SearchServidores searches for public servants by name.
+func (c *Client) SearchServidores(ctx context.Context, nome string, page, pageSize int) (*ServidoresResponse, error) {
+	params, err := servidoresParams(nome, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	page, pageSize = queryPage(params)
+
+	body, err := c.doRequest(ctx, "/servidores", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var servidores []Servidor
+	if err := decodeList(body, &servidores); err != nil {
+		return nil, err
+	}
+
+	return &ServidoresResponse{
+		Servidores: servidores,
+		PageCount:  len(servidores),
+		PageInfo:   newPageInfo(page, pageSize, len(servidores)),
+		Source:     "portal_transparencia_api",
+	}, nil
+}
+
+// servidoresParams builds the query of SearchServidores, validating its
+// arguments.
+func servidoresParams(nome string, page, pageSize int) (url.Values, error) {
+	if nome == "" {
+		return nil, fmt.Errorf("nome is required")
+	}
+	page, pageSize = pageBounds(page, pageSize)
+
+	params := url.Values{}
+	params.Set("nome", nome)
+	params.Set("pagina", fmt.Sprintf("%d", page))
+	params.Set("tamanhoPagina", fmt.Sprintf("%d", pageSize))
+	return params, nil
+}
+
+// SearchServidoresURL returns the URL SearchServidores would request with the
+// same arguments, without calling the API.
+func (c *Client) SearchServidoresURL(nome string, page, pageSize int) (string, error) {
+	params, err := servidoresParams(nome, page, pageSize)
+	if err != nil {
+		return "", err
+	}
+	return c.requestURL("/servidores", params), nil
+}
+
+// Remuneracao represents a public servant's salary.
+type Remuneracao struct {
+	MesAno                 string  `json:"mesAno"`
+	RemuneracaoBasicaBruta float64 `json:"remuneracaoBasicaBruta"`
+	AbateGratificacao      float64 `json:"abateGratificacao"`
+	GratificacaoNatalina   float64 `json:"gratificacaoNatalina"`
+	AbateTeto              float64 `json:"abateTeto"`
+	RendimentoLiquido      float64 `json:"rendimentoLiquido"`
+}
+
+// RemuneracaoResponse represents the API response for salary data.
+type RemuneracaoResponse struct {
+	CPF         string        `json:"cpf"`
+	Remuneracao []Remuneracao `json:"remuneracao"`
+	MesAno      string        `json:"mesAno"`
+	Source      string        `json:"source"`
+}
+
+// GetServidorRemuneracao gets salary data for a public servant by CPF, given
+// with or without formatting.
+func (c *Client) GetServidorRemuneracao(ctx context.Context, cpf, mesAno string) (*RemuneracaoResponse, error) {
+	if cpf == "" {
+		return nil, fmt.Errorf("cpf is required")
+	}
+	cpf = document.NormalizeDigits(cpf)
+	if len(cpf) != 11 {
+		return nil, fmt.Errorf("invalid CPF: must have 11 digits, got %d", len(cpf))
+	}
+	if mesAno == "" {
+		// Default to last month
+		lastMonth := time.Now().AddDate(0, -1, 0)
+		mesAno = lastMonth.Format("01/2006")
+	}
+
+	params := url.Values{}
+	params.Set("mesAno", mesAno)
+
+	endpoint := fmt.Sprintf("/servidores/%s/remuneracao", cpf)
+	body, err := c.doRequest(ctx, endpoint, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var remuneracoes []Remuneracao
+	if err := decodeList(body, &remuneracoes); err != nil {
+		return nil, err
+	}
+
+	return &RemuneracaoResponse{
+		CPF:         cpf,
+		Remuneracao: remuneracoes,
+		MesAno:      mesAno,
+		Source:      "portal_transparencia_api",
+	}, nil
+}
+
+// Convenio represents a government agreement/covenant.
+type Convenio struct {
+	Numero          string  `json:"numero"`
+	Objeto          string  `json:"objeto"`
+	SituacaoConveni string  `json:"situacaoConvenio"`
+	ValorLiberado   float64 `json:"valorLiberado"`
+	ValorConvenio   float64 `json:"valorConvenio"`
+	UF              string  `json:"uf"`
+	Municipio       string  `json:"municipio"`
+	OrgaoSuperior   string  `json:"orgaoSuperior"`
+	DataInicio      string  `json:"dataInicioVigencia"`
+	DataFim         string  `json:"dataFimVigencia"`
+}
+
+// ConveniosResponse represents the API response for agreements.
+type ConveniosResponse struct {
+	Convenios []Convenio `json:"convenios"`
+	PageCount int        `json:"registrosNaPagina"`
+	PageInfo
+	UF     string `json:"uf"`
+	Source string `json:"source"`
+}
+
+// SearchConvenios searches for government agreements by state.
+func (c *Client) SearchConvenios(ctx context.Context, uf string, page, pageSize int) (*ConveniosResponse, error) {
+	params, err := conveniosParams(uf, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	page, pageSize = queryPage(params)
+
+	body, err := c.doRequest(ctx, "/convenios", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var convenios []Convenio
+	if err := decodeList(body, &convenios); err != nil {
+		return nil, err
+	}
+
+	return &ConveniosResponse{
+		Convenios: convenios,
+		PageCount: len(convenios),
+		PageInfo:  newPageInfo(page, pageSize, len(convenios)),
+		UF:        params.Get("uf"),
+		Source:    "portal_transparencia_api",
+	}, nil
+}
+
+// conveniosParams builds the query of SearchConvenios, validating its
+// arguments.
+func conveniosParams(uf string, page, pageSize int) (url.Values, error) {
+	if uf == "" {
+		uf = "MG" // Default: Minas Gerais
+	}
+	page, pageSize = pageBounds(page, pageSize)
+
+	params := url.Values{}
+	params.Set("uf", uf)
+	params.Set("pagina", fmt.Sprintf("%d", page))
+	params.Set("tamanhoPagina", fmt.Sprintf("%d", pageSize))
+	return params, nil
+}
+
+// SearchConveniosURL returns the URL SearchConvenios would request with the
+// same arguments, without calling the API.
+func (c *Client) SearchConveniosURL(uf string, page, pageSize int) (string, error) {
+	params, err := conveniosParams(uf, page, pageSize)
+	if err != nil {
+		return "", err
+	}
+	return c.requestURL("/convenios", params), nil
+}
+
+// CEIS represents a company in the sanctions list.
+type CEIS struct {
+	CNPJ            string `json:"cnpjSancionado"`
+	RazaoSocial     string `json:"razaoSocialSancionado"`
+	NomeFantasia    string `json:"nomeFantasia"`
+	TipoSancao      string `json:"tipoSancao"`
+	DataInicioSanca string `json:"dataInicioSancao"`
+	DataFimSancao   string `json:"dataFimSancao"`
+	OrgaoSancionado string `json:"orgaoSancionador"`
+}
+
+// CEISResponse represents the API response for sanctions.
+type CEISResponse struct {
+	Empresas  []CEIS `json:"empresas"`
+	PageCount int    `json:"registrosNaPagina"`
+	PageInfo
+	Source string `json:"source"`
+}
+
+// SearchCEIS searches for sanctioned companies.
+func (c *Client) SearchCEIS(ctx context.Context, cnpj string, page, pageSize int) (*CEISResponse, error) {
+	params, err := ceisParams(cnpj, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	page, pageSize = queryPage(params)
+
+	body, err := c.doRequest(ctx, "/ceis", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var empresas []CEIS
+	if err := decodeList(body, &empresas); err != nil {
+		return nil, err
+	}
+
+	return &CEISResponse{
+		Empresas:  empresas,
+		PageCount: len(empresas),
+		PageInfo:  newPageInfo(page, pageSize, len(empresas)),
+		Source:    "portal_transparencia_api",
+	}, nil
+}
+
+// ceisParams builds the query of SearchCEIS, validating its arguments.
+func ceisParams(cnpj string, page, pageSize int) (url.Values, error) {
+	page, pageSize = pageBounds(page, pageSize)
+
+	params := url.Values{}
+	if cnpj != "" {
+		params.Set("cnpj", document.NormalizeDigits(cnpj))
+	}
+	params.Set("pagina", fmt.Sprintf("%d", page))
+	params.Set("tamanhoPagina", fmt.Sprintf("%d", pageSize))
+	return params, nil
+}
+
+// SearchCEISURL returns the URL SearchCEIS would request with the same
+// arguments, without calling the API.
+func (c *Client) SearchCEISURL(cnpj string, page, pageSize int) (string, error) {
+	params, err := ceisParams(cnpj, page, pageSize)
+	if err != nil {
+		return "", err
+	}
+	return c.requestURL("/ceis", params), nil
+}
+
+// CNEP represents a company punished under the anti-corruption law (Lei 12.846/2013).
+type CNEP struct {
+	CNPJ             string  `json:"cnpjSancionado"`
+	RazaoSocial      string  `json:"razaoSocialSancionado"`
+	NomeFantasia     string  `json:"nomeFantasia"`
+	TipoSancao       string  `json:"tipoSancao"`
+	ValorMulta       float64 `json:"valorMulta"`
+	DataInicioSancao string  `json:"dataInicioSancao"`
+	DataFimSancao    string  `json:"dataFimSancao"`
+	OrgaoSancionador string  `json:"orgaoSancionador"`
+}
+
+// CNEPResponse represents the API response for the CNEP registry.
+type CNEPResponse struct {
+	Empresas  []CNEP `json:"empresas"`
+	PageCount int    `json:"registrosNaPagina"`
+	PageInfo
+	Source string `json:"source"`
+}
+
+// SearchCNEP searches for companies punished under the anti-corruption law.
+func (c *Client) SearchCNEP(ctx context.Context, cnpj string, page, pageSize int) (*CNEPResponse, error) {
+	params, err := cnepParams(cnpj, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	page, pageSize = queryPage(params)
+
+	body, err := c.doRequest(ctx, "/cnep", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var empresas []CNEP
+	if err := decodeList(body, &empresas); err != nil {
+		return nil, err
+	}
+	if empresas == nil {
+		empresas = []CNEP{}
+	}
+
+	return &CNEPResponse{
+		Empresas:  empresas,
+		PageCount: len(empresas),
+		PageInfo:  newPageInfo(page, pageSize, len(empresas)),
+		Source:    "portal_transparencia_api",
+	}, nil
+}
+
+// cnepParams builds the query of SearchCNEP, validating its arguments.
+func cnepParams(cnpj string, page, pageSize int) (url.Values, error) {
+	page, pageSize = pageBounds(page, pageSize)
+
+	params := url.Values{}
+	if cnpj != "" {
+		params.Set("cnpj", document.NormalizeDigits(cnpj))
+	}
+	params.Set("pagina", fmt.Sprintf("%d", page))
+	params.Set("tamanhoPagina", fmt.Sprintf("%d", pageSize))
+	return params, nil
+}
+
+// SearchCNEPURL returns the URL SearchCNEP would request with the same
+// arguments, without calling the API.
+func (c *Client) SearchCNEPURL(cnpj string, page, pageSize int) (string, error) {
+	params, err := cnepParams(cnpj, page, pageSize)
+	if err != nil {
+		return "", err
+	}
+	return c.requestURL("/cnep", params), nil
+}
+
+// CEPIM represents a non-profit entity impeded from receiving federal transfers.
+type CEPIM struct {
+	CNPJ           string `json:"cnpjEntidade"`
+	RazaoSocial    string `json:"razaoSocialEntidade"`
+	Convenio       string `json:"numeroConvenio"`
+	Motivo         string `json:"motivoImpedimento"`
+	OrgaoSuperior  string `json:"orgaoSuperior"`
+	DataReferencia string `json:"dataReferencia"`
+}
+
+// CEPIMResponse represents the API response for the CEPIM registry.
+type CEPIMResponse struct {
+	Entidades []CEPIM `json:"entidades"`
+	PageCount int     `json:"registrosNaPagina"`
+	PageInfo
+	Source string `json:"source"`
+}
+
+// SearchCEPIM searches for non-profit entities impeded from receiving federal transfers.
+func (c *Client) SearchCEPIM(ctx context.Context, cnpj string, page, pageSize int) (*CEPIMResponse, error) {
+	params, err := cepimParams(cnpj, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	page, pageSize = queryPage(params)
+
+	body, err := c.doRequest(ctx, "/cepim", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var entidades []CEPIM
+	if err := decodeList(body, &entidades); err != nil {
+		return nil, err
+	}
+	if entidades == nil {
+		entidades = []CEPIM{}
+	}
+
+	return &CEPIMResponse{
+		Entidades: entidades,
+		PageCount: len(entidades),
+		PageInfo:  newPageInfo(page, pageSize, len(entidades)),
+		Source:    "portal_transparencia_api",
+	}, nil
+}
+
+// cepimParams builds the query of SearchCEPIM, validating its arguments.
+func cepimParams(cnpj string, page, pageSize int) (url.Values, error) {
+	page, pageSize = pageBounds(page, pageSize)
+
+	params := url.Values{}
+	if cnpj != "" {
+		params.Set("cnpj", document.NormalizeDigits(cnpj))
+	}
+	params.Set("pagina", fmt.Sprintf("%d", page))
+	params.Set("tamanhoPagina", fmt.Sprintf("%d", pageSize))
+	return params, nil
+}
+
+// SearchCEPIMURL returns the URL SearchCEPIM would request with the same
+// arguments, without calling the API.
+func (c *Client) SearchCEPIMURL(cnpj string, page, pageSize int) (string, error) {
+	params, err := cepimParams(cnpj, page, pageSize)
+	if err != nil {
+		return "", err
+	}
+	return c.requestURL("/cepim", params), nil
+}
+
+// Despesa represents an expense execution record (empenho, liquidação or pagamento).
+type Despesa struct {
+	Ano              int     `json:"ano"`
+	CodigoOrgao      string  `json:"codigoOrgao"`
+	NomeOrgao        string  `json:"orgao"`
+	Fase             string  `json:"fase"`
+	Documento        string  `json:"documento"`
+	Data             string  `json:"data"`
+	CodigoFavorecido string  `json:"codigoFavorecido"`
+	NomeFavorecido   string  `json:"nomeFavorecido"`
+	ValorEmpenhado   float64 `json:"valorEmpenhado"`
+	ValorLiquidado   float64 `json:"valorLiquidado"`
+	ValorPago        float64 `json:"valorPago"`
+}
+
+// DespesasResponse represents the API response for expenses.
+type DespesasResponse struct {
+	Despesas  []Despesa `json:"despesas"`
+	PageCount int       `json:"registrosNaPagina"`
+	PageInfo
+	OrgaoCode string `json:"orgaoConsultado"`
+	Ano       string `json:"ano"`
+	Source    string `json:"source"`
+}
+
+// SearchDespesas searches expense execution data for an organization in a given year.
+func (c *Client) SearchDespesas(ctx context.Context, orgaoCode, ano string, page, pageSize int) (*DespesasResponse, error) {
+	params, err := despesasParams(orgaoCode, ano, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	page, pageSize = queryPage(params)
+
+	body, err := c.doRequest(ctx, "/despesas/por-orgao", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var despesas []Despesa
+	if err := decodeList(body, &despesas); err != nil {
+		return nil, err
+	}
+
+	return &DespesasResponse{
+		Despesas:  despesas,
+		PageCount: len(despesas),
+		PageInfo:  newPageInfo(page, pageSize, len(despesas)),
+		OrgaoCode: params.Get("orgao"),
+		Ano:       params.Get("ano"),
+		Source:    "portal_transparencia_api",
+	}, nil
+}
+
+// despesasParams builds the query of SearchDespesas, validating its arguments.
+func despesasParams(orgaoCode, ano string, page, pageSize int) (url.Values, error) {
+	if orgaoCode == "" {
+		orgaoCode = "36000" // Default: Ministerio da Saude
+	}
+	if ano == "" {
+		ano = fmt.Sprintf("%d", time.Now().Year())
+	} else if _, err := time.Parse("2006", ano); err != nil {
+		return nil, fmt.Errorf("invalid ano %q: expected YYYY", ano)
+	}
+	page, pageSize = pageBounds(page, pageSize)
+
+	params := url.Values{}
+	params.Set("orgao", orgaoCode)
+	params.Set("ano", ano)
+	params.Set("pagina", fmt.Sprintf("%d", page))
+	params.Set("tamanhoPagina", fmt.Sprintf("%d", pageSize))
+	return params, nil
+}
+
+// SearchDespesasURL returns the URL SearchDespesas would request with the same
+// arguments, without calling the API.
+func (c *Client) SearchDespesasURL(orgaoCode, ano string, page, pageSize int) (string, error) {
+	params, err := despesasParams(orgaoCode, ano, page, pageSize)
+	if err != nil {
+		return "", err
+	}
+	return c.requestURL("/despesas/por-orgao", params), nil
+}
+
+// maxDespesasPages caps the pages AggregateDespesasByFavorecido fetches.
+const maxDespesasPages = 20
+
+// DefaultTopFavorecidos is the number of recipients returned when no limit
+// is given.
+const DefaultTopFavorecidos = 20
+
+// FavorecidoTotal is the amount an organization paid to one recipient.
+type FavorecidoTotal struct {
+	CodigoFavorecido string  `json:"codigoFavorecido"`
+	NomeFavorecido   string  `json:"nomeFavorecido"`
+	ValorPago        float64 `json:"valorPago"`
+	Count            int     `json:"quantidade"`
+}
+
+// TopFavorecidosResponse ranks the recipients of an organization's expenses
+// by amount paid. Truncated is set when the page cap was reached before the
+// last page, so the totals only cover part of the year.
+type TopFavorecidosResponse struct {
+	Favorecidos      []FavorecidoTotal `json:"favorecidos"`
+	TotalFavorecidos int               `json:"quantidadeFavorecidos"`
+	ValorPagoTotal   float64           `json:"valorPagoTotal"`
+	OrgaoCode        string            `json:"orgaoConsultado"`
+	Ano              string            `json:"ano"`
+	Pages            int               `json:"paginasConsultadas"`
+	Truncated        bool              `json:"truncado"`
+	Source           string            `json:"source"`
+}
+
+// favorecidoTotals sums valorPago per recipient as pages arrive, so no page
+// has to be kept once it is counted.
+type favorecidoTotals struct {
+	index  map[string]int
+	totals []FavorecidoTotal
+}
+
+// add counts the expenses of one page, keyed by recipient code (or name when
+// the code is missing).
+func (f *favorecidoTotals) add(despesas []Despesa) {
+	if f.index == nil {
+		f.index = make(map[string]int)
+	}
+	for _, d := range despesas {
+		key := d.CodigoFavorecido
+		if key == "" {
+			key = d.NomeFavorecido
+		}
+		i, ok := f.index[key]
+		if !ok {
+			i = len(f.totals)
+			f.index[key] = i
+			f.totals = append(f.totals, FavorecidoTotal{CodigoFavorecido: d.CodigoFavorecido, NomeFavorecido: d.NomeFavorecido})
+		}
+		f.totals[i].ValorPago += d.ValorPago
+		f.totals[i].Count++
+	}
+}
+
+// ranked returns the recipients by amount paid, largest first, or an empty
+// list when there were none.
+func (f *favorecidoTotals) ranked() []FavorecidoTotal {
+	totals := f.totals
+	if totals == nil {
+		totals = []FavorecidoTotal{}
+	}
+	sort.SliceStable(totals, func(i, j int) bool { return totals[i].ValorPago > totals[j].ValorPago })
+	return totals
+}
+
+// AggregateDespesasByFavorecido sums the amount paid (valorPago) per
+// recipient of an organization's expenses in a year and returns the top
+// limit recipients (DefaultTopFavorecidos when limit < 1). At most
+// maxDespesasPages pages are fetched.
+func (c *Client) AggregateDespesasByFavorecido(ctx context.Context, orgaoCode, ano string, limit int) (*TopFavorecidosResponse, error) {
+	if limit < 1 {
+		limit = DefaultTopFavorecidos
+	}
+
+	response := &TopFavorecidosResponse{Source: "portal_transparencia_api"}
+	var totals favorecidoTotals
+	for page := 1; ; page++ {
+		resp, err := c.SearchDespesas(ctx, orgaoCode, ano, page, exportPageSize)
+		if err != nil {
+			return nil, fmt.Errorf("fetching page %d: %w", page, err)
+		}
+		response.OrgaoCode, response.Ano, response.Pages = resp.OrgaoCode, resp.Ano, page
+		totals.add(resp.Despesas)
+		if !resp.HasMore {
+			break
+		}
+		if page == maxDespesasPages {
+			response.Truncated = true
+			break
+		}
+	}
+
+	ranked := totals.ranked()
+	for _, f := range ranked {
+		response.ValorPagoTotal += f.ValorPago
+	}
+	response.TotalFavorecidos = len(ranked)
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	response.Favorecidos = ranked
+	return response, nil
+}
+
+// Viagem represents a public servant's official trip.
+type Viagem struct {
+	ID             int64   `json:"id"`
+	NomeServidor   string  `json:"nomeServidor"`
+	Cargo          string  `json:"cargo"`
+	CodigoOrgao    string  `json:"codigoOrgao"`
+	NomeOrgao      string  `json:"nomeOrgao"`
+	Destino        string  `json:"destino"`
+	Motivo         string  `json:"motivo"`
+	DataInicio     string  `json:"dataInicioAfastamento"`
+	DataFim        string  `json:"dataFimAfastamento"`
+	ValorDiarias   float64 `json:"valorDiarias"`
+	ValorPassagens float64 `json:"valorPassagens"`
+	ValorTotal     float64 `json:"valorTotal"`
+}
+
+// ViagensResponse represents the API response for official trips.
+type ViagensResponse struct {
+	Viagens   []Viagem `json:"viagens"`
+	PageCount int      `json:"registrosNaPagina"`
+	PageInfo
+	OrgaoCode  string `json:"orgaoConsultado"`
+	DataInicio string `json:"dataInicio"`
+	DataFim    string `json:"dataFim"`
+	Source     string `json:"source"`
+}
+
+// SearchViagens searches official trips of an organization's servants whose
+// departure falls within dataInicio and dataFim (YYYY-MM-DD). The window
+// defaults to the last 30 days.
+func (c *Client) SearchViagens(ctx context.Context, orgaoCode, dataInicio, dataFim string, page, pageSize int) (*ViagensResponse, error) {
+	params, err := viagensParams(orgaoCode, dataInicio, dataFim, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	page, pageSize = queryPage(params)
+
+	body, err := c.doRequest(ctx, "/viagens", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var viagens []Viagem
+	if err := decodeList(body, &viagens); err != nil {
+		return nil, err
+	}
+	if viagens == nil {
+		viagens = []Viagem{}
+	}
+
+	return &ViagensResponse{
+		Viagens:    viagens,
+		PageCount:  len(viagens),
+		PageInfo:   newPageInfo(page, pageSize, len(viagens)),
+		OrgaoCode:  params.Get("codigoOrgao"),
+		DataInicio: queryDate(params, "dataIdaDe"),
+		DataFim:    queryDate(params, "dataIdaAte"),
+		Source:     "portal_transparencia_api",
+	}, nil
+}
+
+// viagensParams builds the query of SearchViagens, validating its arguments.
+func viagensParams(orgaoCode, dataInicio, dataFim string, page, pageSize int) (url.Values, error) {
+	if orgaoCode == "" {
+		orgaoCode = "36000" // Default: Ministerio da Saude
+	}
+	dataInicio, dataFim, err := defaultWindow(dataInicio, dataFim)
+	if err != nil {
+		return nil, err
+	}
+	apiStart, apiEnd, err := parseDateRange(dataInicio, dataFim)
+	if err != nil {
+		return nil, err
+	}
+	page, pageSize = pageBounds(page, pageSize)
+
+	params := url.Values{}
+	params.Set("codigoOrgao", orgaoCode)
+	params.Set("dataIdaDe", apiStart)
+	params.Set("dataIdaAte", apiEnd)
+	params.Set("pagina", fmt.Sprintf("%d", page))
+	params.Set("tamanhoPagina", fmt.Sprintf("%d", pageSize))
+	return params, nil
+}
+
+// SearchViagensURL returns the URL SearchViagens would request with the same
+// arguments, without calling the API.
+func (c *Client) SearchViagensURL(orgaoCode, dataInicio, dataFim string, page, pageSize int) (string, error) {
+	params, err := viagensParams(orgaoCode, dataInicio, dataFim, page, pageSize)
+	if err != nil {
+		return "", err
+	}
+	return c.requestURL("/viagens", params), nil
+}
+
+// maxLicitacaoWindowDays is the widest date window /licitacoes accepts.
+const maxLicitacaoWindowDays = 31
+
+// Licitacao represents a bidding process (licitação).
+type Licitacao struct {
+	ID            int64   `json:"id"`
+	Numero        string  `json:"numero"`
+	Processo      string  `json:"processo"`
+	Modalidade    string  `json:"modalidade"`
+	Objeto        string  `json:"objeto"`
+	Situacao      string  `json:"situacao"`
+	Valor         float64 `json:"valor"`
+	DataAbertura  string  `json:"dataAbertura"`
+	DataResultado string  `json:"dataResultado"`
+}
+
+// licitacaoRecord mirrors the nested /licitacoes payload.
+type licitacaoRecord struct {
+	ID        int64 `json:"id"`
+	Licitacao struct {
+		Numero   string `json:"numero"`
+		Processo string `json:"numeroProcesso"`
+		Objeto   string `json:"objeto"`
+	} `json:"licitacao"`
+	Modalidade struct {
+		Descricao string `json:"descricao"`
+	} `json:"modalidadeLicitacao"`
+	Situacao struct {
+		Descricao string `json:"descricao"`
+	} `json:"situacaoCompra"`
+	Valor         float64 `json:"valor"`
+	DataAbertura  string  `json:"dataAbertura"`
+	DataResultado string  `json:"dataResultadoCompra"`
+}
+
+func (r licitacaoRecord) flatten() Licitacao {
+	return Licitacao{
+		ID:            r.ID,
+		Numero:        r.Licitacao.Numero,
+		Processo:      r.Licitacao.Processo,
+		Modalidade:    r.Modalidade.Descricao,
+		Objeto:        r.Licitacao.Objeto,
+		Situacao:      r.Situacao.Descricao,
+		Valor:         r.Valor,
+		DataAbertura:  r.DataAbertura,
+		DataResultado: r.DataResultado,
+	}
+}
+
+// parseLicitacoes decodes a /licitacoes page into flat records.
+func parseLicitacoes(body []byte) ([]Licitacao, error) {
+	var records []licitacaoRecord
+	if err := decodeList(body, &records); err != nil {
+		return nil, err
+	}
+	licitacoes := make([]Licitacao, 0, len(records))
+	for _, r := range records {
+		licitacoes = append(licitacoes, r.flatten())
+	}
+	return licitacoes, nil
+}
+
+// LicitacoesResponse represents the API response for bidding processes.
+type LicitacoesResponse struct {
+	Licitacoes []Licitacao `json:"licitacoes"`
+	PageCount  int         `json:"registrosNaPagina"`
+	PageInfo
+	OrgaoCode  string `json:"orgaoConsultado"`
+	DataInicio string `json:"dataInicio"`
+	DataFim    string `json:"dataFim"`
+	Source     string `json:"source"`
+}
+
+// SearchLicitacoes searches an organization's bidding processes opened
+// between dataInicio and dataFim (YYYY-MM-DD). The window defaults to the
+// last 30 days and may not exceed one month.
+func (c *Client) SearchLicitacoes(ctx context.Context, orgaoCode, dataInicio, dataFim string, page, pageSize int) (*LicitacoesResponse, error) {
+	params, err := licitacoesParams(orgaoCode, dataInicio, dataFim, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	page, pageSize = queryPage(params)
+
+	body, err := c.doRequest(ctx, "/licitacoes", params)
+	if err != nil {
+		return nil, err
+	}
+
+	licitacoes, err := parseLicitacoes(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LicitacoesResponse{
+		Licitacoes: licitacoes,
+		PageCount:  len(licitacoes),
+		PageInfo:   newPageInfo(page, pageSize, len(licitacoes)),
+		OrgaoCode:  params.Get("codigoOrgao"),
+		DataInicio: queryDate(params, "dataInicial"),
+		DataFim:    queryDate(params, "dataFinal"),
+		Source:     "portal_transparencia_api",
+	}, nil
+}
+
+// licitacoesParams builds the query of SearchLicitacoes, validating its
+// arguments.
+func licitacoesParams(orgaoCode, dataInicio, dataFim string, page, pageSize int) (url.Values, error) {
+	if orgaoCode == "" {
+		orgaoCode = "36000" // Default: Ministerio da Saude
+	}
+	dataInicio, dataFim, err := defaultWindow(dataInicio, dataFim)
+	if err != nil {
+		return nil, err
+	}
+	apiStart, apiEnd, err := parseDateRange(dataInicio, dataFim)
+	if err != nil {
+		return nil, err
+	}
+	start, _ := time.Parse(DateLayout, dataInicio)
+	end, _ := time.Parse(DateLayout, dataFim)
+	if end.Sub(start) > maxLicitacaoWindowDays*24*time.Hour {
+		return nil, fmt.Errorf("date window %s to %s exceeds %d days", dataInicio, dataFim, maxLicitacaoWindowDays)
+	}
+	page, pageSize = pageBounds(page, pageSize)
+
+	params := url.Values{}
+	params.Set("codigoOrgao", orgaoCode)
+	params.Set("dataInicial", apiStart)
+	params.Set("dataFinal", apiEnd)
+	params.Set("pagina", fmt.Sprintf("%d", page))
+	params.Set("tamanhoPagina", fmt.Sprintf("%d", pageSize))
+	return params, nil
+}
+
+// SearchLicitacoesURL returns the URL SearchLicitacoes would request with the
+// same arguments, without calling the API.
+func (c *Client) SearchLicitacoesURL(orgaoCode, dataInicio, dataFim string, page, pageSize int) (string, error) {
+	params, err := licitacoesParams(orgaoCode, dataInicio, dataFim, page, pageSize)
+	if err != nil {
+		return "", err
+	}
+	return c.requestURL("/licitacoes", params), nil
+}
+
+// GastoCartao represents a transaction made with a government payment card (CPGF).
+type GastoCartao struct {
+	ID              int64   `json:"id"`
+	Portador        string  `json:"nomePortador"`
+	CPFPortador     string  `json:"cpfPortador"`
+	CodigoOrgao     string  `json:"codigoOrgao"`
+	NomeOrgao       string  `json:"nomeOrgao"`
+	Estabelecimento string  `json:"nomeEstabelecimento"`
+	CNPJEstabelec   string  `json:"cnpjEstabelecimento"`
+	DataTransacao   string  `json:"dataTransacao"`
+	Valor           float64 `json:"valorTransacao"`
+	TipoCartao      string  `json:"tipoCartao"`
+}
+
+// CartoesResponse represents the API response for payment card spending.
+type CartoesResponse struct {
+	Gastos    []GastoCartao `json:"gastos"`
+	PageCount int           `json:"registrosNaPagina"`
+	PageInfo
+	OrgaoCode    string `json:"orgaoConsultado"`
+	MesAnoInicio string `json:"mesAnoInicio"`
+	MesAnoFim    string `json:"mesAnoFim"`
+	Source       string `json:"source"`
+}
+
+// SearchCartoes searches government payment card spending for an organization
+// between two MM/YYYY statement months. Both default to last month.
+func (c *Client) SearchCartoes(ctx context.Context, orgaoCode, mesAnoInicio, mesAnoFim string, page, pageSize int) (*CartoesResponse, error) {
+	params, err := cartoesParams(orgaoCode, mesAnoInicio, mesAnoFim, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	page, pageSize = queryPage(params)
+
+	body, err := c.doRequest(ctx, "/cartoes", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var gastos []GastoCartao
+	if err := decodeList(body, &gastos); err != nil {
+		return nil, err
+	}
+
+	return &CartoesResponse{
+		Gastos:       gastos,
+		PageCount:    len(gastos),
+		PageInfo:     newPageInfo(page, pageSize, len(gastos)),
+		OrgaoCode:    params.Get("codigoOrgao"),
+		MesAnoInicio: params.Get("mesExtratoInicio"),
+		MesAnoFim:    params.Get("mesExtratoFim"),
+		Source:       "portal_transparencia_api",
+	}, nil
+}
+
+// cartoesParams builds the query of SearchCartoes, validating its arguments.
+func cartoesParams(orgaoCode, mesAnoInicio, mesAnoFim string, page, pageSize int) (url.Values, error) {
+	if orgaoCode == "" {
+		orgaoCode = "36000" // Default: Ministerio da Saude
+	}
+	if mesAnoInicio == "" {
+		mesAnoInicio = previousMonth()
+	}
+	if mesAnoFim == "" {
+		mesAnoFim = previousMonth()
+	}
+	start, err := parseMesAno(mesAnoInicio)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseMesAno(mesAnoFim)
+	if err != nil {
+		return nil, err
+	}
+	if start.After(end) {
+		return nil, fmt.Errorf("start period %s is after end period %s", mesAnoInicio, mesAnoFim)
+	}
+	page, pageSize = pageBounds(page, pageSize)
+
+	params := url.Values{}
+	params.Set("codigoOrgao", orgaoCode)
+	params.Set("mesExtratoInicio", mesAnoInicio)
+	params.Set("mesExtratoFim", mesAnoFim)
+	params.Set("pagina", fmt.Sprintf("%d", page))
+	params.Set("tamanhoPagina", fmt.Sprintf("%d", pageSize))
+	return params, nil
+}
+
+// SearchCartoesURL returns the URL SearchCartoes would request with the same
+// arguments, without calling the API.
+func (c *Client) SearchCartoesURL(orgaoCode, mesAnoInicio, mesAnoFim string, page, pageSize int) (string, error) {
+	params, err := cartoesParams(orgaoCode, mesAnoInicio, mesAnoFim, page, pageSize)
+	if err != nil {
+		return "", err
+	}
+	return c.requestURL("/cartoes", params), nil
+}
+
+// Emenda represents a parliamentary amendment to the federal budget.
+type Emenda struct {
+	Codigo         string  `json:"codigoEmenda"`
+	Ano            int     `json:"ano"`
+	Autor          string  `json:"nomeAutor"`
+	Tipo           string  `json:"tipoEmenda"`
+	Funcao         string  `json:"funcao"`
+	Subfuncao      string  `json:"subfuncao"`
+	Localidade     string  `json:"localidadeDoGasto"`
+	ValorEmpenhado float64 `json:"valorEmpenhado"`
+	ValorLiquidado float64 `json:"valorLiquidado"`
+	ValorPago      float64 `json:"valorPago"`
+}
+
+// EmendasResponse represents the API response for parliamentary amendments.
+type EmendasResponse struct {
+	Emendas   []Emenda `json:"emendas"`
+	PageCount int      `json:"registrosNaPagina"`
+	PageInfo
+	Ano    string `json:"ano"`
+	Source string `json:"source"`
+}
+
+// SearchEmendas searches parliamentary amendments for a year (default current
+// year), optionally filtered by author name and state.
+func (c *Client) SearchEmendas(ctx context.Context, ano, autor, uf string, page, pageSize int) (*EmendasResponse, error) {
+	params, err := emendasParams(ano, autor, uf, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	page, pageSize = queryPage(params)
+
+	body, err := c.doRequest(ctx, "/emendas", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var emendas []Emenda
+	if err := decodeList(body, &emendas); err != nil {
+		return nil, err
+	}
+
+	return &EmendasResponse{
+		Emendas:   emendas,
+		PageCount: len(emendas),
+		PageInfo:  newPageInfo(page, pageSize, len(emendas)),
+		Ano:       params.Get("ano"),
+		Source:    "portal_transparencia_api",
+	}, nil
+}
+
+// emendasParams builds the query of SearchEmendas, validating its arguments.
+func emendasParams(ano, autor, uf string, page, pageSize int) (url.Values, error) {
+	if ano == "" {
+		ano = fmt.Sprintf("%d", time.Now().Year())
+	} else if _, err := time.Parse("2006", ano); err != nil {
+		return nil, fmt.Errorf("invalid ano %q: expected YYYY", ano)
+	}
+	page, pageSize = pageBounds(page, pageSize)
+
+	params := url.Values{}
+	params.Set("ano", ano)
+	if autor != "" {
+		params.Set("nomeAutor", autor)
+	}
+	if uf != "" {
+		params.Set("uf", uf)
+	}
+	params.Set("pagina", fmt.Sprintf("%d", page))
+	params.Set("tamanhoPagina", fmt.Sprintf("%d", pageSize))
+	return params, nil
+}
+
+// SearchEmendasURL returns the URL SearchEmendas would request with the same
+// arguments, without calling the API.
+func (c *Client) SearchEmendasURL(ano, autor, uf string, page, pageSize int) (string, error) {
+	params, err := emendasParams(ano, autor, uf, page, pageSize)
+	if err != nil {
+		return "", err
+	}
+	return c.requestURL("/emendas", params), nil
+}
+
+// BolsaFamiliaMunicipio represents the aggregate Novo Bolsa Família disbursement for a municipality.
+type BolsaFamiliaMunicipio struct {
+	ID                     int64   `json:"id"`
+	DataReferencia         string  `json:"dataReferencia"`
+	CodigoIBGE             string  `json:"codigoIbge"`
+	Municipio              string  `json:"nomeMunicipio"`
+	UF                     string  `json:"uf"`
+	Valor                  float64 `json:"valor"`
+	QuantidadeBeneficiados int     `json:"quantidadeBeneficiados"`
+}
+
+// BolsaFamiliaResponse represents the API response for Bolsa Família aggregates.
+type BolsaFamiliaResponse struct {
+	Registros []BolsaFamiliaMunicipio `json:"registros"`
+	PageCount int                     `json:"registrosNaPagina"`
+	PageInfo
+	CodigoIBGE string `json:"codigoIbge"`
+	MesAno     string `json:"mesAno"`
+	Source     string `json:"source"`
+}
+
+// SearchBolsaFamilia returns the Novo Bolsa Família disbursement aggregates for
+// a municipality (7-digit IBGE code) in a MM/YYYY month, defaulting to last month.
+func (c *Client) SearchBolsaFamilia(ctx context.Context, codigoIbge, mesAno string, page, pageSize int) (*BolsaFamiliaResponse, error) {
+	params, err := bolsaFamiliaParams(codigoIbge, mesAno, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	page, pageSize = queryPage(params)
+
+	body, err := c.doRequest(ctx, "/novo-bolsa-familia-por-municipio", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var registros []BolsaFamiliaMunicipio
+	if err := decodeList(body, &registros); err != nil {
+		return nil, err
+	}
+	period, _ := time.Parse("200601", params.Get("mesAno"))
+
+	return &BolsaFamiliaResponse{
+		Registros:  registros,
+		PageCount:  len(registros),
+		PageInfo:   newPageInfo(page, pageSize, len(registros)),
+		CodigoIBGE: codigoIbge,
+		MesAno:     period.Format(MesAnoLayout),
+		Source:     "portal_transparencia_api",
+	}, nil
+}
+
+// bolsaFamiliaParams builds the query of SearchBolsaFamilia, validating its
+// arguments.
+func bolsaFamiliaParams(codigoIbge, mesAno string, page, pageSize int) (url.Values, error) {
+	if len(codigoIbge) != 7 || strings.Trim(codigoIbge, "0123456789") != "" {
+		return nil, fmt.Errorf("invalid IBGE municipality code %q: must have 7 digits", codigoIbge)
+	}
+	if mesAno == "" {
+		mesAno = previousMonth()
+	}
+	period, err := parseMesAno(mesAno)
+	if err != nil {
+		return nil, err
+	}
+	page, pageSize = pageBounds(page, pageSize)
+
+	params := url.Values{}
+	params.Set("codigoIbge", codigoIbge)
+	params.Set("mesAno", period.Format("200601"))
+	params.Set("pagina", fmt.Sprintf("%d", page))
+	params.Set("tamanhoPagina", fmt.Sprintf("%d", pageSize))
+	return params, nil
+}
+
+// SearchBolsaFamiliaURL returns the URL SearchBolsaFamilia would request with
+// the same arguments, without calling the API.
+func (c *Client) SearchBolsaFamiliaURL(codigoIbge, mesAno string, page, pageSize int) (string, error) {
+	params, err := bolsaFamiliaParams(codigoIbge, mesAno, page, pageSize)
+	if err != nil {
+		return "", err
+	}
+	return c.requestURL("/novo-bolsa-familia-por-municipio", params), nil
+}
+
+// SanctionReport consolidates the sanction registries for a single company.
+// Sanctioned is nil when no record was found but a registry could not be
+// checked, since the company may be listed in that one.
+type SanctionReport struct {
+	CNPJ       string            `json:"cnpj"`
+	Sanctioned *bool             `json:"sancionada"`
+	CEIS       []CEIS            `json:"ceis"`
+	CNEP       []CNEP            `json:"cnep"`
+	CEPIM      []CEPIM           `json:"cepim"`
+	Errors     map[string]string `json:"erros,omitempty"`
+	Source     string            `json:"source"`
+}
+
+// ScreenCompany checks a CNPJ against CEIS, CNEP and CEPIM concurrently.
+// Only records of that CNPJ are kept, in case a registry ignores the cnpj
+// filter. A failing registry is reported in Errors instead of failing the
+// whole screening; an error is only returned when every registry fails.
+func (c *Client) ScreenCompany(ctx context.Context, cnpjNum string) (*SanctionReport, error) {
+	digits, err := cnpj.Validate(cnpjNum)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SanctionReport{
+		CNPJ:   digits,
+		CEIS:   []CEIS{},
+		CNEP:   []CNEP{},
+		CEPIM:  []CEPIM{},
+		Errors: map[string]string{},
+		Source: "portal_transparencia_api",
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	setErr := func(source string, err error) {
+		mu.Lock()
+		report.Errors[source] = err.Error()
+		mu.Unlock()
+	}
+
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		resp, err := c.SearchCEIS(ctx, digits, 1, 100)
+		if err != nil {
+			setErr("ceis", err)
+			return
+		}
+		for _, e := range resp.Empresas {
+			if document.NormalizeDigits(e.CNPJ) == digits {
+				report.CEIS = append(report.CEIS, e)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		resp, err := c.SearchCNEP(ctx, digits, 1, 100)
+		if err != nil {
+			setErr("cnep", err)
+			return
+		}
+		for _, e := range resp.Empresas {
+			if document.NormalizeDigits(e.CNPJ) == digits {
+				report.CNEP = append(report.CNEP, e)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		resp, err := c.SearchCEPIM(ctx, digits, 1, 100)
+		if err != nil {
+			setErr("cepim", err)
+			return
+		}
+		for _, e := range resp.Entidades {
+			if document.NormalizeDigits(e.CNPJ) == digits {
+				report.CEPIM = append(report.CEPIM, e)
+			}
+		}
+	}()
+	wg.Wait()
+
+	if len(report.Errors) == 3 {
+		return nil, fmt.Errorf("screening %s: all sanction registries failed: %v", digits, report.Errors)
+	}
+
+	sanctioned := len(report.CEIS) > 0 || len(report.CNEP) > 0 || len(report.CEPIM) > 0
+	if sanctioned || len(report.Errors) == 0 {
+		report.Sanctioned = &sanctioned
+	}
+	return report, nil
+}
+
+// Orgao represents a government organization as listed in SIAFI.
+type Orgao struct {
+	Codigo    string `json:"codigo"`
+	Descricao string `json:"descricao"`
+}
+
+// FetchOrgaos retrieves the full list of organizations from the
+// /orgaos-siafi endpoint, reading pages until an empty one is returned.
+func (c *Client) FetchOrgaos(ctx context.Context) ([]Orgao, error) {
+	var orgaos []Orgao
+	for page := 1; page <= maxOrgaoPages; page++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		params := url.Values{}
+		params.Set("pagina", fmt.Sprintf("%d", page))
+
+		body, err := c.doRequest(ctx, "/orgaos-siafi", params)
+		if err != nil {
+			return nil, err
+		}
+
+		var pageOrgaos []Orgao
+		if err := decodeList(body, &pageOrgaos); err != nil {
+			return nil, err
+		}
+		if len(pageOrgaos) == 0 {
+			break
+		}
+		orgaos = append(orgaos, pageOrgaos...)
+	}
+	return orgaos, nil
+}
+
+// cachedOrgaos returns the organization list, fetching it on first use.
+// Concurrent callers share one fetch, and the lock is not held while it
+// runs. A failed fetch is remembered for orgaosRetryDelay so that lookups do
+// not retry all pages each time.
+func (c *Client) cachedOrgaos(ctx context.Context) ([]Orgao, error) {
+	c.orgaosMu.Lock()
+	orgaos, err, failedAt := c.orgaos, c.orgaosErr, c.orgaosErrAt
+	c.orgaosMu.Unlock()
+	if orgaos != nil {
+		return orgaos, nil
+	}
+	if err != nil && time.Since(failedAt) < orgaosRetryDelay {
+		return nil, err
+	}
+
+	v, err := c.sharedOrgaoFetch(ctx, "orgaos", func(ctx context.Context) (interface{}, error) {
+		orgaos, err := c.FetchOrgaos(ctx)
+		if err == nil && len(orgaos) == 0 {
+			err = fmt.Errorf("empty organization list")
+		}
+
+		c.orgaosMu.Lock()
+		defer c.orgaosMu.Unlock()
+		if err != nil {
+			c.orgaosErr, c.orgaosErrAt = err, time.Now()
+			return nil, err
+		}
+		c.orgaos, c.orgaosErr = orgaos, nil
+		return orgaos, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]Orgao), nil
+}
+
+// cachedOrgaoName looks up a single organization code on /orgaos-siafi,
+// caching the answer per code. A failed lookup is remembered for
+// orgaosRetryDelay, like a failed list fetch.
+func (c *Client) cachedOrgaoName(ctx context.Context, code string) (string, error) {
+	c.orgaosMu.Lock()
+	lookup, ok := c.orgaoNames[code]
+	c.orgaosMu.Unlock()
+	if ok && (lookup.err == nil || time.Since(lookup.at) < orgaosRetryDelay) {
+		return lookup.name, lookup.err
+	}
+
+	v, err := c.sharedOrgaoFetch(ctx, "orgao:"+code, func(ctx context.Context) (interface{}, error) {
+		params := url.Values{}
+		params.Set("codigo", code)
+		params.Set("pagina", "1")
+
+		var name string
+		body, err := c.doRequest(ctx, "/orgaos-siafi", params)
+		if err == nil {
+			var orgaos []Orgao
+			err = decodeList(body, &orgaos)
+			for _, o := range orgaos {
+				if o.Codigo == code {
+					name = o.Descricao
+					break
+				}
+			}
+		}
+
+		c.orgaosMu.Lock()
+		defer c.orgaosMu.Unlock()
+		if c.orgaoNames == nil {
+			c.orgaoNames = map[string]orgaoLookup{}
+		}
+		c.orgaoNames[code] = orgaoLookup{name: name, err: err, at: time.Now()}
+		return name, err
+	})
+	if err != nil {
+		return "", err
+	}
+	return v.(string), nil
+}
+
+// sharedOrgaoFetch runs fetch once for all concurrent callers with the same
+// key. The fetch is detached from ctx, so a caller giving up does not fail
+// the others waiting on it; that caller alone returns ctx.Err().
+func (c *Client) sharedOrgaoFetch(ctx context.Context, key string, fetch func(context.Context) (interface{}, error)) (interface{}, error) {
+	ch := c.orgaosFlight.DoChan(key, func() (interface{}, error) {
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), orgaosFetchTimeout)
+		defer cancel()
+		return fetch(fetchCtx)
+	})
+	select {
+	case res := <-ch:
+		return res.Val, res.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// knownOrgaosList converts KnownOrgaos into a list sorted by code.
+func knownOrgaosList() []Orgao {
+	result := make([]Orgao, 0, len(KnownOrgaos))
+	for code, name := range KnownOrgaos {
+		result = append(result, Orgao{Codigo: code, Descricao: name})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Codigo < result[j].Codigo })
+	return result
+}
+
+// orgaoName resolves an organization code to its name. It uses the SIAFI
+// list when ListOrgaos already cached it, then KnownOrgaos, and only then
+// looks the single code up on the API.
+func (c *Client) orgaoName(ctx context.Context, code string) string {
+	c.orgaosMu.Lock()
+	orgaos := c.orgaos
+	c.orgaosMu.Unlock()
+	for _, o := range orgaos {
+		if o.Codigo == code {
+			return o.Descricao
+		}
+	}
+	if name, ok := KnownOrgaos[code]; ok {
+		return name
+	}
+	if name, err := c.cachedOrgaoName(ctx, code); err == nil && name != "" {
+		return name
+	}
+	return "Orgao Desconhecido"
+}
+
+// OrgaosResponse represents the list of organization codes.
+type OrgaosResponse struct {
+	Orgaos []Orgao `json:"orgaos"`
+	Total  int     `json:"total"`
+	Source string  `json:"source"`
+}
+
+// ListOrgaos returns the organization codes from the Portal API, or the
+// hardcoded KnownOrgaos when the list cannot be fetched.
+func (c *Client) ListOrgaos(ctx context.Context) *OrgaosResponse {
+	orgaos, err := c.cachedOrgaos(ctx)
+	source := "portal_transparencia_api"
+	if err != nil {
+		orgaos = knownOrgaosList()
+		source = "known_orgaos"
+	}
+	return &OrgaosResponse{
+		Orgaos: orgaos,
+		Total:  len(orgaos),
+		Source: source,
 	}
-	return result
 }
@@ -0,0 +1,1604 @@
+package transparencia
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anderson-ufrj/mcp-brasil/pkg/apierror"
+	"github.com/anderson-ufrj/mcp-brasil/pkg/paging"
+)
+
+// newTestClient returns a client whose requests are served by h, with
+// retries disabled.
+func newTestClient(t *testing.T, h http.HandlerFunc, opts ...Option) *Client {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return NewClient("test-key", append([]Option{WithBaseURL(srv.URL), WithRetry(1, 0)}, opts...)...)
+}
+
+// replyWith answers requests to path with body, recording the last query
+// in *query, and every other request (such as the organization list) with
+// an empty list.
+func replyWith(path, body string, query *url.Values) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			w.Write([]byte(`[]`))
+			return
+		}
+		if query != nil {
+			*query = r.URL.Query()
+		}
+		w.Write([]byte(body))
+	}
+}
+
+func TestSearchContractsDateRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		start     string
+		end       string
+		wantStart string
+		wantEnd   string
+		wantErr   string
+	}{
+		{name: "no dates"},
+		{name: "start only", start: "2024-01-15", wantStart: "15/01/2024"},
+		{name: "end only", end: "2024-03-31", wantEnd: "31/03/2024"},
+		{name: "both", start: "2024-01-01", end: "2024-01-31", wantStart: "01/01/2024", wantEnd: "31/01/2024"},
+		{name: "same day", start: "2024-01-01", end: "2024-01-01", wantStart: "01/01/2024", wantEnd: "01/01/2024"},
+		{name: "bad start", start: "15/01/2024", wantErr: "invalid start date"},
+		{name: "bad end", end: "2024-13-01", wantErr: "invalid end date"},
+		{name: "start after end", start: "2024-02-01", end: "2024-01-01", wantErr: "is after end date"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query url.Values
+			c := newTestClient(t, replyWith("/contratos", `[]`, &query))
+			_, err := c.SearchContracts(context.Background(), "36000", tt.start, tt.end, "", 1, 10)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				if query != nil {
+					t.Fatalf("request sent despite invalid dates: %v", query)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for key, want := range map[string]string{"dataInicial": tt.wantStart, "dataFinal": tt.wantEnd} {
+				if _, set := query[key]; set != (want != "") {
+					t.Errorf("%s set = %v, want %v", key, set, want != "")
+				}
+				if got := query.Get(key); got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchContractsSupplierCNPJ(t *testing.T) {
+	tests := []struct {
+		name    string
+		cnpj    string
+		want    string
+		wantErr bool
+	}{
+		{name: "not set", cnpj: ""},
+		{name: "digits", cnpj: "11222333000181", want: "11222333000181"},
+		{name: "formatted", cnpj: "11.222.333/0001-81", want: "11222333000181"},
+		{name: "bad check digit", cnpj: "11.222.333/0001-80", wantErr: true},
+		{name: "too short", cnpj: "1122233300018", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query url.Values
+			requests := 0
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				replyWith("/contratos", `[]`, &query)(w, r)
+			})
+			_, err := c.SearchContracts(context.Background(), "36000", "", "", tt.cnpj, 1, 10)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				if requests != 0 {
+					t.Fatalf("%d requests sent for an invalid CNPJ", requests)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, set := query["cnpjContratado"]; set != (tt.want != "") {
+				t.Errorf("cnpjContratado set = %v, want %v", set, tt.want != "")
+			}
+			if got := query.Get("cnpjContratado"); got != tt.want {
+				t.Errorf("cnpjContratado = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchCNEP(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantCount int
+		wantMsg   bool
+	}{
+		{
+			name:      "populated",
+			body:      `[{"cnpjSancionado":"11222333000181","razaoSocialSancionado":"ACME LTDA","tipoSancao":"Multa","valorMulta":15000.5,"dataInicioSancao":"01/02/2023"}]`,
+			wantCount: 1,
+		},
+		{name: "empty list", body: `[]`, wantMsg: true},
+		{name: "null", body: `null`, wantMsg: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query url.Values
+			c := newTestClient(t, replyWith("/cnep", tt.body, &query))
+			resp, err := c.SearchCNEP(context.Background(), "11.222.333/0001-81", 1, 10)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := query.Get("cnpj"); got != "11222333000181" {
+				t.Errorf("cnpj = %q, want digits only", got)
+			}
+			if resp.Empresas == nil || len(resp.Empresas) != tt.wantCount || resp.PageCount != tt.wantCount {
+				t.Fatalf("got %d records (count %d), want %d", len(resp.Empresas), resp.PageCount, tt.wantCount)
+			}
+			if (resp.Message != "") != tt.wantMsg {
+				t.Errorf("message = %q, want set %v", resp.Message, tt.wantMsg)
+			}
+			if tt.wantCount > 0 {
+				e := resp.Empresas[0]
+				if e.RazaoSocial != "ACME LTDA" || e.TipoSancao != "Multa" || e.ValorMulta != 15000.5 || e.DataInicioSancao != "01/02/2023" {
+					t.Errorf("record = %+v", e)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchCEPIM(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantCount int
+	}{
+		{
+			name:      "populated",
+			body:      `[{"cnpjEntidade":"11222333000181","razaoSocialEntidade":"INSTITUTO X","numeroConvenio":"123456","motivoImpedimento":"Omissão no dever de prestar contas","orgaoSuperior":"Ministério da Saúde"}]`,
+			wantCount: 1,
+		},
+		{name: "empty", body: `[]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, replyWith("/cepim", tt.body, nil))
+			resp, err := c.SearchCEPIM(context.Background(), "11222333000181", 1, 10)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Entidades == nil || len(resp.Entidades) != tt.wantCount {
+				t.Fatalf("got %d records, want %d", len(resp.Entidades), tt.wantCount)
+			}
+			if tt.wantCount == 0 {
+				if resp.Message == "" {
+					t.Error("empty response has no message")
+				}
+				return
+			}
+			e := resp.Entidades[0]
+			if e.RazaoSocial != "INSTITUTO X" || e.Convenio != "123456" || e.Motivo == "" || e.OrgaoSuperior != "Ministério da Saúde" {
+				t.Errorf("record = %+v", e)
+			}
+		})
+	}
+}
+
+func TestScreenCompany(t *testing.T) {
+	const hit = `[{"cnpjSancionado":"11222333000181","razaoSocialSancionado":"ACME LTDA"}]`
+	const other = `[{"cnpjSancionado":"99888777000166","razaoSocialSancionado":"OUTRA LTDA"}]`
+	yes, no := true, false
+	tests := []struct {
+		name           string
+		bodies         map[string]string // path -> body; missing paths fail with 503
+		wantSanctioned *bool
+		wantRecords    int
+		wantErrors     []string
+		wantErr        bool
+	}{
+		{
+			name:           "all clear",
+			bodies:         map[string]string{"/ceis": `[]`, "/cnep": `[]`, "/cepim": `[]`},
+			wantSanctioned: &no,
+		},
+		{
+			name:           "single hit",
+			bodies:         map[string]string{"/ceis": `[]`, "/cnep": hit, "/cepim": `[]`},
+			wantSanctioned: &yes,
+			wantRecords:    1,
+		},
+		{
+			name:           "unfiltered upstream",
+			bodies:         map[string]string{"/ceis": other, "/cnep": other, "/cepim": `[{"cnpjEntidade":"99888777000166"}]`},
+			wantSanctioned: &no,
+		},
+		{
+			name:       "partial failure",
+			bodies:     map[string]string{"/ceis": `[]`, "/cnep": `[]`},
+			wantErrors: []string{"cepim"},
+		},
+		{
+			name:           "partial failure with a hit",
+			bodies:         map[string]string{"/ceis": hit},
+			wantSanctioned: &yes,
+			wantRecords:    1,
+			wantErrors:     []string{"cnep", "cepim"},
+		},
+		{
+			name:    "all fail",
+			bodies:  map[string]string{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				body, ok := tt.bodies[r.URL.Path]
+				if !ok {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.Write([]byte(body))
+			})
+			report, err := c.ScreenCompany(context.Background(), "11.222.333/0001-81")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error when every registry fails")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			switch {
+			case tt.wantSanctioned == nil && report.Sanctioned != nil:
+				t.Errorf("Sanctioned = %v, want nil", *report.Sanctioned)
+			case tt.wantSanctioned != nil && (report.Sanctioned == nil || *report.Sanctioned != *tt.wantSanctioned):
+				t.Errorf("Sanctioned = %v, want %v", report.Sanctioned, *tt.wantSanctioned)
+			}
+			if got := len(report.CEIS) + len(report.CNEP) + len(report.CEPIM); got != tt.wantRecords {
+				t.Errorf("got %d records, want %d", got, tt.wantRecords)
+			}
+			if len(report.Errors) != len(tt.wantErrors) {
+				t.Fatalf("Errors = %v, want %v", report.Errors, tt.wantErrors)
+			}
+			for _, source := range tt.wantErrors {
+				if report.Errors[source] == "" {
+					t.Errorf("no error reported for %s", source)
+				}
+			}
+			if report.CEIS == nil || report.CNEP == nil || report.CEPIM == nil {
+				t.Errorf("nil record lists in %+v", report)
+			}
+		})
+	}
+}
+
+func TestScreenCompanyInvalidCNPJ(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s", r.URL.Path)
+	})
+	if _, err := c.ScreenCompany(context.Background(), "11.222.333/0001-80"); err == nil {
+		t.Fatal("expected an error for an invalid CNPJ")
+	}
+}
+
+func TestSearchDespesas(t *testing.T) {
+	const body = `[{"ano":2024,"codigoOrgao":"36000","orgao":"Ministério da Saúde","fase":"Pagamento","documento":"2024OB000123","data":"15/03/2024","codigoFavorecido":"11222333000181","nomeFavorecido":"ACME LTDA","valorEmpenhado":1000.5,"valorLiquidado":900.25,"valorPago":800}]`
+	currentYear := time.Now().Format("2006")
+
+	tests := []struct {
+		name    string
+		ano     string
+		wantAno string
+		wantErr bool
+	}{
+		{name: "explicit year", ano: "2024", wantAno: "2024"},
+		{name: "defaults to current year", ano: "", wantAno: currentYear},
+		{name: "invalid year", ano: "24", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query url.Values
+			c := newTestClient(t, replyWith("/despesas/por-orgao", body, &query))
+			resp, err := c.SearchDespesas(context.Background(), "36000", tt.ano, 1, 10)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := query.Get("ano"); got != tt.wantAno || resp.Ano != tt.wantAno {
+				t.Errorf("ano = %q (response %q), want %q", got, resp.Ano, tt.wantAno)
+			}
+			if len(resp.Despesas) != 1 {
+				t.Fatalf("got %d records, want 1", len(resp.Despesas))
+			}
+			d := resp.Despesas[0]
+			if d.Fase != "Pagamento" || d.Documento != "2024OB000123" || d.NomeFavorecido != "ACME LTDA" ||
+				d.ValorEmpenhado != 1000.5 || d.ValorLiquidado != 900.25 || d.ValorPago != 800 {
+				t.Errorf("record = %+v", d)
+			}
+		})
+	}
+}
+
+func TestSearchViagens(t *testing.T) {
+	const trip = `[{"id":1,"nomeServidor":"FULANO DE TAL","cargo":"Analista","destino":"Brasília/DF","motivo":"Reunião técnica","dataInicioAfastamento":"10/03/2024","dataFimAfastamento":"12/03/2024","valorDiarias":850.5,"valorPassagens":1200,"valorTotal":2050.5}]`
+	today := time.Now()
+
+	tests := []struct {
+		name      string
+		start     string
+		end       string
+		body      string
+		wantStart string
+		wantEnd   string
+		wantCount int
+		wantErr   bool
+	}{
+		{
+			name: "typical record", start: "2024-03-01", end: "2024-03-31", body: trip,
+			wantStart: "01/03/2024", wantEnd: "31/03/2024", wantCount: 1,
+		},
+		{
+			name: "empty result", start: "2024-03-01", end: "2024-03-31", body: `[]`,
+			wantStart: "01/03/2024", wantEnd: "31/03/2024",
+		},
+		{
+			name: "defaults to last 30 days", body: `[]`,
+			wantStart: today.AddDate(0, 0, -30).Format(apiDateLayout), wantEnd: today.Format(apiDateLayout),
+		},
+		{name: "start after end", start: "2024-04-01", end: "2024-03-01", wantErr: true},
+		{name: "invalid date", start: "01/03/2024", end: "2024-03-31", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query url.Values
+			c := newTestClient(t, replyWith("/viagens", tt.body, &query))
+			resp, err := c.SearchViagens(context.Background(), "36000", tt.start, tt.end, 1, 10)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if query.Get("dataIdaDe") != tt.wantStart || query.Get("dataIdaAte") != tt.wantEnd {
+				t.Errorf("window = %s..%s, want %s..%s", query.Get("dataIdaDe"), query.Get("dataIdaAte"), tt.wantStart, tt.wantEnd)
+			}
+			if start, _ := time.Parse(DateLayout, resp.DataInicio); start.Format(apiDateLayout) != tt.wantStart {
+				t.Errorf("DataInicio = %q, want the queried %s", resp.DataInicio, tt.wantStart)
+			}
+			if end, _ := time.Parse(DateLayout, resp.DataFim); end.Format(apiDateLayout) != tt.wantEnd {
+				t.Errorf("DataFim = %q, want the queried %s", resp.DataFim, tt.wantEnd)
+			}
+			if resp.Viagens == nil || len(resp.Viagens) != tt.wantCount {
+				t.Fatalf("got %d records, want %d", len(resp.Viagens), tt.wantCount)
+			}
+			if tt.wantCount == 0 {
+				return
+			}
+			v := resp.Viagens[0]
+			if v.NomeServidor != "FULANO DE TAL" || v.Destino != "Brasília/DF" || v.ValorDiarias != 850.5 || v.ValorPassagens != 1200 || v.DataFim != "12/03/2024" {
+				t.Errorf("record = %+v", v)
+			}
+		})
+	}
+}
+
+func TestSearchCartoes(t *testing.T) {
+	const body = `[{"id":7,"nomePortador":"FULANO DE TAL","cpfPortador":"***.456.789-**","nomeEstabelecimento":"PAPELARIA CENTRAL","cnpjEstabelecimento":"11222333000181","dataTransacao":"05/02/2024","valorTransacao":123.45,"tipoCartao":"CPGF"}]`
+	lastMonth := time.Now().AddDate(0, -1, 0).Format(MesAnoLayout)
+
+	tests := []struct {
+		name      string
+		start     string
+		end       string
+		wantStart string
+		wantEnd   string
+		wantErr   bool
+	}{
+		{name: "explicit period", start: "01/2024", end: "03/2024", wantStart: "01/2024", wantEnd: "03/2024"},
+		{name: "defaults to last month", wantStart: lastMonth, wantEnd: lastMonth},
+		{name: "wrong layout", start: "2024-01", end: "03/2024", wantErr: true},
+		{name: "invalid month", start: "13/2024", end: "13/2024", wantErr: true},
+		{name: "start after end", start: "04/2024", end: "03/2024", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query url.Values
+			c := newTestClient(t, replyWith("/cartoes", body, &query))
+			resp, err := c.SearchCartoes(context.Background(), "36000", tt.start, tt.end, 1, 10)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if query.Get("mesExtratoInicio") != tt.wantStart || query.Get("mesExtratoFim") != tt.wantEnd {
+				t.Errorf("period = %s..%s, want %s..%s", query.Get("mesExtratoInicio"), query.Get("mesExtratoFim"), tt.wantStart, tt.wantEnd)
+			}
+			if len(resp.Gastos) != 1 {
+				t.Fatalf("got %d records, want 1", len(resp.Gastos))
+			}
+			g := resp.Gastos[0]
+			if g.Portador != "FULANO DE TAL" || g.Estabelecimento != "PAPELARIA CENTRAL" || g.Valor != 123.45 || g.DataTransacao != "05/02/2024" || g.TipoCartao != "CPGF" {
+				t.Errorf("record = %+v", g)
+			}
+		})
+	}
+}
+
+func TestSearchEmendasOptionalParams(t *testing.T) {
+	currentYear := time.Now().Format("2006")
+
+	tests := []struct {
+		name  string
+		ano   string
+		autor string
+		uf    string
+		want  map[string]string // expected query; keys absent here must not be sent
+	}{
+		{name: "defaults", want: map[string]string{"ano": currentYear}},
+		{name: "year", ano: "2023", want: map[string]string{"ano": "2023"}},
+		{name: "author", ano: "2023", autor: "FULANO", want: map[string]string{"ano": "2023", "nomeAutor": "FULANO"}},
+		{name: "state", ano: "2023", uf: "MG", want: map[string]string{"ano": "2023", "uf": "MG"}},
+		{name: "all", ano: "2023", autor: "FULANO", uf: "MG", want: map[string]string{"ano": "2023", "nomeAutor": "FULANO", "uf": "MG"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query url.Values
+			c := newTestClient(t, replyWith("/emendas", `[]`, &query))
+			resp, err := c.SearchEmendas(context.Background(), tt.ano, tt.autor, tt.uf, 1, 10)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, key := range []string{"ano", "nomeAutor", "uf"} {
+				want, sent := tt.want[key]
+				if _, set := query[key]; set != sent {
+					t.Errorf("%s set = %v, want %v", key, set, sent)
+				}
+				if got := query.Get(key); got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+			if resp.Ano != tt.want["ano"] {
+				t.Errorf("Ano = %q, want %q", resp.Ano, tt.want["ano"])
+			}
+		})
+	}
+}
+
+func TestSearchEmendasInvalidYear(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s", r.URL.Path)
+	})
+	if _, err := c.SearchEmendas(context.Background(), "23", "", "", 1, 10); err == nil {
+		t.Fatal("expected an error for a 2-digit year")
+	}
+}
+
+func TestSearchBolsaFamilia(t *testing.T) {
+	const body = `[{"id":1,"dataReferencia":"01/03/2024","codigoIbge":"3106200","nomeMunicipio":"BELO HORIZONTE","uf":"MG","valor":98765432.1,"quantidadeBeneficiados":150000}]`
+
+	tests := []struct {
+		name       string
+		codigoIbge string
+		mesAno     string
+		wantMesAno string
+		wantErr    bool
+	}{
+		{name: "valid", codigoIbge: "3106200", mesAno: "03/2024", wantMesAno: "202403"},
+		{name: "defaults to last month", codigoIbge: "3106200", wantMesAno: time.Now().AddDate(0, -1, 0).Format("200601")},
+		{name: "short code", codigoIbge: "310620", mesAno: "03/2024", wantErr: true},
+		{name: "non-digit code", codigoIbge: "31062OO", mesAno: "03/2024", wantErr: true},
+		{name: "bad period", codigoIbge: "3106200", mesAno: "2024-03", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query url.Values
+			requests := 0
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				replyWith("/novo-bolsa-familia-por-municipio", body, &query)(w, r)
+			})
+			resp, err := c.SearchBolsaFamilia(context.Background(), tt.codigoIbge, tt.mesAno, 1, 10)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				if requests != 0 {
+					t.Fatalf("%d requests sent for invalid arguments", requests)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if query.Get("codigoIbge") != tt.codigoIbge || query.Get("mesAno") != tt.wantMesAno {
+				t.Errorf("query = %v", query)
+			}
+			if period, _ := time.Parse(MesAnoLayout, resp.MesAno); period.Format("200601") != tt.wantMesAno {
+				t.Errorf("MesAno = %q, want the queried %s", resp.MesAno, tt.wantMesAno)
+			}
+			if len(resp.Registros) != 1 {
+				t.Fatalf("got %d records, want 1", len(resp.Registros))
+			}
+			r := resp.Registros[0]
+			if r.Municipio != "BELO HORIZONTE" || r.Valor != 98765432.1 || r.QuantidadeBeneficiados != 150000 {
+				t.Errorf("record = %+v", r)
+			}
+		})
+	}
+}
+
+func TestOrgaoNameCache(t *testing.T) {
+	var lookups, listPages int
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/orgaos-siafi" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		if r.URL.Query().Get("codigo") == "20101" {
+			lookups++
+			w.Write([]byte(`[{"codigo":"20101","descricao":"Presidência da República"}]`))
+			return
+		}
+		listPages++
+		if r.URL.Query().Get("pagina") == "1" {
+			w.Write([]byte(`[{"codigo":"20101","descricao":"Presidência da República"}]`))
+			return
+		}
+		w.Write([]byte(`[]`))
+	})
+
+	for _, code := range []string{"20101", "20101", "20101", "36000"} {
+		resp, err := c.SearchContracts(context.Background(), code, "", "", "", 1, 10)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if code == "20101" && resp.OrgaoName != "Presidência da República" {
+			t.Errorf("OrgaoName = %q", resp.OrgaoName)
+		}
+	}
+	if lookups != 1 || listPages != 0 {
+		t.Errorf("%d code lookups and %d list pages, want one lookup and no list fetch", lookups, listPages)
+	}
+
+	list := c.ListOrgaos(context.Background())
+	if list.Source != "portal_transparencia_api" || list.Total != 1 {
+		t.Errorf("ListOrgaos = %+v", list)
+	}
+	c.ListOrgaos(context.Background())
+	if listPages != 2 {
+		t.Errorf("%d list pages, want 2 (one fetch of two pages)", listPages)
+	}
+}
+
+func TestOrgaoNameFallback(t *testing.T) {
+	orgaoRequests := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/orgaos-siafi" {
+			orgaoRequests++
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`[]`))
+	})
+
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"36000", "Ministério da Saúde"},
+		{"99999", "Orgao Desconhecido"},
+		{"99999", "Orgao Desconhecido"},
+	}
+	for _, tt := range tests {
+		resp, err := c.SearchContracts(context.Background(), tt.code, "", "", "", 1, 10)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.OrgaoName != tt.want {
+			t.Errorf("OrgaoName(%s) = %q, want %q", tt.code, resp.OrgaoName, tt.want)
+		}
+	}
+	if orgaoRequests != 1 {
+		t.Errorf("%d organization requests, want the failed lookup remembered after 1", orgaoRequests)
+	}
+
+	for i := 0; i < 2; i++ {
+		list := c.ListOrgaos(context.Background())
+		if list.Source != "known_orgaos" || list.Total != len(KnownOrgaos) {
+			t.Errorf("ListOrgaos = %+v, want the known organizations", list)
+		}
+	}
+	if orgaoRequests != 2 {
+		t.Errorf("%d organization requests, want the failed list fetch remembered after 1", orgaoRequests)
+	}
+}
+
+func TestOrgaoNameDetachedFetch(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var requests atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		w.Write([]byte(`[{"codigo":"20101","descricao":"Presidência da República"}]`))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan string)
+	go func() { done <- c.orgaoName(ctx, "20101") }()
+	<-started
+	cancel()
+	if got := <-done; got != "Orgao Desconhecido" {
+		t.Errorf("cancelled caller got %q", got)
+	}
+
+	close(release)
+	if got := c.orgaoName(context.Background(), "20101"); got != "Presidência da República" {
+		t.Errorf("OrgaoName = %q after the first caller gave up", got)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("%d requests, want the cancelled caller's fetch to finish and be shared", n)
+	}
+}
+
+func TestPageInfo(t *testing.T) {
+	searches := []struct {
+		name   string
+		path   string
+		search func(c *Client, page, pageSize int) (PageInfo, int, error)
+	}{
+		{"contracts", "/contratos", func(c *Client, page, pageSize int) (PageInfo, int, error) {
+			r, err := c.SearchContracts(context.Background(), "36000", "", "", "", page, pageSize)
+			if err != nil {
+				return PageInfo{}, 0, err
+			}
+			return r.PageInfo, r.PageCount, nil
+		}},
+		{"servidores", "/servidores", func(c *Client, page, pageSize int) (PageInfo, int, error) {
+			r, err := c.SearchServidores(context.Background(), "FULANO", page, pageSize)
+			if err != nil {
+				return PageInfo{}, 0, err
+			}
+			return r.PageInfo, r.PageCount, nil
+		}},
+		{"convenios", "/convenios", func(c *Client, page, pageSize int) (PageInfo, int, error) {
+			r, err := c.SearchConvenios(context.Background(), "MG", page, pageSize)
+			if err != nil {
+				return PageInfo{}, 0, err
+			}
+			return r.PageInfo, r.PageCount, nil
+		}},
+		{"ceis", "/ceis", func(c *Client, page, pageSize int) (PageInfo, int, error) {
+			r, err := c.SearchCEIS(context.Background(), "", page, pageSize)
+			if err != nil {
+				return PageInfo{}, 0, err
+			}
+			return r.PageInfo, r.PageCount, nil
+		}},
+	}
+	pages := []struct {
+		name         string
+		body         string
+		wantCount    int
+		wantHasMore  bool
+		wantNextPage int
+	}{
+		{"full page", `[{},{}]`, 2, true, 4},
+		{"short page", `[{}]`, 1, false, 0},
+	}
+	for _, s := range searches {
+		for _, p := range pages {
+			t.Run(s.name+"/"+p.name, func(t *testing.T) {
+				c := newTestClient(t, replyWith(s.path, p.body, nil))
+				info, count, err := s.search(c, 3, 2)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if count != p.wantCount {
+					t.Errorf("PageCount = %d, want %d", count, p.wantCount)
+				}
+				if info.HasMore != p.wantHasMore || info.NextPage != p.wantNextPage {
+					t.Errorf("HasMore = %v, NextPage = %d, want %v, %d", info.HasMore, info.NextPage, p.wantHasMore, p.wantNextPage)
+				}
+				if info.Page != 3 || info.PageSize != 2 {
+					t.Errorf("Page = %d, PageSize = %d, want 3, 2", info.Page, info.PageSize)
+				}
+			})
+		}
+	}
+}
+
+func TestSearchLicitacoes(t *testing.T) {
+	const record = `[{
+		"id": 301,
+		"licitacao": {"numero": "000122024", "numeroProcesso": "25000.012345/2024-10", "objeto": "Aquisição de vacinas"},
+		"modalidadeLicitacao": {"descricao": "Pregão - Registro de Preço"},
+		"situacaoCompra": {"descricao": "Publicado"},
+		"valor": 1250000.75,
+		"dataAbertura": "15/03/2024",
+		"dataResultadoCompra": "02/04/2024"
+	}]`
+	tests := []struct {
+		name      string
+		orgao     string
+		start     string
+		end       string
+		wantQuery url.Values
+		wantErr   string
+	}{
+		{
+			name: "window", orgao: "26000", start: "2024-03-01", end: "2024-03-31",
+			wantQuery: url.Values{"codigoOrgao": {"26000"}, "dataInicial": {"01/03/2024"}, "dataFinal": {"31/03/2024"}, "pagina": {"1"}},
+		},
+		{
+			name: "default orgao", start: "2024-03-01", end: "2024-03-15",
+			wantQuery: url.Values{"codigoOrgao": {"36000"}, "dataInicial": {"01/03/2024"}, "dataFinal": {"15/03/2024"}},
+		},
+		{
+			name: "default window",
+			wantQuery: url.Values{
+				"codigoOrgao": {"36000"},
+				"dataInicial": {time.Now().AddDate(0, 0, -30).Format(apiDateLayout)},
+				"dataFinal":   {time.Now().Format(apiDateLayout)},
+			},
+		},
+		{name: "window too wide", start: "2024-01-01", end: "2024-03-01", wantErr: "exceeds 31 days"},
+		{name: "reversed", start: "2024-03-31", end: "2024-03-01", wantErr: "start date"},
+		{name: "bad date", start: "01/03/2024", end: "2024-03-31", wantErr: "YYYY-MM-DD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query url.Values
+			c := newTestClient(t, replyWith("/licitacoes", record, &query))
+			resp, err := c.SearchLicitacoes(context.Background(), tt.orgao, tt.start, tt.end, 1, 15)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				if query != nil {
+					t.Errorf("request sent for an invalid window: %v", query)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for key, want := range tt.wantQuery {
+				if got := query.Get(key); got != want[0] {
+					t.Errorf("%s = %q, want %q", key, got, want[0])
+				}
+			}
+			want := Licitacao{
+				ID:            301,
+				Numero:        "000122024",
+				Processo:      "25000.012345/2024-10",
+				Modalidade:    "Pregão - Registro de Preço",
+				Objeto:        "Aquisição de vacinas",
+				Situacao:      "Publicado",
+				Valor:         1250000.75,
+				DataAbertura:  "15/03/2024",
+				DataResultado: "02/04/2024",
+			}
+			if len(resp.Licitacoes) != 1 || resp.Licitacoes[0] != want {
+				t.Errorf("licitacoes = %+v, want [%+v]", resp.Licitacoes, want)
+			}
+			if queryDate(query, "dataInicial") != resp.DataInicio || queryDate(query, "dataFinal") != resp.DataFim {
+				t.Errorf("window = %s..%s, want the queried one", resp.DataInicio, resp.DataFim)
+			}
+			if resp.OrgaoCode != tt.wantQuery.Get("codigoOrgao") || tt.start != "" && (resp.DataInicio != tt.start || resp.DataFim != tt.end) {
+				t.Errorf("response = %+v", resp)
+			}
+		})
+	}
+}
+
+func TestMissingAPIKey(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+	c := NewClient("", WithBaseURL(srv.URL), WithRetry(1, 0))
+
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"SearchCEIS", func() error { _, err := c.SearchCEIS(ctx, "", 1, 15); return err }},
+		{"SearchServidores", func() error { _, err := c.SearchServidores(ctx, "MARIA", 1, 15); return err }},
+		{"SearchDespesas", func() error { _, err := c.SearchDespesas(ctx, "26000", "2024", 1, 15); return err }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			if !errors.Is(err, apierror.ErrUnauthorized) || !strings.Contains(err.Error(), "TRANSPARENCY_API_KEY is required") {
+				t.Errorf("error = %v, want ErrUnauthorized naming TRANSPARENCY_API_KEY", err)
+			}
+		})
+	}
+	if calls != 0 {
+		t.Errorf("%d requests sent without an API key", calls)
+	}
+}
+
+func TestNewPageInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		page     int
+		pageSize int
+		count    int
+		want     PageInfo
+	}{
+		{name: "full page", page: 1, pageSize: 15, count: 15, want: PageInfo{Page: 1, PageSize: 15, HasMore: true, NextPage: 2}},
+		{name: "full later page", page: 4, pageSize: 15, count: 15, want: PageInfo{Page: 4, PageSize: 15, HasMore: true, NextPage: 5}},
+		{name: "short page", page: 2, pageSize: 15, count: 7, want: PageInfo{Page: 2, PageSize: 15}},
+		{name: "empty page", page: 3, pageSize: 15, count: 0, want: PageInfo{Page: 3, PageSize: 15, Message: paging.NoResults(3, false)}},
+		{name: "no page size", page: 1, pageSize: 0, count: 0, want: PageInfo{Page: 1, Message: paging.NoResults(1, false)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newPageInfo(tt.page, tt.pageSize, tt.count); got != tt.want {
+				t.Errorf("newPageInfo(%d, %d, %d) = %+v, want %+v", tt.page, tt.pageSize, tt.count, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	const key = "s3cr3t-api-key"
+	var gotKey string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey = r.Header.Get("chave-api-dados")
+		w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	c := NewClient(key, WithBaseURL(srv.URL), WithRetry(1, 0), WithLogger(logger))
+	if _, err := c.SearchCEIS(context.Background(), "", 1, 15); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gotKey != key {
+		t.Errorf("API key header = %q, want it sent", gotKey)
+	}
+	out := logs.String()
+	for _, want := range []string{"upstream request", "method=GET", "/ceis?", "status=200", "duration="} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, key) {
+		t.Errorf("log contains the API key:\n%s", out)
+	}
+
+	logs.Reset()
+	quiet := NewClient(key, WithBaseURL(srv.URL), WithRetry(1, 0), WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	if _, err := quiet.SearchCEIS(context.Background(), "", 1, 15); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("info-level logger wrote:\n%s", logs.String())
+	}
+}
+
+func TestMaskCPF(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "12345678909", want: "***.456.789-**"},
+		{in: "123.456.789-09", want: "***.456.789-**"},
+		{in: "***.456.789-**", want: "***.456.789-**"},
+		{in: "1234", want: "***.***.***-**"},
+		{in: "", want: ""},
+	}
+	for _, tt := range tests {
+		if got := MaskCPF(tt.in); got != tt.want {
+			t.Errorf("MaskCPF(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMaskCPFsInJSON(t *testing.T) {
+	raw := json.RawMessage(`[{"cpf":"123.456.789-09","nome":"MARIA","servidor":{"cpfFormatado":"12345678909"},"cpfCnpj":"11222333000181","valor":1.10}]`)
+	masked, err := MaskCPFsInJSON(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := string(masked)
+	for _, want := range []string{`"cpf":"***.456.789-**"`, `"cpfFormatado":"***.456.789-**"`, `"cpfCnpj":"11222333000181"`, `"valor":1.10`, `"nome":"MARIA"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("masked JSON lacks %s: %s", want, got)
+		}
+	}
+	if strings.Contains(got, "12345678909") || strings.Contains(got, "123.456.789-09") {
+		t.Errorf("masked JSON keeps a raw CPF: %s", got)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		params   map[string]string
+		wantURI  string
+		wantErr  string
+	}{
+		{name: "params", endpoint: "/peti", params: map[string]string{"mesAno": "202401", "codigoIbge": "3550308", "pagina": "1"}, wantURI: "/peti?codigoIbge=3550308&mesAno=202401&pagina=1"},
+		{name: "nested path", endpoint: "/bpc-por-municipio", wantURI: "/bpc-por-municipio"},
+		{name: "escaped value", endpoint: "/servidores", params: map[string]string{"nome": "JOSÉ & FILHOS"}, wantURI: "/servidores?nome=JOS%C3%89+%26+FILHOS"},
+		{name: "relative", endpoint: "peti", wantErr: "must start with a single /"},
+		{name: "scheme relative", endpoint: "//evil.example/x", wantErr: "must start with a single /"},
+		{name: "traversal", endpoint: "/../admin", wantErr: "must not contain .."},
+		{name: "encoded traversal", endpoint: "/%2e%2e/admin", wantErr: "must not contain .."},
+		{name: "double encoded", endpoint: "/%252e%252e/admin", wantErr: "percent-encoded twice"},
+		{name: "query in endpoint", endpoint: "/peti?mesAno=1", wantErr: "must not contain ?"},
+		{name: "backslash", endpoint: "/peti\\admin", wantErr: "must not contain ?, # or"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uris []string
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				uris = append(uris, r.URL.RequestURI())
+				w.Write([]byte(`[{"id":1}]`))
+			})
+			raw, err := c.Query(context.Background(), tt.endpoint, tt.params)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				if len(uris) != 0 {
+					t.Errorf("requests sent for a rejected endpoint: %v", uris)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(uris) != 1 || uris[0] != tt.wantURI {
+				t.Errorf("requests = %v, want [%s]", uris, tt.wantURI)
+			}
+			if string(raw) != `[{"id":1}]` {
+				t.Errorf("raw = %s", raw)
+			}
+		})
+	}
+
+	t.Run("not JSON", func(t *testing.T) {
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`<html>manutenção</html>`))
+		})
+		if _, err := c.Query(context.Background(), "/peti", nil); err == nil || !strings.Contains(err.Error(), "did not return JSON") {
+			t.Errorf("error = %v, want a non-JSON error", err)
+		}
+	})
+}
+
+// contractPages serves total contracts at /contratos, exportPageSize per
+// page, with an initial value of 10 times their id, failing page failPage
+// when it is not zero.
+func contractPages(total, failPage int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/contratos" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("pagina"))
+		if page == failPage {
+			http.Error(w, "erro", http.StatusInternalServerError)
+			return
+		}
+		contracts := []Contract{}
+		for id := (page-1)*exportPageSize + 1; id <= total && id <= page*exportPageSize; id++ {
+			contracts = append(contracts, Contract{
+				ID:           int64(id),
+				Numero:       fmt.Sprintf("%d/2024", id),
+				Objeto:       "Aquisição de insumos, lote " + strconv.Itoa(id),
+				ValorInicial: float64(id * 10),
+				CodigoOrgao:  r.URL.Query().Get("codigoOrgao"),
+			})
+		}
+		json.NewEncoder(w).Encode(contracts)
+	}
+}
+
+func TestExportContracts(t *testing.T) {
+	tests := []struct {
+		name, format string
+		total        int
+		wantPages    int
+		wantLines    int
+	}{
+		{name: "csv", format: "csv", total: 3, wantPages: 1, wantLines: 4},
+		{name: "default format", total: 3, wantPages: 1, wantLines: 4},
+		{name: "csv two pages", format: "csv", total: exportPageSize + 2, wantPages: 2, wantLines: exportPageSize + 3},
+		{name: "ndjson", format: "ndjson", total: exportPageSize + 2, wantPages: 2, wantLines: exportPageSize + 2},
+		{name: "empty csv", format: "csv", wantPages: 1, wantLines: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			c := newTestClient(t, contractPages(tt.total, 0), WithExportDir(dir))
+
+			result, err := c.ExportContracts(context.Background(), "36000", "contratos.out", tt.format)
+			if err != nil {
+				t.Fatalf("ExportContracts: %v", err)
+			}
+			if result.Records != tt.total || result.Pages != tt.wantPages || result.Truncated {
+				t.Errorf("result = %+v, want %d records in %d pages", result, tt.total, tt.wantPages)
+			}
+			root, _ := filepath.EvalSymlinks(dir)
+			if want := filepath.Join(root, "contratos.out"); result.Path != want {
+				t.Errorf("path = %q, want %q", result.Path, want)
+			}
+
+			data, err := os.ReadFile(result.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+			if len(lines) != tt.wantLines {
+				t.Fatalf("file has %d lines, want %d", len(lines), tt.wantLines)
+			}
+			if tt.format == "ndjson" {
+				var ct Contract
+				if err := json.Unmarshal([]byte(lines[len(lines)-1]), &ct); err != nil || ct.ID != int64(tt.total) {
+					t.Errorf("last record = %+v, %v", ct, err)
+				}
+				return
+			}
+			if lines[0] != strings.Join(contractCSVHeader, ",") {
+				t.Errorf("header = %q", lines[0])
+			}
+			if tt.total > 0 && !strings.HasPrefix(lines[1], `1,1/2024,"Aquisição de insumos, lote 1",`) {
+				t.Errorf("first row = %q", lines[1])
+			}
+		})
+	}
+}
+
+func TestExportContractsErrors(t *testing.T) {
+	tests := []struct {
+		name, path, format string
+		noDir, existing    bool
+		failPage           int
+	}{
+		{name: "no export dir", path: "out.csv", noDir: true},
+		{name: "absolute path", path: "/tmp/out.csv"},
+		{name: "escapes dir", path: "../out.csv"},
+		{name: "empty path"},
+		{name: "unknown format", path: "out.xml", format: "xml"},
+		{name: "existing file", path: "out.csv", existing: true},
+		{name: "upstream failure", path: "out.csv", failPage: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var opts []Option
+			if !tt.noDir {
+				opts = append(opts, WithExportDir(dir))
+			}
+			if tt.existing {
+				if err := os.WriteFile(filepath.Join(dir, tt.path), []byte("keep"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			c := newTestClient(t, contractPages(exportPageSize+1, tt.failPage), opts...)
+
+			if _, err := c.ExportContracts(context.Background(), "36000", tt.path, tt.format); err == nil {
+				t.Fatal("ExportContracts succeeded")
+			}
+			entries, _ := os.ReadDir(dir)
+			wantFiles := 0
+			if tt.existing {
+				wantFiles = 1
+			}
+			if len(entries) != wantFiles {
+				t.Errorf("export dir holds %d files, want %d", len(entries), wantFiles)
+			}
+		})
+	}
+}
+
+func TestAggregateContracts(t *testing.T) {
+	tests := []struct {
+		total     int
+		wantPages int
+		want      ContractStats
+	}{
+		{total: 0, wantPages: 1, want: ContractStats{}},
+		{total: 1, wantPages: 1, want: ContractStats{Count: 1, Total: 10, Average: 10, Min: 10, Max: 10}},
+		{total: 4, wantPages: 1, want: ContractStats{Count: 4, Total: 100, Average: 25, Min: 10, Max: 40}},
+		{
+			total:     exportPageSize + 1,
+			wantPages: 2,
+			want: ContractStats{
+				Count:   exportPageSize + 1,
+				Total:   10 * float64((exportPageSize+1)*(exportPageSize+2)/2),
+				Average: 5 * float64(exportPageSize+2),
+				Min:     10,
+				Max:     10 * float64(exportPageSize+1),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.total), func(t *testing.T) {
+			c := newTestClient(t, contractPages(tt.total, 0))
+			stats, err := c.AggregateContracts(context.Background(), "36000")
+			if err != nil {
+				t.Fatalf("AggregateContracts: %v", err)
+			}
+			if stats.Count != tt.want.Count || stats.Total != tt.want.Total || stats.Average != tt.want.Average ||
+				stats.Min != tt.want.Min || stats.Max != tt.want.Max {
+				t.Errorf("stats = %+v, want %+v", stats, tt.want)
+			}
+			if stats.Pages != tt.wantPages || stats.Truncated || stats.OrgaoCode != "36000" {
+				t.Errorf("pages = %d, truncated = %v, orgao = %q", stats.Pages, stats.Truncated, stats.OrgaoCode)
+			}
+			if _, err := json.Marshal(stats); err != nil {
+				t.Errorf("stats do not encode: %v", err)
+			}
+		})
+	}
+
+	c := newTestClient(t, contractPages(exportPageSize+1, 2))
+	if _, err := c.AggregateContracts(context.Background(), "36000"); err == nil {
+		t.Error("AggregateContracts ignored a failed page")
+	}
+}
+
+func TestDocumentFormatting(t *testing.T) {
+	cpfs := []string{"123.456.789-09", "12345678909", " 123 456 789 09 ", "123.456.789/09"}
+	for _, cpf := range cpfs {
+		var path string
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			w.Write([]byte(`[]`))
+		})
+		resp, err := c.GetServidorRemuneracao(context.Background(), cpf, "01/2024")
+		if err != nil {
+			t.Fatalf("GetServidorRemuneracao(%q): %v", cpf, err)
+		}
+		if path != "/servidores/12345678909/remuneracao" || resp.CPF != "12345678909" {
+			t.Errorf("cpf %q: path = %s, CPF = %s", cpf, path, resp.CPF)
+		}
+	}
+	if _, err := newTestClient(t, replyWith("", "", nil)).GetServidorRemuneracao(context.Background(), "123.456.789", ""); err == nil {
+		t.Error("a 9-digit CPF was accepted")
+	}
+
+	cnpjs := []string{"33.000.167/0001-01", "33000167000101", " 33 000 167 0001 01 "}
+	for _, cnpjNum := range cnpjs {
+		var query url.Values
+		c := newTestClient(t, replyWith("/ceis", `[]`, &query))
+		if _, err := c.SearchCEIS(context.Background(), cnpjNum, 1, 15); err != nil {
+			t.Fatalf("SearchCEIS(%q): %v", cnpjNum, err)
+		}
+		if got := query.Get("cnpj"); got != "33000167000101" {
+			t.Errorf("CEIS cnpj %q sent as %q", cnpjNum, got)
+		}
+
+		c = newTestClient(t, replyWith("/contratos", `[]`, &query))
+		if _, err := c.SearchContracts(context.Background(), "36000", "", "", cnpjNum, 1, 15); err != nil {
+			t.Fatalf("SearchContracts(%q): %v", cnpjNum, err)
+		}
+		if got := query.Get("cnpjContratado"); got != "33000167000101" {
+			t.Errorf("contracts cnpjContratado %q sent as %q", cnpjNum, got)
+		}
+
+		c = newTestClient(t, replyWith("/contratos/cpf-cnpj", `[]`, &query))
+		if _, err := c.SearchContractsBySupplier(context.Background(), cnpjNum, 1, 15); err != nil {
+			t.Fatalf("SearchContractsBySupplier(%q): %v", cnpjNum, err)
+		}
+		if got := query.Get("cpfCnpj"); got != "33000167000101" {
+			t.Errorf("supplier cpfCnpj %q sent as %q", cnpjNum, got)
+		}
+	}
+}
+
+func TestErrorObjectBody(t *testing.T) {
+	searches := []struct {
+		name string
+		call func(c *Client) error
+	}{
+		{"SearchContracts", func(c *Client) error {
+			_, err := c.SearchContracts(context.Background(), "36000", "", "", "", 1, 15)
+			return err
+		}},
+		{"SearchContractsBySupplier", func(c *Client) error {
+			_, err := c.SearchContractsBySupplier(context.Background(), "33000167000101", 1, 15)
+			return err
+		}},
+		{"SearchServidores", func(c *Client) error { _, err := c.SearchServidores(context.Background(), "MARIA", 1, 15); return err }},
+		{"SearchConvenios", func(c *Client) error { _, err := c.SearchConvenios(context.Background(), "MG", 1, 15); return err }},
+		{"SearchCEIS", func(c *Client) error { _, err := c.SearchCEIS(context.Background(), "", 1, 15); return err }},
+		{"SearchCNEP", func(c *Client) error { _, err := c.SearchCNEP(context.Background(), "", 1, 15); return err }},
+		{"SearchCEPIM", func(c *Client) error { _, err := c.SearchCEPIM(context.Background(), "", 1, 15); return err }},
+		{"SearchDespesas", func(c *Client) error {
+			_, err := c.SearchDespesas(context.Background(), "36000", "2024", 1, 15)
+			return err
+		}},
+		{"SearchViagens", func(c *Client) error {
+			_, err := c.SearchViagens(context.Background(), "36000", "2024-03-01", "2024-03-31", 1, 15)
+			return err
+		}},
+		{"SearchLicitacoes", func(c *Client) error {
+			_, err := c.SearchLicitacoes(context.Background(), "36000", "2024-03-01", "2024-03-31", 1, 15)
+			return err
+		}},
+		{"SearchCartoes", func(c *Client) error {
+			_, err := c.SearchCartoes(context.Background(), "36000", "01/2024", "03/2024", 1, 15)
+			return err
+		}},
+		{"SearchEmendas", func(c *Client) error {
+			_, err := c.SearchEmendas(context.Background(), "2024", "", "", 1, 15)
+			return err
+		}},
+		{"SearchBolsaFamilia", func(c *Client) error {
+			_, err := c.SearchBolsaFamilia(context.Background(), "3106200", "03/2024", 1, 15)
+			return err
+		}},
+	}
+	bodies := []struct {
+		name, body string
+		want       string // substring of the error, or "" for success
+	}{
+		{name: "message", body: `{"message":"Parâmetro codigoOrgao inválido"}`, want: "Parâmetro codigoOrgao inválido"},
+		{name: "error", body: `{"error":"Período máximo excedido"}`, want: "Período máximo excedido"},
+		{name: "other object", body: `{"status":400}`, want: "parsing response"},
+		{name: "empty", body: ``, want: "parsing response"},
+		{name: "blank", body: " \n", want: "parsing response"},
+		{name: "null", body: `null`},
+	}
+	for _, s := range searches {
+		for _, b := range bodies {
+			t.Run(s.name+"/"+b.name, func(t *testing.T) {
+				c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte(b.body))
+				})
+				err := s.call(c)
+				if b.want == "" {
+					if err != nil {
+						t.Errorf("error = %v, want an empty result", err)
+					}
+					return
+				}
+				if err == nil || !strings.Contains(err.Error(), b.want) {
+					t.Errorf("error = %v, want it to mention %q", err, b.want)
+				}
+				if b.want != "parsing response" && !errors.Is(err, apierror.ErrUpstream) {
+					t.Errorf("error = %v, want ErrUpstream", err)
+				}
+			})
+		}
+	}
+}
+
+func TestAggregateDespesasByFavorecido(t *testing.T) {
+	acme := Despesa{CodigoFavorecido: "11222333000181", NomeFavorecido: "ACME LTDA", ValorPago: 1}
+	full := make([]Despesa, exportPageSize)
+	for i := range full {
+		full[i] = acme
+	}
+	pages := [][]Despesa{
+		full,
+		{
+			{CodigoFavorecido: "33000167000101", NomeFavorecido: "PETROBRAS", ValorPago: 600},
+			{NomeFavorecido: "FULANO DE TAL", ValorPago: 150.25},
+			acme,
+			{NomeFavorecido: "FULANO DE TAL", ValorPago: 49.75},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		limit     int
+		pages     [][]Despesa
+		endless   bool
+		want      []FavorecidoTotal
+		wantTotal float64
+		wantCount int
+		wantPages int
+		truncated bool
+	}{
+		{
+			name:  "ranked",
+			pages: pages,
+			want: []FavorecidoTotal{
+				{CodigoFavorecido: "33000167000101", NomeFavorecido: "PETROBRAS", ValorPago: 600, Count: 1},
+				{CodigoFavorecido: "11222333000181", NomeFavorecido: "ACME LTDA", ValorPago: 501, Count: 501},
+				{NomeFavorecido: "FULANO DE TAL", ValorPago: 200, Count: 2},
+			},
+			wantTotal: 1301, wantCount: 3, wantPages: 2,
+		},
+		{
+			name:  "limited",
+			limit: 1,
+			pages: pages,
+			want: []FavorecidoTotal{
+				{CodigoFavorecido: "33000167000101", NomeFavorecido: "PETROBRAS", ValorPago: 600, Count: 1},
+			},
+			wantTotal: 1301, wantCount: 3, wantPages: 2,
+		},
+		{name: "no expenses", want: []FavorecidoTotal{}, wantPages: 1},
+		{
+			name:    "page cap",
+			endless: true,
+			want: []FavorecidoTotal{
+				{CodigoFavorecido: "11222333000181", NomeFavorecido: "ACME LTDA", ValorPago: maxDespesasPages * exportPageSize, Count: maxDespesasPages * exportPageSize},
+			},
+			wantTotal: maxDespesasPages * exportPageSize, wantCount: 1, wantPages: maxDespesasPages, truncated: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/despesas/por-orgao" {
+					w.Write([]byte(`[]`))
+					return
+				}
+				page, _ := strconv.Atoi(r.URL.Query().Get("pagina"))
+				switch {
+				case tt.endless:
+					json.NewEncoder(w).Encode(full)
+				case page <= len(tt.pages):
+					json.NewEncoder(w).Encode(tt.pages[page-1])
+				default:
+					w.Write([]byte(`[]`))
+				}
+			})
+
+			resp, err := c.AggregateDespesasByFavorecido(context.Background(), "36000", "2024", tt.limit)
+			if err != nil {
+				t.Fatalf("AggregateDespesasByFavorecido: %v", err)
+			}
+			if resp.Favorecidos == nil || len(resp.Favorecidos) != len(tt.want) {
+				t.Fatalf("favorecidos = %+v, want %+v", resp.Favorecidos, tt.want)
+			}
+			for i, f := range resp.Favorecidos {
+				if f != tt.want[i] {
+					t.Errorf("rank %d = %+v, want %+v", i+1, f, tt.want[i])
+				}
+			}
+			if resp.ValorPagoTotal != tt.wantTotal || resp.TotalFavorecidos != tt.wantCount {
+				t.Errorf("total = %v over %d recipients, want %v over %d", resp.ValorPagoTotal, resp.TotalFavorecidos, tt.wantTotal, tt.wantCount)
+			}
+			if resp.Pages != tt.wantPages || resp.Truncated != tt.truncated || resp.OrgaoCode != "36000" || resp.Ano != "2024" {
+				t.Errorf("pages = %d, truncated = %v, orgao = %q, ano = %q", resp.Pages, resp.Truncated, resp.OrgaoCode, resp.Ano)
+			}
+		})
+	}
+}
+
+func TestSearchURLs(t *testing.T) {
+	var uris []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uris = append(uris, r.URL.RequestURI())
+		w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+	c := NewClient("test-key", WithBaseURL(srv.URL), WithRetry(1, 0))
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		url    func() (string, error)
+		search func() error
+	}{
+		{
+			"SearchContracts",
+			func() (string, error) {
+				return c.SearchContractsURL("36000", "2024-01-01", "2024-03-31", "33.000.167/0001-01", 2, 50)
+			},
+			func() error {
+				_, err := c.SearchContracts(ctx, "36000", "2024-01-01", "2024-03-31", "33.000.167/0001-01", 2, 50)
+				return err
+			},
+		},
+		{
+			"SearchContractsBySupplier",
+			func() (string, error) { return c.SearchContractsBySupplierURL("33000167000101", 1, 15) },
+			func() error { _, err := c.SearchContractsBySupplier(ctx, "33000167000101", 1, 15); return err },
+		},
+		{
+			"SearchServidores",
+			func() (string, error) { return c.SearchServidoresURL("MARIA JOSÉ", 1, 15) },
+			func() error { _, err := c.SearchServidores(ctx, "MARIA JOSÉ", 1, 15); return err },
+		},
+		{
+			"SearchConvenios",
+			func() (string, error) { return c.SearchConveniosURL("mg", 3, 20) },
+			func() error { _, err := c.SearchConvenios(ctx, "mg", 3, 20); return err },
+		},
+		{
+			"SearchCEIS",
+			func() (string, error) { return c.SearchCEISURL("33.000.167/0001-01", 1, 15) },
+			func() error { _, err := c.SearchCEIS(ctx, "33.000.167/0001-01", 1, 15); return err },
+		},
+		{
+			"SearchCNEP",
+			func() (string, error) { return c.SearchCNEPURL("33000167000101", 1, 15) },
+			func() error { _, err := c.SearchCNEP(ctx, "33000167000101", 1, 15); return err },
+		},
+		{
+			"SearchCEPIM",
+			func() (string, error) { return c.SearchCEPIMURL("", 1, 15) },
+			func() error { _, err := c.SearchCEPIM(ctx, "", 1, 15); return err },
+		},
+		{
+			"SearchDespesas",
+			func() (string, error) { return c.SearchDespesasURL("36000", "2024", 1, 15) },
+			func() error { _, err := c.SearchDespesas(ctx, "36000", "2024", 1, 15); return err },
+		},
+		{
+			"SearchViagens",
+			func() (string, error) { return c.SearchViagensURL("36000", "2024-03-01", "2024-03-31", 1, 15) },
+			func() error { _, err := c.SearchViagens(ctx, "36000", "2024-03-01", "2024-03-31", 1, 15); return err },
+		},
+		{
+			"SearchLicitacoes",
+			func() (string, error) { return c.SearchLicitacoesURL("36000", "2024-03-01", "2024-03-31", 1, 15) },
+			func() error {
+				_, err := c.SearchLicitacoes(ctx, "36000", "2024-03-01", "2024-03-31", 1, 15)
+				return err
+			},
+		},
+		{
+			"SearchCartoes",
+			func() (string, error) { return c.SearchCartoesURL("36000", "01/2024", "03/2024", 1, 15) },
+			func() error { _, err := c.SearchCartoes(ctx, "36000", "01/2024", "03/2024", 1, 15); return err },
+		},
+		{
+			"SearchEmendas",
+			func() (string, error) { return c.SearchEmendasURL("2024", "FULANO", "MG", 1, 15) },
+			func() error { _, err := c.SearchEmendas(ctx, "2024", "FULANO", "MG", 1, 15); return err },
+		},
+		{
+			"SearchBolsaFamilia",
+			func() (string, error) { return c.SearchBolsaFamiliaURL("3106200", "03/2024", 1, 15) },
+			func() error { _, err := c.SearchBolsaFamilia(ctx, "3106200", "03/2024", 1, 15); return err },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uris = nil
+			got, err := tt.url()
+			if err != nil {
+				t.Fatalf("URL: %v", err)
+			}
+			if len(uris) != 0 {
+				t.Fatalf("building the URL sent requests: %v", uris)
+			}
+			if err := tt.search(); err != nil {
+				t.Fatalf("search: %v", err)
+			}
+			if len(uris) == 0 || srv.URL+uris[0] != got {
+				t.Errorf("URL = %s, search requested %v", got, uris)
+			}
+			if strings.Contains(got, "test-key") {
+				t.Errorf("URL %s leaks the API key", got)
+			}
+		})
+	}
+
+	// Invalid arguments fail the same way without a request.
+	uris = nil
+	_, urlErr := c.SearchViagensURL("36000", "2024-04-01", "2024-03-01", 1, 15)
+	_, searchErr := c.SearchViagens(ctx, "36000", "2024-04-01", "2024-03-01", 1, 15)
+	if urlErr == nil || searchErr == nil || urlErr.Error() != searchErr.Error() || len(uris) != 0 {
+		t.Errorf("URL error = %v, search error = %v, requests = %v", urlErr, searchErr, uris)
+	}
+}
+
+func TestSearchEmptyPage(t *testing.T) {
+	ctx := context.Background()
+	searches := []struct {
+		name   string
+		search func(c *Client) (any, error)
+	}{
+		{"SearchContracts", func(c *Client) (any, error) {
+			return c.SearchContracts(ctx, "36000", "2024-01-01", "2024-03-31", "", 1, 15)
+		}},
+		{"SearchContractsBySupplier", func(c *Client) (any, error) { return c.SearchContractsBySupplier(ctx, "33000167000101", 1, 15) }},
+		{"SearchServidores", func(c *Client) (any, error) { return c.SearchServidores(ctx, "MARIA", 1, 15) }},
+		{"SearchConvenios", func(c *Client) (any, error) { return c.SearchConvenios(ctx, "MG", 1, 15) }},
+		{"SearchCEIS", func(c *Client) (any, error) { return c.SearchCEIS(ctx, "33000167000101", 1, 15) }},
+		{"SearchCNEP", func(c *Client) (any, error) { return c.SearchCNEP(ctx, "33000167000101", 1, 15) }},
+		{"SearchCEPIM", func(c *Client) (any, error) { return c.SearchCEPIM(ctx, "", 1, 15) }},
+		{"SearchDespesas", func(c *Client) (any, error) { return c.SearchDespesas(ctx, "36000", "2024", 1, 15) }},
+		{"SearchViagens", func(c *Client) (any, error) {
+			return c.SearchViagens(ctx, "36000", "2024-03-01", "2024-03-31", 1, 15)
+		}},
+		{"SearchLicitacoes", func(c *Client) (any, error) {
+			return c.SearchLicitacoes(ctx, "36000", "2024-03-01", "2024-03-31", 1, 15)
+		}},
+		{"SearchCartoes", func(c *Client) (any, error) { return c.SearchCartoes(ctx, "36000", "01/2024", "03/2024", 1, 15) }},
+		{"SearchEmendas", func(c *Client) (any, error) { return c.SearchEmendas(ctx, "2024", "", "MG", 1, 15) }},
+		{"SearchBolsaFamilia", func(c *Client) (any, error) { return c.SearchBolsaFamilia(ctx, "3106200", "03/2024", 1, 15) }},
+	}
+	for _, body := range []string{`[]`, `null`} {
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		})
+		for _, s := range searches {
+			t.Run(s.name+"/"+strconv.Quote(body), func(t *testing.T) {
+				resp, err := s.search(c)
+				if err != nil {
+					t.Fatalf("empty page returned error: %v", err)
+				}
+				b, err := json.Marshal(resp)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var got map[string]any
+				if err := json.Unmarshal(b, &got); err != nil {
+					t.Fatal(err)
+				}
+				if msg, _ := got["message"].(string); msg == "" {
+					t.Errorf("no message in %s", b)
+				}
+				if got["registrosNaPagina"] != 0.0 || got["temMaisPaginas"] != false || got["proximaPagina"] != 0.0 {
+					t.Errorf("empty page reported as %s", b)
+				}
+				for k, v := range got {
+					if v == nil {
+						t.Errorf("%s is null in %s", k, b)
+					}
+				}
+			})
+		}
+	}
+}